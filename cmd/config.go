@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagConfigSources bool
+	flagConfigOutput  string
+)
+
+// flagSettings maps the flags that override settings to their keys.
+var flagSettings = map[string]string{
+	"dry-run":       "sync.dry_run",
+	"google-admin":  "google.admin_email",
+	"google-creds":  "google.credentials_file",
+	"members-group": "google.members_group",
+	"owners-group":  "google.owners_group",
+	"github-org":    "github.organization",
+	"github-token":  "github.token",
+	"log-level":     "log.level",
+	"log-format":    "log.format",
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the effective configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with secrets redacted",
+	Example: `  sync config show
+  sync config show --sources --profile acme,prod --ssm-path /github-sync/prod
+  sync config show --sources --output json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(flagConfigOutput); err != nil {
+			return err
+		}
+		_, sources, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		for name, key := range flagSettings {
+			if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+				var value any = flag.Value.String()
+				if name == "dry-run" {
+					value = flagDryRun
+				}
+				sources.Override(key, value, "flag:--"+name)
+			}
+		}
+		return printSettings(sources, flagConfigSources, flagConfigOutput)
+	},
+}
+
+func printSettings(sources *config.Sources, withSources bool, output string) error {
+	settings := sources.Settings()
+	if output == "json" {
+		if !withSources {
+			for i := range settings {
+				settings[i].Source = ""
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if withSources {
+			return enc.Encode(struct {
+				Layers   []string         `json:"layers"`
+				Settings []config.Setting `json:"settings"`
+			}{sources.Layers, settings})
+		}
+		return enc.Encode(settings)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if withSources {
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	} else {
+		fmt.Fprintln(w, "KEY\tVALUE")
+	}
+	for _, s := range settings {
+		if withSources {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, formatSetting(s.Value), s.Source)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", s.Key, formatSetting(s.Value))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if withSources {
+		fmt.Fprintf(os.Stdout, "layers: %s\n", strings.Join(sources.Layers, " < "))
+	}
+	return nil
+}
+
+func formatSetting(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case []string:
+		return strings.Join(v, ",")
+	case []any:
+		parts := make([]string, len(v))
+		for i, part := range v {
+			parts[i] = fmt.Sprint(part)
+		}
+		return strings.Join(parts, ",")
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&flagConfigSources, "sources", false, "Show where each value came from and the layers read")
+	configShowCmd.Flags().StringVar(&flagConfigOutput, "output", "text", "Output format: text or json")
+
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
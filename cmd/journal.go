@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagJournalUser   string
+	flagJournalAction string
+	flagJournalSince  string
+	flagJournalUntil  string
+	flagJournalLimit  int
+	flagJournalOutput string
+
+	journalOpener func(ctx context.Context, cfg *config.Config) (interfaces.ActionJournal, error)
+)
+
+// SetJournalOpener registers how the journal command opens the action journal.
+func SetJournalOpener(opener func(ctx context.Context, cfg *config.Config) (interfaces.ActionJournal, error)) {
+	journalOpener = opener
+}
+
+var journalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "Query the journal of executed sync actions",
+	Example: `  sync journal --user jane@example.com
+  sync journal --action remove --since 2025-01-01 --until 2025-02-01
+  sync journal --output json --limit 500`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		overrideConfigFromFlags(cmd, cfg)
+
+		filter := models.JournalFilter{
+			Org:    cfg.GitHub.Organization,
+			User:   flagJournalUser,
+			Action: models.ActionType(flagJournalAction),
+			Limit:  flagJournalLimit,
+		}
+		if filter.Since, err = parseJournalDate(flagJournalSince, false); err != nil {
+			return fmt.Errorf("--since: %w", err)
+		}
+		if filter.Until, err = parseJournalDate(flagJournalUntil, true); err != nil {
+			return fmt.Errorf("--until: %w", err)
+		}
+
+		if journalOpener == nil {
+			return fmt.Errorf("action journal is not configured")
+		}
+		ctx := context.Background()
+		j, err := journalOpener(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		entries, err := j.Query(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		switch flagJournalOutput {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				if err := enc.Encode(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		case "text":
+			return printJournal(entries)
+		default:
+			return fmt.Errorf("--output must be text or json")
+		}
+	},
+}
+
+// parseJournalDate accepts RFC 3339 timestamps or YYYY-MM-DD dates. A bare date
+// used as an upper bound includes the whole day.
+func parseJournalDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func printJournal(entries []models.JournalEntry) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tRUN\tACTOR\tACTION\tUSER\tBEFORE\tAFTER\tOUTCOME")
+	for _, e := range entries {
+		user := e.Email
+		if e.Username != "" && e.Username != e.Email {
+			user += " (" + e.Username + ")"
+		}
+		outcome := string(e.Outcome)
+		if e.HTTPStatus != 0 {
+			outcome = fmt.Sprintf("%s (%d)", outcome, e.HTTPStatus)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.RunID, e.Actor, e.Action, user,
+			formatMembership(e.Before), formatMembership(e.After), outcome)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%d entries\n", len(entries))
+	return nil
+}
+
+func formatMembership(s models.MembershipState) string {
+	if s.Role != nil {
+		return s.Status + ":" + string(*s.Role)
+	}
+	return s.Status
+}
+
+func init() {
+	journalCmd.Flags().StringVar(&flagJournalUser, "user", "", "Filter by Google email or GitHub username")
+	journalCmd.Flags().StringVar(&flagJournalAction, "action", "", "Filter by action type: invite, remove, update_role, cancel_invite")
+	journalCmd.Flags().StringVar(&flagJournalSince, "since", "", "Only entries at or after this date (YYYY-MM-DD or RFC 3339)")
+	journalCmd.Flags().StringVar(&flagJournalUntil, "until", "", "Only entries before the end of this date (YYYY-MM-DD or RFC 3339)")
+	journalCmd.Flags().IntVar(&flagJournalLimit, "limit", 100, "Maximum entries to return (0 for all)")
+	journalCmd.Flags().StringVar(&flagJournalOutput, "output", "text", "Output format: text or json")
+	rootCmd.AddCommand(journalCmd)
+}
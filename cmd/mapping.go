@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/mapping"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMappingRole   string
+	flagMappingAll    bool
+	flagMappingStatus string
+	flagMappingManual bool
+	flagMappingOutput string
+)
+
+var mappingCmd = &cobra.Command{
+	Use:   "mapping",
+	Short: "Manage Google email → GitHub login mappings by hand",
+	Long: `Manage Google email → GitHub login mappings in the invitation store.
+
+Use link when automatic matching fails (private GitHub email, no verified domain).
+Manual mappings are trusted by the sync like any resolved mapping, take precedence
+over automatic ones for the same email, and never expire.`,
+}
+
+var mappingLinkCmd = &cobra.Command{
+	Use:   "link <google-email> <github-login>",
+	Short: "Assert that a Google email belongs to a GitHub login",
+	Example: `  sync mapping link jane@example.com jane-gh
+  sync mapping link cto@example.com cto-gh --role admin`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		role := models.OrgRole(flagMappingRole)
+		if role != models.RoleMember && role != models.RoleOwner {
+			return fmt.Errorf("--role must be member or admin")
+		}
+		return withMappingStore(cmd, func(ctx context.Context, store interfaces.InvitationStore, org string) error {
+			m, replaced, err := mapping.Link(ctx, store, org, args[0], args[1], role)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "Linked %s → %s (%s)\n", m.Email, *m.GitHubLogin, m.SK)
+			for _, sk := range replaced {
+				fmt.Fprintf(os.Stdout, "Replaced earlier manual mapping %s\n", sk)
+			}
+			return nil
+		})
+	},
+}
+
+var mappingUnlinkCmd = &cobra.Command{
+	Use:   "unlink <google-email>",
+	Short: "Remove the manual mapping for a Google email",
+	Long: `Remove the manual mapping for a Google email. With --all, automatic resolved
+mappings for the email are also marked removed, so a wrong automatic match stops
+being trusted.`,
+	Example: `  sync mapping unlink jane@example.com
+  sync mapping unlink jane@example.com --all`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMappingStore(cmd, func(ctx context.Context, store interfaces.InvitationStore, org string) error {
+			result, err := mapping.Unlink(ctx, store, org, args[0], flagMappingAll)
+			for _, sk := range result.Deleted {
+				fmt.Fprintf(os.Stdout, "Deleted manual mapping %s\n", sk)
+			}
+			for _, sk := range result.Removed {
+				fmt.Fprintf(os.Stdout, "Marked %s removed\n", sk)
+			}
+			return err
+		})
+	},
+}
+
+var mappingShowCmd = &cobra.Command{
+	Use:   "show <google-email|github-login>",
+	Short: "Show every mapping for a Google email or GitHub login",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(flagMappingOutput); err != nil {
+			return err
+		}
+		return withMappingStore(cmd, func(ctx context.Context, store interfaces.InvitationStore, org string) error {
+			mappings, err := mapping.Show(ctx, store, org, args[0])
+			if err != nil {
+				return err
+			}
+			if len(mappings) == 0 && flagMappingOutput == "text" {
+				return fmt.Errorf("no mapping for %s", args[0])
+			}
+			return printMappings(mappings, flagMappingOutput)
+		})
+	},
+}
+
+var mappingListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the organization's mappings",
+	Example: `  sync mapping list
+  sync mapping list --manual
+  sync mapping list --status resolved --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(flagMappingOutput); err != nil {
+			return err
+		}
+		filter := mapping.Filter{Status: models.InvitationStatus(flagMappingStatus), ManualOnly: flagMappingManual}
+		return withMappingStore(cmd, func(ctx context.Context, store interfaces.InvitationStore, org string) error {
+			mappings, err := mapping.List(ctx, store, org, filter)
+			if err != nil {
+				return err
+			}
+			return printMappings(mappings, flagMappingOutput)
+		})
+	},
+}
+
+// withMappingStore loads the configuration, opens the configured invitation store and runs fn.
+func withMappingStore(cmd *cobra.Command, fn func(ctx context.Context, store interfaces.InvitationStore, org string) error) error {
+	cfg, err := loadStoreConfig(cmd)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	store, closeStore, err := openStore(ctx, cfg, "")
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	return fn(ctx, store, cfg.GitHub.Organization)
+}
+
+func validateOutput(output string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("--output must be text or json")
+	}
+	return nil
+}
+
+func printMappings(mappings []models.InvitationMapping, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		for _, m := range mappings {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tLOGIN\tSTATUS\tROLE\tSOURCE\tRECORD\tRESOLVED")
+	for _, m := range mappings {
+		login := "-"
+		if m.GitHubLogin != nil {
+			login = *m.GitHubLogin
+		}
+		source := "auto"
+		if m.Manual {
+			source = "manual"
+		}
+		resolved := "-"
+		if m.ResolvedAt != nil {
+			resolved = m.ResolvedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Email, login, m.Status, m.Role, source, m.SK, resolved)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%d mappings\n", len(mappings))
+	return nil
+}
+
+func init() {
+	mappingLinkCmd.Flags().StringVar(&flagMappingRole, "role", string(models.RoleMember), "Organization role recorded on the mapping: member or admin")
+	mappingUnlinkCmd.Flags().BoolVar(&flagMappingAll, "all", false, "Also mark automatic resolved mappings for the email removed")
+	mappingListCmd.Flags().StringVar(&flagMappingStatus, "status", "", "Only mappings with this status: "+strings.Join(mappingStatuses(), ", "))
+	mappingListCmd.Flags().BoolVar(&flagMappingManual, "manual", false, "Only manual mappings")
+	for _, c := range []*cobra.Command{mappingShowCmd, mappingListCmd} {
+		c.Flags().StringVar(&flagMappingOutput, "output", "text", "Output format: text or json")
+	}
+
+	mappingCmd.AddCommand(mappingLinkCmd, mappingUnlinkCmd, mappingShowCmd, mappingListCmd)
+	rootCmd.AddCommand(mappingCmd)
+}
+
+func mappingStatuses() []string {
+	return []string{
+		string(models.InvitationPending), string(models.InvitationResolved), string(models.InvitationFailed),
+		string(models.InvitationExpired), string(models.InvitationCancelled), string(models.InvitationRemoved),
+	}
+}
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -15,6 +17,8 @@ import (
 
 var (
 	cfgFile     string
+	flagProfiles []string
+	flagSSMPath  string
 	flagDryRun  bool
 	flagLogLevel  string
 	flagLogFormat string
@@ -24,13 +28,17 @@ var (
 	flagOwnersGroup  string
 	flagGitHubOrg    string
 	flagGitHubToken  string
+	flagUsers        []string
+	flagGroups       []string
 
-	lambdaHandler func(ctx context.Context, event models.LambdaEvent) (*models.LambdaResponse, error)
+	lambdaHandler func(ctx context.Context, payload json.RawMessage) (any, error)
 	runSync       func(ctx context.Context, cfg *config.Config) (*models.SyncResult, error)
 )
 
-// SetLambdaHandler registers the Lambda handler used in Lambda mode.
-func SetLambdaHandler(handler func(ctx context.Context, event models.LambdaEvent) (*models.LambdaResponse, error)) {
+// SetLambdaHandler registers the Lambda handler used in Lambda mode. It receives
+// the raw event, which may be a scheduled event, a direct invocation or an HTTP
+// request.
+func SetLambdaHandler(handler func(ctx context.Context, payload json.RawMessage) (any, error)) {
 	lambdaHandler = handler
 }
 
@@ -43,7 +51,7 @@ var rootCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync Google Workspace users to GitHub Organization",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := config.Load(cfgFile)
+		cfg, _, err := loadConfig(cmd)
 		if err != nil {
 			return err
 		}
@@ -53,16 +61,17 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
-		logger := log.NewLogger(cfg.Log.Level, cfg.Log.Format)
-		logrus.SetFormatter(logger.Formatter)
-		logrus.SetLevel(logger.Level)
-		logrus.SetOutput(logger.Out)
+		setupLogging(cfg)
 
 		if runSync == nil {
 			return fmt.Errorf("sync engine is not configured")
 		}
 
 		result, err := runSync(context.Background(), cfg)
+		if errors.Is(err, models.ErrRunLocked) {
+			logrus.WithError(err).Info("⏭️ Sync skipped: another run is in progress")
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -111,6 +120,8 @@ func printUserList(title string, users []string) {
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file path")
+	rootCmd.PersistentFlags().StringSliceVar(&flagProfiles, "profile", nil, "Profile overlays applied over the config file in order, e.g. acme,prod (default $CONFIG_PROFILES)")
+	rootCmd.PersistentFlags().StringVar(&flagSSMPath, "ssm-path", "", "Parameter Store path prefix read over the config files (default $CONFIG_SSM_PATH)")
 	rootCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", true, "Preview changes without applying")
 	rootCmd.PersistentFlags().StringVar(&flagGoogleAdmin, "google-admin", "", "Google Workspace admin email for impersonation")
 	rootCmd.PersistentFlags().StringVar(&flagGoogleCreds, "google-creds", "", "Path to Google service account JSON")
@@ -118,10 +129,33 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&flagOwnersGroup, "owners-group", "", "Google Group email for organization owners")
 	rootCmd.PersistentFlags().StringVar(&flagGitHubOrg, "github-org", "", "GitHub organization name")
 	rootCmd.PersistentFlags().StringVar(&flagGitHubToken, "github-token", "", "GitHub Personal Access Token")
+	rootCmd.Flags().StringSliceVar(&flagUsers, "user", nil, "Limit the run to these Google emails or GitHub logins (repeatable)")
+	rootCmd.Flags().StringSliceVar(&flagGroups, "group", nil, "Limit the run to the members of these Google groups (repeatable)")
 	rootCmd.PersistentFlags().StringVar(&flagLogLevel, "log-level", "", "Log level: debug, info, warn, error")
 	rootCmd.PersistentFlags().StringVar(&flagLogFormat, "log-format", "", "Log format: text or json")
 }
 
+// setupLogging applies the configured log level and format to the standard logger.
+func setupLogging(cfg *config.Config) {
+	logger := log.NewLogger(cfg.Log.Level, cfg.Log.Format)
+	logrus.SetFormatter(logger.Formatter)
+	logrus.SetLevel(logger.Level)
+	logrus.SetOutput(logger.Out)
+}
+
+// loadConfig loads the configuration layers selected by --config, --profile
+// and --ssm-path. Flags overriding settings are applied by the caller.
+func loadConfig(cmd *cobra.Command) (*config.Config, *config.Sources, error) {
+	opts := config.OptionsFromEnv(cfgFile)
+	if cmd.Flags().Changed("profile") {
+		opts.Profiles = flagProfiles
+	}
+	if cmd.Flags().Changed("ssm-path") {
+		opts.SSMPath = flagSSMPath
+	}
+	return config.LoadLayers(context.Background(), opts)
+}
+
 func isLambda() bool {
 	return os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != ""
 }
@@ -148,6 +182,12 @@ func overrideConfigFromFlags(cmd *cobra.Command, cfg *config.Config) {
 	if cmd.Flags().Changed("github-token") {
 		cfg.GitHub.Token = flagGitHubToken
 	}
+	if cmd.Flags().Changed("user") {
+		cfg.Sync.OnlyUsers = flagUsers
+	}
+	if cmd.Flags().Changed("group") {
+		cfg.Sync.OnlyGroups = flagGroups
+	}
 	if cmd.Flags().Changed("log-level") {
 		cfg.Log.Level = flagLogLevel
 	}
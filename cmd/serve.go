@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/daemon"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagServeSchedule string
+	flagServeListen   string
+
+	metricsHandler func(cfg *config.Config) http.Handler
+)
+
+// SetMetricsHandler registers the Prometheus handler the serve command mounts at
+// /metrics. It returns nil when /metrics is not served on the serve listener.
+func SetMetricsHandler(handler func(cfg *config.Config) http.Handler) {
+	metricsHandler = handler
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run syncs on a schedule and serve health endpoints until stopped",
+	Example: `  sync serve --schedule "@every 30m"
+  sync serve --schedule "CRON_TZ=Europe/Rome 0 7-19 * * 1-5" --dry-run=false`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, _, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		overrideConfigFromFlags(cmd, cfg)
+		if cmd.Flags().Changed("schedule") {
+			cfg.Serve.Schedule = flagServeSchedule
+		}
+		if cmd.Flags().Changed("listen-address") {
+			cfg.Serve.ListenAddress = flagServeListen
+		}
+		if err := config.Validate(cfg); err != nil {
+			return err
+		}
+		if err := config.ValidateServe(cfg); err != nil {
+			return err
+		}
+		setupLogging(cfg)
+
+		if runSync == nil {
+			return fmt.Errorf("sync engine is not configured")
+		}
+		d, err := daemon.New(cfg.Serve, func(ctx context.Context) (*models.SyncResult, error) {
+			runCfg := *cfg
+			return runSync(ctx, &runCfg)
+		})
+		if err != nil {
+			return err
+		}
+		if metricsHandler != nil {
+			if handler := metricsHandler(cfg); handler != nil {
+				d.Handle("GET /metrics", handler)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+		defer stop()
+		logrus.WithFields(logrus.Fields{
+			"schedule": cfg.Serve.Schedule,
+			"dry_run":  cfg.Sync.DryRun,
+		}).Info("🚀 Sync daemon started")
+		if err := d.Run(ctx); err != nil {
+			return err
+		}
+		logrus.Info("👋 Sync daemon stopped")
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&flagServeSchedule, "schedule", "", `Cron expression or "@every <duration>" (default serve.schedule)`)
+	serveCmd.Flags().StringVar(&flagServeListen, "listen-address", "", "Address serving /healthz, /readyz and /status (default serve.listen_address)")
+	rootCmd.AddCommand(serveCmd)
+}
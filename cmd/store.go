@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/storeio"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagStoreOutput string
+	flagStoreInput  string
+	flagStoreFrom   string
+	flagStoreTo     string
+
+	flagDoctorRepair bool
+	flagDoctorOutput string
+
+	storeOpener func(ctx context.Context, cfg *config.Config, backend string) (interfaces.InvitationStore, func(), error)
+	storeDoctor func(ctx context.Context, cfg *config.Config, repair bool) (*models.DoctorReport, error)
+)
+
+// SetStoreOpener registers how the store commands open an invitation store for a backend.
+// An empty backend selects the configured one.
+func SetStoreOpener(opener func(ctx context.Context, cfg *config.Config, backend string) (interfaces.InvitationStore, func(), error)) {
+	storeOpener = opener
+}
+
+// SetStoreDoctor registers the consistency check used by the store doctor command.
+func SetStoreDoctor(doctor func(ctx context.Context, cfg *config.Config, repair bool) (*models.DoctorReport, error)) {
+	storeDoctor = doctor
+}
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Back up, restore and migrate the invitation store",
+}
+
+var storeExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export invitation mappings and the audit log cursor as JSON Lines",
+	Example: `  sync store export --output mappings.jsonl
+  sync store export > mappings.jsonl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadStoreConfig(cmd)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		store, closeStore, err := openStore(ctx, cfg, "")
+		if err != nil {
+			return err
+		}
+		defer closeStore()
+
+		var w io.Writer = os.Stdout
+		if flagStoreOutput != "" && flagStoreOutput != "-" {
+			f, err := os.OpenFile(flagStoreOutput, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+			if err != nil {
+				return fmt.Errorf("creating export file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		result, err := storeio.Export(ctx, store, cfg.GitHub.Organization, w)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d invitation mappings and %d cursors for %s\n",
+			result.Invitations, result.Cursors, cfg.GitHub.Organization)
+		return nil
+	},
+}
+
+var storeImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Upsert invitation mappings from a JSON Lines export",
+	Long: `Upsert invitation mappings from a JSON Lines export into the configured store.
+Runs as a dry run unless --dry-run=false is given.`,
+	Example: `  sync store import --input mappings.jsonl
+  sync store import --input mappings.jsonl --dry-run=false`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadStoreConfig(cmd)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		store, closeStore, err := openStore(ctx, cfg, "")
+		if err != nil {
+			return err
+		}
+		defer closeStore()
+
+		var r io.Reader = os.Stdin
+		if flagStoreInput != "" && flagStoreInput != "-" {
+			f, err := os.Open(flagStoreInput)
+			if err != nil {
+				return fmt.Errorf("opening import file: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		result, err := storeio.Import(ctx, store, cfg.GitHub.Organization, r, storeDryRun(cmd))
+		printStoreResult("Import", result)
+		return err
+	},
+}
+
+var storeMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy invitation mappings from one store backend to another",
+	Long: `Copy every invitation mapping and the audit log cursor for the organization
+from one store backend to another, upserting into the destination.
+Both backends are configured through the usual store settings.
+Runs as a dry run unless --dry-run=false is given.`,
+	Example: `  sync store migrate --from dynamodb --to postgres
+  sync store migrate --from dynamodb --to sqlite --dry-run=false`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagStoreFrom == flagStoreTo {
+			return fmt.Errorf("--from and --to must be different backends")
+		}
+		cfg, err := loadStoreConfig(cmd)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		from, closeFrom, err := openStore(ctx, cfg, flagStoreFrom)
+		if err != nil {
+			return fmt.Errorf("opening %s store: %w", flagStoreFrom, err)
+		}
+		defer closeFrom()
+		to, closeTo, err := openStore(ctx, cfg, flagStoreTo)
+		if err != nil {
+			return fmt.Errorf("opening %s store: %w", flagStoreTo, err)
+		}
+		defer closeTo()
+
+		result, err := storeio.Migrate(ctx, from, to, cfg.GitHub.Organization, storeDryRun(cmd))
+		printStoreResult(fmt.Sprintf("Migration %s → %s", flagStoreFrom, flagStoreTo), result)
+		return err
+	},
+}
+
+var storeDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Cross-check invitation mappings against live GitHub members and invitations",
+	Long: `Cross-check every invitation mapping against live GitHub members and invitations
+and report inconsistencies by category:
+
+  resolved_not_member  resolved mapping whose GitHub login has left the org
+  stale_pending        pending mapping whose invitation GitHub no longer has
+  duplicate            several active mappings with the same status for one email
+
+With --repair, resolved_not_member mappings are marked removed, stale_pending ones
+failed, expired or cancelled, and duplicates are merged into the most recent mapping.
+Run it after a sync so accepted invitations have been resolved from the audit log.
+Exits non-zero while unrepaired issues remain.`,
+	Example: `  sync store doctor
+  sync store doctor --repair
+  sync store doctor --output json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagDoctorOutput != "text" && flagDoctorOutput != "json" {
+			return fmt.Errorf("--output must be text or json")
+		}
+		cfg, err := loadStoreConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if storeDoctor == nil {
+			return fmt.Errorf("store doctor is not configured")
+		}
+		report, err := storeDoctor(context.Background(), cfg, flagDoctorRepair)
+		if err != nil {
+			return err
+		}
+
+		if flagDoctorOutput == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else if err := printDoctorReport(report); err != nil {
+			return err
+		}
+
+		if remaining := len(report.Issues) - report.Repaired; remaining > 0 || len(report.Errors) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d inconsistencies remain, %d repair errors", remaining, len(report.Errors))
+		}
+		return nil
+	},
+}
+
+func printDoctorReport(report *models.DoctorReport) error {
+	fmt.Fprintf(os.Stdout, "Checked %d invitation mappings for %s\n", report.Mappings, report.Org)
+	for _, category := range models.DoctorCategories {
+		fmt.Fprintf(os.Stdout, "  %-20s %d\n", category, report.Count(category))
+	}
+	if len(report.Issues) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(os.Stdout)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tEMAIL\tRECORD\tLOGIN\tSTATUS\tDETAIL\tREPAIR")
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			issue.Category, issue.Email, issue.SK, issue.GitHubLogin, issue.Status, issue.Detail, formatRepair(issue))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, e := range report.Errors {
+		fmt.Fprintf(os.Stdout, "error: %s\n", e)
+	}
+	if report.Repaired > 0 {
+		fmt.Fprintf(os.Stdout, "%d repaired\n", report.Repaired)
+	}
+	return nil
+}
+
+func formatRepair(issue models.DoctorIssue) string {
+	var repair string
+	switch {
+	case issue.Delete:
+		repair = "merge into " + issue.KeptSK
+	case issue.NewStatus != "":
+		repair = "mark " + string(issue.NewStatus)
+	default:
+		return "manual"
+	}
+	if issue.Repaired {
+		return repair + " (done)"
+	}
+	return repair
+}
+
+func loadStoreConfig(cmd *cobra.Command) (*config.Config, error) {
+	cfg, _, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	overrideConfigFromFlags(cmd, cfg)
+	if cfg.GitHub.Organization == "" {
+		return nil, fmt.Errorf("github organization is required (--github-org or GITHUB_ORG)")
+	}
+	return cfg, nil
+}
+
+// storeDryRun reports whether a store write command runs as a dry run. Only an
+// explicit --dry-run counts: sync.dry_run and DRY_RUN configure the sync, not these commands.
+func storeDryRun(cmd *cobra.Command) bool {
+	if cmd.Flags().Changed("dry-run") {
+		return flagDryRun
+	}
+	return true
+}
+
+func openStore(ctx context.Context, cfg *config.Config, backend string) (interfaces.InvitationStore, func(), error) {
+	if storeOpener == nil {
+		return nil, nil, fmt.Errorf("invitation store is not configured")
+	}
+	return storeOpener(ctx, cfg, backend)
+}
+
+func printStoreResult(operation string, result storeio.Result) {
+	mode := ""
+	if result.DryRun {
+		mode = " (dry run — nothing written)"
+	}
+	fmt.Fprintf(os.Stderr, "%s%s: %d invitation mappings, %d cursors — %d created, %d updated, %d unchanged\n",
+		operation, mode, result.Invitations, result.Cursors, result.Created, result.Updated, result.Unchanged)
+}
+
+func init() {
+	storeExportCmd.Flags().StringVar(&flagStoreOutput, "output", "", "Export file (default stdout)")
+	storeImportCmd.Flags().StringVar(&flagStoreInput, "input", "", "Export file to import (default stdin)")
+	storeMigrateCmd.Flags().StringVar(&flagStoreFrom, "from", "", "Source backend: dynamodb, sqlite or postgres")
+	storeMigrateCmd.Flags().StringVar(&flagStoreTo, "to", "", "Destination backend: dynamodb, sqlite or postgres")
+	_ = storeMigrateCmd.MarkFlagRequired("from")
+	_ = storeMigrateCmd.MarkFlagRequired("to")
+	storeDoctorCmd.Flags().BoolVar(&flagDoctorRepair, "repair", false, "Repair the inconsistencies that can be fixed automatically")
+	storeDoctorCmd.Flags().StringVar(&flagDoctorOutput, "output", "text", "Output format: text or json")
+
+	storeCmd.AddCommand(storeExportCmd, storeImportCmd, storeMigrateCmd, storeDoctorCmd)
+	rootCmd.AddCommand(storeCmd)
+}
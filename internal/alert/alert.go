@@ -0,0 +1,211 @@
+// Package alert evaluates alert rules after each sync run. A rule is sent to the
+// alert sinks when it starts firing, again every repeat interval while it keeps
+// firing, and once more when it resolves. Rule state and the run history the
+// rules look back on are kept in the invitation store.
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// Run describes a finished sync run to evaluate.
+type Run struct {
+	Org    string
+	Result *models.SyncResult // nil when the run failed before producing a result
+	Err    error
+}
+
+// completed reports whether the run got far enough for its result to describe
+// the organization.
+func (r Run) completed() bool {
+	return r.Err == nil && r.Result != nil
+}
+
+// verdict is a rule's outcome for one run.
+type verdict struct {
+	firing  bool
+	message string
+}
+
+type route struct {
+	name string
+	sink notify.AlertSink
+}
+
+// Evaluator evaluates the configured rules and sends alerts.
+type Evaluator struct {
+	cfg   config.AlertsConfig
+	store interfaces.AlertStateStore
+	sinks []route
+	now   func() time.Time
+}
+
+// New creates an evaluator keeping its state in store. Add sinks with AddSink.
+func New(cfg config.AlertsConfig, store interfaces.AlertStateStore) *Evaluator {
+	return &Evaluator{cfg: cfg, store: store, now: time.Now}
+}
+
+// AddSink registers sink under name.
+func (e *Evaluator) AddSink(name string, sink notify.AlertSink) {
+	e.sinks = append(e.sinks, route{name: name, sink: sink})
+}
+
+// Evaluate updates the org's run history with run, evaluates every configured
+// rule and sends the alerts that are due. The state is saved even if sending
+// fails; an alert no sink received is sent again on the next run.
+func (e *Evaluator) Evaluate(ctx context.Context, run Run) error {
+	now := e.now().UTC()
+	state, err := e.store.GetAlertState(ctx, run.Org)
+	if err != nil {
+		return fmt.Errorf("loading alert state: %w", err)
+	}
+	if state == nil {
+		state = &models.AlertState{Org: run.Org, FirstRunAt: now}
+	}
+	if state.Rules == nil {
+		state.Rules = map[string]models.AlertRuleState{}
+	}
+	record(state, run, now)
+
+	var errs []error
+	repeat := time.Duration(e.cfg.RepeatIntervalMinutes) * time.Minute
+	for _, rule := range e.cfg.Rules {
+		v, ok := e.evaluate(rule, state, run, now)
+		if !ok {
+			continue // Not enough information in this run; the rule keeps its state.
+		}
+		rs, wasFiring := state.Rules[rule]
+		switch {
+		case v.firing:
+			if !wasFiring {
+				rs = models.AlertRuleState{FiringSince: now}
+				logrus.WithContext(ctx).WithFields(logrus.Fields{"rule": rule, "org": run.Org}).Warn("🚨 Alert firing: " + v.message)
+			}
+			if rs.NotifiedAt.IsZero() || now.Sub(rs.NotifiedAt) >= repeat {
+				delivered, err := e.send(ctx, e.alert(rule, notify.AlertFiring, v.message, rs.FiringSince, run))
+				if err != nil {
+					errs = append(errs, err)
+				}
+				if delivered {
+					rs.NotifiedAt = now
+				}
+			}
+			state.Rules[rule] = rs
+		case wasFiring:
+			logrus.WithContext(ctx).WithFields(logrus.Fields{"rule": rule, "org": run.Org}).Info("✅ Alert resolved: " + v.message)
+			if _, err := e.send(ctx, e.alert(rule, notify.AlertResolved, v.message, rs.FiringSince, run)); err != nil {
+				errs = append(errs, err)
+			}
+			delete(state.Rules, rule)
+		}
+	}
+	// Rules removed from the configuration stop firing without a notification.
+	for rule := range state.Rules {
+		if !e.cfg.HasRule(rule) {
+			delete(state.Rules, rule)
+		}
+	}
+
+	state.UpdatedAt = now
+	if err := e.store.SaveAlertState(ctx, *state); err != nil {
+		errs = append(errs, fmt.Errorf("saving alert state: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// record adds run to the history in state.
+func record(state *models.AlertState, run Run, now time.Time) {
+	if !run.completed() {
+		return
+	}
+	if run.Result.IsSuccess() {
+		state.LastSuccessAt = now
+	}
+	orphans := run.Result.Summary.OrphanedGitHub
+	if state.OrphanCountKnown && orphans > state.OrphanCount {
+		state.OrphanGrowthRuns++
+	} else {
+		state.OrphanGrowthRuns = 0
+	}
+	state.OrphanCount = orphans
+	state.OrphanCountKnown = true
+}
+
+// evaluate returns rule's verdict for run, or false if the run does not tell.
+func (e *Evaluator) evaluate(rule string, state *models.AlertState, run Run, now time.Time) (verdict, bool) {
+	switch rule {
+	case config.AlertRuleFailedActions:
+		if run.Result == nil {
+			return verdict{}, false
+		}
+		failed := run.Result.Summary.ActionsFailed
+		return verdict{
+			firing:  failed > e.cfg.FailedActionsThreshold,
+			message: fmt.Sprintf("%d actions failed (threshold %d)", failed, e.cfg.FailedActionsThreshold),
+		}, true
+	case config.AlertRuleOrphanGrowth:
+		if !run.completed() {
+			return verdict{}, false
+		}
+		return verdict{
+			firing:  state.OrphanGrowthRuns >= e.cfg.OrphanGrowthRuns,
+			message: fmt.Sprintf("%d orphaned GitHub members, grown %d runs in a row (threshold %d)", state.OrphanCount, state.OrphanGrowthRuns, e.cfg.OrphanGrowthRuns),
+		}, true
+	case config.AlertRuleReconcileErrors:
+		if run.Result == nil || run.Result.Reconciliation == nil {
+			return verdict{}, false
+		}
+		reconcileErrs := run.Result.Reconciliation.Errors
+		if len(reconcileErrs) == 0 {
+			return verdict{message: "reconciliation has no errors"}, true
+		}
+		return verdict{firing: true, message: fmt.Sprintf("%d reconciliation errors, first: %s", len(reconcileErrs), reconcileErrs[0])}, true
+	case config.AlertRuleNoSuccess:
+		limit := time.Duration(e.cfg.NoSuccessHours) * time.Hour
+		if state.LastSuccessAt.IsZero() {
+			return verdict{
+				firing:  now.Sub(state.FirstRunAt) >= limit,
+				message: fmt.Sprintf("no successful run since alerting started at %s", state.FirstRunAt.Format(time.RFC3339)),
+			}, true
+		}
+		return verdict{
+			firing:  now.Sub(state.LastSuccessAt) >= limit,
+			message: fmt.Sprintf("last successful run at %s (threshold %dh)", state.LastSuccessAt.Format(time.RFC3339), e.cfg.NoSuccessHours),
+		}, true
+	default:
+		return verdict{}, false
+	}
+}
+
+func (e *Evaluator) alert(rule, status, message string, since time.Time, run Run) notify.Alert {
+	a := notify.Alert{Rule: rule, Org: run.Org, Status: status, Message: message, FiringSince: since}
+	if run.Result != nil {
+		a.RunID = run.Result.RunID
+		a.TraceID = run.Result.TraceID
+	}
+	return a
+}
+
+// send sends a to every sink. It reports whether any sink received it; the
+// errors of those that did not are joined.
+func (e *Evaluator) send(ctx context.Context, a notify.Alert) (bool, error) {
+	var delivered bool
+	var errs []error
+	for _, r := range e.sinks {
+		if err := r.sink.SendAlert(ctx, a); err != nil {
+			errs = append(errs, fmt.Errorf("%s alert %s: %w", r.name, a.Rule, err))
+			continue
+		}
+		delivered = true
+	}
+	return delivered, errors.Join(errs...)
+}
@@ -0,0 +1,168 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	ddb "github.com/daniloc96/google-workspace-github-sync/internal/dynamodb"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/notify"
+)
+
+type fakeSink struct {
+	alerts []notify.Alert
+	err    error
+}
+
+func (f *fakeSink) SendAlert(ctx context.Context, a notify.Alert) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.alerts = append(f.alerts, a)
+	return nil
+}
+
+// newEvaluator returns an evaluator over a store that keeps the last saved state,
+// and a clock the test advances.
+func newEvaluator(cfg config.AlertsConfig) (*Evaluator, *fakeSink, *ddb.MockStore, *time.Time) {
+	store := &ddb.MockStore{}
+	store.GetAlertStateFunc = func(ctx context.Context, org string) (*models.AlertState, error) {
+		if len(store.SavedAlertStates) == 0 {
+			return nil, nil
+		}
+		state := store.SavedAlertStates[len(store.SavedAlertStates)-1]
+		rules := map[string]models.AlertRuleState{}
+		for k, v := range state.Rules {
+			rules[k] = v
+		}
+		state.Rules = rules
+		return &state, nil
+	}
+	cfg.Enabled = true
+	if cfg.RepeatIntervalMinutes == 0 {
+		cfg.RepeatIntervalMinutes = 60
+	}
+	sink := &fakeSink{}
+	e := New(cfg, store)
+	e.AddSink("test", sink)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	return e, sink, store, &now
+}
+
+func result(failed, orphans int, reconcileErrs ...string) *models.SyncResult {
+	return &models.SyncResult{
+		RunID:          "run-1",
+		Summary:        models.SyncSummary{ActionsFailed: failed, OrphanedGitHub: orphans},
+		Reconciliation: &models.ReconcileResult{Errors: reconcileErrs},
+	}
+}
+
+func TestFailedActionsDedupeAndResolve(t *testing.T) {
+	e, sink, _, now := newEvaluator(config.AlertsConfig{Rules: []string{config.AlertRuleFailedActions}, FailedActionsThreshold: 2})
+	ctx := context.Background()
+
+	if err := e.Evaluate(ctx, Run{Org: "example-org", Result: result(2, 0)}); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if len(sink.alerts) != 0 {
+		t.Fatalf("expected no alert at the threshold, got %+v", sink.alerts)
+	}
+
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(3, 0)})
+	if len(sink.alerts) != 1 || sink.alerts[0].Status != notify.AlertFiring || sink.alerts[0].RunID != "run-1" {
+		t.Fatalf("expected a firing alert above the threshold, got %+v", sink.alerts)
+	}
+
+	*now = now.Add(15 * time.Minute)
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(5, 0)})
+	if len(sink.alerts) != 1 {
+		t.Fatalf("expected a still-firing rule not to be re-sent within the repeat interval, got %d alerts", len(sink.alerts))
+	}
+
+	*now = now.Add(time.Hour)
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(5, 0)})
+	if len(sink.alerts) != 2 || sink.alerts[1].Status != notify.AlertFiring {
+		t.Fatalf("expected a re-send after the repeat interval, got %+v", sink.alerts)
+	}
+
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(0, 0)})
+	if len(sink.alerts) != 3 || sink.alerts[2].Status != notify.AlertResolved {
+		t.Fatalf("expected a resolved alert, got %+v", sink.alerts)
+	}
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(0, 0)})
+	if len(sink.alerts) != 3 {
+		t.Fatalf("expected nothing once resolved, got %+v", sink.alerts)
+	}
+}
+
+func TestOrphanGrowth(t *testing.T) {
+	e, sink, _, _ := newEvaluator(config.AlertsConfig{Rules: []string{config.AlertRuleOrphanGrowth}, OrphanGrowthRuns: 2})
+	ctx := context.Background()
+
+	for _, orphans := range []int{1, 2, 2, 3} {
+		_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(0, orphans)})
+	}
+	if len(sink.alerts) != 0 {
+		t.Fatalf("expected a flat run to reset the streak, got %+v", sink.alerts)
+	}
+	// A failed run says nothing about orphans and keeps the streak.
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Err: errors.New("google unavailable")})
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(0, 4)})
+	if len(sink.alerts) != 1 || sink.alerts[0].Rule != config.AlertRuleOrphanGrowth {
+		t.Fatalf("expected an alert after 2 runs of growth, got %+v", sink.alerts)
+	}
+}
+
+func TestReconcileErrorsKeepStateWithoutReconciliation(t *testing.T) {
+	e, sink, _, _ := newEvaluator(config.AlertsConfig{Rules: []string{config.AlertRuleReconcileErrors}})
+	ctx := context.Background()
+
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(0, 0, "resolving invitation 7: throttled")})
+	if len(sink.alerts) != 1 || sink.alerts[0].Message != "1 reconciliation errors, first: resolving invitation 7: throttled" {
+		t.Fatalf("expected a reconcile error alert, got %+v", sink.alerts)
+	}
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: &models.SyncResult{}})
+	if len(sink.alerts) != 1 {
+		t.Fatalf("expected a run without reconciliation not to resolve the alert, got %+v", sink.alerts)
+	}
+}
+
+func TestNoSuccess(t *testing.T) {
+	e, sink, store, now := newEvaluator(config.AlertsConfig{Rules: []string{config.AlertRuleNoSuccess}, NoSuccessHours: 6})
+	ctx := context.Background()
+
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(0, 0)})
+	*now = now.Add(5 * time.Hour)
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Err: errors.New("github unavailable")})
+	if len(sink.alerts) != 0 {
+		t.Fatalf("expected no alert within the window, got %+v", sink.alerts)
+	}
+	*now = now.Add(2 * time.Hour)
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(1, 0)})
+	if len(sink.alerts) != 1 || sink.alerts[0].Status != notify.AlertFiring {
+		t.Fatalf("expected an alert after 7 hours without success, got %+v", sink.alerts)
+	}
+	saved := store.SavedAlertStates[len(store.SavedAlertStates)-1]
+	if _, ok := saved.Rules[config.AlertRuleNoSuccess]; !ok {
+		t.Fatalf("expected the firing rule to be saved, got %+v", saved)
+	}
+}
+
+func TestUndeliveredAlertIsRetried(t *testing.T) {
+	e, sink, _, _ := newEvaluator(config.AlertsConfig{Rules: []string{config.AlertRuleFailedActions}})
+	ctx := context.Background()
+
+	sink.err = errors.New("slack down")
+	if err := e.Evaluate(ctx, Run{Org: "example-org", Result: result(1, 0)}); err == nil {
+		t.Fatalf("expected the delivery error")
+	}
+	sink.err = nil
+	_ = e.Evaluate(ctx, Run{Org: "example-org", Result: result(1, 0)})
+	if len(sink.alerts) != 1 {
+		t.Fatalf("expected the undelivered alert on the next run, got %+v", sink.alerts)
+	}
+}
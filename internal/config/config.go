@@ -1,16 +1,26 @@
 package config
 
 import (
-	"errors"
+	"context"
 	"os"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
-// Load reads configuration from file, environment variables, and defaults.
+// Load reads configuration from defaults, configFile, the profile overlays and
+// Parameter Store path named by CONFIG_PROFILES and CONFIG_SSM_PATH, and
+// environment variables.
 func Load(configFile string) (*Config, error) {
+	cfg, _, err := LoadLayers(context.Background(), OptionsFromEnv(configFile))
+	return cfg, err
+}
+
+// LoadLayers reads the configuration layers selected by opts and reports where
+// each setting came from.
+func LoadLayers(ctx context.Context, opts Options) (*Config, *Sources, error) {
 	v := viper.New()
+	v.SetDefault("google.auth_mode", AuthModeServiceAccountKey)
 	v.SetDefault("sync.dry_run", true)
 	v.SetDefault("sync.ignore_suspended", true)
 	v.SetDefault("sync.remove_extra_members", false)
@@ -20,48 +30,166 @@ func Load(configFile string) (*Config, error) {
 	v.SetDefault("dynamodb.table_name", "invitation-mappings")
 	v.SetDefault("dynamodb.region", "eu-west-1")
 	v.SetDefault("dynamodb.ttl_days", 90)
+	v.SetDefault("dynamodb.consistent_read", false)
+	v.SetDefault("cache.enabled", false)
+	v.SetDefault("cache.backend", CacheBackendFile)
+	v.SetDefault("cache.directory", ".cache/http")
+	v.SetDefault("rate_limit.low_priority_reserve", 20)
+	v.SetDefault("rate_limit.max_wait_seconds", 60)
+	v.SetDefault("store.backend", StoreBackendDynamoDB)
+	v.SetDefault("store.sqlite.path", "sync.db")
+	v.SetDefault("store.sqlite.ttl_days", 90)
+	v.SetDefault("store.postgres.ttl_days", 90)
+	v.SetDefault("journal.enabled", false)
+	v.SetDefault("journal.backend", JournalBackendFile)
+	v.SetDefault("journal.path", "journal.jsonl")
+	v.SetDefault("lock.enabled", true)
+	v.SetDefault("lock.ttl_seconds", 300)
+	v.SetDefault("metrics.enabled", false)
+	v.SetDefault("metrics.backends", []string{MetricsBackendCloudWatch})
+	v.SetDefault("metrics.namespace", "GoogleGitHubSync")
+	v.SetDefault("metrics.dimensions", []string{MetricsDimensionOrg, MetricsDimensionDryRun})
+	v.SetDefault("metrics.prometheus.listen_address", ":9090")
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.service_name", "google-workspace-github-sync")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("notify.slack.enabled", false)
+	v.SetDefault("notify.slack.trigger", NotifyOnChange)
+	v.SetDefault("notify.webhook.enabled", false)
+	v.SetDefault("notify.webhook.trigger", NotifyAlways)
+	v.SetDefault("report.enabled", false)
+	v.SetDefault("report.formats", []string{ReportFormatHTML, ReportFormatMarkdown})
+	v.SetDefault("report.backend", ReportBackendDirectory)
+	v.SetDefault("report.directory", "reports")
+	v.SetDefault("report.s3.prefix", "reports/")
+	v.SetDefault("report.retention_days", 90)
+	v.SetDefault("http.enabled", false)
+	v.SetDefault("http.auth", HTTPAuthIAM)
+	v.SetDefault("http.max_skew_seconds", 300)
+	v.SetDefault("serve.schedule", "@every 15m")
+	v.SetDefault("serve.jitter_seconds", 30)
+	v.SetDefault("serve.run_on_start", true)
+	v.SetDefault("serve.listen_address", ":8080")
+	v.SetDefault("serve.shutdown_timeout_seconds", 60)
+	v.SetDefault("serve.max_consecutive_failures", 3)
+	v.SetDefault("secrets.cache_ttl_seconds", 300)
+	v.SetDefault("alerts.enabled", false)
+	v.SetDefault("alerts.rules", []string{AlertRuleFailedActions, AlertRuleOrphanGrowth, AlertRuleReconcileErrors, AlertRuleNoSuccess})
+	v.SetDefault("alerts.sinks", []string{AlertSinkSlack})
+	v.SetDefault("alerts.repeat_interval_minutes", 240)
+	v.SetDefault("alerts.failed_actions_threshold", 0)
+	v.SetDefault("alerts.orphan_growth_runs", 3)
+	v.SetDefault("alerts.no_success_hours", 24)
 
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
-	_ = v.BindEnv("google.admin_email", "GOOGLE_ADMIN_EMAIL")
-	_ = v.BindEnv("google.credentials_file", "GOOGLE_CREDENTIALS_FILE")
-	_ = v.BindEnv("google.credentials_secret", "GOOGLE_CREDENTIALS_SECRET")
-	_ = v.BindEnv("google.members_group", "GOOGLE_MEMBERS_GROUP")
-	_ = v.BindEnv("google.owners_group", "GOOGLE_OWNERS_GROUP")
-	_ = v.BindEnv("github.organization", "GITHUB_ORG")
-	_ = v.BindEnv("github.token", "GITHUB_TOKEN")
-	_ = v.BindEnv("github.token_secret", "GITHUB_TOKEN_SECRET")
-	_ = v.BindEnv("sync.dry_run", "DRY_RUN")
-	_ = v.BindEnv("sync.ignore_suspended", "IGNORE_SUSPENDED")
-	_ = v.BindEnv("sync.remove_extra_members", "REMOVE_EXTRA_MEMBERS")
-	_ = v.BindEnv("log.level", "LOG_LEVEL")
-	_ = v.BindEnv("log.format", "LOG_FORMAT")
-	_ = v.BindEnv("dynamodb.enabled", "DYNAMODB_ENABLED")
-	_ = v.BindEnv("dynamodb.table_name", "DYNAMODB_TABLE_NAME")
-	_ = v.BindEnv("dynamodb.region", "DYNAMODB_REGION")
-	_ = v.BindEnv("dynamodb.endpoint", "DYNAMODB_ENDPOINT")
-	_ = v.BindEnv("dynamodb.ttl_days", "DYNAMODB_TTL_DAYS")
-
-	if configFile != "" {
-		v.SetConfigFile(configFile)
-		if err := v.ReadInConfig(); err != nil {
-			return nil, err
-		}
-	} else {
-		v.SetConfigName("config")
-		v.AddConfigPath(".")
-		if err := v.ReadInConfig(); err != nil {
-			var notFound viper.ConfigFileNotFoundError
-			if !errors.As(err, &notFound) {
-				return nil, err
-			}
-		}
+	envNames := map[string]string{}
+	bindEnv := func(key string, name string) {
+		_ = v.BindEnv(key, name)
+		envNames[key] = name
+	}
+
+	bindEnv("google.admin_email", "GOOGLE_ADMIN_EMAIL")
+	bindEnv("google.credentials_file", "GOOGLE_CREDENTIALS_FILE")
+	bindEnv("google.credentials_secret", "GOOGLE_CREDENTIALS_SECRET")
+	bindEnv("google.members_group", "GOOGLE_MEMBERS_GROUP")
+	bindEnv("google.owners_group", "GOOGLE_OWNERS_GROUP")
+	bindEnv("google.auth_mode", "GOOGLE_AUTH_MODE")
+	bindEnv("google.service_account", "GOOGLE_SERVICE_ACCOUNT")
+	bindEnv("github.organization", "GITHUB_ORG")
+	bindEnv("github.token", "GITHUB_TOKEN")
+	bindEnv("github.token_secret", "GITHUB_TOKEN_SECRET")
+	bindEnv("sync.dry_run", "DRY_RUN")
+	bindEnv("sync.ignore_suspended", "IGNORE_SUSPENDED")
+	bindEnv("sync.remove_extra_members", "REMOVE_EXTRA_MEMBERS")
+	bindEnv("log.level", "LOG_LEVEL")
+	bindEnv("log.format", "LOG_FORMAT")
+	bindEnv("dynamodb.enabled", "DYNAMODB_ENABLED")
+	bindEnv("dynamodb.table_name", "DYNAMODB_TABLE_NAME")
+	bindEnv("dynamodb.region", "DYNAMODB_REGION")
+	bindEnv("dynamodb.endpoint", "DYNAMODB_ENDPOINT")
+	bindEnv("dynamodb.ttl_days", "DYNAMODB_TTL_DAYS")
+	bindEnv("dynamodb.consistent_read", "DYNAMODB_CONSISTENT_READ")
+	bindEnv("cache.enabled", "CACHE_ENABLED")
+	bindEnv("cache.backend", "CACHE_BACKEND")
+	bindEnv("cache.directory", "CACHE_DIRECTORY")
+	bindEnv("rate_limit.low_priority_reserve", "RATE_LIMIT_LOW_PRIORITY_RESERVE")
+	bindEnv("rate_limit.max_wait_seconds", "RATE_LIMIT_MAX_WAIT_SECONDS")
+	bindEnv("store.backend", "STORE_BACKEND")
+	bindEnv("store.sqlite.path", "STORE_SQLITE_PATH")
+	bindEnv("store.sqlite.ttl_days", "STORE_SQLITE_TTL_DAYS")
+	bindEnv("store.postgres.dsn", "STORE_POSTGRES_DSN")
+	bindEnv("store.postgres.dsn_secret", "STORE_POSTGRES_DSN_SECRET")
+	bindEnv("store.postgres.ttl_days", "STORE_POSTGRES_TTL_DAYS")
+	bindEnv("journal.enabled", "JOURNAL_ENABLED")
+	bindEnv("journal.backend", "JOURNAL_BACKEND")
+	bindEnv("journal.path", "JOURNAL_PATH")
+	bindEnv("lock.enabled", "LOCK_ENABLED")
+	bindEnv("lock.ttl_seconds", "LOCK_TTL_SECONDS")
+	bindEnv("metrics.enabled", "METRICS_ENABLED")
+	bindEnv("metrics.backends", "METRICS_BACKENDS")
+	bindEnv("metrics.namespace", "METRICS_NAMESPACE")
+	bindEnv("metrics.region", "METRICS_REGION")
+	bindEnv("metrics.endpoint", "METRICS_ENDPOINT")
+	bindEnv("metrics.dimensions", "METRICS_DIMENSIONS")
+	bindEnv("metrics.prometheus.listen_address", "METRICS_PROMETHEUS_LISTEN_ADDRESS")
+	bindEnv("tracing.enabled", "TRACING_ENABLED")
+	bindEnv("tracing.endpoint", "TRACING_ENDPOINT")
+	bindEnv("tracing.service_name", "TRACING_SERVICE_NAME")
+	bindEnv("tracing.sample_ratio", "TRACING_SAMPLE_RATIO")
+	bindEnv("notify.slack.enabled", "NOTIFY_SLACK_ENABLED")
+	bindEnv("notify.slack.url", "NOTIFY_SLACK_URL")
+	bindEnv("notify.slack.url_secret", "NOTIFY_SLACK_URL_SECRET")
+	bindEnv("notify.slack.trigger", "NOTIFY_SLACK_TRIGGER")
+	bindEnv("notify.webhook.enabled", "NOTIFY_WEBHOOK_ENABLED")
+	bindEnv("notify.webhook.url", "NOTIFY_WEBHOOK_URL")
+	bindEnv("notify.webhook.url_secret", "NOTIFY_WEBHOOK_URL_SECRET")
+	bindEnv("notify.webhook.trigger", "NOTIFY_WEBHOOK_TRIGGER")
+	bindEnv("report.enabled", "REPORT_ENABLED")
+	bindEnv("report.formats", "REPORT_FORMATS")
+	bindEnv("report.backend", "REPORT_BACKEND")
+	bindEnv("report.directory", "REPORT_DIRECTORY")
+	bindEnv("report.s3.bucket", "REPORT_S3_BUCKET")
+	bindEnv("report.s3.prefix", "REPORT_S3_PREFIX")
+	bindEnv("report.s3.region", "REPORT_S3_REGION")
+	bindEnv("report.s3.endpoint", "REPORT_S3_ENDPOINT")
+	bindEnv("report.retention_days", "REPORT_RETENTION_DAYS")
+	bindEnv("http.enabled", "HTTP_ENABLED")
+	bindEnv("http.auth", "HTTP_AUTH")
+	bindEnv("http.hmac_key", "HTTP_HMAC_KEY")
+	bindEnv("http.hmac_key_secret", "HTTP_HMAC_KEY_SECRET")
+	bindEnv("http.max_skew_seconds", "HTTP_MAX_SKEW_SECONDS")
+	bindEnv("http.iam_principals", "HTTP_IAM_PRINCIPALS")
+	bindEnv("serve.schedule", "SERVE_SCHEDULE")
+	bindEnv("serve.jitter_seconds", "SERVE_JITTER_SECONDS")
+	bindEnv("serve.run_on_start", "SERVE_RUN_ON_START")
+	bindEnv("serve.listen_address", "SERVE_LISTEN_ADDRESS")
+	bindEnv("serve.shutdown_timeout_seconds", "SERVE_SHUTDOWN_TIMEOUT_SECONDS")
+	bindEnv("serve.max_consecutive_failures", "SERVE_MAX_CONSECUTIVE_FAILURES")
+	bindEnv("secrets.cache_ttl_seconds", "SECRETS_CACHE_TTL_SECONDS")
+	bindEnv("secrets.region", "SECRETS_REGION")
+	bindEnv("secrets.vault.address", "VAULT_ADDR")
+	bindEnv("secrets.vault.token", "VAULT_TOKEN")
+	bindEnv("secrets.vault.token_file", "VAULT_TOKEN_FILE")
+	bindEnv("secrets.vault.namespace", "VAULT_NAMESPACE")
+	bindEnv("alerts.enabled", "ALERTS_ENABLED")
+	bindEnv("alerts.rules", "ALERTS_RULES")
+	bindEnv("alerts.sinks", "ALERTS_SINKS")
+	bindEnv("alerts.repeat_interval_minutes", "ALERTS_REPEAT_INTERVAL_MINUTES")
+	bindEnv("alerts.failed_actions_threshold", "ALERTS_FAILED_ACTIONS_THRESHOLD")
+	bindEnv("alerts.orphan_growth_runs", "ALERTS_ORPHAN_GROWTH_RUNS")
+	bindEnv("alerts.no_success_hours", "ALERTS_NO_SUCCESS_HOURS")
+
+	sources := newSources()
+	if err := readLayers(ctx, v, opts, sources); err != nil {
+		return nil, nil, err
 	}
+	sources.finish(v, envNames)
 
 	cfg := &Config{}
 	if err := v.Unmarshal(cfg); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Explicitly map values to avoid tag mismatch issues.
@@ -70,6 +198,8 @@ func Load(configFile string) (*Config, error) {
 	cfg.Google.CredentialsSecret = v.GetString("google.credentials_secret")
 	cfg.Google.MembersGroup = v.GetString("google.members_group")
 	cfg.Google.OwnersGroup = v.GetString("google.owners_group")
+	cfg.Google.AuthMode = v.GetString("google.auth_mode")
+	cfg.Google.ServiceAccount = v.GetString("google.service_account")
 
 	cfg.GitHub.Organization = v.GetString("github.organization")
 	cfg.GitHub.Token = v.GetString("github.token")
@@ -87,8 +217,109 @@ func Load(configFile string) (*Config, error) {
 	cfg.DynamoDB.Region = v.GetString("dynamodb.region")
 	cfg.DynamoDB.Endpoint = v.GetString("dynamodb.endpoint")
 	cfg.DynamoDB.TTLDays = v.GetInt("dynamodb.ttl_days")
+	cfg.DynamoDB.ConsistentRead = v.GetBool("dynamodb.consistent_read")
+
+	cfg.Cache.Enabled = v.GetBool("cache.enabled")
+	cfg.Cache.Backend = v.GetString("cache.backend")
+	cfg.Cache.Directory = v.GetString("cache.directory")
+
+	cfg.RateLimit.LowPriorityReserve = v.GetInt("rate_limit.low_priority_reserve")
+	cfg.RateLimit.MaxWaitSeconds = v.GetInt("rate_limit.max_wait_seconds")
+
+	cfg.Store.Backend = v.GetString("store.backend")
+	cfg.Store.SQLite.Path = v.GetString("store.sqlite.path")
+	cfg.Store.SQLite.TTLDays = v.GetInt("store.sqlite.ttl_days")
+	cfg.Store.Postgres.DSN = v.GetString("store.postgres.dsn")
+	cfg.Store.Postgres.DSNSecret = v.GetString("store.postgres.dsn_secret")
+	cfg.Store.Postgres.TTLDays = v.GetInt("store.postgres.ttl_days")
+
+	cfg.Journal.Enabled = v.GetBool("journal.enabled")
+	cfg.Journal.Backend = v.GetString("journal.backend")
+	cfg.Journal.Path = v.GetString("journal.path")
+
+	cfg.Lock.Enabled = v.GetBool("lock.enabled")
+	cfg.Lock.TTLSeconds = v.GetInt("lock.ttl_seconds")
+
+	cfg.Metrics.Enabled = v.GetBool("metrics.enabled")
+	cfg.Metrics.Backends = stringList(v.GetStringSlice("metrics.backends"))
+	cfg.Metrics.Namespace = v.GetString("metrics.namespace")
+	cfg.Metrics.Region = v.GetString("metrics.region")
+	cfg.Metrics.Endpoint = v.GetString("metrics.endpoint")
+	cfg.Metrics.Dimensions = stringList(v.GetStringSlice("metrics.dimensions"))
+	cfg.Metrics.Prometheus.ListenAddress = v.GetString("metrics.prometheus.listen_address")
+
+	cfg.Tracing.Enabled = v.GetBool("tracing.enabled")
+	cfg.Tracing.Endpoint = v.GetString("tracing.endpoint")
+	cfg.Tracing.ServiceName = v.GetString("tracing.service_name")
+	cfg.Tracing.SampleRatio = v.GetFloat64("tracing.sample_ratio")
+
+	cfg.Notify.Slack = notifySink(v, "notify.slack")
+	cfg.Notify.Webhook = notifySink(v, "notify.webhook")
+
+	cfg.Report.Enabled = v.GetBool("report.enabled")
+	cfg.Report.Formats = stringList(v.GetStringSlice("report.formats"))
+	cfg.Report.Backend = v.GetString("report.backend")
+	cfg.Report.Directory = v.GetString("report.directory")
+	cfg.Report.S3.Bucket = v.GetString("report.s3.bucket")
+	cfg.Report.S3.Prefix = v.GetString("report.s3.prefix")
+	cfg.Report.S3.Region = v.GetString("report.s3.region")
+	cfg.Report.S3.Endpoint = v.GetString("report.s3.endpoint")
+	cfg.Report.RetentionDays = v.GetInt("report.retention_days")
+
+	cfg.HTTP.Enabled = v.GetBool("http.enabled")
+	cfg.HTTP.Auth = v.GetString("http.auth")
+	cfg.HTTP.HMACKey = v.GetString("http.hmac_key")
+	cfg.HTTP.HMACKeySecret = v.GetString("http.hmac_key_secret")
+	cfg.HTTP.MaxSkewSeconds = v.GetInt("http.max_skew_seconds")
+	cfg.HTTP.IAMPrincipals = stringList(v.GetStringSlice("http.iam_principals"))
+
+	cfg.Serve.Schedule = v.GetString("serve.schedule")
+	cfg.Serve.JitterSeconds = v.GetInt("serve.jitter_seconds")
+	cfg.Serve.RunOnStart = v.GetBool("serve.run_on_start")
+	cfg.Serve.ListenAddress = v.GetString("serve.listen_address")
+	cfg.Serve.ShutdownTimeoutSeconds = v.GetInt("serve.shutdown_timeout_seconds")
+	cfg.Serve.MaxConsecutiveFailures = v.GetInt("serve.max_consecutive_failures")
+
+	cfg.Secrets.CacheTTLSeconds = v.GetInt("secrets.cache_ttl_seconds")
+	cfg.Secrets.Region = v.GetString("secrets.region")
+	cfg.Secrets.Vault.Address = v.GetString("secrets.vault.address")
+	cfg.Secrets.Vault.Token = v.GetString("secrets.vault.token")
+	cfg.Secrets.Vault.TokenFile = v.GetString("secrets.vault.token_file")
+	cfg.Secrets.Vault.Namespace = v.GetString("secrets.vault.namespace")
+
+	cfg.Alerts.Enabled = v.GetBool("alerts.enabled")
+	cfg.Alerts.Rules = stringList(v.GetStringSlice("alerts.rules"))
+	cfg.Alerts.Sinks = stringList(v.GetStringSlice("alerts.sinks"))
+	cfg.Alerts.RepeatIntervalMinutes = v.GetInt("alerts.repeat_interval_minutes")
+	cfg.Alerts.FailedActionsThreshold = v.GetInt("alerts.failed_actions_threshold")
+	cfg.Alerts.OrphanGrowthRuns = v.GetInt("alerts.orphan_growth_runs")
+	cfg.Alerts.NoSuccessHours = v.GetInt("alerts.no_success_hours")
 
 	cfg.IsLambda = os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != ""
 
-	return cfg, nil
+	return cfg, sources, nil
+}
+
+// notifySink reads the notification sink settings under key.
+func notifySink(v *viper.Viper, key string) NotifySinkConfig {
+	return NotifySinkConfig{
+		Enabled:   v.GetBool(key + ".enabled"),
+		URL:       v.GetString(key + ".url"),
+		URLSecret: v.GetString(key + ".url_secret"),
+		Trigger:   v.GetString(key + ".trigger"),
+	}
+}
+
+// stringList normalizes a list setting. Lists set through environment variables
+// arrive as a single comma-separated string.
+func stringList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
 }
@@ -79,6 +79,359 @@ func TestValidateConfig(t *testing.T) {
 			isLambda: true,
 			wantErr: false,
 		},
+		{
+			name: "workload identity without key",
+			cfg: func() Config {
+				c := validLocal
+				c.Google.CredentialsFile = ""
+				c.Google.AuthMode = AuthModeWorkloadIdentity
+				c.Google.ServiceAccount = "sync@project.iam.gserviceaccount.com"
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "workload identity missing service account",
+			cfg: func() Config {
+				c := validLocal
+				c.Google.AuthMode = AuthModeWorkloadIdentity
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "dynamodb cache without dynamodb",
+			cfg: func() Config {
+				c := validLocal
+				c.Cache = CacheConfig{Enabled: true, Backend: CacheBackendDynamoDB}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "file cache",
+			cfg: func() Config {
+				c := validLocal
+				c.Cache = CacheConfig{Enabled: true, Backend: CacheBackendFile, Directory: "/tmp/cache"}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "rate limit reserve out of range",
+			cfg: func() Config {
+				c := validLocal
+				c.RateLimit.LowPriorityReserve = 150
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "file journal in lambda",
+			cfg: func() Config {
+				c := validLocal
+				c.Google.CredentialsFile = ""
+				c.GitHub.Token = ""
+				c.Google.CredentialsSecret = "google-creds"
+				c.GitHub.TokenSecret = "github-token"
+				c.Journal = JournalConfig{Enabled: true, Backend: JournalBackendFile, Path: "/tmp/journal.jsonl"}
+				return c
+			}(),
+			isLambda: true,
+			wantErr: true,
+		},
+		{
+			name: "lock ttl too short",
+			cfg: func() Config {
+				c := validLocal
+				c.Lock = LockConfig{Enabled: true, TTLSeconds: 5}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "unknown metrics dimension",
+			cfg: func() Config {
+				c := validLocal
+				c.Metrics = MetricsConfig{Enabled: true, Backends: []string{"cloudwatch"}, Namespace: "Sync", Dimensions: []string{"org", "region"}}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "unknown metrics backend",
+			cfg: func() Config {
+				c := validLocal
+				c.Metrics = MetricsConfig{Enabled: true, Backends: []string{"statsd"}, Namespace: "Sync"}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "prometheus metrics",
+			cfg: func() Config {
+				c := validLocal
+				c.Metrics = MetricsConfig{Enabled: true, Backends: []string{"prometheus"}, Prometheus: PrometheusConfig{ListenAddress: ":9090"}}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "prometheus metrics in lambda",
+			cfg: func() Config {
+				c := validLocal
+				c.Google.CredentialsFile = ""
+				c.GitHub.Token = ""
+				c.Google.CredentialsSecret = "google-creds"
+				c.GitHub.TokenSecret = "github-token"
+				c.Metrics = MetricsConfig{Enabled: true, Backends: []string{"prometheus"}, Prometheus: PrometheusConfig{ListenAddress: ":9090"}}
+				return c
+			}(),
+			isLambda: true,
+			wantErr: true,
+		},
+		{
+			name: "tracing endpoint without scheme",
+			cfg: func() Config {
+				c := validLocal
+				c.Tracing = TracingConfig{Enabled: true, Endpoint: "localhost:4318", ServiceName: "sync", SampleRatio: 1}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "tracing sample ratio out of range",
+			cfg: func() Config {
+				c := validLocal
+				c.Tracing = TracingConfig{Enabled: true, ServiceName: "sync", SampleRatio: 2}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "slack notifications without url",
+			cfg: func() Config {
+				c := validLocal
+				c.Notify.Slack = NotifySinkConfig{Enabled: true, Trigger: NotifyOnFailure}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "webhook notifications with unknown trigger",
+			cfg: func() Config {
+				c := validLocal
+				c.Notify.Webhook = NotifySinkConfig{Enabled: true, URL: "https://hooks.example.com/sync", Trigger: "sometimes"}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "slack notifications from secret",
+			cfg: func() Config {
+				c := validLocal
+				c.Notify.Slack = NotifySinkConfig{Enabled: true, URLSecret: "slack-webhook", Trigger: NotifyOnChange}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "alerts to a slack webhook used only for alerts",
+			cfg: func() Config {
+				c := validLocal
+				c.Store = StoreConfig{Backend: StoreBackendSQLite, SQLite: SQLiteConfig{Path: "sync.db", TTLDays: 90}}
+				c.Notify.Slack = NotifySinkConfig{URL: "https://hooks.slack.com/services/T/B/X"}
+				c.Alerts = AlertsConfig{Enabled: true, Rules: []string{AlertRuleFailedActions, AlertRuleNoSuccess}, Sinks: []string{AlertSinkSlack}, RepeatIntervalMinutes: 240, NoSuccessHours: 24}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "alerts without an invitation store",
+			cfg: func() Config {
+				c := validLocal
+				c.Notify.Slack = NotifySinkConfig{URL: "https://hooks.slack.com/services/T/B/X"}
+				c.Alerts = AlertsConfig{Enabled: true, Rules: []string{AlertRuleReconcileErrors}, Sinks: []string{AlertSinkSlack}, RepeatIntervalMinutes: 240}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "alerts to a sink without url",
+			cfg: func() Config {
+				c := validLocal
+				c.Store = StoreConfig{Backend: StoreBackendSQLite, SQLite: SQLiteConfig{Path: "sync.db", TTLDays: 90}}
+				c.Alerts = AlertsConfig{Enabled: true, Rules: []string{AlertRuleReconcileErrors}, Sinks: []string{AlertSinkWebhook}, RepeatIntervalMinutes: 240}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "alerts with unknown rule",
+			cfg: func() Config {
+				c := validLocal
+				c.Store = StoreConfig{Backend: StoreBackendSQLite, SQLite: SQLiteConfig{Path: "sync.db", TTLDays: 90}}
+				c.Notify.Slack = NotifySinkConfig{URL: "https://hooks.slack.com/services/T/B/X"}
+				c.Alerts = AlertsConfig{Enabled: true, Rules: []string{"too_quiet"}, Sinks: []string{AlertSinkSlack}, RepeatIntervalMinutes: 240}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "reports to s3",
+			cfg: func() Config {
+				c := validLocal
+				c.Report = ReportConfig{Enabled: true, Formats: []string{ReportFormatHTML}, Backend: ReportBackendS3, S3: ReportS3Config{Bucket: "sync-reports", Endpoint: "http://localhost:9000"}, RetentionDays: 30}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "lambda reports to a directory",
+			cfg: func() Config {
+				c := validLocal
+				c.Google.CredentialsSecret = "google-creds"
+				c.GitHub.TokenSecret = "github-token"
+				c.Report = ReportConfig{Enabled: true, Formats: []string{ReportFormatMarkdown}, Backend: ReportBackendDirectory, Directory: "reports"}
+				return c
+			}(),
+			isLambda: true,
+			wantErr: true,
+		},
+		{
+			name: "reports with unknown format",
+			cfg: func() Config {
+				c := validLocal
+				c.Report = ReportConfig{Enabled: true, Formats: []string{"pdf"}, Backend: ReportBackendDirectory, Directory: "reports"}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "http trigger with hmac key secret",
+			cfg: func() Config {
+				c := validLocal
+				c.HTTP = HTTPConfig{Enabled: true, Auth: HTTPAuthHMAC, HMACKeySecret: "sync-hmac-key", MaxSkewSeconds: 300}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "http trigger with hmac but no key",
+			cfg: func() Config {
+				c := validLocal
+				c.HTTP = HTTPConfig{Enabled: true, Auth: HTTPAuthHMAC, MaxSkewSeconds: 300}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "http trigger with unknown auth",
+			cfg: func() Config {
+				c := validLocal
+				c.HTTP = HTTPConfig{Enabled: true, Auth: "none"}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "sqlite store",
+			cfg: func() Config {
+				c := validLocal
+				c.Store = StoreConfig{Backend: StoreBackendSQLite, SQLite: SQLiteConfig{Path: "sync.db", TTLDays: 90}}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: false,
+		},
+		{
+			name: "postgres store without dsn",
+			cfg: func() Config {
+				c := validLocal
+				c.Store = StoreConfig{Backend: StoreBackendPostgres, Postgres: PostgresConfig{TTLDays: 90}}
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "unknown store backend",
+			cfg: func() Config {
+				c := validLocal
+				c.Store.Backend = "redis"
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "unknown auth mode",
+			cfg: func() Config {
+				c := validLocal
+				c.Google.AuthMode = "magic"
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "secret references with schemes",
+			cfg: func() Config {
+				c := validLocal
+				c.GitHub.Token = ""
+				c.GitHub.TokenSecret = "vault://secret/github-sync#token"
+				c.Google.CredentialsFile = ""
+				c.Google.CredentialsSecret = "ssm:///github-sync/google-credentials"
+				c.Secrets.Vault = VaultConfig{Address: "https://vault.example.com:8200", TokenFile: "/vault/secrets/token"}
+				return c
+			}(),
+			isLambda: true,
+			wantErr: false,
+		},
+		{
+			name: "unknown secret scheme",
+			cfg: func() Config {
+				c := validLocal
+				c.GitHub.TokenSecret = "gcpsm://projects/p/secrets/token"
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
+		{
+			name: "vault secret without vault address",
+			cfg: func() Config {
+				c := validLocal
+				c.GitHub.TokenSecret = "vault://secret/github-sync#token"
+				c.Secrets.Vault.Token = "hvs.test"
+				return c
+			}(),
+			isLambda: false,
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -96,3 +449,45 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadMetricsDimensionsFromEnv(t *testing.T) {
+	t.Setenv("METRICS_DIMENSIONS", "org, dry_run")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Metrics.Dimensions) != 2 || cfg.Metrics.Dimensions[0] != "org" || cfg.Metrics.Dimensions[1] != "dry_run" {
+		t.Fatalf("expected [org dry_run], got %q", cfg.Metrics.Dimensions)
+	}
+}
+
+func TestValidateServe(t *testing.T) {
+	t.Setenv("SERVE_SCHEDULE", "CRON_TZ=Europe/Rome 0 */2 * * *")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := ValidateServe(cfg); err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(*ServeConfig)
+	}{
+		{name: "invalid schedule", modify: func(s *ServeConfig) { s.Schedule = "every day" }},
+		{name: "negative jitter", modify: func(s *ServeConfig) { s.JitterSeconds = -1 }},
+		{name: "missing listen address", modify: func(s *ServeConfig) { s.ListenAddress = "" }},
+		{name: "zero shutdown timeout", modify: func(s *ServeConfig) { s.ShutdownTimeoutSeconds = 0 }},
+		{name: "negative failure threshold", modify: func(s *ServeConfig) { s.MaxConsecutiveFailures = -1 }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			invalid := *cfg
+			tc.modify(&invalid.Serve)
+			if err := ValidateServe(&invalid); err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+		})
+	}
+}
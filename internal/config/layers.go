@@ -0,0 +1,272 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/spf13/viper"
+)
+
+// Environment variables selecting configuration layers.
+const (
+	EnvProfiles = "CONFIG_PROFILES" // Comma-separated profile overlays
+	EnvSSMPath  = "CONFIG_SSM_PATH" // Parameter Store path prefix
+)
+
+// Setting sources other than a file, parameter, environment variable or flag.
+const (
+	SourceDefault = "default"
+	SourceUnset   = "unset"
+)
+
+// Redacted replaces secret values in Sources.Settings.
+const Redacted = "<redacted>"
+
+// secretKeys are the settings whose values are secrets rather than references
+// to them.
+var secretKeys = map[string]bool{
+	"github.token":        true,
+	"http.hmac_key":       true,
+	"store.postgres.dsn":  true,
+	"notify.slack.url":    true,
+	"notify.webhook.url":  true,
+	"secrets.vault.token": true,
+}
+
+// IsSecretKey reports whether the setting key holds a secret value.
+func IsSecretKey(key string) bool {
+	return secretKeys[key]
+}
+
+// SSMAPI defines the SSM client interface used to read configuration parameters.
+type SSMAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
+
+// Options selects the configuration layers. Later layers override earlier ones:
+// defaults, the base file, each profile overlay in order, Parameter Store, then
+// environment variables. Command-line flags are applied on top by the CLI.
+type Options struct {
+	File     string   // Base file; empty looks for config.yaml (or .json, .toml) in the working directory
+	Profiles []string // Overlays <base>.<profile>.<ext> next to the base file, e.g. config.prod.yaml
+	SSMPath  string   // Parameter Store path prefix; /p/github/organization sets github.organization
+	SSM      SSMAPI   // Parameter Store client; created from the default AWS config when nil
+}
+
+// OptionsFromEnv returns the options for file with the profiles and Parameter
+// Store path taken from CONFIG_PROFILES and CONFIG_SSM_PATH.
+func OptionsFromEnv(file string) Options {
+	return Options{
+		File:     file,
+		Profiles: stringList([]string{os.Getenv(EnvProfiles)}),
+		SSMPath:  os.Getenv(EnvSSMPath),
+	}
+}
+
+// Setting is one effective setting and where its value came from.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source,omitempty"` // default, unset, file:<path>, ssm:<parameter>, env:<VARIABLE> or flag:--<name>
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Sources records the layers a configuration was loaded from and the source of
+// each setting.
+type Sources struct {
+	Layers   []string // Layers read, lowest precedence first
+	settings map[string]Setting
+}
+
+func newSources() *Sources {
+	return &Sources{Layers: []string{SourceDefault}, settings: map[string]Setting{}}
+}
+
+// Override records that key was set to value by source, e.g. a flag.
+func (s *Sources) Override(key string, value any, source string) {
+	setting := s.settings[key]
+	setting.Key, setting.Value, setting.Source = key, value, source
+	setting.Secret = setting.Secret || IsSecretKey(key)
+	s.settings[key] = setting
+}
+
+// Source returns where key's value came from.
+func (s *Sources) Source(key string) string {
+	if setting, ok := s.settings[key]; ok {
+		return setting.Source
+	}
+	return SourceUnset
+}
+
+// Settings returns the settings sorted by key, with secret values redacted.
+func (s *Sources) Settings() []Setting {
+	settings := make([]Setting, 0, len(s.settings))
+	for _, setting := range s.settings {
+		if setting.Secret && setting.Value != nil && fmt.Sprint(setting.Value) != "" {
+			setting.Value = Redacted
+		}
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+func (s *Sources) record(key string, source string, secret bool) {
+	s.settings[key] = Setting{Key: key, Source: source, Secret: secret || IsSecretKey(key)}
+}
+
+// finish records the settings read from environment variables and defaults and
+// the effective value of every setting.
+func (s *Sources) finish(v *viper.Viper, envNames map[string]string) {
+	s.Layers = append(s.Layers, "env")
+	for _, key := range v.AllKeys() {
+		// Viper checks the automatic name before the bound one.
+		for _, name := range []string{strings.ToUpper(strings.ReplaceAll(key, ".", "_")), envNames[key]} {
+			if name == "" {
+				continue
+			}
+			if value, ok := os.LookupEnv(name); ok && value != "" {
+				s.record(key, "env:"+name, false)
+				break
+			}
+		}
+		setting, ok := s.settings[key]
+		if !ok {
+			setting = Setting{Key: key, Source: SourceUnset, Secret: IsSecretKey(key)}
+		}
+		setting.Value = v.Get(key)
+		if !ok && setting.Value != nil {
+			setting.Source = SourceDefault
+		}
+		s.settings[key] = setting
+	}
+}
+
+// readLayers merges the file and Parameter Store layers of opts into v.
+func readLayers(ctx context.Context, v *viper.Viper, opts Options, sources *Sources) error {
+	base := viper.New()
+	if opts.File != "" {
+		base.SetConfigFile(opts.File)
+		if err := base.ReadInConfig(); err != nil {
+			return err
+		}
+	} else {
+		base.SetConfigName("config")
+		base.AddConfigPath(".")
+		if err := base.ReadInConfig(); err != nil {
+			var notFound viper.ConfigFileNotFoundError
+			if !errors.As(err, &notFound) {
+				return err
+			}
+		}
+	}
+	baseFile := base.ConfigFileUsed()
+	if baseFile != "" {
+		if err := mergeLayer(v, base, "file:"+baseFile, sources); err != nil {
+			return err
+		}
+	}
+
+	for _, profile := range opts.Profiles {
+		path := profilePath(baseFile, profile)
+		overlay := viper.New()
+		overlay.SetConfigFile(path)
+		if err := overlay.ReadInConfig(); err != nil {
+			return fmt.Errorf("reading profile %s: %w", profile, err)
+		}
+		if err := mergeLayer(v, overlay, "file:"+path, sources); err != nil {
+			return err
+		}
+	}
+
+	if opts.SSMPath != "" {
+		if err := readParameters(ctx, v, opts, sources); err != nil {
+			return fmt.Errorf("reading parameters under %s: %w", opts.SSMPath, err)
+		}
+	}
+	return nil
+}
+
+// profilePath returns the overlay file of profile: config.prod.yaml for
+// config.yaml, next to it.
+func profilePath(baseFile string, profile string) string {
+	if baseFile == "" {
+		return "config." + profile + ".yaml"
+	}
+	ext := filepath.Ext(baseFile)
+	return strings.TrimSuffix(baseFile, ext) + "." + profile + ext
+}
+
+func mergeLayer(v *viper.Viper, layer *viper.Viper, source string, sources *Sources) error {
+	if err := v.MergeConfigMap(layer.AllSettings()); err != nil {
+		return fmt.Errorf("merging %s: %w", source, err)
+	}
+	for _, key := range layer.AllKeys() {
+		sources.record(key, source, false)
+	}
+	sources.Layers = append(sources.Layers, source)
+	return nil
+}
+
+// readParameters merges the parameters under opts.SSMPath into v. Each
+// parameter's name below the path is its key, with slashes for dots.
+func readParameters(ctx context.Context, v *viper.Viper, opts Options, sources *Sources) error {
+	client := opts.SSM
+	if client == nil {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("loading AWS config: %w", err)
+		}
+		client = ssm.NewFromConfig(awsCfg)
+	}
+
+	prefix := "/" + strings.Trim(opts.SSMPath, "/")
+	settings := map[string]any{}
+	paginator := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(prefix),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, parameter := range page.Parameters {
+			name := aws.ToString(parameter.Name)
+			key := strings.ToLower(strings.ReplaceAll(strings.Trim(strings.TrimPrefix(name, prefix), "/"), "/", "."))
+			if key == "" {
+				continue
+			}
+			setNested(settings, strings.Split(key, "."), aws.ToString(parameter.Value))
+			sources.record(key, "ssm:"+name, parameter.Type == types.ParameterTypeSecureString)
+		}
+	}
+	if err := v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("merging parameters: %w", err)
+	}
+	sources.Layers = append(sources.Layers, "ssm:"+prefix)
+	return nil
+}
+
+// setNested sets path in the nested map m to value.
+func setNested(m map[string]any, path []string, value string) {
+	for _, part := range path[:len(path)-1] {
+		child, ok := m[part].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[part] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = value
+}
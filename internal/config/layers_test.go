@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type mockSSM struct {
+	path       string
+	decryption bool
+	pages      [][]types.Parameter
+}
+
+func (m *mockSSM) GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	m.path, m.decryption = aws.ToString(params.Path), aws.ToBool(params.WithDecryption)
+	page := 0
+	if params.NextToken != nil {
+		page = 1
+	}
+	out := &ssm.GetParametersByPathOutput{Parameters: m.pages[page]}
+	if page+1 < len(m.pages) {
+		out.NextToken = aws.String("next")
+	}
+	return out, nil
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "sync.yaml")
+	writeFile(t, base, `
+google:
+  admin_email: admin@example.com
+  members_group: members@example.com
+github:
+  organization: base-org
+  token: ghp_from_file
+log:
+  level: debug
+`)
+	writeFile(t, filepath.Join(dir, "sync.acme.yaml"), `
+github:
+  organization: acme
+google:
+  members_group: acme-members@example.com
+`)
+	writeFile(t, filepath.Join(dir, "sync.prod.yaml"), `
+sync:
+  dry_run: false
+log:
+  level: warn
+`)
+	client := &mockSSM{pages: [][]types.Parameter{
+		{{Name: aws.String("/github-sync/prod/log/level"), Value: aws.String("error"), Type: types.ParameterTypeString}},
+		{
+			{Name: aws.String("/github-sync/prod/notify/slack/url"), Value: aws.String("https://hooks.slack.com/services/T/B/x"), Type: types.ParameterTypeString},
+			{Name: aws.String("/github-sync/prod/metrics/backends"), Value: aws.String("cloudwatch,prometheus"), Type: types.ParameterTypeStringList},
+			{Name: aws.String("/github-sync/prod/google/credentials_secret"), Value: aws.String("ssm:///github-sync/prod/google"), Type: types.ParameterTypeSecureString},
+		},
+	}}
+	t.Setenv("GITHUB_ORG", "acme-env")
+
+	cfg, sources, err := LoadLayers(context.Background(), Options{
+		File:     base,
+		Profiles: []string{"acme", "prod"},
+		SSMPath:  "github-sync/prod/",
+		SSM:      client,
+	})
+	if err != nil {
+		t.Fatalf("LoadLayers: %v", err)
+	}
+	if client.path != "/github-sync/prod" || !client.decryption {
+		t.Fatalf("expected a decrypted read of /github-sync/prod, got %q (decryption %v)", client.path, client.decryption)
+	}
+
+	if cfg.GitHub.Organization != "acme-env" || cfg.Google.MembersGroup != "acme-members@example.com" || cfg.Google.AdminEmail != "admin@example.com" {
+		t.Fatalf("expected env over overlay over base, got %+v / %+v", cfg.GitHub, cfg.Google)
+	}
+	if cfg.Sync.DryRun || cfg.Log.Level != "error" || cfg.Log.Format != "json" {
+		t.Fatalf("expected parameters over overlays and defaults below, got %+v / %+v", cfg.Sync, cfg.Log)
+	}
+	if len(cfg.Metrics.Backends) != 2 || cfg.Notify.Slack.URL == "" {
+		t.Fatalf("expected list and nested parameters, got %v / %q", cfg.Metrics.Backends, cfg.Notify.Slack.URL)
+	}
+
+	wantSources := map[string]string{
+		"github.organization":       "env:GITHUB_ORG",
+		"google.members_group":      "file:" + filepath.Join(dir, "sync.acme.yaml"),
+		"google.admin_email":        "file:" + base,
+		"sync.dry_run":              "file:" + filepath.Join(dir, "sync.prod.yaml"),
+		"log.level":                 "ssm:/github-sync/prod/log/level",
+		"log.format":                SourceDefault,
+		"google.credentials_file":   SourceUnset,
+		"google.credentials_secret": "ssm:/github-sync/prod/google/credentials_secret",
+	}
+	for key, want := range wantSources {
+		if got := sources.Source(key); got != want {
+			t.Errorf("source of %s: got %q, want %q", key, got, want)
+		}
+	}
+	if len(sources.Layers) != 6 || sources.Layers[4] != "ssm:/github-sync/prod" {
+		t.Fatalf("unexpected layers %v", sources.Layers)
+	}
+
+	sources.Override("sync.dry_run", true, "flag:--dry-run")
+	redacted := map[string]bool{}
+	for _, setting := range sources.Settings() {
+		if setting.Value == Redacted {
+			redacted[setting.Key] = true
+		}
+		if setting.Key == "sync.dry_run" && (setting.Source != "flag:--dry-run" || setting.Value != true) {
+			t.Errorf("expected the flag override, got %+v", setting)
+		}
+	}
+	for _, key := range []string{"github.token", "notify.slack.url", "google.credentials_secret"} {
+		if !redacted[key] {
+			t.Errorf("expected %s to be redacted", key)
+		}
+	}
+	if redacted["github.organization"] || redacted["http.hmac_key"] {
+		t.Errorf("expected only set secrets to be redacted, got %v", redacted)
+	}
+}
+
+func TestLoadLayersMissingProfile(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, base, "github:\n  organization: example-org\n")
+	if _, _, err := LoadLayers(context.Background(), Options{File: base, Profiles: []string{"staging"}}); err == nil {
+		t.Fatalf("expected an error for a missing profile overlay")
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv(EnvProfiles, "acme, prod")
+	t.Setenv(EnvSSMPath, "/github-sync/prod")
+	opts := OptionsFromEnv("config.yaml")
+	if len(opts.Profiles) != 2 || opts.Profiles[1] != "prod" || opts.SSMPath != "/github-sync/prod" || opts.File != "config.yaml" {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
@@ -2,12 +2,274 @@ package config
 
 // Config holds all configuration for the sync operation.
 type Config struct {
-	Google   GoogleConfig   `json:"google"`
-	GitHub   GitHubConfig   `json:"github"`
-	Sync     SyncConfig     `json:"sync"`
-	Log      LogConfig      `json:"log"`
-	DynamoDB DynamoDBConfig `json:"dynamodb"`
-	IsLambda bool           `json:"-"`
+	Google    GoogleConfig    `json:"google"`
+	GitHub    GitHubConfig    `json:"github"`
+	Sync      SyncConfig      `json:"sync"`
+	Log       LogConfig       `json:"log"`
+	DynamoDB  DynamoDBConfig  `json:"dynamodb"`
+	Store     StoreConfig     `json:"store"`
+	Cache     CacheConfig     `json:"cache"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	Journal   JournalConfig   `json:"journal"`
+	Lock      LockConfig      `json:"lock"`
+	Metrics   MetricsConfig   `json:"metrics"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Notify    NotifyConfig    `json:"notify"`
+	Alerts    AlertsConfig    `json:"alerts"`
+	Report    ReportConfig    `json:"report"`
+	HTTP      HTTPConfig      `json:"http"`
+	Serve     ServeConfig     `json:"serve"`
+	Secrets   SecretsConfig   `json:"secrets"`
+	IsLambda  bool            `json:"-"`
+}
+
+// Journal backends.
+const (
+	JournalBackendFile     = "file"
+	JournalBackendDynamoDB = "dynamodb"
+)
+
+// JournalConfig holds action journal settings.
+type JournalConfig struct {
+	Enabled bool   `json:"enabled"`
+	Backend string `json:"backend"`
+	Path    string `json:"path,omitempty"` // JSON Lines file (file backend)
+}
+
+// Report backends.
+const (
+	ReportBackendDirectory = "directory"
+	ReportBackendS3        = "s3"
+)
+
+// Report formats.
+const (
+	ReportFormatHTML     = "html"
+	ReportFormatMarkdown = "markdown"
+)
+
+// ReportConfig holds per-run report settings.
+type ReportConfig struct {
+	Enabled       bool           `json:"enabled"`
+	Formats       []string       `json:"formats"` // html and/or markdown
+	Backend       string         `json:"backend"`
+	Directory     string         `json:"directory,omitempty"` // directory backend
+	S3            ReportS3Config `json:"s3"`
+	RetentionDays int            `json:"retention_days"` // Reports older than this are deleted; 0 keeps them
+}
+
+// ReportS3Config holds the S3 report backend settings.
+type ReportS3Config struct {
+	Bucket   string `json:"bucket,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"` // Local S3-compatible stand-in (e.g. MinIO, LocalStack)
+}
+
+// HTTP trigger authentication modes.
+const (
+	HTTPAuthHMAC = "hmac" // Requests signed with a shared key
+	HTTPAuthIAM  = "iam"  // Requests SigV4-signed and verified by AWS (function URL or API Gateway IAM auth)
+)
+
+// HTTPConfig holds settings for on-demand runs requested over HTTP through an
+// API Gateway HTTP API or a Lambda function URL.
+type HTTPConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Auth           string   `json:"auth"` // hmac or iam
+	HMACKey        string   `json:"-"`
+	HMACKeySecret  string   `json:"hmac_key_secret,omitempty"` // Secret reference holding the HMAC key
+	MaxSkewSeconds int      `json:"max_skew_seconds"`          // hmac: how old a signed request may be
+	IAMPrincipals  []string `json:"iam_principals,omitempty"`  // iam: allowed account IDs, user or role ARNs; empty allows any signed caller
+}
+
+// Secret reference schemes. Settings holding a secret reference, such as
+// github.token_secret, take a bare Secrets Manager name or ARN or a URI with one
+// of these schemes. A "#field" suffix selects a field of a JSON secret.
+const (
+	SecretSchemeAWSSM = "awssm" // awssm://name-or-arn
+	SecretSchemeSSM   = "ssm"   // ssm:///parameter/name; SecureString values are decrypted
+	SecretSchemeVault = "vault" // vault://mount/path#field, a HashiCorp Vault KV v2 secret
+	SecretSchemeEnv   = "env"   // env://VARIABLE
+	SecretSchemeFile  = "file"  // file:///path/to/file
+)
+
+// SecretsConfig holds settings for resolving secret references.
+type SecretsConfig struct {
+	CacheTTLSeconds int         `json:"cache_ttl_seconds"` // How long resolved secrets are reused, across warm Lambda invocations too; 0 disables caching
+	Region          string      `json:"region,omitempty"`  // AWS region for awssm and ssm; defaults to the AWS SDK region
+	Vault           VaultConfig `json:"vault"`
+}
+
+// VaultConfig holds the HashiCorp Vault settings of vault:// references.
+type VaultConfig struct {
+	Address   string `json:"address,omitempty"`    // e.g. https://vault.example.com:8200
+	Token     string `json:"-"`                    // Vault token
+	TokenFile string `json:"token_file,omitempty"` // File holding the token, e.g. written by Vault Agent; read on each request
+	Namespace string `json:"namespace,omitempty"`  // Vault Enterprise namespace
+}
+
+// SecretRefs returns the secret references set in c, keyed by setting name.
+func (c *Config) SecretRefs() map[string]string {
+	refs := map[string]string{}
+	for key, ref := range map[string]string{
+		"google.credentials_secret": c.Google.CredentialsSecret,
+		"github.token_secret":       c.GitHub.TokenSecret,
+		"store.postgres.dsn_secret": c.Store.Postgres.DSNSecret,
+		"notify.slack.url_secret":   c.Notify.Slack.URLSecret,
+		"notify.webhook.url_secret": c.Notify.Webhook.URLSecret,
+		"http.hmac_key_secret":      c.HTTP.HMACKeySecret,
+	} {
+		if ref != "" {
+			refs[key] = ref
+		}
+	}
+	return refs
+}
+
+// ServeConfig holds settings for the serve command, which runs syncs on a
+// schedule as a long-running process and serves health endpoints.
+type ServeConfig struct {
+	Schedule               string `json:"schedule"`                 // Cron expression or "@every <duration>"
+	JitterSeconds          int    `json:"jitter_seconds"`           // Random delay added to each scheduled run
+	RunOnStart             bool   `json:"run_on_start"`             // Run once at startup instead of waiting for the schedule
+	ListenAddress          string `json:"listen_address"`           // Address serving /healthz, /readyz and /status
+	ShutdownTimeoutSeconds int    `json:"shutdown_timeout_seconds"` // How long a run in flight may take to stop on SIGTERM
+	MaxConsecutiveFailures int    `json:"max_consecutive_failures"` // /readyz fails after this many failed runs in a row; 0 disables
+}
+
+// LockConfig holds settings for the distributed run lock. The lock is kept in
+// the invitation store, so it only applies when a store is enabled.
+type LockConfig struct {
+	Enabled    bool `json:"enabled"`
+	TTLSeconds int  `json:"ttl_seconds"` // Lease length; renewed every third of it while a run is in progress
+}
+
+// Metric dimensions that can be attached to every run metric.
+const (
+	MetricsDimensionOrg    = "org"
+	MetricsDimensionDryRun = "dry_run"
+)
+
+// Metrics backends.
+const (
+	MetricsBackendCloudWatch = "cloudwatch"
+	MetricsBackendPrometheus = "prometheus"
+)
+
+// MetricsConfig holds run metrics settings.
+type MetricsConfig struct {
+	Enabled    bool             `json:"enabled"`
+	Backends   []string         `json:"backends"`             // cloudwatch and/or prometheus
+	Namespace  string           `json:"namespace"`            // CloudWatch namespace
+	Region     string           `json:"region,omitempty"`     // Defaults to the AWS SDK region (AWS_REGION in Lambda)
+	Endpoint   string           `json:"endpoint,omitempty"`   // Local stand-in for CloudWatch (tests, LocalStack)
+	Dimensions []string         `json:"dimensions,omitempty"` // org and/or dry_run
+	Prometheus PrometheusConfig `json:"prometheus"`
+}
+
+// PrometheusConfig holds settings for the Prometheus metrics backend.
+type PrometheusConfig struct {
+	ListenAddress string `json:"listen_address"` // Address serving /metrics, e.g. ":9090"
+}
+
+// HasBackend reports whether metrics are enabled and sent to backend.
+func (m MetricsConfig) HasBackend(backend string) bool {
+	return m.Enabled && contains(m.Backends, backend)
+}
+
+// TracingConfig holds OpenTelemetry tracing settings. Spans are exported over
+// OTLP/HTTP; the standard OTEL_EXPORTER_OTLP_* variables apply when Endpoint is empty.
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`
+	Endpoint    string  `json:"endpoint,omitempty"` // OTLP/HTTP collector URL, e.g. http://localhost:4318
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"` // Fraction of runs traced, 0 to 1
+}
+
+// Notification triggers.
+const (
+	NotifyAlways    = "always"     // Every run
+	NotifyOnChange  = "on_change"  // Runs that changed, or in dry-run would change, membership, and failed runs
+	NotifyOnFailure = "on_failure" // Runs with errors or failed actions
+)
+
+// NotifyConfig holds run notification settings. Each sink is configured separately.
+type NotifyConfig struct {
+	Slack   NotifySinkConfig `json:"slack"`   // Slack incoming webhook
+	Webhook NotifySinkConfig `json:"webhook"` // Generic JSON webhook
+}
+
+// NotifySinkConfig holds the settings of one notification sink.
+type NotifySinkConfig struct {
+	Enabled   bool   `json:"enabled"`
+	URL       string `json:"url,omitempty"`
+	URLSecret string `json:"url_secret,omitempty"` // Secret reference holding the URL
+	Trigger   string `json:"trigger"`              // always, on_change or on_failure
+}
+
+// Alert rules.
+const (
+	AlertRuleFailedActions   = "failed_actions"   // More than FailedActionsThreshold actions failed in a run
+	AlertRuleOrphanGrowth    = "orphan_growth"    // The orphaned member count grew OrphanGrowthRuns runs in a row
+	AlertRuleReconcileErrors = "reconcile_errors" // Invitation reconciliation reported errors
+	AlertRuleNoSuccess       = "no_success"       // No run succeeded for NoSuccessHours
+)
+
+// Alert sinks: the notification sinks alerts can be sent to.
+const (
+	AlertSinkSlack   = "slack"
+	AlertSinkWebhook = "webhook"
+)
+
+// AlertsConfig holds rule-based alerting settings. Rules are evaluated after each
+// run; their state is kept in the invitation store.
+type AlertsConfig struct {
+	Enabled                bool     `json:"enabled"`
+	Rules                  []string `json:"rules"`                   // Rules evaluated
+	Sinks                  []string `json:"sinks"`                   // slack and/or webhook, using the notify.<sink> URL
+	RepeatIntervalMinutes  int      `json:"repeat_interval_minutes"` // How often a rule that keeps firing is re-sent
+	FailedActionsThreshold int      `json:"failed_actions_threshold"`
+	OrphanGrowthRuns       int      `json:"orphan_growth_runs"`
+	NoSuccessHours         int      `json:"no_success_hours"`
+}
+
+// HasRule reports whether alerting is enabled with rule.
+func (a AlertsConfig) HasRule(rule string) bool {
+	return a.Enabled && contains(a.Rules, rule)
+}
+
+// HasSink reports whether alerting is enabled and sends to sink.
+func (a AlertsConfig) HasSink(sink string) bool {
+	return a.Enabled && contains(a.Sinks, sink)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig holds API budget settings.
+type RateLimitConfig struct {
+	LowPriorityReserve int `json:"low_priority_reserve"` // Percent of each budget kept for normal-priority work
+	MaxWaitSeconds     int `json:"max_wait_seconds"`     // Longest a single request is paced
+}
+
+// Cache backends.
+const (
+	CacheBackendFile     = "file"
+	CacheBackendDynamoDB = "dynamodb"
+)
+
+// CacheConfig holds HTTP conditional-request cache settings.
+type CacheConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Backend   string `json:"backend"`
+	Directory string `json:"directory,omitempty"`
 }
 
 // DynamoDBConfig holds DynamoDB settings for invitation tracking.
@@ -17,29 +279,92 @@ type DynamoDBConfig struct {
 	Endpoint  string `json:"endpoint,omitempty"`
 	Enabled   bool   `json:"enabled"`
 	TTLDays   int    `json:"ttl_days"`
+	// ConsistentRead makes store reads strongly consistent. Status and email
+	// lookups then query the base table instead of the eventually consistent GSIs.
+	ConsistentRead bool `json:"consistent_read,omitempty"`
+}
+
+// Invitation store backends.
+const (
+	StoreBackendDynamoDB = "dynamodb"
+	StoreBackendSQLite   = "sqlite"
+	StoreBackendPostgres = "postgres"
+)
+
+// StoreConfig selects the invitation store backend used for reconciliation.
+type StoreConfig struct {
+	Backend  string         `json:"backend"`
+	SQLite   SQLiteConfig   `json:"sqlite"`
+	Postgres PostgresConfig `json:"postgres"`
 }
 
+// SQLiteConfig holds settings for the embedded SQLite invitation store.
+type SQLiteConfig struct {
+	Path    string `json:"path"`
+	TTLDays int    `json:"ttl_days"`
+}
+
+// PostgresConfig holds settings for the PostgreSQL invitation store.
+type PostgresConfig struct {
+	DSN       string `json:"-"`                    // Connection string (contains credentials)
+	DSNSecret string `json:"dsn_secret,omitempty"` // Secret reference holding the DSN
+	TTLDays   int    `json:"ttl_days"`
+}
+
+// StoreEnabled reports whether an invitation store is configured. The DynamoDB
+// backend is opt-in through dynamodb.enabled; other backends are enabled by selecting them.
+func (c *Config) StoreEnabled() bool {
+	if c.Store.Backend == "" || c.Store.Backend == StoreBackendDynamoDB {
+		return c.DynamoDB.Enabled
+	}
+	return true
+}
+
+// Google authentication modes.
+const (
+	// AuthModeServiceAccountKey authenticates with a service-account key JSON.
+	AuthModeServiceAccountKey = "service_account_key"
+	// AuthModeWorkloadIdentity authenticates keylessly via ADC / workload identity
+	// federation and impersonates the service account through IAM Credentials.
+	AuthModeWorkloadIdentity = "workload_identity"
+)
+
 // GoogleConfig holds Google Workspace settings.
 type GoogleConfig struct {
 	AdminEmail        string `json:"admin_email"`
 	MembersGroup      string `json:"members_group"`
 	OwnersGroup       string `json:"owners_group"`
+	AuthMode          string `json:"auth_mode"`
+	ServiceAccount    string `json:"service_account,omitempty"`
 	CredentialsFile   string `json:"credentials_file,omitempty"`
-	CredentialsSecret string `json:"credentials_secret,omitempty"`
+	CredentialsSecret string `json:"credentials_secret,omitempty"` // Secret reference holding the credentials JSON
+}
+
+// IsKeyless reports whether Google authentication uses workload identity instead of a key.
+func (g GoogleConfig) IsKeyless() bool {
+	return g.AuthMode == AuthModeWorkloadIdentity
 }
 
 // GitHubConfig holds GitHub settings.
 type GitHubConfig struct {
 	Organization string `json:"organization"`
 	Token        string `json:"-"`
-	TokenSecret  string `json:"token_secret,omitempty"`
+	TokenSecret  string `json:"token_secret,omitempty"` // Secret reference holding the token
 }
 
 // SyncConfig holds sync behavior settings.
 type SyncConfig struct {
-	DryRun            bool `json:"dry_run"`
-	IgnoreSuspended   bool `json:"ignore_suspended"`
-	RemoveExtraMembers bool `json:"remove_extra_members"`
+	DryRun             bool     `json:"dry_run"`
+	IgnoreSuspended    bool     `json:"ignore_suspended"`
+	RemoveExtraMembers bool     `json:"remove_extra_members"`
+	OnlyUsers          []string `json:"only_users,omitempty"`  // Google emails or GitHub logins a run is limited to; set per run, not from the config file
+	OnlyGroups         []string `json:"only_groups,omitempty"` // Google groups whose members a run is limited to; set per run, not from the config file
+}
+
+// IsScoped reports whether a run is limited to some users rather than the whole
+// organization.
+func (s SyncConfig) IsScoped() bool {
+	return len(s.OnlyUsers) > 0 || len(s.OnlyGroups) > 0
 }
 
 // LogConfig holds logging settings.
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"net/mail"
+	"net/url"
 	"strings"
+
+	"github.com/robfig/cron/v3"
 )
 
 // Validate ensures configuration is complete and well-formed.
@@ -35,11 +38,24 @@ func Validate(cfg *Config) error {
 	requireEmail(cfg.Google.OwnersGroup, "google.owners_group")
 	requireNonEmpty(cfg.GitHub.Organization, "github.organization")
 
+	switch cfg.Google.AuthMode {
+	case "", AuthModeServiceAccountKey:
+		if cfg.IsLambda {
+			requireNonEmpty(cfg.Google.CredentialsSecret, "google.credentials_secret")
+		} else {
+			requireNonEmpty(cfg.Google.CredentialsFile, "google.credentials_file")
+		}
+	case AuthModeWorkloadIdentity:
+		// Credentials file/secret are optional here: when set they hold an
+		// external account configuration, otherwise ADC is used.
+		requireEmail(cfg.Google.ServiceAccount, "google.service_account")
+	default:
+		errs = append(errs, fmt.Sprintf("google.auth_mode must be %q or %q", AuthModeServiceAccountKey, AuthModeWorkloadIdentity))
+	}
+
 	if cfg.IsLambda {
-		requireNonEmpty(cfg.Google.CredentialsSecret, "google.credentials_secret")
 		requireNonEmpty(cfg.GitHub.TokenSecret, "github.token_secret")
 	} else {
-		requireNonEmpty(cfg.Google.CredentialsFile, "google.credentials_file")
 		requireNonEmpty(cfg.GitHub.Token, "github.token")
 	}
 
@@ -51,9 +67,276 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	switch cfg.Store.Backend {
+	case "", StoreBackendDynamoDB:
+	case StoreBackendSQLite:
+		requireNonEmpty(cfg.Store.SQLite.Path, "store.sqlite.path")
+		if cfg.Store.SQLite.TTLDays <= 0 {
+			errs = append(errs, "store.sqlite.ttl_days must be positive")
+		}
+		if cfg.IsLambda {
+			errs = append(errs, "store.backend sqlite is not durable in Lambda mode; use dynamodb")
+		}
+	case StoreBackendPostgres:
+		if cfg.Store.Postgres.DSN == "" && cfg.Store.Postgres.DSNSecret == "" {
+			errs = append(errs, "store.postgres.dsn or store.postgres.dsn_secret is required")
+		}
+		if cfg.Store.Postgres.TTLDays <= 0 {
+			errs = append(errs, "store.postgres.ttl_days must be positive")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("store.backend must be %q, %q or %q", StoreBackendDynamoDB, StoreBackendSQLite, StoreBackendPostgres))
+	}
+
+	if cfg.Cache.Enabled {
+		switch cfg.Cache.Backend {
+		case CacheBackendFile:
+			requireNonEmpty(cfg.Cache.Directory, "cache.directory")
+		case CacheBackendDynamoDB:
+			if !cfg.DynamoDB.Enabled {
+				errs = append(errs, "cache.backend dynamodb requires dynamodb.enabled")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("cache.backend must be %q or %q", CacheBackendFile, CacheBackendDynamoDB))
+		}
+	}
+
+	if cfg.Journal.Enabled {
+		switch cfg.Journal.Backend {
+		case JournalBackendFile:
+			requireNonEmpty(cfg.Journal.Path, "journal.path")
+			if cfg.IsLambda {
+				errs = append(errs, "journal.backend file is not durable in Lambda mode; use dynamodb")
+			}
+		case JournalBackendDynamoDB:
+			if !cfg.DynamoDB.Enabled {
+				errs = append(errs, "journal.backend dynamodb requires dynamodb.enabled")
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("journal.backend must be %q or %q", JournalBackendFile, JournalBackendDynamoDB))
+		}
+	}
+
+	if cfg.Lock.Enabled && cfg.Lock.TTLSeconds < 30 {
+		errs = append(errs, "lock.ttl_seconds must be at least 30")
+	}
+
+	if cfg.Metrics.Enabled {
+		if len(cfg.Metrics.Backends) == 0 {
+			errs = append(errs, "metrics.backends must not be empty")
+		}
+		for _, backend := range cfg.Metrics.Backends {
+			if backend != MetricsBackendCloudWatch && backend != MetricsBackendPrometheus {
+				errs = append(errs, fmt.Sprintf("metrics.backends entries must be %q or %q", MetricsBackendCloudWatch, MetricsBackendPrometheus))
+				break
+			}
+		}
+		if cfg.Metrics.HasBackend(MetricsBackendCloudWatch) {
+			requireNonEmpty(cfg.Metrics.Namespace, "metrics.namespace")
+		}
+		if cfg.Metrics.HasBackend(MetricsBackendPrometheus) {
+			requireNonEmpty(cfg.Metrics.Prometheus.ListenAddress, "metrics.prometheus.listen_address")
+			if cfg.IsLambda {
+				errs = append(errs, "metrics backend prometheus needs a long-running process to scrape; use cloudwatch in Lambda mode")
+			}
+		}
+		for _, dimension := range cfg.Metrics.Dimensions {
+			if dimension != MetricsDimensionOrg && dimension != MetricsDimensionDryRun {
+				errs = append(errs, fmt.Sprintf("metrics.dimensions entries must be %q or %q", MetricsDimensionOrg, MetricsDimensionDryRun))
+				break
+			}
+		}
+	}
+
+	if cfg.Tracing.Enabled {
+		requireNonEmpty(cfg.Tracing.ServiceName, "tracing.service_name")
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			errs = append(errs, "tracing.sample_ratio must be between 0 and 1")
+		}
+		if cfg.Tracing.Endpoint != "" {
+			if u, err := url.Parse(cfg.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, "tracing.endpoint must be an http or https URL")
+			}
+		}
+	}
+
+	for _, n := range []struct {
+		name  string
+		sink  NotifySinkConfig
+		alert bool
+	}{
+		{"notify.slack", cfg.Notify.Slack, cfg.Alerts.HasSink(AlertSinkSlack)},
+		{"notify.webhook", cfg.Notify.Webhook, cfg.Alerts.HasSink(AlertSinkWebhook)},
+	} {
+		name, sink := n.name, n.sink
+		if !sink.Enabled && !n.alert {
+			continue
+		}
+		if sink.URL == "" && sink.URLSecret == "" {
+			errs = append(errs, fmt.Sprintf("%s.url or %s.url_secret is required", name, name))
+		}
+		if sink.URL != "" {
+			if u, err := url.Parse(sink.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Sprintf("%s.url must be an http or https URL", name))
+			}
+		}
+		if !sink.Enabled {
+			continue
+		}
+		switch sink.Trigger {
+		case NotifyAlways, NotifyOnChange, NotifyOnFailure:
+		default:
+			errs = append(errs, fmt.Sprintf("%s.trigger must be %q, %q or %q", name, NotifyAlways, NotifyOnChange, NotifyOnFailure))
+		}
+	}
+
+	if cfg.Report.Enabled {
+		if len(cfg.Report.Formats) == 0 {
+			errs = append(errs, "report.formats must not be empty")
+		}
+		for _, format := range cfg.Report.Formats {
+			if format != ReportFormatHTML && format != ReportFormatMarkdown {
+				errs = append(errs, fmt.Sprintf("report.formats entry %q must be %q or %q", format, ReportFormatHTML, ReportFormatMarkdown))
+			}
+		}
+		switch cfg.Report.Backend {
+		case ReportBackendDirectory:
+			requireNonEmpty(cfg.Report.Directory, "report.directory")
+			if cfg.IsLambda {
+				errs = append(errs, "report.backend directory is not durable in Lambda mode; use s3")
+			}
+		case ReportBackendS3:
+			requireNonEmpty(cfg.Report.S3.Bucket, "report.s3.bucket")
+			if cfg.Report.S3.Endpoint != "" {
+				if u, err := url.Parse(cfg.Report.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					errs = append(errs, "report.s3.endpoint must be an http or https URL")
+				}
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("report.backend must be %q or %q", ReportBackendDirectory, ReportBackendS3))
+		}
+		if cfg.Report.RetentionDays < 0 {
+			errs = append(errs, "report.retention_days must not be negative")
+		}
+	}
+
+	if cfg.HTTP.Enabled {
+		switch cfg.HTTP.Auth {
+		case HTTPAuthHMAC:
+			if cfg.HTTP.HMACKey == "" && cfg.HTTP.HMACKeySecret == "" {
+				errs = append(errs, "http.hmac_key or http.hmac_key_secret is required")
+			}
+			if cfg.HTTP.MaxSkewSeconds <= 0 {
+				errs = append(errs, "http.max_skew_seconds must be > 0")
+			}
+		case HTTPAuthIAM:
+		default:
+			errs = append(errs, fmt.Sprintf("http.auth must be %q or %q", HTTPAuthHMAC, HTTPAuthIAM))
+		}
+	}
+
+	if cfg.Alerts.Enabled {
+		if !cfg.StoreEnabled() {
+			errs = append(errs, "alerts.enabled requires an invitation store to keep alert state")
+		}
+		if len(cfg.Alerts.Rules) == 0 {
+			errs = append(errs, "alerts.rules must not be empty")
+		}
+		for _, rule := range cfg.Alerts.Rules {
+			switch rule {
+			case AlertRuleFailedActions, AlertRuleOrphanGrowth, AlertRuleReconcileErrors, AlertRuleNoSuccess:
+			default:
+				errs = append(errs, fmt.Sprintf("alerts.rules entry %q must be %q, %q, %q or %q", rule, AlertRuleFailedActions, AlertRuleOrphanGrowth, AlertRuleReconcileErrors, AlertRuleNoSuccess))
+			}
+		}
+		if len(cfg.Alerts.Sinks) == 0 {
+			errs = append(errs, "alerts.sinks must not be empty")
+		}
+		for _, sink := range cfg.Alerts.Sinks {
+			if sink != AlertSinkSlack && sink != AlertSinkWebhook {
+				errs = append(errs, fmt.Sprintf("alerts.sinks entry %q must be %q or %q", sink, AlertSinkSlack, AlertSinkWebhook))
+			}
+		}
+		if cfg.Alerts.RepeatIntervalMinutes <= 0 {
+			errs = append(errs, "alerts.repeat_interval_minutes must be > 0")
+		}
+		if cfg.Alerts.HasRule(AlertRuleFailedActions) && cfg.Alerts.FailedActionsThreshold < 0 {
+			errs = append(errs, "alerts.failed_actions_threshold must not be negative")
+		}
+		if cfg.Alerts.HasRule(AlertRuleOrphanGrowth) && cfg.Alerts.OrphanGrowthRuns <= 0 {
+			errs = append(errs, "alerts.orphan_growth_runs must be > 0")
+		}
+		if cfg.Alerts.HasRule(AlertRuleNoSuccess) && cfg.Alerts.NoSuccessHours <= 0 {
+			errs = append(errs, "alerts.no_success_hours must be > 0")
+		}
+	}
+
+	usesVault := false
+	for key, ref := range cfg.SecretRefs() {
+		scheme, _, ok := strings.Cut(ref, "://")
+		if !ok {
+			continue
+		}
+		switch scheme {
+		case SecretSchemeAWSSM, SecretSchemeSSM, SecretSchemeEnv, SecretSchemeFile:
+		case SecretSchemeVault:
+			usesVault = true
+		default:
+			errs = append(errs, fmt.Sprintf("%s has unknown scheme %q; use %s, %s, %s, %s or %s", key, scheme,
+				SecretSchemeAWSSM, SecretSchemeSSM, SecretSchemeVault, SecretSchemeEnv, SecretSchemeFile))
+		}
+	}
+	if usesVault {
+		if u, err := url.Parse(cfg.Secrets.Vault.Address); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, "secrets.vault.address must be an http or https URL when a vault:// secret is used")
+		}
+		if cfg.Secrets.Vault.Token == "" && cfg.Secrets.Vault.TokenFile == "" {
+			errs = append(errs, "secrets.vault.token or secrets.vault.token_file is required when a vault:// secret is used")
+		}
+	}
+	if cfg.Secrets.CacheTTLSeconds < 0 {
+		errs = append(errs, "secrets.cache_ttl_seconds must not be negative")
+	}
+
+	if cfg.RateLimit.LowPriorityReserve < 0 || cfg.RateLimit.LowPriorityReserve > 100 {
+		errs = append(errs, "rate_limit.low_priority_reserve must be between 0 and 100")
+	}
+	if cfg.RateLimit.MaxWaitSeconds < 0 {
+		errs = append(errs, "rate_limit.max_wait_seconds must not be negative")
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("config validation failed: %s", strings.Join(errs, "; "))
 	}
 
 	return nil
 }
+
+// ValidateServe checks the settings of the serve command. They are validated
+// apart from Validate because only that command uses them.
+func ValidateServe(cfg *Config) error {
+	var errs []string
+	if _, err := cron.ParseStandard(cfg.Serve.Schedule); err != nil {
+		errs = append(errs, fmt.Sprintf("serve.schedule is invalid: %v", err))
+	}
+	if cfg.Serve.JitterSeconds < 0 {
+		errs = append(errs, "serve.jitter_seconds must not be negative")
+	}
+	if cfg.Serve.ListenAddress == "" {
+		errs = append(errs, "serve.listen_address is required")
+	}
+	if cfg.Serve.ShutdownTimeoutSeconds <= 0 {
+		errs = append(errs, "serve.shutdown_timeout_seconds must be > 0")
+	}
+	if cfg.Serve.MaxConsecutiveFailures < 0 {
+		errs = append(errs, "serve.max_consecutive_failures must not be negative")
+	}
+	if cfg.IsLambda {
+		errs = append(errs, "serve does not run in Lambda mode")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config validation failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
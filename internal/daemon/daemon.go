@@ -0,0 +1,308 @@
+// Package daemon runs syncs on a schedule as a long-running process, for
+// deployments such as Kubernetes where no external scheduler invokes the sync.
+// It serves liveness, readiness and last-run status over HTTP and stops
+// gracefully: a run in flight finishes its current action before it returns.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	stdsync "sync"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/sync"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// RunFunc runs one sync. A run skipped because another one holds the run lock
+// returns models.ErrRunLocked.
+type RunFunc func(ctx context.Context) (*models.SyncResult, error)
+
+// Run outcomes reported by /status.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeSkipped   = "skipped"
+)
+
+// Daemon states reported by /status.
+const (
+	StateStarting = "starting"
+	StateIdle     = "idle"
+	StateRunning  = "running"
+	StateStopping = "stopping"
+)
+
+// LastRun describes the most recent run.
+type LastRun struct {
+	RunID      string              `json:"run_id,omitempty"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	DurationMs int64               `json:"duration_ms"`
+	Outcome    string              `json:"outcome"`
+	Error      string              `json:"error,omitempty"`
+	DryRun     bool                `json:"dry_run"`
+	Summary    *models.SyncSummary `json:"summary,omitempty"`
+}
+
+// Status is the body served at /status.
+type Status struct {
+	State               string     `json:"state"`
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+	LastRun             *LastRun   `json:"last_run,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// Daemon runs syncs on a schedule.
+type Daemon struct {
+	cfg      config.ServeConfig
+	schedule cron.Schedule
+	run      RunFunc
+	mux      *http.ServeMux
+
+	now             func() time.Time
+	jitter          func() time.Duration
+	shutdownTimeout time.Duration
+
+	mu     stdsync.Mutex
+	status Status
+}
+
+// New creates a daemon that calls run on cfg.Schedule.
+func New(cfg config.ServeConfig, run RunFunc) (*Daemon, error) {
+	schedule, err := cron.ParseStandard(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("parsing serve.schedule: %w", err)
+	}
+	d := &Daemon{
+		cfg:             cfg,
+		schedule:        schedule,
+		run:             run,
+		mux:             http.NewServeMux(),
+		now:             time.Now,
+		shutdownTimeout: time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second,
+		status:          Status{State: StateStarting},
+	}
+	d.jitter = func() time.Duration {
+		if cfg.JitterSeconds <= 0 {
+			return 0
+		}
+		return rand.N(time.Duration(cfg.JitterSeconds) * time.Second)
+	}
+	d.mux.HandleFunc("GET /healthz", d.serveHealth)
+	d.mux.HandleFunc("GET /readyz", d.serveReady)
+	d.mux.HandleFunc("GET /status", d.serveStatus)
+	return d, nil
+}
+
+// Handle serves handler at pattern next to the daemon endpoints, e.g. /metrics.
+func (d *Daemon) Handle(pattern string, handler http.Handler) {
+	d.mux.Handle(pattern, handler)
+}
+
+// Handler returns the daemon's HTTP handler.
+func (d *Daemon) Handler() http.Handler {
+	return d.mux
+}
+
+// Status returns the current status.
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// Run serves the HTTP endpoints and runs syncs until ctx ends. A run in flight
+// when ctx ends is asked to stop after its current action and given the
+// shutdown timeout to do so; after that its context is cancelled.
+func (d *Daemon) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", d.cfg.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", d.cfg.ListenAddress, err)
+	}
+	server := &http.Server{Handler: d.mux, ReadHeaderTimeout: 10 * time.Second}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(listener) }()
+	logrus.WithFields(logrus.Fields{
+		"address":  listener.Addr().String(),
+		"schedule": d.cfg.Schedule,
+	}).Info("🩺 Serving /healthz, /readyz and /status")
+
+	d.loop(ctx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Warn("⚠ HTTP server shutdown incomplete (non-fatal)")
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving HTTP: %w", err)
+	}
+	return nil
+}
+
+// loop runs syncs one after another on the schedule until ctx ends.
+func (d *Daemon) loop(ctx context.Context) {
+	runNow := d.cfg.RunOnStart
+	for {
+		if !runNow {
+			next := d.nextRun()
+			d.setIdle(next)
+			logrus.WithField("next_run_at", next.Format(time.RFC3339)).Info("⏰ Next sync scheduled")
+			timer := time.NewTimer(next.Sub(d.now()))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				d.setState(StateStopping)
+				return
+			case <-timer.C:
+			}
+		}
+		runNow = false
+		d.runOnce(ctx)
+		if ctx.Err() != nil {
+			d.setState(StateStopping)
+			return
+		}
+	}
+}
+
+// nextRun returns when the next scheduled run starts, jitter included.
+func (d *Daemon) nextRun() time.Time {
+	return d.schedule.Next(d.now()).Add(d.jitter())
+}
+
+// runOnce runs one sync. The run gets its own context so that shutdown asks it
+// to stop between actions instead of cancelling the action in flight.
+func (d *Daemon) runOnce(ctx context.Context) {
+	stop := make(chan struct{})
+	runCtx, cancel := context.WithCancel(sync.WithStop(context.WithoutCancel(ctx), stop))
+	defer cancel()
+
+	started := d.now()
+	d.setState(StateRunning)
+
+	type outcome struct {
+		result *models.SyncResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := d.run(runCtx)
+		done <- outcome{result, err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		close(stop)
+		d.setState(StateStopping)
+		logrus.WithField("timeout", d.shutdownTimeout.String()).Info("🛑 Shutdown requested — waiting for the current action to finish")
+		timer := time.NewTimer(d.shutdownTimeout)
+		select {
+		case out = <-done:
+			timer.Stop()
+		case <-timer.C:
+			logrus.Warn("⚠ Run did not stop within the shutdown timeout — cancelling it")
+			cancel()
+			out = <-done
+		}
+	}
+	d.record(started, out.result, out.err)
+}
+
+// record stores the outcome of a run started at started.
+func (d *Daemon) record(started time.Time, result *models.SyncResult, runErr error) {
+	finished := d.now()
+	last := &LastRun{StartedAt: started, FinishedAt: finished, DurationMs: finished.Sub(started).Milliseconds()}
+	if result != nil {
+		summary := result.Summary
+		last.RunID, last.DryRun, last.Summary = result.RunID, result.DryRun, &summary
+	}
+
+	entry := logrus.WithFields(logrus.Fields{"run_id": last.RunID, "duration_ms": last.DurationMs})
+	switch {
+	case errors.Is(runErr, models.ErrRunLocked):
+		last.Outcome = OutcomeSkipped
+		entry.Info("⏭️ Sync skipped: another run is in progress")
+	case runErr != nil:
+		last.Outcome, last.Error = OutcomeFailed, runErr.Error()
+	case !result.IsSuccess():
+		last.Outcome, last.Error = OutcomeFailed, fmt.Sprintf("%d actions failed", result.Summary.ActionsFailed)
+		if len(result.Errors) > 0 {
+			last.Error = result.Errors[0]
+		}
+	default:
+		last.Outcome = OutcomeSucceeded
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.LastRun = last
+	switch last.Outcome {
+	case OutcomeSucceeded:
+		d.status.LastSuccessAt = &finished
+		d.status.ConsecutiveFailures = 0
+		entry.Info("✅ Scheduled sync finished")
+	case OutcomeFailed:
+		d.status.ConsecutiveFailures++
+		entry.WithField("consecutive_failures", d.status.ConsecutiveFailures).WithField("error", last.Error).Warn("⚠ Scheduled sync failed")
+	}
+}
+
+func (d *Daemon) setIdle(next time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.State = StateIdle
+	d.status.NextRunAt = &next
+}
+
+func (d *Daemon) setState(state string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.State = state
+	d.status.NextRunAt = nil
+}
+
+// serveHealth reports that the process is alive.
+func (d *Daemon) serveHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// serveReady reports whether the process should receive traffic: not while it
+// is stopping, nor after MaxConsecutiveFailures failed runs in a row.
+func (d *Daemon) serveReady(w http.ResponseWriter, r *http.Request) {
+	status := d.Status()
+	switch {
+	case status.State == StateStopping:
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "stopping"})
+	case d.cfg.MaxConsecutiveFailures > 0 && status.ConsecutiveFailures >= d.cfg.MaxConsecutiveFailures:
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status":               "failing",
+			"consecutive_failures": status.ConsecutiveFailures,
+		})
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	}
+}
+
+// serveStatus reports the state and the last run.
+func (d *Daemon) serveStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, d.Status())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/sync"
+)
+
+func testConfig() config.ServeConfig {
+	return config.ServeConfig{
+		Schedule:               "@every 1h",
+		RunOnStart:             true,
+		ListenAddress:          "127.0.0.1:0",
+		ShutdownTimeoutSeconds: 5,
+		MaxConsecutiveFailures: 2,
+	}
+}
+
+func get(t *testing.T, d *Daemon, path string, body any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if body != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), body); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestEndpoints(t *testing.T) {
+	d, err := New(testConfig(), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return started.Add(2 * time.Second) }
+
+	if code := get(t, d, "/healthz", nil); code != http.StatusOK {
+		t.Fatalf("expected /healthz 200, got %d", code)
+	}
+
+	d.record(started, &models.SyncResult{RunID: "run-1", Summary: models.SyncSummary{ActionsFailed: 1}}, nil)
+	if code := get(t, d, "/readyz", nil); code != http.StatusOK {
+		t.Fatalf("expected /readyz 200 after one failure, got %d", code)
+	}
+	d.record(started, nil, models.ErrRunLocked)
+	d.record(started, nil, errors.New("listing members: boom"))
+	if code := get(t, d, "/readyz", nil); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected /readyz 503 after two failures, skipped runs aside, got %d", code)
+	}
+
+	var status Status
+	get(t, d, "/status", &status)
+	if status.ConsecutiveFailures != 2 || status.LastRun == nil || status.LastRun.Outcome != OutcomeFailed || status.LastRun.Error != "listing members: boom" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	d.record(started, &models.SyncResult{RunID: "run-2", DryRun: true}, nil)
+	get(t, d, "/status", &status)
+	if status.ConsecutiveFailures != 0 || status.LastSuccessAt == nil || status.LastRun.RunID != "run-2" || status.LastRun.DurationMs != 2000 {
+		t.Fatalf("expected a successful run to reset failures, got %+v", status)
+	}
+	if code := get(t, d, "/readyz", nil); code != http.StatusOK {
+		t.Fatalf("expected /readyz 200 after a success, got %d", code)
+	}
+}
+
+func TestNewRejectsInvalidSchedule(t *testing.T) {
+	cfg := testConfig()
+	cfg.Schedule = "sometimes"
+	if _, err := New(cfg, nil); err == nil {
+		t.Fatalf("expected an invalid schedule to be rejected")
+	}
+}
+
+func TestRunStopsGracefully(t *testing.T) {
+	ctx, shutdown := context.WithCancel(context.Background())
+	inFlight := make(chan struct{})
+	var runs int
+	d, err := New(testConfig(), func(runCtx context.Context) (*models.SyncResult, error) {
+		runs++
+		close(inFlight)
+		for !sync.StopRequested(runCtx) {
+			time.Sleep(time.Millisecond)
+		}
+		if runCtx.Err() != nil {
+			t.Errorf("expected the run not to be cancelled while it stops")
+		}
+		return &models.SyncResult{RunID: "run-1", Errors: []string{"run stopped before 3 of 4 actions"}}, nil
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	<-inFlight
+	shutdown()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected Run to return after the run stopped")
+	}
+	status := d.Status()
+	if runs != 1 || status.State != StateStopping || status.LastRun == nil || status.LastRun.RunID != "run-1" {
+		t.Fatalf("expected one recorded run, got %d runs and %+v", runs, status)
+	}
+}
+
+func TestRunCancelsAfterShutdownTimeout(t *testing.T) {
+	ctx, shutdown := context.WithCancel(context.Background())
+	inFlight := make(chan struct{})
+	d, err := New(testConfig(), func(runCtx context.Context) (*models.SyncResult, error) {
+		close(inFlight)
+		<-runCtx.Done()
+		return nil, runCtx.Err()
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.shutdownTimeout = 10 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	<-inFlight
+	shutdown()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the run to be cancelled after the shutdown timeout")
+	}
+	if last := d.Status().LastRun; last == nil || last.Outcome != OutcomeFailed {
+		t.Fatalf("expected the cancelled run to be recorded as failed, got %+v", last)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	cfg := testConfig()
+	cfg.Schedule = "CRON_TZ=UTC 0 */6 * * *"
+	cfg.JitterSeconds = 30
+	d, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.now = func() time.Time { return time.Date(2024, 3, 1, 13, 5, 0, 0, time.UTC) }
+
+	due := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		next := d.nextRun()
+		if next.Before(due) || !next.Before(due.Add(30*time.Second)) {
+			t.Fatalf("expected the next run within 30s after %s, got %s", due, next)
+		}
+	}
+
+	d.jitter = func() time.Duration { return 0 }
+	if next := d.nextRun(); !next.Equal(due) {
+		t.Fatalf("expected %s without jitter, got %s", due, next)
+	}
+}
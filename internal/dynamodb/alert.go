@@ -0,0 +1,71 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// alertStateItem stores an org's alert state as JSON, so that the rule map can
+// grow without schema changes.
+type alertStateItem struct {
+	PK    string `dynamodbav:"pk"` // ALERT#<org>
+	SK    string `dynamodbav:"sk"` // STATE
+	State string `dynamodbav:"state"`
+}
+
+func alertStateKey(org string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "ALERT#" + org},
+		"sk": &types.AttributeValueMemberS{Value: "STATE"},
+	}
+}
+
+// GetAlertState returns the org's alert state, or nil if none was saved.
+func (s *Store) GetAlertState(ctx context.Context, org string) (*models.AlertState, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName),
+		Key:            alertStateKey(org),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting alert state: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	var item alertStateItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("unmarshaling alert state: %w", err)
+	}
+	var state models.AlertState
+	if err := json.Unmarshal([]byte(item.State), &state); err != nil {
+		return nil, fmt.Errorf("decoding alert state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveAlertState replaces the org's alert state.
+func (s *Store) SaveAlertState(ctx context.Context, state models.AlertState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding alert state: %w", err)
+	}
+	item, err := attributevalue.MarshalMap(alertStateItem{PK: "ALERT#" + state.Org, SK: "STATE", State: string(data)})
+	if err != nil {
+		return fmt.Errorf("marshaling alert state: %w", err)
+	}
+	if _, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      item,
+	}); err != nil {
+		return fmt.Errorf("saving alert state: %w", err)
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package dynamodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -13,13 +15,17 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
 )
 
 // Store implements the InvitationStore interface using DynamoDB.
 type Store struct {
-	client    *dynamodb.Client
-	tableName string
-	ttlDays   int
+	client         *dynamodb.Client
+	query          dynamodb.QueryAPIClient
+	tableName      string
+	ttlDays        int
+	consistentRead bool
+	stats          queryStats
 }
 
 // NewStore creates a new DynamoDB-backed InvitationStore.
@@ -38,6 +44,7 @@ func NewStore(ctx context.Context, cfg config.DynamoDBConfig) (*Store, error) {
 	if err != nil {
 		return nil, fmt.Errorf("loading AWS config: %w", err)
 	}
+	tracing.InstrumentAWS(&awsCfg)
 
 	var clientOpts []func(*dynamodb.Options)
 	if cfg.Endpoint != "" {
@@ -54,9 +61,11 @@ func NewStore(ctx context.Context, cfg config.DynamoDBConfig) (*Store, error) {
 	}
 
 	return &Store{
-		client:    client,
-		tableName: cfg.TableName,
-		ttlDays:   ttlDays,
+		client:         client,
+		query:          client,
+		tableName:      cfg.TableName,
+		ttlDays:        ttlDays,
+		consistentRead: cfg.ConsistentRead,
 	}, nil
 }
 
@@ -78,17 +87,53 @@ func (s *Store) SaveInvitation(ctx context.Context, mapping models.InvitationMap
 	return nil
 }
 
+// CreateMapping stores mapping only if no mapping with its key exists.
+func (s *Store) CreateMapping(ctx context.Context, mapping models.InvitationMapping) error {
+	item, err := attributevalue.MarshalMap(mapping)
+	if err != nil {
+		return fmt.Errorf("marshaling mapping: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		current, getErr := s.getMapping(ctx, mapping.PK, mapping.SK, true)
+		if getErr != nil {
+			return fmt.Errorf("reading mapping after rejected create: %w", getErr)
+		}
+		err = models.ExistingMapping(mapping, current)
+	}
+	if err != nil {
+		return fmt.Errorf("creating mapping: %w", err)
+	}
+	return nil
+}
+
 // GetInvitation retrieves an invitation by org and invitation ID.
 func (s *Store) GetInvitation(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error) {
+	mapping, err := s.getMapping(ctx, "ORG#"+org, fmt.Sprintf("INV#%d", invitationID), s.consistentRead)
+	if err != nil {
+		return nil, fmt.Errorf("getting invitation: %w", err)
+	}
+	return mapping, nil
+}
+
+// getMapping reads one mapping by key, returning nil if it does not exist.
+func (s *Store) getMapping(ctx context.Context, pk string, sk string, consistent bool) (*models.InvitationMapping, error) {
 	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(s.tableName),
 		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("INV#%d", invitationID)},
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
 		},
+		ConsistentRead: aws.Bool(consistent),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("getting invitation: %w", err)
+		return nil, err
 	}
 
 	if result.Item == nil {
@@ -105,51 +150,53 @@ func (s *Store) GetInvitation(ctx context.Context, org string, invitationID int6
 
 // GetPendingInvitations returns all pending invitations for an org using status-index GSI.
 func (s *Store) GetPendingInvitations(ctx context.Context, org string) ([]models.InvitationMapping, error) {
-	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String(s.tableName),
-		IndexName:              aws.String("status-index"),
-		KeyConditionExpression: aws.String("gsi2pk = :pk AND gsi2sk = :sk"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
-			":sk": &types.AttributeValueMemberS{Value: "STATUS#pending"},
-		},
-	})
+	mappings, err := s.queryMappings(ctx, opPendingInvitations, s.statusQuery(org, models.InvitationPending))
 	if err != nil {
 		return nil, fmt.Errorf("querying pending invitations: %w", err)
 	}
+	return mappings, nil
+}
 
-	var mappings []models.InvitationMapping
-	if err := attributevalue.UnmarshalListOfMaps(result.Items, &mappings); err != nil {
-		return nil, fmt.Errorf("unmarshaling pending invitations: %w", err)
+// statusQuery selects an org's mappings with the given status from the status-index GSI.
+// GSIs are only eventually consistent, so with consistent reads enabled the org's
+// partition is read from the base table and filtered on the same attributes instead.
+func (s *Store) statusQuery(org string, status models.InvitationStatus) *dynamodb.QueryInput {
+	values := map[string]types.AttributeValue{
+		":pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
+		":sk": &types.AttributeValueMemberS{Value: "STATUS#" + string(status)},
+	}
+	if s.consistentRead {
+		return &dynamodb.QueryInput{
+			TableName:                 aws.String(s.tableName),
+			KeyConditionExpression:    aws.String("pk = :pk"),
+			FilterExpression:          aws.String("gsi2sk = :sk"),
+			ExpressionAttributeValues: values,
+			ConsistentRead:            aws.Bool(true),
+		}
+	}
+	return &dynamodb.QueryInput{
+		TableName:                 aws.String(s.tableName),
+		IndexName:                 aws.String("status-index"),
+		KeyConditionExpression:    aws.String("gsi2pk = :pk AND gsi2sk = :sk"),
+		ExpressionAttributeValues: values,
 	}
-
-	return mappings, nil
 }
 
-// ResolveInvitation updates an invitation with the resolved GitHub username.
+// ResolveInvitation updates a pending invitation with the resolved GitHub username.
 func (s *Store) ResolveInvitation(ctx context.Context, org string, invitationID int64, githubLogin string) error {
 	now := time.Now().UTC()
 	ttl := now.AddDate(0, 0, s.ttlDays).Unix()
 
-	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(s.tableName),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("INV#%d", invitationID)},
-		},
-		UpdateExpression: aws.String("SET github_login = :login, #st = :status, resolved_at = :resolved, gsi2sk = :gsi2sk, #ttl = :ttl"),
-		ExpressionAttributeNames: map[string]string{
-			"#st":  "status",
+	err := s.updateStatus(ctx, org, fmt.Sprintf("INV#%d", invitationID), models.InvitationResolved,
+		"github_login = :login, resolved_at = :resolved, #ttl = :ttl",
+		map[string]string{
 			"#ttl": "ttl",
 		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
+		map[string]types.AttributeValue{
 			":login":    &types.AttributeValueMemberS{Value: githubLogin},
-			":status":   &types.AttributeValueMemberS{Value: string(models.InvitationResolved)},
 			":resolved": &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
-			":gsi2sk":   &types.AttributeValueMemberS{Value: "STATUS#" + string(models.InvitationResolved)},
 			":ttl":      &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", ttl)},
-		},
-	})
+		})
 	if err != nil {
 		return fmt.Errorf("resolving invitation: %w", err)
 	}
@@ -159,44 +206,102 @@ func (s *Store) ResolveInvitation(ctx context.Context, org string, invitationID
 
 // UpdateStatus changes the status of an invitation (failed, expired, cancelled, removed).
 func (s *Store) UpdateStatus(ctx context.Context, org string, invitationID int64, status models.InvitationStatus) error {
-	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	err := s.updateStatus(ctx, org, fmt.Sprintf("INV#%d", invitationID), status, "", map[string]string{}, map[string]types.AttributeValue{})
+	if err != nil {
+		return fmt.Errorf("updating invitation status: %w", err)
+	}
+
+	return nil
+}
+
+// updateStatus moves mapping sk to status, also applying the extra SET clauses in set.
+// The write is conditioned on the current status being one status may be reached from.
+func (s *Store) updateStatus(ctx context.Context, org string, sk string, status models.InvitationStatus, set string, names map[string]string, values map[string]types.AttributeValue) error {
+	sources := models.TransitionSources(status)
+	if len(sources) == 0 {
+		return &models.TransitionError{SK: sk, To: status, Err: models.ErrInvalidTransition}
+	}
+	placeholders := make([]string, len(sources))
+	for i, from := range sources {
+		placeholders[i] = fmt.Sprintf(":from%d", i)
+		values[placeholders[i]] = &types.AttributeValueMemberS{Value: string(from)}
+	}
+
+	update := "SET #st = :status, gsi2sk = :gsi2sk"
+	if set != "" {
+		update += ", " + set
+	}
+	names["#st"] = "status"
+	values[":status"] = &types.AttributeValueMemberS{Value: string(status)}
+	values[":gsi2sk"] = &types.AttributeValueMemberS{Value: "STATUS#" + string(status)}
+
+	return s.conditionalUpdate(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(s.tableName),
 		Key: map[string]types.AttributeValue{
 			"pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
-			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("INV#%d", invitationID)},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+		UpdateExpression:          aws.String(update),
+		ConditionExpression:       aws.String("attribute_exists(pk) AND #st IN (" + strings.Join(placeholders, ", ") + ")"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}, status)
+}
+
+// TransitionMapping moves a mapping of any kind to status, provided it still has
+// the status and version it was read with.
+func (s *Store) TransitionMapping(ctx context.Context, mapping models.InvitationMapping, status models.InvitationStatus) error {
+	if !mapping.Status.CanTransitionTo(status) {
+		return &models.TransitionError{SK: mapping.SK, From: mapping.Status, To: status, Err: models.ErrInvalidTransition}
+	}
+
+	values := map[string]types.AttributeValue{
+		":from":   &types.AttributeValueMemberS{Value: string(mapping.Status)},
+		":status": &types.AttributeValueMemberS{Value: string(status)},
+		":gsi2sk": &types.AttributeValueMemberS{Value: "STATUS#" + string(status)},
+	}
+	condition := "attribute_exists(pk) AND #st = :from AND attribute_not_exists(#ver)"
+	if mapping.Version > 0 {
+		condition = "attribute_exists(pk) AND #st = :from AND #ver = :version"
+		values[":version"] = &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", mapping.Version)}
+	}
+
+	err := s.conditionalUpdate(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: mapping.PK},
+			"sk": &types.AttributeValueMemberS{Value: mapping.SK},
 		},
-		UpdateExpression: aws.String("SET #st = :status, gsi2sk = :gsi2sk"),
+		UpdateExpression:    aws.String("SET #st = :status, gsi2sk = :gsi2sk"),
+		ConditionExpression: aws.String(condition),
 		ExpressionAttributeNames: map[string]string{
 			"#st": "status",
 		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":status": &types.AttributeValueMemberS{Value: string(status)},
-			":gsi2sk": &types.AttributeValueMemberS{Value: "STATUS#" + string(status)},
-		},
-	})
+		ExpressionAttributeValues: values,
+	}, status)
 	if err != nil {
-		return fmt.Errorf("updating invitation status: %w", err)
+		return fmt.Errorf("updating mapping status: %w", err)
 	}
-
 	return nil
 }
 
-// UpdateRole updates the role of an invitation mapping.
+// UpdateRole updates the role of an existing invitation mapping.
 func (s *Store) UpdateRole(ctx context.Context, org string, invitationID int64, role models.OrgRole) error {
-	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	err := s.conditionalUpdate(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(s.tableName),
 		Key: map[string]types.AttributeValue{
 			"pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
 			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("INV#%d", invitationID)},
 		},
-		UpdateExpression: aws.String("SET #r = :role"),
+		UpdateExpression:    aws.String("SET #r = :role"),
+		ConditionExpression: aws.String("attribute_exists(pk)"),
 		ExpressionAttributeNames: map[string]string{
 			"#r": "role",
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":role": &types.AttributeValueMemberS{Value: string(role)},
 		},
-	})
+	}, "")
 	if err != nil {
 		return fmt.Errorf("updating invitation role: %w", err)
 	}
@@ -204,26 +309,52 @@ func (s *Store) UpdateRole(ctx context.Context, org string, invitationID int64,
 	return nil
 }
 
+// conditionalUpdate applies input and increments the mapping's version. When the
+// condition fails, the mapping is re-read to report why as a *models.TransitionError.
+func (s *Store) conditionalUpdate(ctx context.Context, input *dynamodb.UpdateItemInput, status models.InvitationStatus) error {
+	input.UpdateExpression = aws.String(*input.UpdateExpression + ", #ver = if_not_exists(#ver, :zero) + :one")
+	input.ExpressionAttributeNames["#ver"] = "version"
+	input.ExpressionAttributeValues[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+	input.ExpressionAttributeValues[":one"] = &types.AttributeValueMemberN{Value: "1"}
+
+	_, err := s.client.UpdateItem(ctx, input)
+	var conditionFailed *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditionFailed) {
+		return err
+	}
+
+	pk := input.Key["pk"].(*types.AttributeValueMemberS).Value
+	sk := input.Key["sk"].(*types.AttributeValueMemberS).Value
+	current, getErr := s.getMapping(ctx, pk, sk, true)
+	if getErr != nil {
+		return fmt.Errorf("reading mapping after rejected update: %w", getErr)
+	}
+	return models.RejectedTransition(sk, current, status)
+}
+
 // GetByEmail retrieves invitation mappings for a specific email using email-index GSI.
+// With consistent reads enabled the org's partition is filtered on the email instead.
 func (s *Store) GetByEmail(ctx context.Context, email string, org string) ([]models.InvitationMapping, error) {
-	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
+	input := &dynamodb.QueryInput{
 		TableName:              aws.String(s.tableName),
 		IndexName:              aws.String("email-index"),
-		KeyConditionExpression: aws.String("gsi1pk = :pk AND gsi1sk = :sk"),
+		KeyConditionExpression: aws.String("gsi1pk = :email AND gsi1sk = :org"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "EMAIL#" + email},
-			":sk": &types.AttributeValueMemberS{Value: "ORG#" + org},
+			":email": &types.AttributeValueMemberS{Value: "EMAIL#" + email},
+			":org":   &types.AttributeValueMemberS{Value: "ORG#" + org},
 		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("querying by email: %w", err)
 	}
-
-	var mappings []models.InvitationMapping
-	if err := attributevalue.UnmarshalListOfMaps(result.Items, &mappings); err != nil {
-		return nil, fmt.Errorf("unmarshaling email results: %w", err)
+	if s.consistentRead {
+		input.IndexName = nil
+		input.KeyConditionExpression = aws.String("pk = :org")
+		input.FilterExpression = aws.String("gsi1pk = :email")
+		input.ConsistentRead = aws.Bool(true)
 	}
 
+	mappings, err := s.queryMappings(ctx, opByEmail, input)
+	if err != nil {
+		return nil, fmt.Errorf("querying by email: %w", err)
+	}
 	return mappings, nil
 }
 
@@ -235,6 +366,7 @@ func (s *Store) GetAuditLogCursor(ctx context.Context, org string) (*models.Audi
 			"pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
 			"sk": &types.AttributeValueMemberS{Value: "CURSOR#audit_log"},
 		},
+		ConsistentRead: aws.Bool(s.consistentRead),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("getting audit log cursor: %w", err)
@@ -272,30 +404,44 @@ func (s *Store) SaveAuditLogCursor(ctx context.Context, cursor models.AuditLogCu
 
 // GetAllResolvedMappings returns all resolved email→username mappings for an org.
 func (s *Store) GetAllResolvedMappings(ctx context.Context, org string) (map[string]string, error) {
-	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
-		TableName:              aws.String(s.tableName),
-		IndexName:              aws.String("status-index"),
-		KeyConditionExpression: aws.String("gsi2pk = :pk AND gsi2sk = :sk"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
-			":sk": &types.AttributeValueMemberS{Value: "STATUS#resolved"},
-		},
-	})
+	mappings, err := s.queryMappings(ctx, opResolvedMappings, s.statusQuery(org, models.InvitationResolved))
 	if err != nil {
 		return nil, fmt.Errorf("querying resolved mappings: %w", err)
 	}
+	return models.ResolvedLogins(mappings), nil
+}
 
-	var mappings []models.InvitationMapping
-	if err := attributevalue.UnmarshalListOfMaps(result.Items, &mappings); err != nil {
-		return nil, fmt.Errorf("unmarshaling resolved mappings: %w", err)
+// ListMappings returns every invitation mapping (INV# and EXISTING# records) for an org.
+func (s *Store) ListMappings(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("pk = :pk"),
+		FilterExpression:       aws.String("NOT begins_with(sk, :cursor)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":     &types.AttributeValueMemberS{Value: "ORG#" + org},
+			":cursor": &types.AttributeValueMemberS{Value: "CURSOR#"},
+		},
+		ConsistentRead: aws.Bool(s.consistentRead),
 	}
 
-	resolved := make(map[string]string, len(mappings))
-	for _, m := range mappings {
-		if m.GitHubLogin != nil {
-			resolved[m.Email] = *m.GitHubLogin
-		}
+	mappings, err := s.queryMappings(ctx, opListMappings, input)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
 	}
+	return mappings, nil
+}
 
-	return resolved, nil
+// DeleteMapping removes an invitation mapping by org and sort key.
+func (s *Store) DeleteMapping(ctx context.Context, org string, sk string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: "ORG#" + org},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("deleting mapping: %w", err)
+	}
+	return nil
 }
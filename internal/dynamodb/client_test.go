@@ -54,3 +54,29 @@ func TestMockStoreTracking(t *testing.T) {
 		t.Fatalf("expected email user@example.com, got %s", store.SavedInvitations[0].Email)
 	}
 }
+
+func TestJournalItemKeys(t *testing.T) {
+	entry := models.JournalEntry{
+		RunID:     "20250301T120000Z-ab12",
+		Sequence:  3,
+		Org:       "my-org",
+		Action:    models.ActionInvite,
+		Email:     "test@example.com",
+		Timestamp: time.Date(2025, 3, 1, 12, 0, 0, 5000, time.UTC),
+	}
+
+	if pk := journalPK(entry.Org); pk != "JOURNAL#my-org" {
+		t.Fatalf("expected PK JOURNAL#my-org, got %s", pk)
+	}
+	sk := journalSK(entry)
+	if sk != "TS#2025-03-01T12:00:00.000005000Z#20250301T120000Z-ab12#0003" {
+		t.Fatalf("unexpected SK %s", sk)
+	}
+
+	later := entry
+	later.Timestamp = entry.Timestamp.Add(time.Second)
+	later.Sequence = 0
+	if journalSK(later) <= sk {
+		t.Fatalf("expected later entries to sort after earlier ones")
+	}
+}
@@ -0,0 +1,142 @@
+package dynamodb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/httpcache"
+	"github.com/sirupsen/logrus"
+)
+
+// maxCacheBodyBytes keeps cache items below the 400 KB DynamoDB item limit.
+const maxCacheBodyBytes = 350 * 1024
+
+// httpCacheTTLDays expires cache items that have not been refreshed.
+const httpCacheTTLDays = 7
+
+// HTTPCache implements httpcache.Store on the invitation mappings table.
+type HTTPCache struct {
+	store *Store
+}
+
+type httpCacheItem struct {
+	PK           string `dynamodbav:"pk"`
+	SK           string `dynamodbav:"sk"`
+	ETag         string `dynamodbav:"etag,omitempty"`
+	LastModified string `dynamodbav:"last_modified,omitempty"`
+	StatusCode   int    `dynamodbav:"status_code"`
+	Header       string `dynamodbav:"header"`
+	Body         []byte `dynamodbav:"body"` // gzip-compressed
+	StoredAt     string `dynamodbav:"stored_at"`
+	TTL          int64  `dynamodbav:"ttl"`
+}
+
+// HTTPCache returns an HTTP response cache backed by this store's table.
+func (s *Store) HTTPCache() *HTTPCache {
+	return &HTTPCache{store: s}
+}
+
+func httpCacheKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "HTTPCACHE#" + key},
+		"sk": &types.AttributeValueMemberS{Value: "ENTRY"},
+	}
+}
+
+// Get returns the cached entry for key, or nil if there is none.
+func (c *HTTPCache) Get(ctx context.Context, key string) (*httpcache.Entry, error) {
+	result, err := c.store.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.store.tableName),
+		Key:       httpCacheKey(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting http cache entry: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var item httpCacheItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("unmarshaling http cache entry: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(item.Body))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing http cache body: %w", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing http cache body: %w", err)
+	}
+
+	var header http.Header
+	if err := json.Unmarshal([]byte(item.Header), &header); err != nil {
+		return nil, fmt.Errorf("decoding http cache header: %w", err)
+	}
+	storedAt, _ := time.Parse(time.RFC3339, item.StoredAt)
+
+	return &httpcache.Entry{
+		ETag:         item.ETag,
+		LastModified: item.LastModified,
+		StatusCode:   item.StatusCode,
+		Header:       header,
+		Body:         body,
+		StoredAt:     storedAt,
+	}, nil
+}
+
+// Set stores the entry for key. Entries too large for a DynamoDB item are skipped.
+func (c *HTTPCache) Set(ctx context.Context, key string, entry httpcache.Entry) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(entry.Body); err != nil {
+		return fmt.Errorf("compressing http cache body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compressing http cache body: %w", err)
+	}
+	if buf.Len() > maxCacheBodyBytes {
+		logrus.WithField("size", buf.Len()).Debug("http cache entry too large for DynamoDB, not cached")
+		return nil
+	}
+
+	header, err := json.Marshal(entry.Header)
+	if err != nil {
+		return fmt.Errorf("encoding http cache header: %w", err)
+	}
+
+	item, err := attributevalue.MarshalMap(httpCacheItem{
+		PK:           "HTTPCACHE#" + key,
+		SK:           "ENTRY",
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+		StatusCode:   entry.StatusCode,
+		Header:       string(header),
+		Body:         buf.Bytes(),
+		StoredAt:     entry.StoredAt.Format(time.RFC3339),
+		TTL:          time.Now().UTC().AddDate(0, 0, httpCacheTTLDays).Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling http cache entry: %w", err)
+	}
+
+	_, err = c.store.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.store.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("saving http cache entry: %w", err)
+	}
+	return nil
+}
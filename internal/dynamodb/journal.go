@@ -0,0 +1,114 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// journalTimeFormat is fixed-width so sort keys order chronologically.
+const journalTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Journal implements ActionJournal on the invitation mappings table.
+// Items are written once and never updated or expired.
+type Journal struct {
+	store *Store
+}
+
+type journalItem struct {
+	PK string `dynamodbav:"pk"` // JOURNAL#<org>
+	SK string `dynamodbav:"sk"` // TS#<timestamp>#<run_id>#<sequence>
+	models.JournalEntry
+}
+
+// Journal returns an action journal backed by this store's table.
+func (s *Store) Journal() *Journal {
+	return &Journal{store: s}
+}
+
+func journalPK(org string) string {
+	return "JOURNAL#" + org
+}
+
+func journalSK(entry models.JournalEntry) string {
+	return fmt.Sprintf("TS#%s#%s#%04d", entry.Timestamp.UTC().Format(journalTimeFormat), entry.RunID, entry.Sequence)
+}
+
+// Append writes entries. A conditional put guarantees existing entries are never overwritten.
+func (j *Journal) Append(ctx context.Context, entries []models.JournalEntry) error {
+	for _, entry := range entries {
+		item, err := attributevalue.MarshalMap(journalItem{
+			PK:           journalPK(entry.Org),
+			SK:           journalSK(entry),
+			JournalEntry: entry,
+		})
+		if err != nil {
+			return fmt.Errorf("marshaling journal entry: %w", err)
+		}
+		_, err = j.store.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(j.store.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		})
+		if err != nil {
+			return fmt.Errorf("saving journal entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Query returns entries for filter.Org matching the filter, newest first.
+// The date range is applied on the sort key; user and action are filtered client-side.
+func (j *Journal) Query(ctx context.Context, filter models.JournalFilter) ([]models.JournalEntry, error) {
+	if filter.Org == "" {
+		return nil, fmt.Errorf("org is required to query the DynamoDB journal")
+	}
+
+	from := "TS#"
+	if !filter.Since.IsZero() {
+		from += filter.Since.UTC().Format(journalTimeFormat)
+	}
+	to := "TS#~"
+	if !filter.Until.IsZero() {
+		to = "TS#" + filter.Until.UTC().Format(journalTimeFormat)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(j.store.tableName),
+		KeyConditionExpression: aws.String("pk = :pk AND sk BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":   &types.AttributeValueMemberS{Value: journalPK(filter.Org)},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+		},
+		ScanIndexForward: aws.Bool(false),
+		ConsistentRead:   aws.Bool(j.store.consistentRead),
+	}
+
+	var entries []models.JournalEntry
+	err := j.store.queryPages(ctx, opJournal, input, func(page []map[string]types.AttributeValue) (bool, error) {
+		var items []journalItem
+		if err := attributevalue.UnmarshalListOfMaps(page, &items); err != nil {
+			return false, fmt.Errorf("unmarshaling journal entries: %w", err)
+		}
+		for _, item := range items {
+			if !filter.Matches(item.JournalEntry) {
+				continue
+			}
+			entries = append(entries, item.JournalEntry)
+			if filter.Limit > 0 && len(entries) >= filter.Limit {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying journal: %w", err)
+	}
+	return entries, nil
+}
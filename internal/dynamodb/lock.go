@@ -0,0 +1,140 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// leaseRetention keeps expired leases around for a day before DynamoDB TTL deletes
+// them. Expiry itself is checked against expires_at, as TTL deletion is lazy.
+const leaseRetention = 24 * time.Hour
+
+type leaseItem struct {
+	PK        string `dynamodbav:"pk"` // LOCK#<name>
+	SK        string `dynamodbav:"sk"` // LEASE
+	Owner     string `dynamodbav:"owner"`
+	ExpiresAt int64  `dynamodbav:"expires_at"` // Unix milliseconds
+	TTL       int64  `dynamodbav:"ttl"`
+}
+
+func leaseKey(name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "LOCK#" + name},
+		"sk": &types.AttributeValueMemberS{Value: "LEASE"},
+	}
+}
+
+// AcquireLease takes the named lease with a conditional put that only succeeds if
+// no lease exists, the lease has expired, or owner already holds it.
+func (s *Store) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error) {
+	now := time.Now()
+	expires := now.Add(ttl)
+	item, err := attributevalue.MarshalMap(leaseItem{
+		PK:        "LOCK#" + name,
+		SK:        "LEASE",
+		Owner:     owner,
+		ExpiresAt: expires.UnixMilli(),
+		TTL:       expires.Add(leaseRetention).Unix(),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("marshaling lease: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(s.tableName),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(pk) OR expires_at < :now OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]string{"#owner": "owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", now.UnixMilli())},
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		holder, getErr := s.getLease(ctx, name)
+		if getErr != nil {
+			return nil, false, getErr
+		}
+		if holder == nil {
+			// Released between the put and the read; the caller may retry.
+			holder = &models.RunLease{Name: name}
+		}
+		return holder, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("acquiring lease: %w", err)
+	}
+	return &models.RunLease{Name: name, Owner: owner, ExpiresAt: time.UnixMilli(expires.UnixMilli()).UTC()}, true, nil
+}
+
+// RenewLease extends the lease if owner still holds it.
+func (s *Store) RenewLease(ctx context.Context, name string, owner string, ttl time.Duration) error {
+	expires := time.Now().Add(ttl)
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(s.tableName),
+		Key:                      leaseKey(name),
+		UpdateExpression:         aws.String("SET expires_at = :expires, #ttl = :ttl"),
+		ConditionExpression:      aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{"#owner": "owner", "#ttl": "ttl"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expires": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expires.UnixMilli())},
+			":ttl":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", expires.Add(leaseRetention).Unix())},
+			":owner":   &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return models.ErrLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("renewing lease: %w", err)
+	}
+	return nil
+}
+
+// ReleaseLease deletes the lease if owner still holds it. A lease taken over by
+// another owner is left alone.
+func (s *Store) ReleaseLease(ctx context.Context, name string, owner string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(s.tableName),
+		Key:                      leaseKey(name),
+		ConditionExpression:      aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{"#owner": "owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) getLease(ctx context.Context, name string) (*models.RunLease, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName),
+		Key:            leaseKey(name),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting lease: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	var item leaseItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("unmarshaling lease: %w", err)
+	}
+	return &models.RunLease{Name: name, Owner: item.Owner, ExpiresAt: time.UnixMilli(item.ExpiresAt).UTC()}, nil
+}
@@ -2,6 +2,7 @@ package dynamodb
 
 import (
 	"context"
+	"time"
 
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 )
@@ -9,6 +10,7 @@ import (
 // MockStore implements InvitationStore for testing.
 type MockStore struct {
 	SaveInvitationFunc         func(ctx context.Context, mapping models.InvitationMapping) error
+	CreateMappingFunc          func(ctx context.Context, mapping models.InvitationMapping) error
 	GetInvitationFunc          func(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error)
 	GetPendingInvitationsFunc  func(ctx context.Context, org string) ([]models.InvitationMapping, error)
 	ResolveInvitationFunc      func(ctx context.Context, org string, invitationID int64, githubLogin string) error
@@ -18,6 +20,14 @@ type MockStore struct {
 	GetAuditLogCursorFunc      func(ctx context.Context, org string) (*models.AuditLogCursor, error)
 	SaveAuditLogCursorFunc     func(ctx context.Context, cursor models.AuditLogCursor) error
 	GetAllResolvedMappingsFunc func(ctx context.Context, org string) (map[string]string, error)
+	ListMappingsFunc           func(ctx context.Context, org string) ([]models.InvitationMapping, error)
+	DeleteMappingFunc          func(ctx context.Context, org string, sk string) error
+	TransitionMappingFunc      func(ctx context.Context, mapping models.InvitationMapping, status models.InvitationStatus) error
+	AcquireLeaseFunc           func(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error)
+	RenewLeaseFunc             func(ctx context.Context, name string, owner string, ttl time.Duration) error
+	ReleaseLeaseFunc           func(ctx context.Context, name string, owner string) error
+	GetAlertStateFunc          func(ctx context.Context, org string) (*models.AlertState, error)
+	SaveAlertStateFunc         func(ctx context.Context, state models.AlertState) error
 
 	// Track calls for assertions.
 	SavedInvitations []models.InvitationMapping
@@ -25,6 +35,12 @@ type MockStore struct {
 	StatusCalls      []StatusCall
 	RoleCalls        []RoleCall
 	SavedCursors     []models.AuditLogCursor
+	DeletedSKs       []string
+	Transitions      []TransitionCall
+	AcquiredLeases   []string
+	RenewedLeases    []string
+	ReleasedLeases   []string
+	SavedAlertStates []models.AlertState
 }
 
 // ResolveCall records a call to ResolveInvitation.
@@ -41,6 +57,12 @@ type StatusCall struct {
 	Status       models.InvitationStatus
 }
 
+// TransitionCall records a call to TransitionMapping.
+type TransitionCall struct {
+	Mapping models.InvitationMapping
+	Status  models.InvitationStatus
+}
+
 // RoleCall records a call to UpdateRole.
 type RoleCall struct {
 	Org          string
@@ -56,6 +78,16 @@ func (m *MockStore) SaveInvitation(ctx context.Context, mapping models.Invitatio
 	return nil
 }
 
+func (m *MockStore) CreateMapping(ctx context.Context, mapping models.InvitationMapping) error {
+	if m.CreateMappingFunc != nil {
+		if err := m.CreateMappingFunc(ctx, mapping); err != nil {
+			return err
+		}
+	}
+	m.SavedInvitations = append(m.SavedInvitations, mapping)
+	return nil
+}
+
 func (m *MockStore) GetInvitation(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error) {
 	if m.GetInvitationFunc != nil {
 		return m.GetInvitationFunc(ctx, org, invitationID)
@@ -122,3 +154,65 @@ func (m *MockStore) GetAllResolvedMappings(ctx context.Context, org string) (map
 	}
 	return map[string]string{}, nil
 }
+
+func (m *MockStore) ListMappings(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+	if m.ListMappingsFunc != nil {
+		return m.ListMappingsFunc(ctx, org)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) DeleteMapping(ctx context.Context, org string, sk string) error {
+	m.DeletedSKs = append(m.DeletedSKs, sk)
+	if m.DeleteMappingFunc != nil {
+		return m.DeleteMappingFunc(ctx, org, sk)
+	}
+	return nil
+}
+
+func (m *MockStore) TransitionMapping(ctx context.Context, mapping models.InvitationMapping, status models.InvitationStatus) error {
+	m.Transitions = append(m.Transitions, TransitionCall{Mapping: mapping, Status: status})
+	if m.TransitionMappingFunc != nil {
+		return m.TransitionMappingFunc(ctx, mapping, status)
+	}
+	return nil
+}
+
+func (m *MockStore) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error) {
+	m.AcquiredLeases = append(m.AcquiredLeases, name)
+	if m.AcquireLeaseFunc != nil {
+		return m.AcquireLeaseFunc(ctx, name, owner, ttl)
+	}
+	return &models.RunLease{Name: name, Owner: owner, ExpiresAt: time.Now().Add(ttl)}, true, nil
+}
+
+func (m *MockStore) RenewLease(ctx context.Context, name string, owner string, ttl time.Duration) error {
+	m.RenewedLeases = append(m.RenewedLeases, name)
+	if m.RenewLeaseFunc != nil {
+		return m.RenewLeaseFunc(ctx, name, owner, ttl)
+	}
+	return nil
+}
+
+func (m *MockStore) ReleaseLease(ctx context.Context, name string, owner string) error {
+	m.ReleasedLeases = append(m.ReleasedLeases, name)
+	if m.ReleaseLeaseFunc != nil {
+		return m.ReleaseLeaseFunc(ctx, name, owner)
+	}
+	return nil
+}
+
+func (m *MockStore) GetAlertState(ctx context.Context, org string) (*models.AlertState, error) {
+	if m.GetAlertStateFunc != nil {
+		return m.GetAlertStateFunc(ctx, org)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) SaveAlertState(ctx context.Context, state models.AlertState) error {
+	m.SavedAlertStates = append(m.SavedAlertStates, state)
+	if m.SaveAlertStateFunc != nil {
+		return m.SaveAlertStateFunc(ctx, state)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Store operations reported by QueryStats.
+const (
+	opPendingInvitations = "pending_invitations"
+	opByEmail            = "by_email"
+	opResolvedMappings   = "resolved_mappings"
+	opListMappings       = "list_mappings"
+	opJournal            = "journal"
+)
+
+// queryStats accumulates per-operation page counts. The zero value is ready to use.
+type queryStats struct {
+	mu  sync.Mutex
+	ops map[string]models.StoreQueryStats
+}
+
+func (q *queryStats) add(op string, call models.StoreQueryStats) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ops == nil {
+		q.ops = make(map[string]models.StoreQueryStats)
+	}
+	total := q.ops[op]
+	total.Queries += call.Queries
+	total.Pages += call.Pages
+	total.Items += call.Items
+	total.ReadUnits += call.ReadUnits
+	if call.Pages > total.MaxPages {
+		total.MaxPages = call.Pages
+	}
+	q.ops[op] = total
+}
+
+// QueryStats returns the pages and items read by each store operation since the store was created.
+func (s *Store) QueryStats() map[string]models.StoreQueryStats {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	stats := make(map[string]models.StoreQueryStats, len(s.stats.ops))
+	for op, st := range s.stats.ops {
+		stats[op] = st
+	}
+	return stats
+}
+
+// queryPages runs input through a paginator until LastEvaluatedKey is empty,
+// passing each page's items to fn. fn returns false to stop early.
+// Every call is recorded in QueryStats under op.
+func (s *Store) queryPages(ctx context.Context, op string, input *dynamodb.QueryInput, fn func(items []map[string]types.AttributeValue) (bool, error)) error {
+	input.ReturnConsumedCapacity = types.ReturnConsumedCapacityTotal
+	paginator := dynamodb.NewQueryPaginator(s.query, input)
+
+	call := models.StoreQueryStats{Queries: 1}
+	defer func() {
+		s.stats.add(op, call)
+		if call.Pages > 1 {
+			logrus.WithFields(logrus.Fields{
+				"operation": op,
+				"pages":     call.Pages,
+				"items":     call.Items,
+			}).Debug("DynamoDB query paginated")
+		}
+	}()
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		call.Pages++
+		call.Items += len(page.Items)
+		if page.ConsumedCapacity != nil && page.ConsumedCapacity.CapacityUnits != nil {
+			call.ReadUnits += *page.ConsumedCapacity.CapacityUnits
+		}
+		more, err := fn(page.Items)
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryMappings reads every invitation mapping matching input.
+func (s *Store) queryMappings(ctx context.Context, op string, input *dynamodb.QueryInput) ([]models.InvitationMapping, error) {
+	var mappings []models.InvitationMapping
+	err := s.queryPages(ctx, op, input, func(items []map[string]types.AttributeValue) (bool, error) {
+		var page []models.InvitationMapping
+		if err := attributevalue.UnmarshalListOfMaps(items, &page); err != nil {
+			return false, fmt.Errorf("unmarshaling mappings: %w", err)
+		}
+		mappings = append(mappings, page...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
@@ -0,0 +1,161 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// pagedQueryClient serves items in fixed-size pages, like DynamoDB does past 1 MB.
+type pagedQueryClient struct {
+	items    []map[string]types.AttributeValue
+	pageSize int
+	inputs   []dynamodb.QueryInput
+}
+
+func (c *pagedQueryClient) Query(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.inputs = append(c.inputs, *input)
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		fmt.Sscanf(input.ExclusiveStartKey["sk"].(*types.AttributeValueMemberS).Value, "page#%d", &start)
+	}
+	end := min(start+c.pageSize, len(c.items))
+	out := &dynamodb.QueryOutput{
+		Items:            c.items[start:end],
+		ConsumedCapacity: &types.ConsumedCapacity{CapacityUnits: aws.Float64(0.5)},
+	}
+	if end < len(c.items) {
+		out.LastEvaluatedKey = map[string]types.AttributeValue{
+			"sk": &types.AttributeValueMemberS{Value: fmt.Sprintf("page#%d", end)},
+		}
+	}
+	return out, nil
+}
+
+func pagedStore(t *testing.T, n, pageSize int, status models.InvitationStatus) (*Store, *pagedQueryClient) {
+	t.Helper()
+	client := &pagedQueryClient{pageSize: pageSize}
+	for i := 0; i < n; i++ {
+		m := models.NewInvitationMapping("org", int64(i+1), fmt.Sprintf("user%d@example.com", i), models.RoleMember, 90)
+		if status == models.InvitationResolved {
+			login := fmt.Sprintf("user%d", i)
+			m.GitHubLogin = &login
+		}
+		m.Status = status
+		m.GSI2SK = "STATUS#" + string(status)
+		item, err := attributevalue.MarshalMap(m)
+		if err != nil {
+			t.Fatalf("marshaling mapping: %v", err)
+		}
+		client.items = append(client.items, item)
+	}
+	return &Store{query: client, tableName: "test"}, client
+}
+
+func TestQueriesReadEveryPage(t *testing.T) {
+	ctx := context.Background()
+
+	store, client := pagedStore(t, 7, 3, models.InvitationPending)
+	pending, err := store.GetPendingInvitations(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetPendingInvitations: %v", err)
+	}
+	if len(pending) != 7 || len(client.inputs) != 3 {
+		t.Fatalf("expected 7 invitations over 3 pages, got %d over %d", len(pending), len(client.inputs))
+	}
+	if client.inputs[0].ExclusiveStartKey != nil || client.inputs[2].ExclusiveStartKey == nil {
+		t.Fatalf("expected LastEvaluatedKey to be passed on to later pages")
+	}
+
+	store, _ = pagedStore(t, 5, 2, models.InvitationResolved)
+	resolved, err := store.GetAllResolvedMappings(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if len(resolved) != 5 || resolved["user4@example.com"] != "user4" {
+		t.Fatalf("expected mappings from every page, got %v", resolved)
+	}
+
+	store, _ = pagedStore(t, 4, 1, models.InvitationPending)
+	byEmail, err := store.GetByEmail(ctx, "user0@example.com", "org")
+	if err != nil || len(byEmail) != 4 {
+		t.Fatalf("expected GetByEmail to read every page, got %d (err %v)", len(byEmail), err)
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	ctx := context.Background()
+	store, _ := pagedStore(t, 5, 2, models.InvitationPending)
+	for i := 0; i < 2; i++ {
+		if _, err := store.GetPendingInvitations(ctx, "org"); err != nil {
+			t.Fatalf("GetPendingInvitations: %v", err)
+		}
+	}
+	if _, err := store.ListMappings(ctx, "org"); err != nil {
+		t.Fatalf("ListMappings: %v", err)
+	}
+
+	stats := store.QueryStats()
+	want := models.StoreQueryStats{Queries: 2, Pages: 6, MaxPages: 3, Items: 10, ReadUnits: 3}
+	if stats[opPendingInvitations] != want {
+		t.Fatalf("expected %+v, got %+v", want, stats[opPendingInvitations])
+	}
+	if stats[opListMappings].Queries != 1 || stats[opListMappings].Pages != 3 {
+		t.Fatalf("unexpected list_mappings stats %+v", stats[opListMappings])
+	}
+}
+
+func TestConsistentReadQueriesBaseTable(t *testing.T) {
+	ctx := context.Background()
+	store, client := pagedStore(t, 2, 5, models.InvitationResolved)
+
+	if _, err := store.GetAllResolvedMappings(ctx, "org"); err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if input := client.inputs[0]; input.IndexName == nil || input.ConsistentRead != nil {
+		t.Fatalf("expected an eventually consistent status-index query, got %+v", input)
+	}
+
+	store.consistentRead = true
+	if _, err := store.GetAllResolvedMappings(ctx, "org"); err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if _, err := store.GetByEmail(ctx, "user0@example.com", "org"); err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	for _, input := range client.inputs[1:] {
+		if input.IndexName != nil || !aws.ToBool(input.ConsistentRead) || input.FilterExpression == nil {
+			t.Fatalf("expected a consistent filtered base-table query, got %+v", input)
+		}
+	}
+}
+
+func TestJournalQueryStopsAtLimit(t *testing.T) {
+	client := &pagedQueryClient{pageSize: 2}
+	for i := 0; i < 6; i++ {
+		item, err := attributevalue.MarshalMap(journalItem{
+			PK:           journalPK("org"),
+			SK:           fmt.Sprintf("TS#%d", i),
+			JournalEntry: models.JournalEntry{Org: "org", RunID: "run", Sequence: i},
+		})
+		if err != nil {
+			t.Fatalf("marshaling entry: %v", err)
+		}
+		client.items = append(client.items, item)
+	}
+	store := &Store{query: client, tableName: "test"}
+
+	entries, err := store.Journal().Query(context.Background(), models.JournalFilter{Org: "org", Limit: 3})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(entries) != 3 || len(client.inputs) != 2 {
+		t.Fatalf("expected 3 entries from 2 pages, got %d from %d", len(entries), len(client.inputs))
+	}
+}
@@ -0,0 +1,74 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/storetest"
+)
+
+// TestStoreBehavior runs the shared InvitationStore suite against DynamoDB Local.
+// Set DYNAMODB_TEST_ENDPOINT (e.g. http://localhost:8000, see `make dynamodb-up`) to enable it.
+func TestStoreBehavior(t *testing.T) {
+	endpoint := os.Getenv("DYNAMODB_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DYNAMODB_TEST_ENDPOINT not set")
+	}
+
+	storetest.Run(t, func(t *testing.T) interfaces.InvitationStore {
+		ctx := context.Background()
+		tableName := fmt.Sprintf("storetest-%d", time.Now().UnixNano())
+		store, err := NewStore(ctx, config.DynamoDBConfig{
+			TableName: tableName,
+			Region:    "eu-west-1",
+			Endpoint:  endpoint,
+			TTLDays:   90,
+		})
+		if err != nil {
+			t.Fatalf("creating store: %v", err)
+		}
+		if err := createTestTable(ctx, store.client, tableName); err != nil {
+			t.Fatalf("creating table: %v", err)
+		}
+		t.Cleanup(func() {
+			_, _ = store.client.DeleteTable(context.Background(), &dynamodb.DeleteTableInput{TableName: aws.String(tableName)})
+		})
+		return store
+	})
+}
+
+// createTestTable creates a table with the same layout as scripts/setup-local-dynamodb.sh.
+func createTestTable(ctx context.Context, client *dynamodb.Client, tableName string) error {
+	attr := func(name string) types.AttributeDefinition {
+		return types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: types.ScalarAttributeTypeS}
+	}
+	key := func(name string, keyType types.KeyType) types.KeySchemaElement {
+		return types.KeySchemaElement{AttributeName: aws.String(name), KeyType: keyType}
+	}
+	index := func(name, pk, sk string) types.GlobalSecondaryIndex {
+		return types.GlobalSecondaryIndex{
+			IndexName:  aws.String(name),
+			KeySchema:  []types.KeySchemaElement{key(pk, types.KeyTypeHash), key(sk, types.KeyTypeRange)},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}
+	}
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            aws.String(tableName),
+		AttributeDefinitions: []types.AttributeDefinition{attr("pk"), attr("sk"), attr("gsi1pk"), attr("gsi1sk"), attr("gsi2pk"), attr("gsi2sk")},
+		KeySchema:            []types.KeySchemaElement{key("pk", types.KeyTypeHash), key("sk", types.KeyTypeRange)},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			index("email-index", "gsi1pk", "gsi1sk"),
+			index("status-index", "gsi2pk", "gsi2sk"),
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	return err
+}
@@ -2,7 +2,10 @@ package github
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,7 +13,9 @@ import (
 	"time"
 
 	"github.com/google/go-github/v60/github"
+	"github.com/daniloc96/google-workspace-github-sync/internal/httpcache"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/ratelimit"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 )
@@ -32,6 +37,39 @@ func IsAlreadyMemberError(err error) bool {
 	return false
 }
 
+// StatusError is returned when a direct REST call gets an unexpected HTTP status.
+type StatusError struct {
+	Op         string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s API returned status %d", e.Op, e.StatusCode)
+}
+
+// StatusCode extracts the HTTP status code from a GitHub API error, or 0 if it has none.
+func StatusCode(err error) int {
+	var respErr *github.ErrorResponse
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		return 0
+	case IsAlreadyMemberError(err):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		return respErr.Response.StatusCode
+	case errors.As(err, &rateErr) && rateErr.Response != nil:
+		return rateErr.Response.StatusCode
+	case errors.As(err, &abuseErr) && abuseErr.Response != nil:
+		return abuseErr.Response.StatusCode
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
 type orgService interface {
 	ListMembers(ctx context.Context, org string, opts *github.ListMembersOptions) ([]*github.User, *github.Response, error)
 	ListPendingOrgInvitations(ctx context.Context, org string, opts *github.ListOptions) ([]*github.Invitation, *github.Response, error)
@@ -45,27 +83,79 @@ type Client struct {
 	orgService orgService
 	httpClient *http.Client
 	token      string
+
+	// verifiedEmailsUnavailable is set once GraphQL rejects organizationVerifiedDomainEmails
+	// (no Enterprise Cloud / verified domain / permission), so later queries omit the field.
+	verifiedEmailsUnavailable bool
+}
+
+// ClientOption configures optional Client behavior.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	transport http.RoundTripper
+}
+
+// WithTransport sets the HTTP transport used below token authentication
+// (e.g. a conditional-request cache).
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
 }
 
 // NewClient creates a GitHub client using a personal access token.
-func NewClient(token string) (*Client, error) {
+func NewClient(token string, opts ...ClientOption) (*Client, error) {
 	if token == "" {
 		return nil, fmt.Errorf("github token is required")
 	}
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	ctx := context.Background()
+	if o.transport != nil {
+		transport := httpcache.WithCredential(o.transport, "github:"+tokenFingerprint(token))
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	}
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	httpClient := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(httpClient)
 	return &Client{orgService: client.Organizations, httpClient: httpClient, token: token}, nil
 }
 
+// tokenFingerprint identifies the token in cache keys without storing it.
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // ListMembers lists current organization members with accurate roles.
-// It fetches admins first to build a set, then fetches all members and tags admins.
+// It uses a single paginated GraphQL query returning login, role, public email,
+// database ID and verified-domain emails. If the token or schema does not allow
+// the query it falls back to the REST API; other GraphQL errors are returned.
 func (c *Client) ListMembers(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
 	if org == "" {
 		return nil, fmt.Errorf("org is required")
 	}
 
+	if c.httpClient != nil {
+		members, err := c.listMembersGraphQL(ctx, org)
+		if err == nil {
+			return members, nil
+		}
+		if !permitsRESTFallback(err) {
+			return nil, fmt.Errorf("listing members via GraphQL: %w", err)
+		}
+		logrus.WithError(err).Warn("⚠ GraphQL member listing not permitted, falling back to REST")
+	}
+
+	return c.listMembersREST(ctx, org)
+}
+
+// listMembersREST lists members via REST.
+// It fetches admins first to build a set, then fetches all members and tags admins.
+func (c *Client) listMembersREST(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
 	// Step 1: Fetch admin users to build an admin set.
 	adminSet := make(map[string]struct{})
 	adminOpts := &github.ListMembersOptions{Role: "admin", ListOptions: github.ListOptions{PerPage: 100}}
@@ -132,9 +222,12 @@ func (c *Client) ListMembers(ctx context.Context, org string) ([]models.GitHubOr
 
 	// Step 3: Enrich members with public profile email where missing.
 	// The List Members API doesn't return user emails; fetch individual profiles.
+	// These lookups are low priority: when the rate-limit budget runs low they are
+	// deferred to a later run instead of starving the sync itself.
+	enrichCtx := ratelimit.WithPriority(ctx, ratelimit.PriorityLow)
 	for i := range result {
 		if result[i].Email == nil && result[i].Username != nil {
-			if email := c.getUserPublicEmail(ctx, *result[i].Username); email != "" {
+			if email := c.getUserPublicEmail(enrichCtx, *result[i].Username); email != "" {
 				e := email
 				result[i].Email = &e
 			}
@@ -144,6 +237,39 @@ func (c *Client) ListMembers(ctx context.Context, org string) ([]models.GitHubOr
 	return result, nil
 }
 
+// AuthenticatedLogin returns the login of the user the token belongs to.
+// GitHub App installation tokens have no user and return an error.
+func (c *Client) AuthenticatedLogin(ctx context.Context) (string, error) {
+	if c.httpClient == nil {
+		return "", fmt.Errorf("http client is not configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/user", nil)
+	if err != nil {
+		return "", fmt.Errorf("creating authenticated user request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("getting authenticated user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &StatusError{Op: "authenticated user", StatusCode: resp.StatusCode}
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", fmt.Errorf("decoding authenticated user: %w", err)
+	}
+	return user.Login, nil
+}
+
 // getUserPublicEmail fetches a user's public profile email via GET /users/{login}.
 // Returns empty string if the email is not public or the request fails.
 func (c *Client) getUserPublicEmail(ctx context.Context, login string) string {
@@ -407,7 +533,7 @@ func (c *Client) CancelInvitation(ctx context.Context, org string, invitationID
 	resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("cancel invitation API returned status %d", resp.StatusCode)
+		return &StatusError{Op: "cancel invitation", StatusCode: resp.StatusCode}
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -458,6 +584,12 @@ func rateLimitWait(err error) (time.Duration, bool) {
 		wait := abuseErr.GetRetryAfter()
 		return wait, true
 	}
+	if statusErr, ok := err.(*graphQLStatusError); ok && statusErr.RateLimited {
+		return statusErr.RetryAfter, true
+	}
+	if gqlErr, ok := err.(*GraphQLError); ok && gqlErr.rateLimited() {
+		return gqlErr.retryAfter, true
+	}
 	return 0, false
 }
 
@@ -625,11 +757,15 @@ func parseLinkNext(linkHeader string) string {
 
 // ListMembersWithVerifiedEmails fetches all org members with their verified domain emails
 // using the GitHub GraphQL API. Returns a map of lowercase email → GitHub username.
-// Requires Enterprise Cloud and a verified domain on the organization.
+// Requires Enterprise Cloud and a verified domain on the organization; once GraphQL
+// has rejected the field it returns an empty map without querying.
 func (c *Client) ListMembersWithVerifiedEmails(ctx context.Context, org string) (map[string]string, error) {
 	if org == "" {
 		return nil, fmt.Errorf("org is required")
 	}
+	if c.verifiedEmailsUnavailable {
+		return map[string]string{}, nil
+	}
 
 	const query = `query($org: String!, $cursor: String) {
 		organization(login: $org) {
@@ -646,29 +782,16 @@ func (c *Client) ListMembersWithVerifiedEmails(ctx context.Context, org string)
 		}
 	}`
 
-	type graphQLRequest struct {
-		Query     string                 `json:"query"`
-		Variables map[string]interface{} `json:"variables"`
-	}
-
-	type graphQLResponse struct {
-		Data struct {
-			Organization struct {
-				MembersWithRole struct {
-					PageInfo struct {
-						HasNextPage bool    `json:"hasNextPage"`
-						EndCursor   *string `json:"endCursor"`
-					} `json:"pageInfo"`
-					Nodes []struct {
-						Login                            string   `json:"login"`
-						OrganizationVerifiedDomainEmails []string `json:"organizationVerifiedDomainEmails"`
-					} `json:"nodes"`
-				} `json:"membersWithRole"`
-			} `json:"organization"`
-		} `json:"data"`
-		Errors []struct {
-			Message string `json:"message"`
-		} `json:"errors,omitempty"`
+	type verifiedEmailsData struct {
+		Organization struct {
+			MembersWithRole struct {
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+				Nodes    []struct {
+					Login                            string   `json:"login"`
+					OrganizationVerifiedDomainEmails []string `json:"organizationVerifiedDomainEmails"`
+				} `json:"nodes"`
+			} `json:"membersWithRole"`
+		} `json:"organization"`
 	}
 
 	result := make(map[string]string)
@@ -680,44 +803,13 @@ func (c *Client) ListMembersWithVerifiedEmails(ctx context.Context, org string)
 			variables["cursor"] = *cursor
 		}
 
-		reqBody := graphQLRequest{Query: query, Variables: variables}
-		bodyBytes, err := json.Marshal(reqBody)
-		if err != nil {
-			return nil, fmt.Errorf("marshaling GraphQL request: %w", err)
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "POST", "https://api.github.com/graphql", strings.NewReader(string(bodyBytes)))
-		if err != nil {
-			return nil, fmt.Errorf("creating GraphQL request: %w", err)
-		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+c.token)
-
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("executing GraphQL request: %w", err)
-		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("reading GraphQL response: %w", err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("GraphQL API returned status %d: %s", resp.StatusCode, string(respBody))
-		}
-
-		var gqlResp graphQLResponse
-		if err := json.Unmarshal(respBody, &gqlResp); err != nil {
-			return nil, fmt.Errorf("parsing GraphQL response: %w", err)
-		}
-
-		if len(gqlResp.Errors) > 0 {
-			return nil, fmt.Errorf("GraphQL errors: %s", gqlResp.Errors[0].Message)
+		var data verifiedEmailsData
+		if err := c.executeGraphQL(ctx, query, variables, &data); err != nil {
+			c.rejectsVerifiedEmails(err)
+			return nil, err
 		}
 
-		members := gqlResp.Data.Organization.MembersWithRole
+		members := data.Organization.MembersWithRole
 		for _, node := range members.Nodes {
 			if node.Login == "" {
 				continue
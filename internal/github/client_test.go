@@ -3,6 +3,9 @@ package github
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -236,3 +239,194 @@ func TestListMembersRetriesOnRateLimit(t *testing.T) {
 		t.Fatalf("expected 1 member after retry, got %d", len(members))
 	}
 }
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestListMembersGraphQL(t *testing.T) {
+	var queries []string
+	pages := []string{
+		`{"data":{"organization":{"membersWithRole":{"pageInfo":{"hasNextPage":true,"endCursor":"c1"},"edges":[
+			{"role":"ADMIN","node":{"login":"admin-user","databaseId":1,"email":"","organizationVerifiedDomainEmails":["admin@example.com"]}}]}}}}`,
+		`{"data":{"organization":{"membersWithRole":{"pageInfo":{"hasNextPage":false,"endCursor":null},"edges":[
+			{"role":"MEMBER","node":{"login":"regular-user","databaseId":2,"email":"public@example.com","organizationVerifiedDomainEmails":[]}}]}}}}`,
+	}
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/graphql" {
+			t.Fatalf("unexpected request to %s", req.URL)
+		}
+		body, _ := io.ReadAll(req.Body)
+		queries = append(queries, string(body))
+		return jsonResponse(http.StatusOK, pages[len(queries)-1]), nil
+	})
+
+	service := &fakeOrgService{memberErr: errors.New("REST should not be called")}
+	client := &Client{orgService: service, httpClient: &http.Client{Transport: transport}}
+	members, err := client.ListMembers(context.Background(), "example-org")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("expected 2 GraphQL pages, got %d", len(queries))
+	}
+	if !strings.Contains(queries[1], `"cursor":"c1"`) {
+		t.Fatalf("expected second page to use cursor, got %s", queries[1])
+	}
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(members))
+	}
+	if members[0].Role != models.RoleOwner || len(members[0].VerifiedEmails) != 1 || *members[0].DatabaseID != 1 {
+		t.Fatalf("unexpected admin member: %#v", members[0])
+	}
+	if members[1].Role != models.RoleMember || members[1].Email == nil || *members[1].Email != "public@example.com" {
+		t.Fatalf("unexpected regular member: %#v", members[1])
+	}
+}
+
+func TestListMembersGraphQLRetriesWithoutVerifiedEmails(t *testing.T) {
+	var queries []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		queries = append(queries, string(body))
+		if strings.Contains(string(body), "organizationVerifiedDomainEmails") {
+			return jsonResponse(http.StatusOK, `{"data":null,"errors":[{"type":"FORBIDDEN",
+				"path":["organization","membersWithRole","edges",0,"node","organizationVerifiedDomainEmails"],
+				"message":"Verified domain emails require GitHub Enterprise Cloud"}]}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"organization":{"membersWithRole":{"pageInfo":{"hasNextPage":false},"edges":[
+			{"role":"MEMBER","node":{"login":"user1","databaseId":7,"email":""}}]}}}}`), nil
+	})
+
+	client := &Client{orgService: &fakeOrgService{}, httpClient: &http.Client{Transport: transport}}
+	members, err := client.ListMembers(context.Background(), "example-org")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(members) != 1 || *members[0].Username != "user1" {
+		t.Fatalf("unexpected members: %#v", members)
+	}
+	if len(queries) != 2 || !client.verifiedEmailsUnavailable {
+		t.Fatalf("expected a retry without verified emails, got %d queries", len(queries))
+	}
+}
+
+func TestListMembersGraphQLKeepsVerifiedEmailsOnOtherErrors(t *testing.T) {
+	for name, payload := range map[string]string{
+		"rate limited": `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`,
+		"not found":    `{"data":{"organization":null},"errors":[{"type":"NOT_FOUND","path":["organization"],"message":"Could not resolve to an Organization with the login of 'example-org'."}]}`,
+	} {
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/graphql" {
+				return jsonResponse(http.StatusOK, payload), nil
+			}
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		})
+		service := &fakeOrgService{}
+		client := &Client{orgService: service, httpClient: &http.Client{Transport: transport}}
+		if _, err := client.ListMembers(context.Background(), "example-org"); err == nil || service.memberCalls != 0 {
+			t.Fatalf("%s: expected the error without a REST fallback, got %v after %d REST calls", name, err, service.memberCalls)
+		}
+		if client.VerifiedEmailsUnavailable() {
+			t.Fatalf("%s: expected verified emails to stay available", name)
+		}
+	}
+}
+
+func TestListMembersWithVerifiedEmailsSkipsRejectedField(t *testing.T) {
+	queries := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		queries++
+		return jsonResponse(http.StatusOK, `{"errors":[{"message":"Field 'organizationVerifiedDomainEmails' doesn't exist on type 'User'"}]}`), nil
+	})
+	client := &Client{httpClient: &http.Client{Transport: transport}}
+
+	if _, err := client.ListMembersWithVerifiedEmails(context.Background(), "example-org"); err == nil {
+		t.Fatalf("expected the schema error")
+	}
+	emails, err := client.ListMembersWithVerifiedEmails(context.Background(), "example-org")
+	if err != nil || len(emails) != 0 || queries != 1 {
+		t.Fatalf("expected no further query once the field was rejected, got %v, %v after %d queries", emails, err, queries)
+	}
+}
+
+func TestListMembersFallsBackToREST(t *testing.T) {
+	for name, graphQL := range map[string]*http.Response{
+		"forbidden":       jsonResponse(http.StatusForbidden, `{"message":"Resource not accessible by integration"}`),
+		"schema rejected": jsonResponse(http.StatusOK, `{"errors":[{"message":"Field 'membersWithRole' doesn't exist on type 'Organization'"}]}`),
+	} {
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/graphql" {
+				return graphQL, nil
+			}
+			// Profile lookups from the REST enrichment step.
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		})
+		service := &fakeOrgService{
+			memberPages: [][]*github.User{{{Login: github.String("user1")}}},
+		}
+
+		client := &Client{orgService: service, httpClient: &http.Client{Transport: transport}}
+		members, err := client.ListMembers(context.Background(), "example-org")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if len(members) != 1 || service.memberCalls != 1 {
+			t.Fatalf("%s: expected REST fallback to list 1 member, got %#v", name, members)
+		}
+	}
+}
+
+func TestListMembersReturnsGraphQLServerErrors(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return jsonResponse(http.StatusBadGateway, `{"message":"bad gateway"}`), nil
+	})
+	service := &fakeOrgService{}
+	client := &Client{orgService: service, httpClient: &http.Client{Transport: transport}}
+	if _, err := client.ListMembers(context.Background(), "example-org"); err == nil || service.memberCalls != 0 {
+		t.Fatalf("expected the GraphQL error without a REST fallback, got %v after %d REST calls", err, service.memberCalls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client = &Client{orgService: service, httpClient: &http.Client{Transport: transport}}
+	if _, err := client.ListMembers(ctx, "example-org"); !errors.Is(err, context.Canceled) || service.memberCalls != 0 {
+		t.Fatalf("expected the cancellation without a REST fallback, got %v", err)
+	}
+}
+
+func TestListMembersGraphQLRetriesOnRateLimit(t *testing.T) {
+	limited := []*http.Response{
+		jsonResponse(http.StatusOK, `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded"}]}`),
+		jsonResponse(http.StatusForbidden, `{"message":"You have exceeded a secondary rate limit"}`),
+	}
+	limited[1].Header.Set("Retry-After", "0")
+	requests := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		if requests <= len(limited) {
+			return limited[requests-1], nil
+		}
+		return jsonResponse(http.StatusOK, `{"data":{"organization":{"membersWithRole":{"pageInfo":{"hasNextPage":false},"edges":[
+			{"role":"MEMBER","node":{"login":"user1","databaseId":7,"email":""}}]}}}}`), nil
+	})
+	service := &fakeOrgService{memberErr: errors.New("REST should not be called")}
+	client := &Client{orgService: service, httpClient: &http.Client{Transport: transport}}
+	members, err := client.ListMembers(context.Background(), "example-org")
+	if err != nil || len(members) != 1 || requests != 3 {
+		t.Fatalf("expected the GraphQL query retried after rate limiting, got %v, %v after %d requests", members, err, requests)
+	}
+}
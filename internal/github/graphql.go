@@ -0,0 +1,340 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+const graphQLEndpoint = "https://api.github.com/graphql"
+
+// membersQuery lists org members with role, public email and database ID in one pass.
+// The %s placeholder receives the optional verified-domain emails field.
+const membersQuery = `query($org: String!, $cursor: String) {
+	organization(login: $org) {
+		membersWithRole(first: 100, after: $cursor) {
+			pageInfo {
+				hasNextPage
+				endCursor
+			}
+			edges {
+				role
+				node {
+					login
+					databaseId
+					email
+					%s
+				}
+			}
+		}
+	}
+}`
+
+const (
+	verifiedEmailsFieldName = "organizationVerifiedDomainEmails"
+	verifiedEmailsField     = verifiedEmailsFieldName + "(login: $org)"
+)
+
+type graphQLPageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+// GraphQLError is returned when the GraphQL API responds with errors in the payload.
+type GraphQLError struct {
+	Messages   []string
+	Errors     []GraphQLErrorItem
+	retryAfter time.Duration // Wait advertised by the response's rate limit headers
+}
+
+// GraphQLErrorItem is one entry of the "errors" payload.
+type GraphQLErrorItem struct {
+	Type    string `json:"type,omitempty"` // e.g. FORBIDDEN, NOT_FOUND, RATE_LIMITED
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"` // Field names and list indexes leading to the failed field
+}
+
+func (e *GraphQLError) Error() string {
+	return "GraphQL errors: " + strings.Join(e.Messages, "; ")
+}
+
+// onlyAbout reports whether every error concerns field: it is on the field's
+// path or, for schema errors without a path, names it. Rate limiting is never
+// about a field.
+func (e *GraphQLError) onlyAbout(field string) bool {
+	if len(e.Errors) == 0 {
+		return false
+	}
+	for _, item := range e.Errors {
+		if item.Type == "RATE_LIMITED" {
+			return false
+		}
+		if !strings.Contains(item.Message, field) && !pathContains(item.Path, field) {
+			return false
+		}
+	}
+	return true
+}
+
+// rateLimited reports whether any error is GraphQL rate limiting.
+func (e *GraphQLError) rateLimited() bool {
+	for _, item := range e.Errors {
+		if item.Type == "RATE_LIMITED" {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLStatusError is returned when the GraphQL endpoint answers with a status other than 200.
+type graphQLStatusError struct {
+	StatusCode  int
+	Body        string
+	RateLimited bool          // 429, or 403 with rate limit headers
+	RetryAfter  time.Duration // Wait advertised by the rate limit headers
+}
+
+func (e *graphQLStatusError) Error() string {
+	return fmt.Sprintf("GraphQL API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// permitsRESTFallback reports whether err means GraphQL cannot serve the query
+// for this token or schema, so the REST API may be used instead: a 401 or a
+// 403 that is not rate limiting, or payload errors that are all permission or
+// schema errors (which GitHub returns without a type). Rate limiting, unknown
+// organizations, server errors and cancellation are returned to the caller.
+func permitsRESTFallback(err error) bool {
+	var statusErr *graphQLStatusError
+	if errors.As(err, &statusErr) {
+		return !statusErr.RateLimited && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden)
+	}
+	var gqlErr *GraphQLError
+	if !errors.As(err, &gqlErr) || len(gqlErr.Errors) == 0 {
+		return false
+	}
+	for _, item := range gqlErr.Errors {
+		switch item.Type {
+		case "", "FORBIDDEN", "INSUFFICIENT_SCOPES":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// rateLimitRetryAfter returns the wait advertised by Retry-After or, failing
+// that, X-RateLimit-Reset when no requests remain.
+func rateLimitRetryAfter(header http.Header) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), 0), true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func pathContains(path []any, field string) bool {
+	for _, element := range path {
+		if name, ok := element.(string); ok && name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// executeGraphQL posts a query to the GitHub GraphQL API and decodes the "data" field into out.
+// Rate-limited requests are retried like REST ones.
+func (c *Client) executeGraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	return retryOnRateLimit(ctx, func() error {
+		return c.postGraphQL(ctx, query, variables, out)
+	})
+}
+
+func (c *Client) postGraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	bodyBytes, err := json.Marshal(struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}{Query: query, Variables: variables})
+	if err != nil {
+		return fmt.Errorf("marshaling GraphQL request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", graphQLEndpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("creating GraphQL request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing GraphQL request: %w", err)
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading GraphQL response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &graphQLStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+		wait, limited := rateLimitRetryAfter(resp.Header)
+		statusErr.RateLimited = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden && limited
+		statusErr.RetryAfter = wait
+		return statusErr
+	}
+
+	var gqlResp struct {
+		Data   json.RawMessage    `json:"data"`
+		Errors []GraphQLErrorItem `json:"errors,omitempty"`
+	}
+	if err := json.Unmarshal(respBody, &gqlResp); err != nil {
+		return fmt.Errorf("parsing GraphQL response: %w", err)
+	}
+
+	if len(gqlResp.Errors) > 0 {
+		gqlErr := &GraphQLError{Errors: gqlResp.Errors}
+		for _, e := range gqlResp.Errors {
+			gqlErr.Messages = append(gqlErr.Messages, e.Message)
+		}
+		gqlErr.retryAfter, _ = rateLimitRetryAfter(resp.Header)
+		return gqlErr
+	}
+
+	if err := json.Unmarshal(gqlResp.Data, out); err != nil {
+		return fmt.Errorf("parsing GraphQL data: %w", err)
+	}
+	return nil
+}
+
+// listMembersGraphQL lists org members through membersWithRole.
+// Verified-domain emails are requested too; if the org does not support them
+// the query is retried without the field.
+func (c *Client) listMembersGraphQL(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+	if !c.verifiedEmailsUnavailable {
+		members, err := c.queryMembers(ctx, org, true)
+		if err == nil {
+			return members, nil
+		}
+		if !c.rejectsVerifiedEmails(err) {
+			return nil, err
+		}
+	}
+	return c.queryMembers(ctx, org, false)
+}
+
+// rejectsVerifiedEmails reports whether err is GraphQL refusing the
+// verified-domain emails field (no Enterprise Cloud, no verified domain or no
+// permission), and if so remembers it so later queries omit the field. Other
+// errors, such as rate limiting or an unknown organization, may pass.
+func (c *Client) rejectsVerifiedEmails(err error) bool {
+	var gqlErr *GraphQLError
+	if !errors.As(err, &gqlErr) || !gqlErr.onlyAbout(verifiedEmailsFieldName) {
+		return false
+	}
+	logrus.WithError(err).Debug("verified domain emails unavailable, listing members without them")
+	c.verifiedEmailsUnavailable = true
+	return true
+}
+
+// VerifiedEmailsUnavailable reports whether GraphQL has rejected the
+// verified-domain emails field for this client, so callers can skip asking for them.
+func (c *Client) VerifiedEmailsUnavailable() bool {
+	return c.verifiedEmailsUnavailable
+}
+
+func (c *Client) queryMembers(ctx context.Context, org string, withVerifiedEmails bool) ([]models.GitHubOrgMember, error) {
+	extraField := ""
+	if withVerifiedEmails {
+		extraField = verifiedEmailsField
+	}
+	query := fmt.Sprintf(membersQuery, extraField)
+
+	type membersData struct {
+		Organization *struct {
+			MembersWithRole struct {
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+				Edges    []struct {
+					Role string `json:"role"`
+					Node struct {
+						Login                            string   `json:"login"`
+						DatabaseID                       *int64   `json:"databaseId"`
+						Email                            string   `json:"email"`
+						OrganizationVerifiedDomainEmails []string `json:"organizationVerifiedDomainEmails"`
+					} `json:"node"`
+				} `json:"edges"`
+			} `json:"membersWithRole"`
+		} `json:"organization"`
+	}
+
+	var result []models.GitHubOrgMember
+	var cursor *string
+	for {
+		variables := map[string]interface{}{"org": org}
+		if cursor != nil {
+			variables["cursor"] = *cursor
+		}
+
+		var data membersData
+		if err := c.executeGraphQL(ctx, query, variables, &data); err != nil {
+			return nil, err
+		}
+		if data.Organization == nil {
+			return nil, fmt.Errorf("organization %s not found via GraphQL", org)
+		}
+
+		members := data.Organization.MembersWithRole
+		for _, edge := range members.Edges {
+			login := edge.Node.Login
+			if login == "" {
+				continue
+			}
+			member := models.GitHubOrgMember{
+				Username:   &login,
+				Role:       models.RoleMember,
+				DatabaseID: edge.Node.DatabaseID,
+			}
+			if edge.Role == "ADMIN" {
+				member.Role = models.RoleOwner
+			}
+			if edge.Node.Email != "" {
+				email := edge.Node.Email
+				member.Email = &email
+			}
+			for _, email := range edge.Node.OrganizationVerifiedDomainEmails {
+				if email != "" {
+					member.VerifiedEmails = append(member.VerifiedEmails, email)
+				}
+			}
+			result = append(result, member)
+		}
+
+		if !members.PageInfo.HasNextPage || members.PageInfo.EndCursor == nil {
+			break
+		}
+		cursor = members.PageInfo.EndCursor
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"org":             org,
+		"members":         len(result),
+		"verified_emails": withVerifiedEmails,
+	}).Debug("listed organization members via GraphQL")
+
+	return result, nil
+}
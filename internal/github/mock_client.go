@@ -18,6 +18,7 @@ type MockClient struct {
 	GetAuditLogAddMemberEventsFunc     func(ctx context.Context, org string, afterTimestamp int64) ([]models.AuditLogEntry, error)
 	ListFailedInvitationsFunc          func(ctx context.Context, org string) ([]models.GitHubOrgMember, error)
 	ListMembersWithVerifiedEmailsFunc  func(ctx context.Context, org string) (map[string]string, error)
+	VerifiedEmailsUnavailableFunc      func() bool
 }
 
 func (m *MockClient) ListMembers(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
@@ -89,3 +90,10 @@ func (m *MockClient) ListMembersWithVerifiedEmails(ctx context.Context, org stri
 	}
 	return m.ListMembersWithVerifiedEmailsFunc(ctx, org)
 }
+
+func (m *MockClient) VerifiedEmailsUnavailable() bool {
+	if m.VerifiedEmailsUnavailableFunc == nil {
+		return false
+	}
+	return m.VerifiedEmailsUnavailableFunc()
+}
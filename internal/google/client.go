@@ -3,13 +3,19 @@ package google
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/option"
 
+	"github.com/daniloc96/google-workspace-github-sync/internal/httpcache"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 )
 
@@ -27,14 +33,46 @@ type userGetter interface {
 	GetUser(ctx context.Context, email string) (*admin.User, error)
 }
 
+type userLister interface {
+	ListUsers(ctx context.Context, pageToken string) ([]*admin.User, string, error)
+}
+
+const (
+	// userListThreshold is the number of emails above which user status is read
+	// from a paginated users.list instead of individual users.get calls.
+	userListThreshold = 50
+	// userGetConcurrency bounds parallel users.get calls for small lookups.
+	userGetConcurrency = 8
+)
+
 // Client implements Google group member operations.
 type Client struct {
 	memberLister memberLister
 	userGetter   userGetter
+	userLister   userLister
+
+	// adminDomain is the domain of the impersonated admin, always one of the
+	// customer's domains.
+	adminDomain string
+}
+
+// ClientOption configures optional Client behavior.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	transport http.RoundTripper
+}
+
+// WithTransport sets the HTTP transport used below OAuth2 authentication
+// (e.g. a conditional-request cache).
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
 }
 
 // NewClient creates a Google Admin SDK client using domain-wide delegation.
-func NewClient(ctx context.Context, credentialsJSON []byte, adminEmail string) (*Client, error) {
+func NewClient(ctx context.Context, credentialsJSON []byte, adminEmail string, opts ...ClientOption) (*Client, error) {
 	if len(credentialsJSON) == 0 {
 		return nil, fmt.Errorf("credentials JSON is required")
 	}
@@ -48,13 +86,71 @@ func NewClient(ctx context.Context, credentialsJSON []byte, adminEmail string) (
 	}
 	config.Subject = adminEmail
 
-	svc, err := admin.NewService(ctx, option.WithTokenSource(config.TokenSource(ctx)))
+	return newClientFromTokenSource(ctx, config.TokenSource(ctx), config.Email, adminEmail, opts...)
+}
+
+// NewKeylessClient creates a Google Admin SDK client without a service-account key.
+// Base credentials come from Application Default Credentials, or from the given
+// external account (workload identity federation) configuration when set. The
+// service account is then impersonated through the IAM Credentials signJwt flow
+// with the admin as subject, so domain-wide delegation keeps working.
+func NewKeylessClient(ctx context.Context, serviceAccount string, adminEmail string, externalAccountJSON []byte, opts ...ClientOption) (*Client, error) {
+	var credOpts []option.ClientOption
+	if len(externalAccountJSON) > 0 {
+		credOpts = append(credOpts, option.WithAuthCredentialsJSON(option.ExternalAccount, externalAccountJSON))
+	}
+	ts, err := newKeylessTokenSource(ctx, serviceAccount, adminEmail, credOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return newClientFromTokenSource(ctx, ts, serviceAccount, adminEmail, opts...)
+}
+
+// newKeylessTokenSource builds a token source that impersonates the admin through the service account.
+func newKeylessTokenSource(ctx context.Context, serviceAccount string, adminEmail string, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+	if serviceAccount == "" {
+		return nil, fmt.Errorf("service account email is required")
+	}
+	if adminEmail == "" {
+		return nil, fmt.Errorf("admin email is required")
+	}
+
+	ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: serviceAccount,
+		Scopes:          []string{membersScope, groupsScope, usersScope},
+		Subject:         adminEmail,
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("impersonating service account %s: %w", serviceAccount, err)
+	}
+	return ts, nil
+}
+
+// credentialIdentity names the service account and subject in cache keys. It
+// stays the same across runs while the access tokens rotate.
+func credentialIdentity(serviceAccount string, adminEmail string) string {
+	return "google:" + serviceAccount + "/" + adminEmail
+}
+
+func newClientFromTokenSource(ctx context.Context, ts oauth2.TokenSource, serviceAccount string, adminEmail string, opts ...ClientOption) (*Client, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	svcOpt := option.WithTokenSource(ts)
+	if o.transport != nil {
+		base := httpcache.WithCredential(o.transport, credentialIdentity(serviceAccount, adminEmail))
+		svcOpt = option.WithHTTPClient(&http.Client{Transport: &oauth2.Transport{Source: ts, Base: base}})
+	}
+
+	svc, err := admin.NewService(ctx, svcOpt)
 	if err != nil {
 		return nil, err
 	}
 
 	directory := &directoryService{svc: svc}
-	return &Client{memberLister: directory, userGetter: directory}, nil
+	return &Client{memberLister: directory, userGetter: directory, userLister: directory, adminDomain: emailDomain(adminEmail)}, nil
 }
 
 // GetGroupMembers returns group members filtered to user accounts.
@@ -100,18 +196,138 @@ func (c *Client) GetGroupMembers(ctx context.Context, groupEmail string) ([]mode
 
 // GetUsersSuspendedStatus returns suspension status for given emails.
 func (c *Client) GetUsersSuspendedStatus(ctx context.Context, emails []string) (map[string]bool, error) {
-	result := make(map[string]bool, len(emails))
-	for _, email := range emails {
-		var user *admin.User
-		var err error
+	statuses, err := c.GetUsersStatus(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(statuses))
+	for email, status := range statuses {
+		result[email] = status.Suspended
+	}
+	return result, nil
+}
+
+// GetUsersStatus returns the account status (suspended, archived, deleted) for given emails.
+// Large lookups page through users.list with a field mask; small ones use bounded-concurrency
+// users.get calls. Emails that match no directory account (primary or alias) are reported as
+// deleted when their domain is one of the customer's domains, and as external otherwise.
+func (c *Client) GetUsersStatus(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error) {
+	if c.userLister != nil && len(emails) > userListThreshold {
+		return c.getUsersStatusFromList(ctx, emails)
+	}
+	return c.getUsersStatusIndividually(ctx, emails)
+}
+
+// getUsersStatusFromList reads the customer's whole directory. The customer's
+// domains are the admin's and those of the listed users.
+func (c *Client) getUsersStatusFromList(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error) {
+	directory := make(map[string]models.GoogleUserStatus)
+	domains := map[string]struct{}{c.adminDomain: {}}
+	pageToken := ""
+	for {
+		var (
+			users     []*admin.User
+			nextToken string
+			err       error
+		)
 		err = retryOnGoogleError(ctx, func() error {
-			user, err = c.userGetter.GetUser(ctx, email)
+			users, nextToken, err = c.userLister.ListUsers(ctx, pageToken)
 			return err
 		})
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("listing users: %w", err)
+		}
+		for _, user := range users {
+			status := models.GoogleUserStatus{Suspended: user.Suspended, Archived: user.Archived}
+			for _, email := range append([]string{user.PrimaryEmail}, user.Aliases...) {
+				email = strings.ToLower(email)
+				directory[email] = status
+				domains[emailDomain(email)] = struct{}{}
+			}
+		}
+		if nextToken == "" {
+			break
+		}
+		pageToken = nextToken
+	}
+
+	result := make(map[string]models.GoogleUserStatus, len(emails))
+	for _, email := range emails {
+		status, ok := directory[strings.ToLower(email)]
+		if !ok {
+			_, customer := domains[emailDomain(email)]
+			status = missingUserStatus(customer)
 		}
-		result[email] = user.Suspended
+		result[email] = status
+	}
+	return result, nil
+}
+
+// missingUserStatus is the status of an email with no directory account: deleted
+// in one of the customer's domains, external (status unknown) elsewhere.
+func missingUserStatus(customerDomain bool) models.GoogleUserStatus {
+	if customerDomain {
+		return models.GoogleUserStatus{Deleted: true}
+	}
+	return models.GoogleUserStatus{External: true}
+}
+
+// emailDomain returns the lowercase domain of email.
+func emailDomain(email string) string {
+	return strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+}
+
+// getUsersStatusIndividually looks each email up with users.get. Without a
+// directory listing, the admin's domain is the only known customer domain.
+func (c *Client) getUsersStatusIndividually(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	result := make(map[string]models.GoogleUserStatus, len(emails))
+	sem := make(chan struct{}, userGetConcurrency)
+
+	for _, email := range emails {
+		email := email
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var user *admin.User
+			var err error
+			err = retryOnGoogleError(ctx, func() error {
+				user, err = c.userGetter.GetUser(ctx, email)
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case isNotFoundGoogleError(err):
+				result[email] = missingUserStatus(emailDomain(email) == c.adminDomain)
+			case isForbiddenGoogleError(err) && emailDomain(email) != c.adminDomain:
+				// Looking up a user of a domain the customer does not own is refused.
+				result[email] = models.GoogleUserStatus{External: true}
+			case err != nil:
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+			default:
+				result[email] = models.GoogleUserStatus{Suspended: user.Suspended, Archived: user.Archived}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
 	}
 	return result, nil
 }
@@ -142,6 +358,16 @@ func retryOnGoogleError(ctx context.Context, fn func() error) error {
 	return nil
 }
 
+func isNotFoundGoogleError(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	return ok && apiErr.Code == http.StatusNotFound
+}
+
+func isForbiddenGoogleError(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	return ok && apiErr.Code == http.StatusForbidden
+}
+
 func isRetryableGoogleError(err error) bool {
 	apiErr, ok := err.(*googleapi.Error)
 	if !ok {
@@ -167,5 +393,20 @@ func (d *directoryService) ListMembers(ctx context.Context, groupEmail string, p
 }
 
 func (d *directoryService) GetUser(ctx context.Context, email string) (*admin.User, error) {
-	return d.svc.Users.Get(email).Context(ctx).Do()
+	return d.svc.Users.Get(email).Fields("primaryEmail", "suspended", "archived").Context(ctx).Do()
+}
+
+func (d *directoryService) ListUsers(ctx context.Context, pageToken string) ([]*admin.User, string, error) {
+	call := d.svc.Users.List().
+		Customer("my_customer").
+		MaxResults(500).
+		Fields("nextPageToken", "users(primaryEmail,aliases,suspended,archived)")
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	resp, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Users, resp.NextPageToken, nil
 }
@@ -2,10 +2,18 @@ package google
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 )
 
 type fakeMemberLister struct {
@@ -115,3 +123,181 @@ func TestGetUsersSuspendedStatus(t *testing.T) {
 		t.Fatalf("expected b@example.com to be active")
 	}
 }
+
+type fakeIAMTransport struct {
+	signPayload string
+	signURL     string
+}
+
+func (f *fakeIAMTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	var respBody string
+	switch {
+	case strings.HasSuffix(req.URL.Path, ":signJwt"):
+		f.signURL = req.URL.String()
+		var signReq struct {
+			Payload string `json:"payload"`
+		}
+		_ = json.Unmarshal(body, &signReq)
+		f.signPayload = signReq.Payload
+		respBody = `{"keyId":"k1","signedJwt":"signed.jwt.value"}`
+	case strings.HasSuffix(req.URL.Path, "/token"):
+		respBody = `{"access_token":"delegated-token","token_type":"Bearer","expires_in":3600}`
+	default:
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(respBody)), Header: http.Header{}}, nil
+}
+
+func TestKeylessTokenSourceDelegatesToAdmin(t *testing.T) {
+	transport := &fakeIAMTransport{}
+	ts, err := newKeylessTokenSource(context.Background(), "sync@project.iam.gserviceaccount.com", "admin@example.com",
+		option.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("expected token, got error %v", err)
+	}
+	if token.AccessToken != "delegated-token" {
+		t.Fatalf("expected delegated-token, got %s", token.AccessToken)
+	}
+	if !strings.Contains(transport.signURL, "serviceAccounts/sync@project.iam.gserviceaccount.com:signJwt") {
+		t.Fatalf("unexpected signJwt URL: %s", transport.signURL)
+	}
+
+	var claims struct {
+		Iss   string `json:"iss"`
+		Sub   string `json:"sub"`
+		Scope string `json:"scope"`
+	}
+	if err := json.Unmarshal([]byte(transport.signPayload), &claims); err != nil {
+		t.Fatalf("decoding signJwt payload: %v", err)
+	}
+	if claims.Sub != "admin@example.com" {
+		t.Fatalf("expected subject admin@example.com, got %s", claims.Sub)
+	}
+	if claims.Iss != "sync@project.iam.gserviceaccount.com" {
+		t.Fatalf("expected issuer to be the service account, got %s", claims.Iss)
+	}
+	if !strings.Contains(claims.Scope, usersScope) {
+		t.Fatalf("expected users scope in %q", claims.Scope)
+	}
+}
+
+func TestKeylessTokenSourceRequiresServiceAccount(t *testing.T) {
+	if _, err := newKeylessTokenSource(context.Background(), "", "admin@example.com"); err == nil {
+		t.Fatalf("expected error for missing service account")
+	}
+	if _, err := newKeylessTokenSource(context.Background(), "sync@project.iam.gserviceaccount.com", ""); err == nil {
+		t.Fatalf("expected error for missing admin email")
+	}
+}
+
+type fakeUserLister struct {
+	pages [][]*admin.User
+	calls int
+}
+
+func (f *fakeUserLister) ListUsers(ctx context.Context, pageToken string) ([]*admin.User, string, error) {
+	if f.calls >= len(f.pages) {
+		return nil, "", nil
+	}
+	page := f.pages[f.calls]
+	f.calls++
+	next := ""
+	if f.calls < len(f.pages) {
+		next = "next"
+	}
+	return page, next, nil
+}
+
+type notFoundUserGetter struct {
+	missing map[string]bool
+}
+
+func (f *notFoundUserGetter) GetUser(ctx context.Context, email string) (*admin.User, error) {
+	if f.missing[email] {
+		return nil, &googleapi.Error{Code: 404, Message: "Resource Not Found: userKey"}
+	}
+	return &admin.User{PrimaryEmail: email, Archived: email == "archived@example.com"}, nil
+}
+
+type forbiddenUserGetter struct{}
+
+func (forbiddenUserGetter) GetUser(ctx context.Context, email string) (*admin.User, error) {
+	return nil, &googleapi.Error{Code: 403, Message: "Not Authorized to access this resource/api"}
+}
+
+func TestGetUsersStatusForbiddenOutsideCustomerDomain(t *testing.T) {
+	client := &Client{userGetter: forbiddenUserGetter{}, adminDomain: "example.com"}
+	statuses, err := client.GetUsersStatus(context.Background(), []string{"contractor@partner.example"})
+	if err != nil || !statuses["contractor@partner.example"].External {
+		t.Fatalf("expected the external email to be marked external, got %+v, %v", statuses, err)
+	}
+	if _, err := client.GetUsersStatus(context.Background(), []string{"user@example.com"}); err == nil {
+		t.Fatalf("expected a 403 in the customer's domain to fail the lookup")
+	}
+}
+
+func TestGetUsersStatusUsesListForLargeLookups(t *testing.T) {
+	var emails []string
+	var users []*admin.User
+	for i := 0; i < userListThreshold+10; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		emails = append(emails, email)
+		users = append(users, &admin.User{PrimaryEmail: email})
+	}
+	users[0].Suspended = true
+	users[1].Archived = true
+	users[2].Aliases = []string{"alias@example.com"}
+	emails = append(emails, "Alias@example.com", "gone@example.com", "contractor@partner.example")
+
+	lister := &fakeUserLister{pages: [][]*admin.User{users[:30], users[30:]}}
+	client := &Client{userLister: lister, userGetter: &fakeUserGetter{}, adminDomain: "example.com"}
+
+	statuses, err := client.GetUsersStatus(context.Background(), emails)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if lister.calls != 2 {
+		t.Fatalf("expected 2 list pages, got %d", lister.calls)
+	}
+	if !statuses["user0@example.com"].Suspended {
+		t.Fatalf("expected user0 to be suspended")
+	}
+	if !statuses["user1@example.com"].Archived {
+		t.Fatalf("expected user1 to be archived")
+	}
+	if statuses["Alias@example.com"] != (models.GoogleUserStatus{}) {
+		t.Fatalf("expected alias to resolve to an active user, got %+v", statuses["Alias@example.com"])
+	}
+	if !statuses["gone@example.com"].Deleted {
+		t.Fatalf("expected unknown email to be reported as deleted")
+	}
+	if status := statuses["contractor@partner.example"]; status.Deleted || !status.External {
+		t.Fatalf("expected an email outside the customer's domains to be external, got %+v", status)
+	}
+}
+
+func TestGetUsersStatusTreatsNotFoundAsDeleted(t *testing.T) {
+	client := &Client{userGetter: &notFoundUserGetter{missing: map[string]bool{"gone@example.com": true, "contractor@partner.example": true}}, adminDomain: "example.com"}
+	statuses, err := client.GetUsersStatus(context.Background(), []string{"gone@example.com", "archived@example.com", "ok@example.com", "contractor@partner.example"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !statuses["gone@example.com"].Deleted {
+		t.Fatalf("expected gone@example.com to be deleted")
+	}
+	if status := statuses["contractor@partner.example"]; status.Deleted || !status.External {
+		t.Fatalf("expected an email outside the customer's domains to be external, got %+v", status)
+	}
+	if !statuses["archived@example.com"].Archived {
+		t.Fatalf("expected archived@example.com to be archived")
+	}
+	if statuses["ok@example.com"] != (models.GoogleUserStatus{}) {
+		t.Fatalf("expected ok@example.com to be active, got %+v", statuses["ok@example.com"])
+	}
+}
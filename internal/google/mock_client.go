@@ -8,8 +8,8 @@ import (
 
 // MockClient is a simple mock implementation of the Google client.
 type MockClient struct {
-	GetGroupMembersFunc        func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error)
-	GetUsersSuspendedStatusFunc func(ctx context.Context, emails []string) (map[string]bool, error)
+	GetGroupMembersFunc func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error)
+	GetUsersStatusFunc  func(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error)
 }
 
 func (m *MockClient) GetGroupMembers(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
@@ -19,9 +19,9 @@ func (m *MockClient) GetGroupMembers(ctx context.Context, groupEmail string) ([]
 	return m.GetGroupMembersFunc(ctx, groupEmail)
 }
 
-func (m *MockClient) GetUsersSuspendedStatus(ctx context.Context, emails []string) (map[string]bool, error) {
-	if m.GetUsersSuspendedStatusFunc == nil {
-		return map[string]bool{}, nil
+func (m *MockClient) GetUsersStatus(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error) {
+	if m.GetUsersStatusFunc == nil {
+		return map[string]models.GoogleUserStatus{}, nil
 	}
-	return m.GetUsersSuspendedStatusFunc(ctx, emails)
+	return m.GetUsersStatusFunc(ctx, emails)
 }
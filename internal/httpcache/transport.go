@@ -0,0 +1,213 @@
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FromCacheHeader is set on responses that were served from the cache.
+const FromCacheHeader = "X-From-Cache"
+
+// Entry is a cached HTTP response validated with ETag / Last-Modified.
+type Entry struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	StoredAt     time.Time   `json:"stored_at"`
+}
+
+// Store persists cache entries by key.
+type Store interface {
+	// Get returns the entry for key, or nil if there is none.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Set stores the entry for key, replacing any previous one.
+	Set(ctx context.Context, key string, entry Entry) error
+}
+
+// Stats counts how requests were served.
+type Stats struct {
+	Hits   int64 `json:"hits"`   // 304 Not Modified, served from cache
+	Misses int64 `json:"misses"` // fetched (and stored if validatable)
+	Errors int64 `json:"errors"` // cache store read/write failures
+}
+
+// Transport is an http.RoundTripper that sends conditional GET requests
+// (If-None-Match / If-Modified-Since) and serves 304 responses from the Store.
+// It must sit below the authentication transport so cache keys can be scoped
+// to the credential in use: the identity set with WithCredential, or else the
+// Authorization header.
+type Transport struct {
+	store  Store
+	base   http.RoundTripper
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// NewTransport wraps base with conditional-request caching backed by store.
+// If base is nil, http.DefaultTransport is used.
+func NewTransport(store Store, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{store: store, base: base}
+}
+
+// Stats returns a snapshot of cache counters.
+func (t *Transport) Stats() Stats {
+	return Stats{Hits: t.hits.Load(), Misses: t.misses.Load(), Errors: t.errors.Load()}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	key := cacheKey(req)
+
+	cached, err := t.store.Get(ctx, key)
+	if err != nil {
+		t.errors.Add(1)
+		logrus.WithError(err).WithField("url", req.URL.String()).Debug("http cache read failed")
+		cached = nil
+	}
+
+	outReq := req
+	if cached != nil {
+		outReq = req.Clone(ctx)
+		if cached.ETag != "" {
+			outReq.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			outReq.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.hits.Add(1)
+		return cached.response(req, resp.Header), nil
+	}
+
+	t.misses.Add(1)
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := Entry{
+		ETag:         etag,
+		LastModified: lastModified,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		StoredAt:     time.Now().UTC(),
+	}
+	if err := t.store.Set(ctx, key, entry); err != nil {
+		t.errors.Add(1)
+		logrus.WithError(err).WithField("url", req.URL.String()).Debug("http cache write failed")
+	}
+
+	return resp, nil
+}
+
+// response rebuilds an HTTP response from the entry. Headers from the fresh
+// 304 response (e.g. rate-limit counters) override the cached ones.
+func (e *Entry) response(req *http.Request, fresh http.Header) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for k, v := range fresh {
+		switch http.CanonicalHeaderKey(k) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		header[k] = v
+	}
+	header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+	header.Set(FromCacheHeader, "1")
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+type credentialKey struct{}
+
+// credentialTransport marks requests with the identity of the credential
+// that authenticates them.
+type credentialTransport struct {
+	identity string
+	base     http.RoundTripper
+}
+
+// WithCredential returns a transport that scopes the cache entries of requests
+// sent through base to identity, a stable name for the credential such as the
+// service account and subject it acts as. Access tokens rotate, so keying on
+// them would miss the cache on every new token. Clients wrap the transport
+// passed to them with their own identity.
+func WithCredential(base http.RoundTripper, identity string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &credentialTransport{identity: identity, base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(context.WithValue(req.Context(), credentialKey{}, t.identity)))
+}
+
+// cacheKey identifies a request by URL, Accept header and a fingerprint of
+// the credential, so responses are never shared across credentials.
+func cacheKey(req *http.Request) string {
+	credential, ok := req.Context().Value(credentialKey{}).(string)
+	if !ok {
+		credential = "authorization:" + req.Header.Get("Authorization")
+	}
+	h := sha256.New()
+	io.WriteString(h, req.URL.String())
+	io.WriteString(h, "\n")
+	io.WriteString(h, req.Header.Get("Accept"))
+	io.WriteString(h, "\n")
+	io.WriteString(h, credential)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,139 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportServesNotModifiedFromCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "4998")
+		_, _ = io.WriteString(w, `[{"login":"user1"}]`)
+	}))
+	defer server.Close()
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	transport := NewTransport(store, nil)
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL + "/orgs/example/invitations")
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `[{"login":"user1"}]` {
+			t.Fatalf("request %d: unexpected response %d %q", i, resp.StatusCode, body)
+		}
+		if i == 1 {
+			if resp.Header.Get(FromCacheHeader) != "1" {
+				t.Fatalf("expected second response to be served from cache")
+			}
+			if resp.Header.Get("X-RateLimit-Remaining") != "4999" {
+				t.Fatalf("expected fresh rate-limit header, got %s", resp.Header.Get("X-RateLimit-Remaining"))
+			}
+		}
+	}
+
+	if requests != 2 {
+		t.Fatalf("expected 2 upstream requests, got %d", requests)
+	}
+	stats := transport.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestTransportScopesCacheByCredential(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("unexpected conditional request for %s", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	store, _ := NewFileStore(t.TempDir())
+	client := &http.Client{Transport: NewTransport(store, nil)}
+
+	for _, token := range []string{"Bearer a", "Bearer b"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestTransportKeysOnCredentialIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = io.WriteString(w, `{"users":[]}`)
+	}))
+	defer server.Close()
+
+	store, _ := NewFileStore(t.TempDir())
+	transport := NewTransport(store, nil)
+	get := func(identity string, token string) {
+		t.Helper()
+		client := &http.Client{Transport: WithCredential(transport, identity)}
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// A new access token for the same service account and subject, as on the next run.
+	get("google:sync@example.iam.gserviceaccount.com/admin@example.com", "Bearer ya29.first")
+	get("google:sync@example.iam.gserviceaccount.com/admin@example.com", "Bearer ya29.second")
+	if stats := transport.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("expected the rotated token to hit the cache, got %+v", stats)
+	}
+	get("google:sync@example.iam.gserviceaccount.com/other@example.com", "Bearer ya29.second")
+	if stats := transport.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatalf("expected another subject to miss the cache, got %+v", stats)
+	}
+}
+
+func TestTransportSkipsNonGET(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	store, _ := NewFileStore(t.TempDir())
+	transport := NewTransport(store, nil)
+	resp, err := (&http.Client{Transport: transport}).Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if stats := transport.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Fatalf("expected POST to bypass the cache, got %+v", stats)
+	}
+}
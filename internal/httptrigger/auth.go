@@ -0,0 +1,152 @@
+package httptrigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+// HMAC request headers.
+const (
+	HeaderTimestamp = "X-Sync-Timestamp" // Unix seconds at signing
+	HeaderSignature = "X-Sync-Signature" // "sha256=" + hex HMAC-SHA256 of SigningString
+)
+
+// Authenticator checks that requests come from an allowed caller.
+type Authenticator struct {
+	cfg     config.HTTPConfig
+	hmacKey []byte
+	now     func() time.Time
+}
+
+// NewAuthenticator creates an authenticator for cfg.Auth. hmacKey is the shared
+// key of the hmac mode, resolved from cfg.
+func NewAuthenticator(cfg config.HTTPConfig, hmacKey string) *Authenticator {
+	return &Authenticator{cfg: cfg, hmacKey: []byte(hmacKey), now: time.Now}
+}
+
+// Authenticate returns the caller of req: the IAM principal ARN, or "hmac" for
+// requests signed with the shared key. Rejected requests return an *Error.
+func (a *Authenticator) Authenticate(req events.APIGatewayV2HTTPRequest) (string, error) {
+	switch a.cfg.Auth {
+	case config.HTTPAuthHMAC:
+		return "hmac", a.verifyHMAC(req)
+	case config.HTTPAuthIAM:
+		return a.verifyIAM(req)
+	default:
+		return "", &Error{Status: http.StatusInternalServerError, Message: "unknown http.auth " + strconv.Quote(a.cfg.Auth)}
+	}
+}
+
+func (a *Authenticator) verifyHMAC(req events.APIGatewayV2HTTPRequest) error {
+	unauthorized := &Error{Status: http.StatusUnauthorized, Message: "invalid or missing request signature"}
+	if len(a.hmacKey) == 0 {
+		return unauthorized
+	}
+	timestamp := header(req, HeaderTimestamp)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return unauthorized
+	}
+	skew := a.now().Sub(time.Unix(seconds, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > time.Duration(a.cfg.MaxSkewSeconds)*time.Second {
+		return &Error{Status: http.StatusUnauthorized, Message: "request timestamp outside the allowed clock skew"}
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return &Error{Status: http.StatusBadRequest, Message: "invalid base64 body"}
+	}
+	got, ok := strings.CutPrefix(header(req, HeaderSignature), "sha256=")
+	if !ok {
+		return unauthorized
+	}
+	gotMAC, err := hex.DecodeString(got)
+	if err != nil {
+		return unauthorized
+	}
+	mac := hmac.New(sha256.New, a.hmacKey)
+	mac.Write([]byte(SigningString(timestamp, req.RequestContext.HTTP.Method, req.RawPath, req.RawQueryString, body)))
+	if !hmac.Equal(gotMAC, mac.Sum(nil)) {
+		return unauthorized
+	}
+	return nil
+}
+
+// verifyIAM checks the caller AWS verified. A request without IAM authorizer
+// context reached the function through an endpoint without IAM auth.
+func (a *Authenticator) verifyIAM(req events.APIGatewayV2HTTPRequest) (string, error) {
+	authorizer := req.RequestContext.Authorizer
+	if authorizer == nil || authorizer.IAM == nil || authorizer.IAM.UserARN == "" {
+		return "", &Error{Status: http.StatusUnauthorized, Message: "request is not IAM-authenticated"}
+	}
+	caller := authorizer.IAM.UserARN
+	if len(a.cfg.IAMPrincipals) == 0 {
+		return caller, nil
+	}
+	for _, principal := range a.cfg.IAMPrincipals {
+		if principalMatches(principal, caller, authorizer.IAM.AccountID) {
+			return caller, nil
+		}
+	}
+	return caller, &Error{Status: http.StatusForbidden, Message: "caller is not allowed: " + caller}
+}
+
+// principalMatches reports whether principal, an account ID, a user or role ARN
+// or an exact caller ARN, covers caller. A role ARN covers its assumed-role
+// sessions.
+func principalMatches(principal, caller, accountID string) bool {
+	if principal == caller || principal == accountID {
+		return true
+	}
+	// arn:aws:iam::123456789012:role/path/Name covers
+	// arn:aws:sts::123456789012:assumed-role/Name/session.
+	parts := strings.SplitN(principal, ":", 6)
+	if len(parts) != 6 || parts[2] != "iam" || !strings.HasPrefix(parts[5], "role/") {
+		return false
+	}
+	name := parts[5][strings.LastIndex(parts[5], "/")+1:]
+	prefix := strings.Join([]string{parts[0], parts[1], "sts", "", parts[4], "assumed-role/" + name + "/"}, ":")
+	return strings.HasPrefix(caller, prefix)
+}
+
+// SigningString is what the hmac mode signs: the timestamp header, method, raw
+// path, raw query string and body, separated by newlines.
+func SigningString(timestamp, method, path, query string, body []byte) string {
+	return timestamp + "\n" + method + "\n" + path + "\n" + query + "\n" + string(body)
+}
+
+// Sign returns the HeaderSignature value for a request, for callers and tests.
+func Sign(key, timestamp, method, path, query string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(SigningString(timestamp, method, path, query, body)))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// header returns the value of req's header name. API Gateway and function URLs
+// deliver header names in lower case.
+func header(req events.APIGatewayV2HTTPRequest, name string) string {
+	if value, ok := req.Headers[strings.ToLower(name)]; ok {
+		return value
+	}
+	return req.Headers[name]
+}
+
+// requestBody returns req's body as sent.
+func requestBody(req events.APIGatewayV2HTTPRequest) ([]byte, error) {
+	if req.IsBase64Encoded {
+		return base64.StdEncoding.DecodeString(req.Body)
+	}
+	return []byte(req.Body), nil
+}
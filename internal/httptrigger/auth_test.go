@@ -0,0 +1,99 @@
+package httptrigger
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+func signed(key string, at time.Time, method, path, query, body string) events.APIGatewayV2HTTPRequest {
+	req := request(method, path, query)
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Body = body
+	req.Headers = map[string]string{
+		"x-sync-timestamp": timestamp,
+		"x-sync-signature": Sign(key, timestamp, method, path, query, []byte(body)),
+	}
+	return req
+}
+
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var e *Error
+	if !errors.As(err, &e) || e.Status != status {
+		t.Fatalf("expected status %d, got %v", status, err)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	auth := NewAuthenticator(config.HTTPConfig{Auth: config.HTTPAuthHMAC, MaxSkewSeconds: 300}, "s3cret")
+	auth.now = func() time.Time { return now }
+
+	req := signed("s3cret", now.Add(-time.Minute), "POST", "/sync", "dry_run=false", "")
+	if _, err := auth.Authenticate(req); err != nil {
+		t.Fatalf("expected a valid signature, got %v", err)
+	}
+
+	encoded := signed("s3cret", now, "POST", "/sync", "", `{"note":"portal"}`)
+	encoded.Body = base64.StdEncoding.EncodeToString([]byte(encoded.Body))
+	encoded.IsBase64Encoded = true
+	if _, err := auth.Authenticate(encoded); err != nil {
+		t.Fatalf("expected the decoded body to be verified, got %v", err)
+	}
+
+	replayed := signed("s3cret", now, "GET", "/plan", "", "")
+	replayed.RawPath = "/sync"
+	replayed.RequestContext.HTTP.Method = "POST"
+	_, err := auth.Authenticate(replayed)
+	wantStatus(t, err, http.StatusUnauthorized)
+
+	_, err = auth.Authenticate(signed("other-key", now, "POST", "/sync", "", ""))
+	wantStatus(t, err, http.StatusUnauthorized)
+
+	_, err = auth.Authenticate(signed("s3cret", now.Add(-10*time.Minute), "POST", "/sync", "", ""))
+	wantStatus(t, err, http.StatusUnauthorized)
+
+	_, err = auth.Authenticate(request("POST", "/sync", ""))
+	wantStatus(t, err, http.StatusUnauthorized)
+}
+
+func iamRequest(account, arn string) events.APIGatewayV2HTTPRequest {
+	req := request("POST", "/sync", "")
+	req.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+		IAM: &events.APIGatewayV2HTTPRequestContextAuthorizerIAMDescription{AccountID: account, UserARN: arn},
+	}
+	return req
+}
+
+func TestIAM(t *testing.T) {
+	portal := "arn:aws:sts::111111111111:assumed-role/SyncPortal/portal-session"
+
+	auth := NewAuthenticator(config.HTTPConfig{Auth: config.HTTPAuthIAM}, "")
+	if caller, err := auth.Authenticate(iamRequest("111111111111", portal)); err != nil || caller != portal {
+		t.Fatalf("expected any signed caller to be allowed without principals, got %q, %v", caller, err)
+	}
+	_, err := auth.Authenticate(request("POST", "/sync", ""))
+	wantStatus(t, err, http.StatusUnauthorized)
+
+	auth = NewAuthenticator(config.HTTPConfig{Auth: config.HTTPAuthIAM, IAMPrincipals: []string{
+		"arn:aws:iam::111111111111:role/service/SyncPortal",
+		"222222222222",
+	}}, "")
+	for _, caller := range []string{portal, "arn:aws:iam::222222222222:user/ops"} {
+		account := caller[13:25]
+		if _, err := auth.Authenticate(iamRequest(account, caller)); err != nil {
+			t.Errorf("expected %s to be allowed, got %v", caller, err)
+		}
+	}
+	_, err = auth.Authenticate(iamRequest("111111111111", "arn:aws:sts::111111111111:assumed-role/SyncPortalAdmin/s"))
+	wantStatus(t, err, http.StatusForbidden)
+	_, err = auth.Authenticate(iamRequest("333333333333", "arn:aws:sts::333333333333:assumed-role/SyncPortal/s"))
+	wantStatus(t, err, http.StatusForbidden)
+}
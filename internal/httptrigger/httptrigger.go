@@ -0,0 +1,184 @@
+// Package httptrigger handles sync runs requested over HTTP, through an API
+// Gateway HTTP API or a Lambda function URL (payload format 2.0): it
+// authenticates requests, maps routes to run modes and builds the responses.
+package httptrigger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// Run modes.
+const (
+	ModeSync   = "sync"    // POST /sync: a full run; dry run as configured unless ?dry_run= is given
+	ModeDryRun = "dry-run" // POST /sync/dry-run: a full dry run
+	ModeUser   = "user"    // POST /sync/users/{user}: a run limited to one Google email or GitHub login
+	ModePlan   = "plan"    // GET /plan: the planned actions of a dry run, optionally for ?user=
+)
+
+// Request is a run requested over HTTP.
+type Request struct {
+	Mode   string
+	DryRun *bool  // Overrides the configured dry-run setting
+	User   string // Google email or GitHub login the run is limited to
+}
+
+// Error is a request that cannot be served, with its HTTP status.
+type Error struct {
+	Status  int
+	Message string
+	Allow   string // Allowed methods, for 405 responses
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// IsRequest reports whether payload is an HTTP request event (payload format 2.0)
+// rather than a scheduled or direct invocation.
+func IsRequest(payload json.RawMessage) bool {
+	var probe struct {
+		Version        string `json:"version"`
+		RequestContext struct {
+			HTTP struct {
+				Method string `json:"method"`
+			} `json:"http"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return false
+	}
+	return probe.Version == "2.0" && probe.RequestContext.HTTP.Method != ""
+}
+
+// Parse maps req's method and path to a run request.
+func Parse(req events.APIGatewayV2HTTPRequest) (Request, error) {
+	method := req.RequestContext.HTTP.Method
+	path := requestPath(req)
+	query, err := url.ParseQuery(req.RawQueryString)
+	if err != nil {
+		return Request{}, &Error{Status: http.StatusBadRequest, Message: "invalid query string"}
+	}
+
+	var r Request
+	allow := http.MethodPost
+	switch {
+	case path == "/sync":
+		r.Mode = ModeSync
+	case path == "/sync/dry-run":
+		r.Mode = ModeDryRun
+	case strings.HasPrefix(path, "/sync/users/"):
+		user, err := url.PathUnescape(strings.TrimPrefix(path, "/sync/users/"))
+		if err != nil || user == "" || strings.Contains(user, "/") {
+			return Request{}, &Error{Status: http.StatusNotFound, Message: "not found: " + path}
+		}
+		r.Mode, r.User = ModeUser, user
+	case path == "/plan":
+		r.Mode, r.User = ModePlan, query.Get("user")
+		allow = http.MethodGet
+	default:
+		return Request{}, &Error{Status: http.StatusNotFound, Message: "not found: " + path}
+	}
+	if method != allow {
+		return Request{}, &Error{Status: http.StatusMethodNotAllowed, Message: "method not allowed: " + method, Allow: allow}
+	}
+
+	if value := query.Get("dry_run"); value != "" && (r.Mode == ModeSync || r.Mode == ModeUser) {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return Request{}, &Error{Status: http.StatusBadRequest, Message: "dry_run must be true or false"}
+		}
+		r.DryRun = &dryRun
+	}
+	return r, nil
+}
+
+// requestPath returns req's path without the API Gateway stage prefix.
+func requestPath(req events.APIGatewayV2HTTPRequest) string {
+	path := req.RawPath
+	if stage := req.RequestContext.Stage; stage != "" && stage != "$default" {
+		path = strings.TrimPrefix(path, "/"+stage)
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// Apply sets the run settings of r on cfg.
+func (r Request) Apply(cfg *config.Config) {
+	switch r.Mode {
+	case ModeDryRun, ModePlan:
+		cfg.Sync.DryRun = true
+	default:
+		if r.DryRun != nil {
+			cfg.Sync.DryRun = *r.DryRun
+		}
+	}
+	cfg.Sync.OnlyUsers = nil
+	cfg.Sync.OnlyGroups = nil
+	if r.User != "" {
+		cfg.Sync.OnlyUsers = []string{r.User}
+	}
+}
+
+// Plan is the response body of a plan request.
+type Plan struct {
+	Message string              `json:"message"`
+	RunID   string              `json:"run_id"`
+	Scope   []string            `json:"scope,omitempty"`
+	Summary models.SyncSummary  `json:"summary"`
+	Actions []models.SyncAction `json:"actions"`
+}
+
+// NewPlan returns the plan of a dry-run result.
+func NewPlan(result *models.SyncResult) Plan {
+	actions := result.Actions
+	if actions == nil {
+		actions = []models.SyncAction{}
+	}
+	return Plan{
+		Message: fmt.Sprintf("Plan: %d actions", len(actions)),
+		RunID:   result.RunID,
+		Scope:   result.Scope,
+		Summary: result.Summary,
+		Actions: actions,
+	}
+}
+
+// JSON returns a response with status and body encoded as JSON.
+func JSON(status int, body any) events.APIGatewayV2HTTPResponse {
+	data, err := json.Marshal(body)
+	if err != nil {
+		status = http.StatusInternalServerError
+		data = []byte(`{"message":"encoding response failed"}`)
+	}
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(data),
+	}
+}
+
+// ErrorResponse returns the response for err: its status if it is an *Error,
+// 500 otherwise.
+func ErrorResponse(err error) events.APIGatewayV2HTTPResponse {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Status: http.StatusInternalServerError, Message: err.Error()}
+	}
+	resp := JSON(e.Status, models.LambdaResponse{StatusCode: e.Status, Message: e.Message})
+	if e.Allow != "" {
+		resp.Headers["Allow"] = e.Allow
+	}
+	return resp
+}
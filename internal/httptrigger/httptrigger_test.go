@@ -0,0 +1,108 @@
+package httptrigger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func request(method, path, query string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{Version: "2.0", RawPath: path, RawQueryString: query}
+	req.RequestContext.Stage = "$default"
+	req.RequestContext.HTTP.Method = method
+	return req
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name       string
+		req        events.APIGatewayV2HTTPRequest
+		wantMode   string
+		wantUser   string
+		wantDryRun *bool
+		wantStatus int
+	}{
+		{name: "full sync", req: request("POST", "/sync", ""), wantMode: ModeSync},
+		{name: "full sync applying changes", req: request("POST", "/sync", "dry_run=false"), wantMode: ModeSync, wantDryRun: new(bool)},
+		{name: "dry run", req: request("POST", "/sync/dry-run", ""), wantMode: ModeDryRun},
+		{name: "single user", req: request("POST", "/sync/users/jane%40example.com", ""), wantMode: ModeUser, wantUser: "jane@example.com"},
+		{name: "plan for a user", req: request("GET", "/plan", "user=octocat"), wantMode: ModePlan, wantUser: "octocat"},
+		{name: "plan on a named stage", req: func() events.APIGatewayV2HTTPRequest {
+			r := request("GET", "/prod/plan/", "")
+			r.RequestContext.Stage = "prod"
+			return r
+		}(), wantMode: ModePlan},
+		{name: "sync with GET", req: request("GET", "/sync", ""), wantStatus: http.StatusMethodNotAllowed},
+		{name: "unknown path", req: request("POST", "/admin", ""), wantStatus: http.StatusNotFound},
+		{name: "user without name", req: request("POST", "/sync/users/", ""), wantStatus: http.StatusNotFound},
+		{name: "invalid dry_run", req: request("POST", "/sync", "dry_run=maybe"), wantStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.req)
+			if tc.wantStatus != 0 {
+				var e *Error
+				if !errors.As(err, &e) || e.Status != tc.wantStatus {
+					t.Fatalf("expected status %d, got %v", tc.wantStatus, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got.Mode != tc.wantMode || got.User != tc.wantUser {
+				t.Fatalf("expected %s for %q, got %+v", tc.wantMode, tc.wantUser, got)
+			}
+			if (got.DryRun == nil) != (tc.wantDryRun == nil) || (got.DryRun != nil && *got.DryRun != *tc.wantDryRun) {
+				t.Fatalf("expected dry_run %v, got %v", tc.wantDryRun, got.DryRun)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	cfg := &config.Config{Sync: config.SyncConfig{DryRun: false}}
+	Request{Mode: ModePlan, User: "jane@example.com"}.Apply(cfg)
+	if !cfg.Sync.DryRun || len(cfg.Sync.OnlyUsers) != 1 {
+		t.Fatalf("expected a dry run limited to jane, got %+v", cfg.Sync)
+	}
+
+	applyChanges := false
+	cfg = &config.Config{Sync: config.SyncConfig{DryRun: true}}
+	Request{Mode: ModeSync, DryRun: &applyChanges}.Apply(cfg)
+	if cfg.Sync.DryRun || cfg.Sync.OnlyUsers != nil {
+		t.Fatalf("expected a full run applying changes, got %+v", cfg.Sync)
+	}
+}
+
+func TestIsRequest(t *testing.T) {
+	httpEvent, _ := json.Marshal(request("POST", "/sync", ""))
+	if !IsRequest(httpEvent) {
+		t.Fatalf("expected an HTTP request event to be recognized")
+	}
+	for _, payload := range []string{`{"source":"aws.events","detail-type":"Scheduled Event"}`, `{"dry_run":true}`, `[]`} {
+		if IsRequest(json.RawMessage(payload)) {
+			t.Errorf("expected %s not to be an HTTP request", payload)
+		}
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	resp := ErrorResponse(&Error{Status: http.StatusMethodNotAllowed, Message: "method not allowed: GET", Allow: http.MethodPost})
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Headers["Allow"] != http.MethodPost {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	var body models.LambdaResponse
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || body.Message != "method not allowed: GET" {
+		t.Fatalf("expected a JSON error body, got %q", resp.Body)
+	}
+
+	if resp := ErrorResponse(errors.New("boom")); resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for other errors, got %d", resp.StatusCode)
+	}
+}
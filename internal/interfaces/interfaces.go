@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 )
@@ -9,7 +10,7 @@ import (
 // GoogleClient defines operations needed from Google Workspace.
 type GoogleClient interface {
 	GetGroupMembers(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error)
-	GetUsersSuspendedStatus(ctx context.Context, emails []string) (map[string]bool, error)
+	GetUsersStatus(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error)
 }
 
 // GitHubClient defines operations needed from GitHub Organization APIs.
@@ -26,6 +27,12 @@ type GitHubClient interface {
 	ListMembersWithVerifiedEmails(ctx context.Context, org string) (map[string]string, error)
 }
 
+// VerifiedEmailsReporter is implemented by GitHub clients that know when the
+// organization's verified-domain emails are unavailable.
+type VerifiedEmailsReporter interface {
+	VerifiedEmailsUnavailable() bool
+}
+
 // SyncEngine defines sync orchestration.
 type SyncEngine interface {
 	Sync(ctx context.Context) (*models.SyncResult, error)
@@ -36,19 +43,26 @@ type InvitationStore interface {
 	// SaveInvitation stores a new pending invitation mapping.
 	SaveInvitation(ctx context.Context, mapping models.InvitationMapping) error
 
+	// CreateMapping stores mapping only if no mapping with its key exists; otherwise it
+	// returns a *models.TransitionError wrapping models.ErrMappingExists.
+	CreateMapping(ctx context.Context, mapping models.InvitationMapping) error
+
 	// GetInvitation retrieves an invitation by org and invitation ID.
 	GetInvitation(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error)
 
 	// GetPendingInvitations returns all pending invitations for an org.
 	GetPendingInvitations(ctx context.Context, org string) ([]models.InvitationMapping, error)
 
-	// ResolveInvitation updates an invitation with the resolved GitHub username.
+	// ResolveInvitation updates a pending invitation with the resolved GitHub username.
+	// Status writes follow the lifecycle in models.InvitationStatus.CanTransitionTo;
+	// a rejected write returns a *models.TransitionError.
 	ResolveInvitation(ctx context.Context, org string, invitationID int64, githubLogin string) error
 
-	// UpdateStatus changes the status of an invitation (failed, expired, cancelled, removed).
+	// UpdateStatus changes the status of an invitation (failed, expired, cancelled, removed)
+	// if its current status may move to status.
 	UpdateStatus(ctx context.Context, org string, invitationID int64, status models.InvitationStatus) error
 
-	// UpdateRole updates the role of an invitation mapping.
+	// UpdateRole updates the role of an existing invitation mapping.
 	UpdateRole(ctx context.Context, org string, invitationID int64, role models.OrgRole) error
 
 	// GetByEmail retrieves invitation mappings for a specific email.
@@ -62,6 +76,49 @@ type InvitationStore interface {
 
 	// GetAllResolvedMappings returns all resolved email→username mappings for an org.
 	GetAllResolvedMappings(ctx context.Context, org string) (map[string]string, error)
+
+	// ListMappings returns every invitation mapping for an org, in any status.
+	ListMappings(ctx context.Context, org string) ([]models.InvitationMapping, error)
+
+	// DeleteMapping removes an invitation mapping by org and sort key (INV#<id> or EXISTING#<login>).
+	DeleteMapping(ctx context.Context, org string, sk string) error
+
+	// TransitionMapping moves a mapping of any kind to status, provided it still has
+	// the status and version it was read with.
+	TransitionMapping(ctx context.Context, mapping models.InvitationMapping, status models.InvitationStatus) error
+}
+
+// ActionJournal defines an append-only record of executed sync actions.
+type ActionJournal interface {
+	// Append records entries. Existing entries are never modified.
+	Append(ctx context.Context, entries []models.JournalEntry) error
+
+	// Query returns entries matching the filter, newest first.
+	Query(ctx context.Context, filter models.JournalFilter) ([]models.JournalEntry, error)
+}
+
+// RunLock defines a lease-based lock shared by every process using the same store.
+type RunLock interface {
+	// AcquireLease takes the named lease for owner until ttl passes, or extends it if
+	// owner already holds it. When another owner holds an unexpired lease it returns
+	// that lease and false.
+	AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error)
+
+	// RenewLease extends a lease held by owner. It returns models.ErrLeaseLost if
+	// owner no longer holds it.
+	RenewLease(ctx context.Context, name string, owner string, ttl time.Duration) error
+
+	// ReleaseLease deletes the lease if owner still holds it.
+	ReleaseLease(ctx context.Context, name string, owner string) error
+}
+
+// AlertStateStore defines persistence for alerting state, one record per org.
+type AlertStateStore interface {
+	// GetAlertState returns the org's alert state, or nil if none was saved.
+	GetAlertState(ctx context.Context, org string) (*models.AlertState, error)
+
+	// SaveAlertState replaces the org's alert state.
+	SaveAlertState(ctx context.Context, state models.AlertState) error
 }
 
 // GitHubAuditLogClient defines operations for reading the GitHub Audit Log.
@@ -0,0 +1,114 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// FileJournal appends entries as JSON lines to a local file.
+type FileJournal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileJournal creates a file-backed journal, creating the parent directory if needed.
+func NewFileJournal(path string) (*FileJournal, error) {
+	if path == "" {
+		return nil, fmt.Errorf("journal path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating journal directory: %w", err)
+	}
+	return &FileJournal{path: path}, nil
+}
+
+// Append writes entries to the end of the journal file.
+func (j *FileJournal) Append(ctx context.Context, entries []models.JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("encoding journal entry: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing journal: %w", err)
+	}
+	return f.Sync()
+}
+
+// Query returns entries matching the filter, newest first.
+func (j *FileJournal) Query(ctx context.Context, filter models.JournalFilter) ([]models.JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []models.JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry models.JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("decoding journal line %d: %w", line, err)
+		}
+		if filter.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading journal: %w", err)
+	}
+
+	SortNewestFirst(entries)
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// SortNewestFirst orders entries by timestamp descending, then by run and sequence.
+func SortNewestFirst(entries []models.JournalEntry) {
+	sort.SliceStable(entries, func(a, b int) bool {
+		ea, eb := entries[a], entries[b]
+		if !ea.Timestamp.Equal(eb.Timestamp) {
+			return ea.Timestamp.After(eb.Timestamp)
+		}
+		if ea.RunID != eb.RunID {
+			return ea.RunID > eb.RunID
+		}
+		return ea.Sequence > eb.Sequence
+	})
+}
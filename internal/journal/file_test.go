@@ -0,0 +1,80 @@
+package journal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func TestFileJournalAppendAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "journal.jsonl")
+	j, err := NewFileJournal(path)
+	if err != nil {
+		t.Fatalf("creating journal: %v", err)
+	}
+	ctx := context.Background()
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := j.Append(ctx, []models.JournalEntry{
+		{RunID: "run-1", Sequence: 0, Org: "example-org", Action: models.ActionInvite, Email: "a@example.com", Outcome: models.JournalSucceeded, Timestamp: day},
+		{RunID: "run-1", Sequence: 1, Org: "example-org", Action: models.ActionRemove, Email: "b@example.com", Username: "bee", Outcome: models.JournalSucceeded, Timestamp: day},
+	}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if err := j.Append(ctx, []models.JournalEntry{
+		{RunID: "run-2", Sequence: 0, Org: "example-org", Action: models.ActionInvite, Email: "c@example.com", Outcome: models.JournalFailed, HTTPStatus: 422, Timestamp: day.AddDate(0, 0, 2)},
+	}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+
+	all, err := j.Query(ctx, models.JournalFilter{})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(all) != 3 || all[0].RunID != "run-2" || all[1].Sequence != 1 {
+		t.Fatalf("expected 3 entries newest first, got %+v", all)
+	}
+
+	byUser, _ := j.Query(ctx, models.JournalFilter{User: "BEE"})
+	if len(byUser) != 1 || byUser[0].Email != "b@example.com" {
+		t.Fatalf("expected lookup by username, got %+v", byUser)
+	}
+
+	invites, _ := j.Query(ctx, models.JournalFilter{Action: models.ActionInvite, Until: day.AddDate(0, 0, 1)})
+	if len(invites) != 1 || invites[0].Email != "a@example.com" {
+		t.Fatalf("expected 1 invite before the cutoff, got %+v", invites)
+	}
+
+	limited, _ := j.Query(ctx, models.JournalFilter{Since: day, Limit: 2})
+	if len(limited) != 2 {
+		t.Fatalf("expected limit to apply, got %d entries", len(limited))
+	}
+}
+
+func TestFileJournalIsAppendOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, _ := NewFileJournal(path)
+	ctx := context.Background()
+
+	entry := models.JournalEntry{RunID: "run-1", Action: models.ActionInvite, Email: "a@example.com", Timestamp: time.Now().UTC()}
+	_ = j.Append(ctx, []models.JournalEntry{entry})
+	before, _ := os.ReadFile(path)
+	_ = j.Append(ctx, []models.JournalEntry{entry})
+	after, _ := os.ReadFile(path)
+
+	if len(after) != 2*len(before) || string(after[:len(before)]) != string(before) {
+		t.Fatalf("expected second append to leave existing content untouched")
+	}
+}
+
+func TestFileJournalMissingFile(t *testing.T) {
+	j, _ := NewFileJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	entries, err := j.Query(context.Background(), models.JournalFilter{})
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected empty result for missing journal, got %v, %v", entries, err)
+	}
+}
@@ -0,0 +1,37 @@
+package journal
+
+import (
+	"context"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// MockJournal implements ActionJournal for testing.
+type MockJournal struct {
+	AppendFunc func(ctx context.Context, entries []models.JournalEntry) error
+	QueryFunc  func(ctx context.Context, filter models.JournalFilter) ([]models.JournalEntry, error)
+
+	// Appended tracks entries passed to Append.
+	Appended []models.JournalEntry
+}
+
+func (m *MockJournal) Append(ctx context.Context, entries []models.JournalEntry) error {
+	m.Appended = append(m.Appended, entries...)
+	if m.AppendFunc != nil {
+		return m.AppendFunc(ctx, entries)
+	}
+	return nil
+}
+
+func (m *MockJournal) Query(ctx context.Context, filter models.JournalFilter) ([]models.JournalEntry, error) {
+	if m.QueryFunc != nil {
+		return m.QueryFunc(ctx, filter)
+	}
+	var result []models.JournalEntry
+	for _, e := range m.Appended {
+		if filter.Matches(e) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,174 @@
+// Package mapping manages identity mappings asserted by an operator, for users
+// reconciliation cannot match automatically (private email, no verified domain).
+package mapping
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// Filter selects mappings for List. Zero values match everything.
+type Filter struct {
+	Status     models.InvitationStatus
+	ManualOnly bool
+}
+
+// UnlinkResult lists the mappings changed by Unlink.
+type UnlinkResult struct {
+	Deleted []string `json:"deleted"` // Manual mappings removed
+	Removed []string `json:"removed"` // Automatic mappings marked removed
+}
+
+// Link records that email belongs to githubLogin with a resolved manual mapping.
+// It replaces any earlier manual mapping for the email and returns the new
+// mapping and the sort keys of the replaced ones. A login has one manual
+// mapping, so linking a login already linked to another email is refused.
+func Link(ctx context.Context, store interfaces.InvitationStore, org string, email string, githubLogin string, role models.OrgRole) (models.InvitationMapping, []string, error) {
+	email = normalizeEmail(email)
+	githubLogin = strings.TrimSpace(githubLogin)
+	if !strings.Contains(email, "@") {
+		return models.InvitationMapping{}, nil, fmt.Errorf("invalid google email %q", email)
+	}
+	if githubLogin == "" || strings.ContainsAny(githubLogin, "@# ") {
+		return models.InvitationMapping{}, nil, fmt.Errorf("invalid github login %q", githubLogin)
+	}
+
+	all, err := store.ListMappings(ctx, org)
+	if err != nil {
+		return models.InvitationMapping{}, nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	mapping := models.NewManualMapping(org, email, githubLogin, role)
+	var existing []models.InvitationMapping
+	for _, m := range all {
+		switch {
+		case strings.EqualFold(m.Email, email):
+			existing = append(existing, m)
+		case strings.EqualFold(m.SK, mapping.SK): // GitHub logins are case-insensitive
+			return models.InvitationMapping{}, nil, fmt.Errorf("github login %s is already linked to %s; unlink it first", githubLogin, m.Email)
+		}
+	}
+
+	if err := store.SaveInvitation(ctx, mapping); err != nil {
+		return models.InvitationMapping{}, nil, fmt.Errorf("saving mapping: %w", err)
+	}
+
+	var replaced []string
+	for _, m := range existing {
+		if !m.Manual || m.SK == mapping.SK {
+			continue
+		}
+		if err := store.DeleteMapping(ctx, org, m.SK); err != nil {
+			return mapping, replaced, fmt.Errorf("deleting replaced mapping %s: %w", m.SK, err)
+		}
+		replaced = append(replaced, m.SK)
+	}
+	return mapping, replaced, nil
+}
+
+// Unlink deletes the manual mappings for email. With all set, automatic
+// resolved mappings for the email are also marked removed, so a wrong
+// automatic match stops being trusted.
+func Unlink(ctx context.Context, store interfaces.InvitationStore, org string, email string, all bool) (UnlinkResult, error) {
+	email = normalizeEmail(email)
+	existing, err := byEmail(ctx, store, org, email)
+	if err != nil {
+		return UnlinkResult{}, err
+	}
+
+	var result UnlinkResult
+	for _, m := range existing {
+		switch {
+		case m.Manual:
+			if err := store.DeleteMapping(ctx, org, m.SK); err != nil {
+				return result, fmt.Errorf("deleting mapping %s: %w", m.SK, err)
+			}
+			result.Deleted = append(result.Deleted, m.SK)
+		case all && m.Status == models.InvitationResolved:
+			if err := store.TransitionMapping(ctx, m, models.InvitationRemoved); err != nil {
+				return result, fmt.Errorf("marking mapping %s removed: %w", m.SK, err)
+			}
+			result.Removed = append(result.Removed, m.SK)
+		}
+	}
+	if len(result.Deleted) == 0 && len(result.Removed) == 0 {
+		if all {
+			return result, fmt.Errorf("no resolved mapping for %s", email)
+		}
+		return result, fmt.Errorf("no manual mapping for %s (use --all to unlink automatic mappings)", email)
+	}
+	return result, nil
+}
+
+// Show returns every mapping for a Google email, or for a GitHub login when
+// identity has no "@". Logins are compared case-insensitively.
+func Show(ctx context.Context, store interfaces.InvitationStore, org string, identity string) ([]models.InvitationMapping, error) {
+	identity = strings.TrimSpace(identity)
+	all, err := store.ListMappings(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	byLogin := !strings.Contains(identity, "@")
+	var mappings []models.InvitationMapping
+	for _, m := range all {
+		if byLogin && m.GitHubLogin != nil && strings.EqualFold(*m.GitHubLogin, identity) ||
+			!byLogin && strings.EqualFold(m.Email, identity) {
+			mappings = append(mappings, m)
+		}
+	}
+	sortMappings(mappings)
+	return mappings, nil
+}
+
+// byEmail returns the org's mappings for email. GetByEmail matches case-sensitively,
+// so the org's mappings are scanned instead; these are operator commands, not the sync path.
+func byEmail(ctx context.Context, store interfaces.InvitationStore, org string, email string) ([]models.InvitationMapping, error) {
+	all, err := store.ListMappings(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	var mappings []models.InvitationMapping
+	for _, m := range all {
+		if strings.EqualFold(m.Email, email) {
+			mappings = append(mappings, m)
+		}
+	}
+	return mappings, nil
+}
+
+// List returns the org's mappings matching filter, ordered by email.
+func List(ctx context.Context, store interfaces.InvitationStore, org string, filter Filter) ([]models.InvitationMapping, error) {
+	all, err := store.ListMappings(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	var mappings []models.InvitationMapping
+	for _, m := range all {
+		if filter.Status != "" && m.Status != filter.Status {
+			continue
+		}
+		if filter.ManualOnly && !m.Manual {
+			continue
+		}
+		mappings = append(mappings, m)
+	}
+	sortMappings(mappings)
+	return mappings, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func sortMappings(mappings []models.InvitationMapping) {
+	sort.SliceStable(mappings, func(a, b int) bool {
+		if mappings[a].Email != mappings[b].Email {
+			return mappings[a].Email < mappings[b].Email
+		}
+		return mappings[a].SK < mappings[b].SK
+	})
+}
@@ -0,0 +1,167 @@
+package mapping
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/sqlite"
+)
+
+func newStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.NewStore(context.Background(), config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "sync.db"), TTLDays: 90})
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestLinkIsTrustedOverAutomaticMapping(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if err := store.SaveInvitation(ctx, models.NewInvitationMapping("org", 1, "Jane@example.com", models.RoleMember, 90)); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+	if err := store.ResolveInvitation(ctx, "org", 1, "wrong-match"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+
+	mapping, replaced, err := Link(ctx, store, "org", " Jane@Example.com ", "jane-gh", models.RoleOwner)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if mapping.SK != "MANUAL#jane-gh" || mapping.Email != "jane@example.com" || !mapping.Manual || len(replaced) != 0 {
+		t.Fatalf("unexpected link result %+v, replaced %v", mapping, replaced)
+	}
+
+	resolved, err := store.GetAllResolvedMappings(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if len(resolved) != 1 || resolved["jane@example.com"] != "jane-gh" {
+		t.Fatalf("expected only the manual mapping to be trusted, got %v", resolved)
+	}
+}
+
+func TestLinkReplacesEarlierManualMapping(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if _, _, err := Link(ctx, store, "org", "jane@example.com", "old-login", models.RoleMember); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	_, replaced, err := Link(ctx, store, "org", "jane@example.com", "new-login", models.RoleMember)
+	if err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if len(replaced) != 1 || replaced[0] != "MANUAL#old-login" {
+		t.Fatalf("expected the old manual mapping replaced, got %v", replaced)
+	}
+	mappings, err := Show(ctx, store, "org", "jane@example.com")
+	if err != nil || len(mappings) != 1 || *mappings[0].GitHubLogin != "new-login" {
+		t.Fatalf("expected only the new mapping, got %+v (err %v)", mappings, err)
+	}
+}
+
+func TestLinkRefusesLoginLinkedToAnotherEmail(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if _, _, err := Link(ctx, store, "org", "jane@example.com", "jane-gh", models.RoleMember); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if _, _, err := Link(ctx, store, "org", "jane.doe@example.com", "jane-gh", models.RoleMember); err == nil {
+		t.Fatalf("expected linking a second email to the login to be refused")
+	}
+	if _, _, err := Link(ctx, store, "org", "jane.doe@example.com", "Jane-GH", models.RoleMember); err == nil {
+		t.Fatalf("expected linking a second email to the login in another case to be refused")
+	}
+	mappings, err := Show(ctx, store, "org", "jane-gh")
+	if err != nil || len(mappings) != 1 || mappings[0].Email != "jane@example.com" {
+		t.Fatalf("expected the first email's mapping to be kept, got %+v (err %v)", mappings, err)
+	}
+	// Relinking the same email, e.g. to change the role, is not a conflict.
+	if _, _, err := Link(ctx, store, "org", "Jane@example.com", "jane-gh", models.RoleOwner); err != nil {
+		t.Fatalf("expected relinking the same email to succeed, got %v", err)
+	}
+}
+
+func TestLinkRejectsInvalidInput(t *testing.T) {
+	store := newStore(t)
+	for _, tc := range [][2]string{{"not-an-email", "login"}, {"a@example.com", ""}, {"a@example.com", "a@b"}} {
+		if _, _, err := Link(context.Background(), store, "org", tc[0], tc[1], models.RoleMember); err == nil {
+			t.Errorf("Link(%q, %q): expected an error", tc[0], tc[1])
+		}
+	}
+}
+
+func TestUnlink(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if err := store.SaveInvitation(ctx, models.NewInvitationMapping("org", 1, "jane@example.com", models.RoleMember, 90)); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+	if err := store.ResolveInvitation(ctx, "org", 1, "auto-login"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+	if _, _, err := Link(ctx, store, "org", "jane@example.com", "jane-gh", models.RoleMember); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+
+	result, err := Unlink(ctx, store, "org", "JANE@example.com", false)
+	if err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	if len(result.Deleted) != 1 || len(result.Removed) != 0 {
+		t.Fatalf("expected only the manual mapping deleted, got %+v", result)
+	}
+	resolved, _ := store.GetAllResolvedMappings(ctx, "org")
+	if resolved["jane@example.com"] != "auto-login" {
+		t.Fatalf("expected the automatic mapping to be trusted again, got %v", resolved)
+	}
+
+	if _, err := Unlink(ctx, store, "org", "jane@example.com", false); err == nil {
+		t.Fatal("expected an error with no manual mapping left")
+	}
+	result, err = Unlink(ctx, store, "org", "jane@example.com", true)
+	if err != nil {
+		t.Fatalf("Unlink --all: %v", err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != "INV#1" {
+		t.Fatalf("expected the automatic mapping marked removed, got %+v", result)
+	}
+	if resolved, _ := store.GetAllResolvedMappings(ctx, "org"); len(resolved) != 0 {
+		t.Fatalf("expected no trusted mappings left, got %v", resolved)
+	}
+}
+
+func TestShowAndList(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if err := store.SaveInvitation(ctx, models.NewInvitationMapping("org", 1, "bob@example.com", models.RoleMember, 90)); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+	if _, _, err := Link(ctx, store, "org", "jane@example.com", "Jane-GH", models.RoleMember); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+
+	byLogin, err := Show(ctx, store, "org", "jane-gh")
+	if err != nil || len(byLogin) != 1 || byLogin[0].Email != "jane@example.com" {
+		t.Fatalf("expected lookup by login to find jane, got %+v (err %v)", byLogin, err)
+	}
+
+	all, err := List(ctx, store, "org", Filter{})
+	if err != nil || len(all) != 2 || all[0].Email != "bob@example.com" {
+		t.Fatalf("expected both mappings ordered by email, got %+v (err %v)", all, err)
+	}
+	manual, err := List(ctx, store, "org", Filter{ManualOnly: true})
+	if err != nil || len(manual) != 1 || manual[0].Email != "jane@example.com" {
+		t.Fatalf("expected only the manual mapping, got %+v (err %v)", manual, err)
+	}
+	pending, err := List(ctx, store, "org", Filter{Status: models.InvitationPending})
+	if err != nil || len(pending) != 1 || pending[0].Email != "bob@example.com" {
+		t.Fatalf("expected only the pending mapping, got %+v (err %v)", pending, err)
+	}
+}
@@ -2,13 +2,22 @@ package metrics
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 )
 
+// maxDatumsPerRequest is the PutMetricData limit on metrics per call.
+const maxDatumsPerRequest = 1000
+
 // CloudWatchAPI defines the CloudWatch client interface used for metrics.
 type CloudWatchAPI interface {
 	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
@@ -16,8 +25,9 @@ type CloudWatchAPI interface {
 
 // Emitter sends sync metrics to CloudWatch.
 type Emitter struct {
-	client    CloudWatchAPI
-	namespace string
+	client     CloudWatchAPI
+	namespace  string
+	dimensions []string // config.MetricsDimension* attached to run metrics
 }
 
 // NewEmitter creates a CloudWatch metrics emitter.
@@ -28,9 +38,121 @@ func NewEmitter(cfg aws.Config, namespace string) *Emitter {
 	}
 }
 
+// New creates an emitter from metrics settings. With cfg.Endpoint set, requests
+// go to that local stand-in with static credentials.
+func New(ctx context.Context, cfg config.MetricsConfig) (*Emitter, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	if cfg.Endpoint != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider("local", "local", ""),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+
+	var clientOpts []func(*cloudwatch.Options)
+	if cfg.Endpoint != "" {
+		clientOpts = append(clientOpts, func(o *cloudwatch.Options) {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		})
+	}
+
+	return &Emitter{
+		client:     cloudwatch.NewFromConfig(awsCfg, clientOpts...),
+		namespace:  cfg.Namespace,
+		dimensions: cfg.Dimensions,
+	}, nil
+}
+
 // EmitSummary publishes sync metrics to CloudWatch.
 func (e *Emitter) EmitSummary(ctx context.Context, summary models.SyncSummary, errors []string) error {
-	metrics := []types.MetricDatum{
+	return e.put(ctx, summaryData(summary, errors))
+}
+
+// RecordRun publishes the metrics of a run: RunSuccess (1 or 0), the summary
+// counts, RunDuration, PhaseDuration per Phase, APICalls per Endpoint and the
+// reconciliation counts. Every metric carries the configured dimensions.
+func (e *Emitter) RecordRun(ctx context.Context, run Run) error {
+	success := 0
+	if run.Err == nil && run.Result != nil && run.Result.IsSuccess() {
+		success = 1
+	}
+	data := []types.MetricDatum{gaugeDatum("RunSuccess", success)}
+
+	result := run.Result
+	if result == nil {
+		data = append(data, metricDatum("Errors", 1))
+		return e.put(ctx, e.withDimensions(data, run))
+	}
+
+	errors := append([]string(nil), result.Errors...)
+	if run.Err != nil {
+		errors = append(errors, run.Err.Error())
+	}
+	data = append(data, summaryData(result.Summary, errors)...)
+	data = append(data, durationDatum("RunDuration", result.DurationMs))
+	for _, phase := range sortedKeys(result.PhaseDurationsMs) {
+		data = append(data, withDimension(durationDatum("PhaseDuration", result.PhaseDurationsMs[phase]), "Phase", phase))
+	}
+	for _, endpoint := range sortedKeys(result.APICalls) {
+		data = append(data, withDimension(metricDatum("APICalls", result.APICalls[endpoint]), "Endpoint", endpoint))
+	}
+	if r := result.Reconciliation; r != nil {
+		data = append(data,
+			metricDatum("ReconcileInvitationsSaved", r.NewInvitationsSaved),
+			metricDatum("ReconcileResolved", r.Resolved),
+			metricDatum("ReconcileFailed", r.Failed),
+			metricDatum("ReconcileExpired", r.Expired),
+			metricDatum("ReconcileCancelled", r.Cancelled),
+			metricDatum("ReconcileMembersRemoved", r.MembersRemoved),
+			metricDatum("ReconcileRolesUpdated", r.RolesUpdated),
+			metricDatum("ReconcileAlreadyInOrgResolved", r.AlreadyInOrgResolved),
+			metricDatum("ReconcileVerifiedEmailsMapped", r.VerifiedEmailsMapped),
+			metricDatum("ReconcileTransitionsRejected", r.TransitionsRejected),
+			metricDatum("ReconcileErrors", len(r.Errors)),
+		)
+	}
+	return e.put(ctx, e.withDimensions(data, run))
+}
+
+// put sends data in as many PutMetricData calls as the per-call limit requires.
+func (e *Emitter) put(ctx context.Context, data []types.MetricDatum) error {
+	for start := 0; start < len(data); start += maxDatumsPerRequest {
+		end := min(start+maxDatumsPerRequest, len(data))
+		_, err := e.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(e.namespace),
+			MetricData: data[start:end],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDimensions adds the configured run dimensions to every datum.
+func (e *Emitter) withDimensions(data []types.MetricDatum, run Run) []types.MetricDatum {
+	for _, name := range e.dimensions {
+		for i := range data {
+			switch name {
+			case config.MetricsDimensionOrg:
+				data[i] = withDimension(data[i], "Organization", run.Org)
+			case config.MetricsDimensionDryRun:
+				data[i] = withDimension(data[i], "DryRun", strconv.FormatBool(run.DryRun))
+			}
+		}
+	}
+	return data
+}
+
+func summaryData(summary models.SyncSummary, errors []string) []types.MetricDatum {
+	return []types.MetricDatum{
 		metricDatum("ActionsPlanned", summary.ActionsPlanned),
 		metricDatum("ActionsExecuted", summary.ActionsExecuted),
 		metricDatum("ActionsFailed", summary.ActionsFailed),
@@ -40,12 +162,6 @@ func (e *Emitter) EmitSummary(ctx context.Context, summary models.SyncSummary, e
 		metricDatum("Skipped", summary.Skipped),
 		metricDatum("Errors", len(errors)),
 	}
-
-	_, err := e.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
-		Namespace:  aws.String(e.namespace),
-		MetricData: metrics,
-	})
-	return err
 }
 
 func metricDatum(name string, value int) types.MetricDatum {
@@ -55,3 +171,34 @@ func metricDatum(name string, value int) types.MetricDatum {
 		Value:      aws.Float64(float64(value)),
 	}
 }
+
+func gaugeDatum(name string, value int) types.MetricDatum {
+	return types.MetricDatum{
+		MetricName: aws.String(name),
+		Unit:       types.StandardUnitNone,
+		Value:      aws.Float64(float64(value)),
+	}
+}
+
+func durationDatum(name string, ms int64) types.MetricDatum {
+	return types.MetricDatum{
+		MetricName: aws.String(name),
+		Unit:       types.StandardUnitMilliseconds,
+		Value:      aws.Float64(float64(ms)),
+	}
+}
+
+func withDimension(datum types.MetricDatum, name string, value string) types.MetricDatum {
+	datum.Dimensions = append(append([]types.Dimension(nil), datum.Dimensions...),
+		types.Dimension{Name: aws.String(name), Value: aws.String(value)})
+	return datum
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,11 +1,19 @@
 package metrics
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 )
 
@@ -46,3 +54,96 @@ func TestEmitSummary(t *testing.T) {
 		t.Fatalf("expected 8 metrics, got %d", len(client.input.MetricData))
 	}
 }
+
+func dimensionValue(datum types.MetricDatum, name string) string {
+	for _, d := range datum.Dimensions {
+		if aws.ToString(d.Name) == name {
+			return aws.ToString(d.Value)
+		}
+	}
+	return ""
+}
+
+func TestRecordRun(t *testing.T) {
+	client := &mockCloudWatch{}
+	emitter := &Emitter{client: client, namespace: "TestNamespace", dimensions: []string{"org", "dry_run"}}
+
+	result := &models.SyncResult{
+		DurationMs:       1200,
+		Summary:          models.SyncSummary{ActionsPlanned: 2, ActionsExecuted: 2, Invited: 2},
+		PhaseDurationsMs: map[string]int64{models.PhaseGoogleLoad: 300, models.PhaseExecute: 900},
+		APICalls:         map[string]int{"GET /orgs/*/members": 3},
+		Reconciliation:   &models.ReconcileResult{NewInvitationsSaved: 2},
+	}
+	if err := emitter.RecordRun(context.Background(), Run{Org: "example-org", Result: result}); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+
+	// RunSuccess + 8 summary + RunDuration + 2 phases + 1 endpoint + 11 reconciliation.
+	if len(client.input.MetricData) != 24 {
+		t.Fatalf("expected 24 metrics, got %d", len(client.input.MetricData))
+	}
+	values := map[string]float64{}
+	for _, datum := range client.input.MetricData {
+		if dimensionValue(datum, "Organization") != "example-org" || dimensionValue(datum, "DryRun") != "false" {
+			t.Fatalf("expected org and dry_run dimensions on %s, got %+v", aws.ToString(datum.MetricName), datum.Dimensions)
+		}
+		key := aws.ToString(datum.MetricName)
+		if phase := dimensionValue(datum, "Phase"); phase != "" {
+			key += "/" + phase
+		}
+		if endpoint := dimensionValue(datum, "Endpoint"); endpoint != "" {
+			key += "/" + endpoint
+		}
+		values[key] = aws.ToFloat64(datum.Value)
+	}
+	if values["RunSuccess"] != 1 || values["PhaseDuration/execute"] != 900 || values["APICalls/GET /orgs/*/members"] != 3 || values["ReconcileInvitationsSaved"] != 2 {
+		t.Fatalf("unexpected metric values: %v", values)
+	}
+}
+
+func TestRecordRunFailure(t *testing.T) {
+	client := &mockCloudWatch{}
+	emitter := &Emitter{client: client, namespace: "TestNamespace"}
+
+	if err := emitter.RecordRun(context.Background(), Run{Org: "example-org", Err: errors.New("github unavailable")}); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+	data := client.input.MetricData
+	if len(data) != 2 || aws.ToString(data[0].MetricName) != "RunSuccess" || aws.ToFloat64(data[0].Value) != 0 {
+		t.Fatalf("expected RunSuccess 0 and Errors, got %+v", data)
+	}
+	if len(data[0].Dimensions) != 0 {
+		t.Fatalf("expected no dimensions when none are configured, got %+v", data[0].Dimensions)
+	}
+}
+
+func TestEmitterLocalEndpoint(t *testing.T) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.HasSuffix(r.URL.Path, "/PutMetricData") {
+			bodies = append(bodies, body)
+		}
+		w.Header().Set("Smithy-Protocol", "rpc-v2-cbor")
+		w.Header().Set("Content-Type", "application/cbor")
+		w.Write([]byte{0xa0}) // empty CBOR map
+	}))
+	defer server.Close()
+
+	emitter, err := New(context.Background(), config.MetricsConfig{
+		Namespace:  "TestNamespace",
+		Region:     "eu-west-1",
+		Endpoint:   server.URL,
+		Dimensions: []string{"org"},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := emitter.RecordRun(context.Background(), Run{Org: "example-org", Result: &models.SyncResult{}}); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+	if len(bodies) != 1 || !bytes.Contains(bodies[0], []byte("RunSuccess")) || !bytes.Contains(bodies[0], []byte("example-org")) {
+		t.Fatalf("expected one PutMetricData call to the local endpoint, got %d", len(bodies))
+	}
+}
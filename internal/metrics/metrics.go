@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/ratelimit"
+)
+
+// Run describes a finished sync run for a Recorder.
+type Run struct {
+	Org    string
+	DryRun bool
+	Result *models.SyncResult // nil when the run failed before producing a result
+	Err    error
+}
+
+// Recorder is a metrics backend that records finished sync runs.
+type Recorder interface {
+	RecordRun(ctx context.Context, run Run) error
+}
+
+// APIObserver is implemented by recorders that track individual API requests.
+type APIObserver interface {
+	// ObserveAPICall records one request to endpoint (see ratelimit.Endpoint) that
+	// finished with status after duration. Status is 0 when no response arrived.
+	ObserveAPICall(resource string, endpoint string, status int, duration time.Duration)
+}
+
+// Multi records to every recorder in turn.
+type Multi []Recorder
+
+// RecordRun records run to every recorder and joins their errors.
+func (m Multi) RecordRun(ctx context.Context, run Run) error {
+	var errs []error
+	for _, r := range m {
+		if err := r.RecordRun(ctx, run); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ObserveAPICall passes the call to every recorder that is an APIObserver.
+func (m Multi) ObserveAPICall(resource string, endpoint string, status int, duration time.Duration) {
+	for _, r := range m {
+		if observer, ok := r.(APIObserver); ok {
+			observer.ObserveAPICall(resource, endpoint, status, duration)
+		}
+	}
+}
+
+// Transport returns an http.RoundTripper that reports the latency of every request
+// to observer. If base is nil, http.DefaultTransport is used.
+func Transport(base http.RoundTripper, observer APIObserver) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, observer: observer}
+}
+
+type transport struct {
+	base     http.RoundTripper
+	observer APIObserver
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.observer.ObserveAPICall(ratelimit.Classify(req), ratelimit.Endpoint(req), status, time.Since(start))
+	return resp, err
+}
+
+// statusLabel formats a response status for a metric label.
+func statusLabel(status int) string {
+	if status == 0 {
+		return "error"
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,236 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusNamespace prefixes every Prometheus metric name.
+const prometheusNamespace = "workspace_sync"
+
+// runLabels identify the run a metric belongs to.
+var runLabels = []string{"org", "dry_run"}
+
+// Prometheus keeps sync metrics in a registry for scraping. Counters and
+// histograms accumulate over every run the process makes; gauges hold the
+// values of the latest run.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	runs             *prometheus.CounterVec
+	lastRunSuccess   *prometheus.GaugeVec
+	lastRunTimestamp *prometheus.GaugeVec
+	runDuration      *prometheus.HistogramVec
+	phaseDuration    *prometheus.HistogramVec
+	actions          *prometheus.CounterVec
+	actionsPerRun    *prometheus.HistogramVec
+	changes          *prometheus.CounterVec
+	errors           *prometheus.CounterVec
+	members          *prometheus.GaugeVec
+	pending          *prometheus.GaugeVec
+	orphaned         *prometheus.GaugeVec
+	reconcile        *prometheus.CounterVec
+	apiDuration      *prometheus.HistogramVec
+	rateRemaining    *prometheus.GaugeVec
+	rateLimit        *prometheus.GaugeVec
+}
+
+// NewPrometheus creates a Prometheus backend with its own registry, which also
+// carries the Go runtime and process collectors.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace, Name: "runs_total",
+			Help: "Sync runs by outcome (success or failure).",
+		}, append(runLabels, "outcome")),
+		lastRunSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "last_run_success",
+			Help: "1 if the latest run succeeded, otherwise 0.",
+		}, runLabels),
+		lastRunTimestamp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "last_run_timestamp_seconds",
+			Help: "Unix time the latest run finished.",
+		}, runLabels),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace, Name: "run_duration_seconds",
+			Help:    "Duration of sync runs.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12), // 1s to ~34m
+		}, runLabels),
+		phaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace, Name: "phase_duration_seconds",
+			Help:    "Duration of each sync phase.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 14), // 50ms to ~7m
+		}, append(runLabels, "phase")),
+		actions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace, Name: "actions_total",
+			Help: "Sync actions by stage (planned, executed or failed).",
+		}, append(runLabels, "stage")),
+		actionsPerRun: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace, Name: "actions_planned_per_run",
+			Help:    "Actions planned by each run.",
+			Buckets: []float64{0, 1, 5, 10, 25, 50, 100, 250, 500},
+		}, runLabels),
+		changes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace, Name: "changes_total",
+			Help: "Membership changes by kind (invited, removed, role_updated, cancelled_invite or skipped).",
+		}, append(runLabels, "kind")),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace, Name: "errors_total",
+			Help: "Errors reported by sync runs.",
+		}, runLabels),
+		members: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "members",
+			Help: "Members seen by the latest run, by source (google or github).",
+		}, append(runLabels, "source")),
+		pending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "pending_invitations",
+			Help: "Pending GitHub invitations seen by the latest run.",
+		}, runLabels),
+		orphaned: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "orphaned_github_members",
+			Help: "GitHub members with no Google account, as seen by the latest run.",
+		}, runLabels),
+		reconcile: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace, Name: "reconcile_total",
+			Help: "Invitation reconciliation outcomes.",
+		}, append(runLabels, "outcome")),
+		apiDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: prometheusNamespace, Name: "api_request_duration_seconds",
+			Help:    "Latency of Google and GitHub API requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"resource", "endpoint", "code"}),
+		rateRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "rate_limit_remaining",
+			Help: "Requests left in each API rate-limit budget after the latest run.",
+		}, []string{"resource"}),
+		rateLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace, Name: "rate_limit_limit",
+			Help: "Size of each API rate-limit budget.",
+		}, []string{"resource"}),
+	}
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.runs, p.lastRunSuccess, p.lastRunTimestamp, p.runDuration, p.phaseDuration,
+		p.actions, p.actionsPerRun, p.changes, p.errors, p.members, p.pending, p.orphaned,
+		p.reconcile, p.apiDuration, p.rateRemaining, p.rateLimit,
+	)
+	return p
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{Registry: p.registry})
+}
+
+// Serve exposes the registry at /metrics on addr for the life of the process.
+// It returns once the listener is bound.
+func (p *Prometheus) Serve(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	return nil
+}
+
+// RecordRun adds the run to the counters and histograms and sets the gauges.
+func (p *Prometheus) RecordRun(ctx context.Context, run Run) error {
+	labels := prometheus.Labels{"org": run.Org, "dry_run": strconv.FormatBool(run.DryRun)}
+	with := func(name string, value string) prometheus.Labels {
+		l := prometheus.Labels{name: value}
+		for k, v := range labels {
+			l[k] = v
+		}
+		return l
+	}
+
+	success := run.Err == nil && run.Result != nil && run.Result.IsSuccess()
+	outcome, successValue := "failure", 0.0
+	if success {
+		outcome, successValue = "success", 1.0
+	}
+	p.runs.With(with("outcome", outcome)).Inc()
+	p.lastRunSuccess.With(labels).Set(successValue)
+	p.lastRunTimestamp.With(labels).Set(float64(time.Now().Unix()))
+
+	result := run.Result
+	if result == nil {
+		p.errors.With(labels).Inc()
+		return nil
+	}
+
+	errors := len(result.Errors)
+	if run.Err != nil {
+		errors++
+	}
+	p.errors.With(labels).Add(float64(errors))
+
+	s := result.Summary
+	p.actions.With(with("stage", "planned")).Add(float64(s.ActionsPlanned))
+	p.actions.With(with("stage", "executed")).Add(float64(s.ActionsExecuted))
+	p.actions.With(with("stage", "failed")).Add(float64(s.ActionsFailed))
+	p.actionsPerRun.With(labels).Observe(float64(s.ActionsPlanned))
+	p.changes.With(with("kind", "invited")).Add(float64(s.Invited))
+	p.changes.With(with("kind", "removed")).Add(float64(s.Removed))
+	p.changes.With(with("kind", "role_updated")).Add(float64(s.RoleUpdated))
+	p.changes.With(with("kind", "cancelled_invite")).Add(float64(s.CancelledInvites))
+	p.changes.With(with("kind", "skipped")).Add(float64(s.Skipped))
+	p.members.With(with("source", "google")).Set(float64(s.TotalGoogleMembers))
+	p.members.With(with("source", "github")).Set(float64(s.TotalGitHubMembers))
+	p.pending.With(labels).Set(float64(s.PendingInvitations))
+	p.orphaned.With(labels).Set(float64(s.OrphanedGitHub))
+
+	p.runDuration.With(labels).Observe(seconds(result.DurationMs))
+	for phase, ms := range result.PhaseDurationsMs {
+		p.phaseDuration.With(with("phase", phase)).Observe(seconds(ms))
+	}
+
+	if r := result.Reconciliation; r != nil {
+		for outcome, n := range map[string]int{
+			"invitations_saved":       r.NewInvitationsSaved,
+			"resolved":                r.Resolved,
+			"failed":                  r.Failed,
+			"expired":                 r.Expired,
+			"cancelled":               r.Cancelled,
+			"members_removed":         r.MembersRemoved,
+			"roles_updated":           r.RolesUpdated,
+			"already_in_org_resolved": r.AlreadyInOrgResolved,
+			"verified_emails_mapped":  r.VerifiedEmailsMapped,
+			"transitions_rejected":    r.TransitionsRejected,
+			"errors":                  len(r.Errors),
+		} {
+			p.reconcile.With(with("outcome", outcome)).Add(float64(n))
+		}
+	}
+
+	for resource, usage := range result.RateLimits {
+		if usage.Limit == 0 {
+			continue // no rate-limit headers seen for this resource
+		}
+		p.rateRemaining.WithLabelValues(resource).Set(float64(usage.Remaining))
+		p.rateLimit.WithLabelValues(resource).Set(float64(usage.Limit))
+	}
+	return nil
+}
+
+// ObserveAPICall records the latency of one API request.
+func (p *Prometheus) ObserveAPICall(resource string, endpoint string, status int, duration time.Duration) {
+	p.apiDuration.WithLabelValues(resource, endpoint, statusLabel(status)).Observe(duration.Seconds())
+}
+
+func seconds(ms int64) float64 {
+	return float64(ms) / 1000
+}
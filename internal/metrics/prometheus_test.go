@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func scrape(t *testing.T, p *Prometheus) string {
+	t.Helper()
+	server := httptest.NewServer(p.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("scraping: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading scrape: %v", err)
+	}
+	return string(body)
+}
+
+func TestPrometheusRecordRun(t *testing.T) {
+	p := NewPrometheus()
+	result := &models.SyncResult{
+		DurationMs: 2500,
+		Summary: models.SyncSummary{
+			TotalGoogleMembers: 10,
+			TotalGitHubMembers: 9,
+			ActionsPlanned:     2,
+			ActionsExecuted:    2,
+			Invited:            1,
+			Removed:            1,
+		},
+		PhaseDurationsMs: map[string]int64{models.PhaseGoogleLoad: 400},
+		RateLimits:       map[string]models.RateLimitUsage{"core": {Limit: 5000, Remaining: 4990}, "search": {}},
+		Reconciliation:   &models.ReconcileResult{Resolved: 3},
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.RecordRun(context.Background(), Run{Org: "example-org", Result: result}); err != nil {
+			t.Fatalf("RecordRun: %v", err)
+		}
+	}
+
+	body := scrape(t, p)
+	for _, want := range []string{
+		`workspace_sync_runs_total{dry_run="false",org="example-org",outcome="success"} 2`,
+		`workspace_sync_last_run_success{dry_run="false",org="example-org"} 1`,
+		`workspace_sync_changes_total{dry_run="false",kind="invited",org="example-org"} 2`,
+		`workspace_sync_actions_total{dry_run="false",org="example-org",stage="planned"} 4`,
+		`workspace_sync_members{dry_run="false",org="example-org",source="google"} 10`,
+		`workspace_sync_run_duration_seconds_sum{dry_run="false",org="example-org"} 5`,
+		`workspace_sync_phase_duration_seconds_count{dry_run="false",org="example-org",phase="google_load"} 2`,
+		`workspace_sync_reconcile_total{dry_run="false",org="example-org",outcome="resolved"} 6`,
+		`workspace_sync_rate_limit_remaining{resource="core"} 4990`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected scrape to contain %s", want)
+		}
+	}
+	if strings.Contains(body, `workspace_sync_rate_limit_remaining{resource="search"}`) {
+		t.Errorf("expected no rate-limit gauge for a resource without headers")
+	}
+}
+
+func TestPrometheusRecordRunFailure(t *testing.T) {
+	p := NewPrometheus()
+	if err := p.RecordRun(context.Background(), Run{Org: "example-org", DryRun: true, Err: errors.New("github unavailable")}); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+
+	body := scrape(t, p)
+	for _, want := range []string{
+		`workspace_sync_runs_total{dry_run="true",org="example-org",outcome="failure"} 1`,
+		`workspace_sync_last_run_success{dry_run="true",org="example-org"} 0`,
+		`workspace_sync_errors_total{dry_run="true",org="example-org"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected scrape to contain %s", want)
+		}
+	}
+}
+
+func TestTransportObservesAPICalls(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer api.Close()
+
+	p := NewPrometheus()
+	client := &http.Client{Transport: Transport(nil, Multi{p})}
+	resp, err := client.Get(api.URL + "/orgs/example-org/members/octocat")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	want := `workspace_sync_api_request_duration_seconds_count{code="404",endpoint="GET /orgs/*/members/*",resource="core"} 1`
+	if body := scrape(t, p); !strings.Contains(body, want) {
+		t.Fatalf("expected scrape to contain %s", want)
+	}
+}
+
+type failingRecorder struct{ calls int }
+
+func (f *failingRecorder) RecordRun(ctx context.Context, run Run) error {
+	f.calls++
+	return errors.New("backend down")
+}
+
+func TestMultiRecordsToEveryBackend(t *testing.T) {
+	first, second := &failingRecorder{}, &failingRecorder{}
+	err := Multi{first, second}.RecordRun(context.Background(), Run{Org: "example-org"})
+	if err == nil {
+		t.Fatalf("expected the backends' errors")
+	}
+	if first.calls != 1 || second.calls != 1 {
+		t.Fatalf("expected every backend to record the run despite errors, got %d and %d", first.calls, second.calls)
+	}
+	Multi{first}.ObserveAPICall("core", "GET /orgs/*/members", 200, time.Millisecond) // ignored by non-observers
+}
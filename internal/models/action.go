@@ -29,6 +29,7 @@ type SyncAction struct {
 	Executed     bool       `json:"executed"`
 	AlreadyInOrg bool       `json:"already_in_org,omitempty"`
 	Error        *string    `json:"error,omitempty"`
+	HTTPStatus   int        `json:"http_status,omitempty"` // HTTP status of the API response, if known
 	Timestamp    *time.Time `json:"timestamp,omitempty"`
 	InvitationID *int64     `json:"invitation_id,omitempty"`
 }
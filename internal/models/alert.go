@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// AlertState is what alerting remembers about an organization between runs: the
+// history some rules look back on, and which rules are firing so that a firing
+// rule is not notified on every run.
+type AlertState struct {
+	Org              string                    `json:"org"`
+	FirstRunAt       time.Time                 `json:"first_run_at"`              // First run evaluated
+	LastSuccessAt    time.Time                 `json:"last_success_at,omitempty"` // Zero until a run succeeds
+	OrphanCount      int                       `json:"orphan_count"`              // Orphaned GitHub members at the last completed run
+	OrphanCountKnown bool                      `json:"orphan_count_known"`        // A completed run has set OrphanCount
+	OrphanGrowthRuns int                       `json:"orphan_growth_runs"`        // Consecutive completed runs in which OrphanCount grew
+	Rules            map[string]AlertRuleState `json:"rules,omitempty"`           // Firing rules, by name
+	UpdatedAt        time.Time                 `json:"updated_at"`
+}
+
+// AlertRuleState records a firing rule.
+type AlertRuleState struct {
+	FiringSince time.Time `json:"firing_since"`
+	NotifiedAt  time.Time `json:"notified_at,omitempty"` // Last successful notification; zero if none got through
+}
@@ -0,0 +1,57 @@
+package models
+
+// DoctorCategory classifies an inconsistency between the invitation store and GitHub.
+type DoctorCategory string
+
+const (
+	// DoctorResolvedNotMember is a resolved mapping whose GitHub login is no longer an org member.
+	DoctorResolvedNotMember DoctorCategory = "resolved_not_member"
+	// DoctorStalePending is a pending mapping whose invitation GitHub no longer lists as pending.
+	DoctorStalePending DoctorCategory = "stale_pending"
+	// DoctorDuplicate is one of several active mappings with the same status for one email.
+	DoctorDuplicate DoctorCategory = "duplicate"
+)
+
+// DoctorCategories lists the categories in report order.
+var DoctorCategories = []DoctorCategory{DoctorResolvedNotMember, DoctorStalePending, DoctorDuplicate}
+
+// DoctorIssue is one inconsistent invitation mapping and its proposed repair.
+// An issue with neither NewStatus nor Delete set needs manual attention.
+type DoctorIssue struct {
+	Category    DoctorCategory    `json:"category"`
+	Email       string            `json:"email"`
+	SK          string            `json:"sk"`
+	GitHubLogin string            `json:"github_login,omitempty"`
+	Status      InvitationStatus  `json:"status"`
+	Detail      string            `json:"detail"`
+	NewStatus   InvitationStatus  `json:"new_status,omitempty"` // Repair: mark the mapping with this status
+	Delete      bool              `json:"delete,omitempty"`     // Repair: delete the mapping (merged into KeptSK)
+	KeptSK      string            `json:"kept_sk,omitempty"`
+	Repaired    bool              `json:"repaired"`
+	Mapping     InvitationMapping `json:"-"`
+}
+
+// Repairable reports whether the doctor can fix the issue automatically.
+func (i DoctorIssue) Repairable() bool {
+	return i.NewStatus != "" || i.Delete
+}
+
+// DoctorReport is the outcome of a store consistency check.
+type DoctorReport struct {
+	Org      string        `json:"org"`
+	Mappings int           `json:"mappings"`
+	Issues   []DoctorIssue `json:"issues"`
+	Repaired int           `json:"repaired"`
+	Errors   []string      `json:"errors,omitempty"`
+}
+
+// Count returns the number of issues in a category.
+func (r *DoctorReport) Count(category DoctorCategory) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Category == category {
+			n++
+		}
+	}
+	return n
+}
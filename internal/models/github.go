@@ -10,11 +10,13 @@ const (
 
 // GitHubOrgMember represents a current or invited member of a GitHub Organization.
 type GitHubOrgMember struct {
-	Username     *string `json:"username,omitempty"`
-	Email        *string `json:"email,omitempty"`
-	Role         OrgRole `json:"role"`
-	IsPending    bool    `json:"is_pending"`
-	InvitationID *int64  `json:"invitation_id,omitempty"`
+	Username       *string  `json:"username,omitempty"`
+	Email          *string  `json:"email,omitempty"`
+	Role           OrgRole  `json:"role"`
+	IsPending      bool     `json:"is_pending"`
+	InvitationID   *int64   `json:"invitation_id,omitempty"`
+	DatabaseID     *int64   `json:"database_id,omitempty"`
+	VerifiedEmails []string `json:"verified_emails,omitempty"` // Organization verified-domain emails (Enterprise Cloud)
 }
 
 // Identifier returns the best identifier for this member (email or username).
@@ -7,9 +7,19 @@ type GoogleGroupMember struct {
 	Type        string `json:"type"`
 	Status      string `json:"status"`
 	IsSuspended bool   `json:"is_suspended"`
+	IsArchived  bool   `json:"is_archived,omitempty"`
+	IsDeleted   bool   `json:"is_deleted,omitempty"`
 }
 
 // IsActive returns true if the member is an active, non-suspended user.
 func (m *GoogleGroupMember) IsActive() bool {
-	return m.Type == "USER" && m.Status == "ACTIVE" && !m.IsSuspended
+	return m.Type == "USER" && m.Status == "ACTIVE" && !m.IsSuspended && !m.IsArchived && !m.IsDeleted
+}
+
+// GoogleUserStatus describes the account state of a Google Workspace user.
+type GoogleUserStatus struct {
+	Suspended bool `json:"suspended"`
+	Archived  bool `json:"archived"`
+	Deleted   bool `json:"deleted"`            // No such account in the directory (deleted or never existed)
+	External  bool `json:"external,omitempty"` // Outside the customer's domains, so the status is unknown
 }
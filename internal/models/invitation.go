@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -19,21 +20,23 @@ const (
 
 // InvitationMapping represents a tracked invitation in DynamoDB.
 type InvitationMapping struct {
-	PK          string           `dynamodbav:"pk"`
-	SK          string           `dynamodbav:"sk"`
-	Email       string           `dynamodbav:"email"`
-	GitHubLogin *string          `dynamodbav:"github_login,omitempty"`
-	Status      InvitationStatus `dynamodbav:"status"`
-	Role        OrgRole          `dynamodbav:"role"`
-	InvitedAt   time.Time        `dynamodbav:"invited_at"`
-	ResolvedAt  *time.Time       `dynamodbav:"resolved_at,omitempty"`
-	TTL         int64            `dynamodbav:"ttl"`
+	PK          string           `dynamodbav:"pk" json:"pk"`
+	SK          string           `dynamodbav:"sk" json:"sk"`
+	Email       string           `dynamodbav:"email" json:"email"`
+	GitHubLogin *string          `dynamodbav:"github_login,omitempty" json:"github_login,omitempty"`
+	Status      InvitationStatus `dynamodbav:"status" json:"status"`
+	Role        OrgRole          `dynamodbav:"role" json:"role"`
+	InvitedAt   time.Time        `dynamodbav:"invited_at" json:"invited_at"`
+	ResolvedAt  *time.Time       `dynamodbav:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	TTL         int64            `dynamodbav:"ttl,omitempty" json:"ttl"`                   // 0: never expires
+	Manual      bool             `dynamodbav:"manual,omitempty" json:"manual,omitempty"`   // Asserted by an operator with `mapping link`
+	Version     int64            `dynamodbav:"version,omitempty" json:"version,omitempty"` // Incremented by every conditional update
 
 	// GSI keys
-	GSI1PK string `dynamodbav:"gsi1pk"` // EMAIL#<email>
-	GSI1SK string `dynamodbav:"gsi1sk"` // ORG#<org>
-	GSI2PK string `dynamodbav:"gsi2pk"` // ORG#<org>
-	GSI2SK string `dynamodbav:"gsi2sk"` // STATUS#<status>
+	GSI1PK string `dynamodbav:"gsi1pk" json:"gsi1pk"` // EMAIL#<email>
+	GSI1SK string `dynamodbav:"gsi1sk" json:"gsi1sk"` // ORG#<org>
+	GSI2PK string `dynamodbav:"gsi2pk" json:"gsi2pk"` // ORG#<org>
+	GSI2SK string `dynamodbav:"gsi2sk" json:"gsi2sk"` // STATUS#<status>
 }
 
 // NewInvitationMapping creates a new InvitationMapping with all key attributes set.
@@ -56,6 +59,51 @@ func NewInvitationMapping(org string, invitationID int64, email string, role Org
 	}
 }
 
+// NewManualMapping creates a resolved mapping asserted by an operator. Manual
+// mappings use the MANUAL#<login> sort key, which reconciliation never writes,
+// and do not expire.
+func NewManualMapping(org string, email string, githubLogin string, role OrgRole) InvitationMapping {
+	now := time.Now().UTC()
+	return InvitationMapping{
+		PK:          "ORG#" + org,
+		SK:          "MANUAL#" + githubLogin,
+		Email:       email,
+		GitHubLogin: &githubLogin,
+		Status:      InvitationResolved,
+		Role:        role,
+		InvitedAt:   now,
+		ResolvedAt:  &now,
+		Manual:      true,
+		GSI1PK:      "EMAIL#" + email,
+		GSI1SK:      "ORG#" + org,
+		GSI2PK:      "ORG#" + org,
+		GSI2SK:      "STATUS#" + string(InvitationResolved),
+	}
+}
+
+// ResolvedLogins maps the email of each resolved mapping to its GitHub login.
+// Manual mappings take precedence over ones found by reconciliation; emails are
+// compared case-insensitively, as CalculateDiff does.
+func ResolvedLogins(mappings []InvitationMapping) map[string]string {
+	manual := make(map[string]struct{})
+	for _, m := range mappings {
+		if m.Manual && m.Status == InvitationResolved {
+			manual[strings.ToLower(m.Email)] = struct{}{}
+		}
+	}
+	resolved := make(map[string]string, len(mappings))
+	for _, m := range mappings {
+		if m.Status != InvitationResolved || m.GitHubLogin == nil {
+			continue
+		}
+		if _, ok := manual[strings.ToLower(m.Email)]; ok && !m.Manual {
+			continue
+		}
+		resolved[m.Email] = *m.GitHubLogin
+	}
+	return resolved
+}
+
 func invitationSK(invitationID int64) string {
 	return "INV#" + formatInt64(invitationID)
 }
@@ -76,10 +124,10 @@ type AuditLogEntry struct {
 
 // AuditLogCursor represents the saved position in the audit log.
 type AuditLogCursor struct {
-	PK            string    `dynamodbav:"pk"`
-	SK            string    `dynamodbav:"sk"`
-	LastTimestamp  int64     `dynamodbav:"last_timestamp"`
-	LastRun       time.Time `dynamodbav:"last_run"`
+	PK            string    `dynamodbav:"pk" json:"pk"`
+	SK            string    `dynamodbav:"sk" json:"sk"`
+	LastTimestamp int64     `dynamodbav:"last_timestamp" json:"last_timestamp"`
+	LastRun       time.Time `dynamodbav:"last_run" json:"last_run"`
 }
 
 // ReconcileResult holds the outcome of an invitation reconciliation run.
@@ -93,5 +141,6 @@ type ReconcileResult struct {
 	RolesUpdated         int      `json:"roles_updated"`
 	AlreadyInOrgResolved int      `json:"already_in_org_resolved"`
 	VerifiedEmailsMapped int      `json:"verified_emails_mapped"`
+	TransitionsRejected  int      `json:"transitions_rejected,omitempty"` // Writes the store refused (see TransitionError)
 	Errors               []string `json:"errors,omitempty"`
 }
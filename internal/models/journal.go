@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// JournalOutcome is the result of an executed action.
+type JournalOutcome string
+
+const (
+	JournalSucceeded JournalOutcome = "succeeded"
+	JournalFailed    JournalOutcome = "failed"
+)
+
+// Membership statuses recorded in the journal.
+const (
+	MembershipMember  = "member"
+	MembershipInvited = "invited"
+	MembershipAbsent  = "absent"
+)
+
+// MembershipState describes a user's organization membership before or after an action.
+type MembershipState struct {
+	Status string   `json:"status" dynamodbav:"status"` // member, invited, absent
+	Role   *OrgRole `json:"role,omitempty" dynamodbav:"role,omitempty"`
+}
+
+// JournalEntry is an append-only record of one executed sync action.
+type JournalEntry struct {
+	RunID        string          `json:"run_id" dynamodbav:"run_id"`
+	Sequence     int             `json:"sequence" dynamodbav:"sequence"` // Position within the run
+	Org          string          `json:"org" dynamodbav:"org"`
+	Actor        string          `json:"actor" dynamodbav:"actor"` // Identity of the token/app that made the change
+	Action       ActionType      `json:"action" dynamodbav:"action"`
+	Email        string          `json:"email" dynamodbav:"email"`
+	Username     string          `json:"username,omitempty" dynamodbav:"username,omitempty"`
+	InvitationID *int64          `json:"invitation_id,omitempty" dynamodbav:"invitation_id,omitempty"`
+	Before       MembershipState `json:"before" dynamodbav:"before"`
+	After        MembershipState `json:"after" dynamodbav:"after"`
+	Outcome      JournalOutcome  `json:"outcome" dynamodbav:"outcome"`
+	HTTPStatus   int             `json:"http_status,omitempty" dynamodbav:"http_status,omitempty"` // Status of the API response
+	Error        string          `json:"error,omitempty" dynamodbav:"error,omitempty"`
+	Reason       string          `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	Timestamp    time.Time       `json:"timestamp" dynamodbav:"timestamp"`
+}
+
+// JournalFilter selects journal entries. Zero values match everything.
+type JournalFilter struct {
+	Org    string
+	User   string // Email or GitHub username (case-insensitive)
+	Action ActionType
+	Since  time.Time // Inclusive
+	Until  time.Time // Exclusive
+	Limit  int
+}
+
+// Matches reports whether the entry satisfies the filter.
+func (f JournalFilter) Matches(e JournalEntry) bool {
+	if f.Org != "" && !strings.EqualFold(f.Org, e.Org) {
+		return false
+	}
+	if f.User != "" && !strings.EqualFold(f.User, e.Email) && !strings.EqualFold(f.User, e.Username) {
+		return false
+	}
+	if f.Action != "" && f.Action != e.Action {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Timestamp.Before(f.Until) {
+		return false
+	}
+	return true
+}
@@ -22,14 +22,29 @@ type LambdaResponse struct {
 	StatusCode int         `json:"status_code"`
 	Message    string      `json:"message"`
 	Result     *SyncResult `json:"result,omitempty"`
+	Skipped    bool        `json:"skipped,omitempty"` // The run did not start (e.g. another invocation holds the run lock)
 }
 
-// NewSuccessResponse creates a success response.
+// NewSkippedResponse creates a response for a run that exited without syncing.
+// It is not an error: the overlapping run is doing the work.
+func NewSkippedResponse(reason string) *LambdaResponse {
+	return &LambdaResponse{
+		StatusCode: 200,
+		Message:    "skipped: " + reason,
+		Skipped:    true,
+	}
+}
+
+// NewSuccessResponse creates a success response. A result whose report was
+// archived is compacted: the report holds the full lists.
 func NewSuccessResponse(result *SyncResult) *LambdaResponse {
 	msg := fmt.Sprintf("Sync completed: %d actions", result.Summary.ActionsPlanned)
 	if result.DryRun {
 		msg = "[DRY RUN] " + msg
 	}
+	if len(result.Report) > 0 {
+		result = result.Compact()
+	}
 	return &LambdaResponse{
 		StatusCode: 200,
 		Message:    msg,
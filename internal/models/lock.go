@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RunLease is a time-limited lock on a sync run, held by one owner (a Lambda
+// invocation or CLI process) until it is released or expires.
+type RunLease struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Run lock errors.
+var (
+	// ErrRunLocked means another owner holds an unexpired lease. Returned wrapped in a *LockedError.
+	ErrRunLocked = errors.New("sync run locked")
+	// ErrLeaseLost means a lease could not be renewed because it expired and was taken over.
+	ErrLeaseLost = errors.New("run lock lease lost")
+)
+
+// LockedError reports the lease that kept a sync run from starting.
+type LockedError struct {
+	Holder RunLease
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%v: held by %s until %s", ErrRunLocked, e.Holder.Owner, e.Holder.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrRunLocked
+}
+
+// SyncLockName is the run lock name for an organization: one sync per org at a time.
+func SyncLockName(org string) string {
+	return "sync#" + org
+}
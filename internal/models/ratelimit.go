@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RateLimitUsage reports API budget consumption for one rate-limit resource
+// (e.g. GitHub "core", "search", "graphql", "audit_log").
+type RateLimitUsage struct {
+	Limit     int       `json:"limit,omitempty"`
+	Remaining int       `json:"remaining,omitempty"`
+	Used      int       `json:"used,omitempty"`
+	Reset     time.Time `json:"reset,omitempty"`
+	Requests  int       `json:"requests"`           // Requests sent during this run
+	Deferred  int       `json:"deferred,omitempty"` // Low-priority requests put off to a later run
+	WaitedMs  int64     `json:"waited_ms,omitempty"`
+}
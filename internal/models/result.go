@@ -7,19 +7,60 @@ import (
 
 // SyncResult contains the outcome of a sync operation.
 type SyncResult struct {
-	DryRun              bool            `json:"dry_run"`
-	StartTime           time.Time       `json:"start_time"`
-	EndTime             time.Time       `json:"end_time"`
-	DurationMs          int64           `json:"duration_ms"`
-	Actions             []SyncAction    `json:"actions"`
-	Summary             SyncSummary     `json:"summary"`
-	Errors              []string          `json:"errors,omitempty"`
-	InvitedUsers        []string          `json:"invited_users,omitempty"`
-	AlreadyInOrgUsers   []string          `json:"already_in_org_users,omitempty"`
-	OrphanedGitHubUsers []string          `json:"orphaned_github_users,omitempty"`
-	Reconciliation      *ReconcileResult  `json:"reconciliation,omitempty"`
+	RunID               string                     `json:"run_id"`
+	TraceID             string                     `json:"trace_id,omitempty"` // OpenTelemetry trace of the run, when traced
+	DryRun              bool                       `json:"dry_run"`
+	StartTime           time.Time                  `json:"start_time"`
+	EndTime             time.Time                  `json:"end_time"`
+	DurationMs          int64                      `json:"duration_ms"`
+	Actions             []SyncAction               `json:"actions"`
+	Summary             SyncSummary                `json:"summary"`
+	Errors              []string                   `json:"errors,omitempty"`
+	InvitedUsers        []string                   `json:"invited_users,omitempty"`
+	AlreadyInOrgUsers   []string                   `json:"already_in_org_users,omitempty"`
+	OrphanedGitHubUsers []string                   `json:"orphaned_github_users,omitempty"`
+	ExternalMembers     []string                   `json:"external_members,omitempty"` // Group members outside the customer's domains, synced without a status check
+	Reconciliation      *ReconcileResult           `json:"reconciliation,omitempty"`
+	RateLimits          map[string]RateLimitUsage  `json:"rate_limits,omitempty"`
+	StoreQueries        map[string]StoreQueryStats `json:"store_queries,omitempty"`
+	PhaseDurationsMs    map[string]int64           `json:"phase_durations_ms,omitempty"`
+	APICalls            map[string]int             `json:"api_calls,omitempty"` // Requests sent per endpoint, e.g. "GET /orgs/*/members"
+	Groups              []GroupInput               `json:"groups,omitempty"`    // Google groups read
+	Scope               []string                   `json:"scope,omitempty"`     // Users the run was limited to; empty for a full run
+	ScopeGroups         []string                   `json:"scope_groups,omitempty"`
+	Report              map[string]string          `json:"report,omitempty"` // Archived report location per format
 }
 
+// GroupInput describes a Google group read by a run.
+type GroupInput struct {
+	Email   string  `json:"email"`
+	Role    OrgRole `json:"role"` // Organization role its members get
+	Members int     `json:"members"`
+	Active  int     `json:"active"` // Members that are active users
+}
+
+// Compact returns a copy of r without its per-user and per-endpoint lists, for
+// responses that link to an archived report holding them.
+func (r *SyncResult) Compact() *SyncResult {
+	c := *r
+	c.Actions = nil
+	c.InvitedUsers = nil
+	c.AlreadyInOrgUsers = nil
+	c.OrphanedGitHubUsers = nil
+	c.ExternalMembers = nil
+	c.APICalls = nil
+	return &c
+}
+
+// Sync phases, in order, as reported in SyncResult.PhaseDurationsMs.
+const (
+	PhaseGoogleLoad = "google_load" // Group members and user status
+	PhaseGitHubLoad = "github_load" // Members, invitations, store mappings and verified emails
+	PhaseDiff       = "diff"
+	PhaseExecute    = "execute" // Actions and journal
+	PhaseReconcile  = "reconcile"
+)
+
 // SyncSummary provides aggregate statistics.
 type SyncSummary struct {
 	TotalGoogleMembers int `json:"total_google_members"`
@@ -0,0 +1,11 @@
+package models
+
+// StoreQueryStats reports the paginated queries one store operation issued
+// during a run (e.g. "pending_invitations", "resolved_mappings").
+type StoreQueryStats struct {
+	Queries   int     `json:"queries"`              // Calls to the operation
+	Pages     int     `json:"pages"`                // Result pages read across all calls
+	MaxPages  int     `json:"max_pages"`            // Most pages read by a single call
+	Items     int     `json:"items"`                // Items returned before client-side filtering
+	ReadUnits float64 `json:"read_units,omitempty"` // Consumed read capacity
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// invitationTransitions is the invitation lifecycle: the statuses each status may
+// move to. Failed, expired, cancelled and removed are final; a user who comes back
+// gets a new invitation and so a new mapping.
+var invitationTransitions = map[InvitationStatus][]InvitationStatus{
+	InvitationPending:  {InvitationResolved, InvitationFailed, InvitationExpired, InvitationCancelled},
+	InvitationResolved: {InvitationRemoved},
+}
+
+// CanTransitionTo reports whether a mapping in status s may move to next.
+func (s InvitationStatus) CanTransitionTo(next InvitationStatus) bool {
+	for _, to := range invitationTransitions[s] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionSources returns the statuses a mapping may be in to move to status.
+// Stores use it to build the condition of a status update.
+func TransitionSources(status InvitationStatus) []InvitationStatus {
+	var sources []InvitationStatus
+	for _, from := range []InvitationStatus{InvitationPending, InvitationResolved, InvitationFailed,
+		InvitationExpired, InvitationCancelled, InvitationRemoved} {
+		if from.CanTransitionTo(status) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// Reasons a conditional mapping write is rejected. Stores return them wrapped in a *TransitionError.
+var (
+	ErrInvalidTransition = errors.New("invalid invitation status transition")
+	ErrVersionConflict   = errors.New("invitation mapping modified concurrently")
+	ErrMappingNotFound   = errors.New("invitation mapping not found")
+	ErrMappingExists     = errors.New("invitation mapping already exists")
+)
+
+// TransitionError describes a conditional mapping write the store rejected.
+type TransitionError struct {
+	SK   string
+	From InvitationStatus // Current status; empty when the mapping does not exist
+	To   InvitationStatus // Requested status; empty for role updates
+	Err  error            // ErrInvalidTransition, ErrVersionConflict, ErrMappingNotFound or ErrMappingExists
+}
+
+func (e *TransitionError) Error() string {
+	switch e.Err {
+	case ErrMappingNotFound:
+		return fmt.Sprintf("mapping %s: %v", e.SK, e.Err)
+	case ErrInvalidTransition:
+		if e.From == "" {
+			return fmt.Sprintf("mapping %s: %v to %s", e.SK, e.Err, e.To)
+		}
+		return fmt.Sprintf("mapping %s: %v %s → %s", e.SK, e.Err, e.From, e.To)
+	default:
+		return fmt.Sprintf("mapping %s (%s): %v", e.SK, e.From, e.Err)
+	}
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// RejectedTransition explains why a conditional write of mapping sk to status to
+// failed, given the mapping as it is now (nil if it no longer exists). A status
+// that may still move to to means the mapping changed since it was read.
+func RejectedTransition(sk string, current *InvitationMapping, to InvitationStatus) *TransitionError {
+	if current == nil {
+		return &TransitionError{SK: sk, To: to, Err: ErrMappingNotFound}
+	}
+	if to != "" && !current.Status.CanTransitionTo(to) {
+		return &TransitionError{SK: sk, From: current.Status, To: to, Err: ErrInvalidTransition}
+	}
+	return &TransitionError{SK: sk, From: current.Status, To: to, Err: ErrVersionConflict}
+}
+
+// ExistingMapping reports that mapping could not be created because current,
+// which may be nil if it was deleted since, has the same key.
+func ExistingMapping(mapping InvitationMapping, current *InvitationMapping) *TransitionError {
+	err := &TransitionError{SK: mapping.SK, To: mapping.Status, Err: ErrMappingExists}
+	if current != nil {
+		err.From = current.Status
+	}
+	return err
+}
+
+// IsRejectedTransition reports whether err is a conditional write the store rejected.
+func IsRejectedTransition(err error) bool {
+	var transitionErr *TransitionError
+	return errors.As(err, &transitionErr)
+}
@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Alert states.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is a rule that started or stopped firing, or that is still firing when it
+// is re-sent.
+type Alert struct {
+	Rule        string    `json:"rule"`
+	Org         string    `json:"org"`
+	Status      string    `json:"status"`  // firing or resolved
+	Message     string    `json:"message"` // Why the rule fired, or that it no longer does
+	FiringSince time.Time `json:"firing_since"`
+	RunID       string    `json:"run_id,omitempty"` // The run that evaluated the rule
+	TraceID     string    `json:"trace_id,omitempty"`
+}
+
+// Title is a one-line description of the alert.
+func (a Alert) Title() string {
+	if a.Status == AlertResolved {
+		return fmt.Sprintf("✅ Resolved: %s for %s", a.Rule, a.Org)
+	}
+	return fmt.Sprintf("🚨 Alert: %s for %s: %s", a.Rule, a.Org, a.Message)
+}
+
+// AlertSink delivers alerts.
+type AlertSink interface {
+	SendAlert(ctx context.Context, a Alert) error
+}
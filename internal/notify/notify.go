@@ -0,0 +1,244 @@
+// Package notify posts sync run summaries to Slack and generic JSON webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// Run describes a finished sync run to notify about.
+type Run struct {
+	Org    string
+	DryRun bool
+	Result *models.SyncResult // nil when the run failed before producing a result
+	Err    error
+}
+
+// Run statuses.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Summary is what a sink reports about a run. In a dry run the user lists hold
+// the changes the run would have made.
+type Summary struct {
+	Org         string             `json:"org"`
+	DryRun      bool               `json:"dry_run"`
+	Status      string             `json:"status"` // success or failure
+	RunID       string             `json:"run_id,omitempty"`
+	TraceID     string             `json:"trace_id,omitempty"`
+	DurationMs  int64              `json:"duration_ms,omitempty"`
+	Counts      models.SyncSummary `json:"counts"`
+	Invited     []string           `json:"invited,omitempty"`
+	Removed     []string           `json:"removed,omitempty"`
+	RoleChanges []RoleChange       `json:"role_changes,omitempty"`
+	Cancelled   []string           `json:"cancelled_invites,omitempty"`
+	Failures    []Failure          `json:"failures,omitempty"`
+	Orphaned    []string           `json:"orphaned,omitempty"` // GitHub members with no Google account
+	Errors      []string           `json:"errors,omitempty"`   // Run errors
+}
+
+// RoleChange is a member whose organization role was changed.
+type RoleChange struct {
+	User string         `json:"user"`
+	Role models.OrgRole `json:"role"`
+}
+
+// Failure is an action that failed.
+type Failure struct {
+	Action     models.ActionType `json:"action"`
+	User       string            `json:"user"`
+	Error      string            `json:"error"`
+	HTTPStatus int               `json:"http_status,omitempty"`
+}
+
+// NewSummary summarizes run.
+func NewSummary(run Run) Summary {
+	s := Summary{Org: run.Org, DryRun: run.DryRun, Status: StatusSuccess}
+	if run.Err != nil {
+		s.Errors = append(s.Errors, run.Err.Error())
+	}
+	result := run.Result
+	if result == nil {
+		s.Status = StatusFailure
+		return s
+	}
+	if run.Err != nil || !result.IsSuccess() {
+		s.Status = StatusFailure
+	}
+	s.RunID = result.RunID
+	s.TraceID = result.TraceID
+	s.DurationMs = result.DurationMs
+	s.Counts = result.Summary
+	s.Orphaned = result.OrphanedGitHubUsers
+	s.Errors = append(append([]string(nil), result.Errors...), s.Errors...)
+
+	for _, action := range result.Actions {
+		user := action.Email
+		if action.Username != "" {
+			user = action.Username
+		}
+		if action.Error != nil {
+			s.Failures = append(s.Failures, Failure{Action: action.Type, User: user, Error: *action.Error, HTTPStatus: action.HTTPStatus})
+			continue
+		}
+		if !action.Executed && !run.DryRun {
+			continue
+		}
+		switch action.Type {
+		case models.ActionInvite:
+			if !action.AlreadyInOrg {
+				s.Invited = append(s.Invited, action.Email)
+			}
+		case models.ActionRemove:
+			s.Removed = append(s.Removed, user)
+		case models.ActionUpdateRole:
+			if action.TargetRole != nil {
+				s.RoleChanges = append(s.RoleChanges, RoleChange{User: user, Role: *action.TargetRole})
+			}
+		case models.ActionCancelInvite:
+			s.Cancelled = append(s.Cancelled, action.Email)
+		}
+	}
+	if len(s.Failures) > 0 {
+		s.Status = StatusFailure
+	}
+	return s
+}
+
+// Failed reports whether the run failed or any of its actions did.
+func (s Summary) Failed() bool {
+	return s.Status == StatusFailure
+}
+
+// Changed reports whether the run changed membership, or in a dry run would have.
+func (s Summary) Changed() bool {
+	return len(s.Invited)+len(s.Removed)+len(s.RoleChanges)+len(s.Cancelled) > 0
+}
+
+// Title is a one-line description of the run.
+func (s Summary) Title() string {
+	var b strings.Builder
+	switch {
+	case s.Failed():
+		b.WriteString("❌ ")
+	case s.DryRun:
+		b.WriteString("🧪 ")
+	default:
+		b.WriteString("✅ ")
+	}
+	if s.DryRun {
+		b.WriteString("[DRY RUN] ")
+	}
+	fmt.Fprintf(&b, "GitHub sync for %s", s.Org)
+	if s.Failed() {
+		b.WriteString(" failed")
+	}
+	verb := ""
+	if s.DryRun {
+		verb = "would have "
+	}
+	fmt.Fprintf(&b, ": %s%d invited, %d removed, %d role changes", verb, len(s.Invited), len(s.Removed), len(s.RoleChanges))
+	if len(s.Failures) > 0 {
+		fmt.Fprintf(&b, ", %d failed", len(s.Failures))
+	}
+	return b.String()
+}
+
+// ShouldNotify reports whether trigger fires for a run with summary s.
+func ShouldNotify(trigger string, s Summary) bool {
+	switch trigger {
+	case config.NotifyAlways:
+		return true
+	case config.NotifyOnChange:
+		return s.Changed() || s.Failed()
+	case config.NotifyOnFailure:
+		return s.Failed()
+	default:
+		return false
+	}
+}
+
+// Sink delivers run summaries.
+type Sink interface {
+	Send(ctx context.Context, s Summary) error
+}
+
+type route struct {
+	name    string
+	sink    Sink
+	trigger string
+}
+
+// Notifier sends run summaries to every sink whose trigger fires.
+type Notifier struct {
+	routes []route
+}
+
+// New creates a notifier without sinks.
+func New() *Notifier {
+	return &Notifier{}
+}
+
+// Add registers sink under name, notified for runs matching trigger.
+func (n *Notifier) Add(name string, sink Sink, trigger string) {
+	n.routes = append(n.routes, route{name: name, sink: sink, trigger: trigger})
+}
+
+// Len returns the number of sinks.
+func (n *Notifier) Len() int {
+	return len(n.routes)
+}
+
+// Notify summarizes run and sends it to the sinks whose trigger fires. Every
+// such sink is tried; their errors are joined.
+func (n *Notifier) Notify(ctx context.Context, run Run) error {
+	s := NewSummary(run)
+	var errs []error
+	for _, r := range n.routes {
+		if !ShouldNotify(r.trigger, s) {
+			continue
+		}
+		if err := r.sink.Send(ctx, s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// httpClient sends every notification.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON sends payload to url and fails on any non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("posting notification: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func testResult() *models.SyncResult {
+	member, admin := models.RoleMember, models.RoleOwner
+	failure := "boom"
+	return &models.SyncResult{
+		RunID: "run-1",
+		Actions: []models.SyncAction{
+			{Type: models.ActionInvite, Email: "new@example.com", TargetRole: &member, Executed: true},
+			{Type: models.ActionInvite, Email: "known@example.com", TargetRole: &member, AlreadyInOrg: true, Executed: true},
+			{Type: models.ActionRemove, Email: "gone@example.com", Username: "gone", Executed: true},
+			{Type: models.ActionUpdateRole, Email: "lead@example.com", TargetRole: &admin, Executed: true},
+			{Type: models.ActionInvite, Email: "broken@example.com", TargetRole: &member, Error: &failure, HTTPStatus: 422},
+			{Type: models.ActionSkip, Email: "suspended@example.com"},
+		},
+		OrphanedGitHubUsers: []string{"stray"},
+	}
+}
+
+func TestNewSummary(t *testing.T) {
+	s := NewSummary(Run{Org: "example-org", Result: testResult()})
+
+	if s.Status != StatusFailure {
+		t.Fatalf("expected a failed action to fail the run, got %s", s.Status)
+	}
+	if len(s.Invited) != 1 || s.Invited[0] != "new@example.com" {
+		t.Fatalf("expected only the new member as invited, got %v", s.Invited)
+	}
+	if len(s.Removed) != 1 || s.Removed[0] != "gone" {
+		t.Fatalf("expected removals by username, got %v", s.Removed)
+	}
+	if len(s.RoleChanges) != 1 || s.RoleChanges[0].Role != models.RoleOwner {
+		t.Fatalf("expected one role change, got %v", s.RoleChanges)
+	}
+	if len(s.Failures) != 1 || s.Failures[0].User != "broken@example.com" || s.Failures[0].HTTPStatus != 422 {
+		t.Fatalf("expected one failure, got %v", s.Failures)
+	}
+	if len(s.Orphaned) != 1 {
+		t.Fatalf("expected orphans, got %v", s.Orphaned)
+	}
+	if !strings.Contains(s.Title(), "failed") {
+		t.Fatalf("expected the title to report the failure, got %q", s.Title())
+	}
+}
+
+func TestNewSummaryDryRun(t *testing.T) {
+	result := testResult()
+	for i := range result.Actions {
+		result.Actions[i].Executed = false
+		result.Actions[i].Error = nil
+	}
+	s := NewSummary(Run{Org: "example-org", DryRun: true, Result: result})
+
+	if len(s.Invited) != 2 || len(s.Removed) != 1 {
+		t.Fatalf("expected planned changes to be listed in a dry run, got %v and %v", s.Invited, s.Removed)
+	}
+	if title := s.Title(); !strings.Contains(title, "[DRY RUN]") || !strings.Contains(title, "would have") {
+		t.Fatalf("expected a dry-run title, got %q", title)
+	}
+}
+
+func TestNewSummaryWithoutResult(t *testing.T) {
+	s := NewSummary(Run{Org: "example-org", Err: errors.New("google unavailable")})
+	if !s.Failed() || len(s.Errors) != 1 {
+		t.Fatalf("expected a failed run carrying its error, got %+v", s)
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	quiet := Summary{Status: StatusSuccess}
+	changed := Summary{Status: StatusSuccess, Invited: []string{"new@example.com"}}
+	failed := Summary{Status: StatusFailure}
+
+	cases := []struct {
+		trigger string
+		summary Summary
+		want    bool
+	}{
+		{config.NotifyAlways, quiet, true},
+		{config.NotifyOnChange, quiet, false},
+		{config.NotifyOnChange, changed, true},
+		{config.NotifyOnChange, failed, true},
+		{config.NotifyOnFailure, changed, false},
+		{config.NotifyOnFailure, failed, true},
+	}
+	for _, tc := range cases {
+		if got := ShouldNotify(tc.trigger, tc.summary); got != tc.want {
+			t.Errorf("ShouldNotify(%s, %+v) = %v, want %v", tc.trigger, tc.summary, got, tc.want)
+		}
+	}
+}
+
+type fakeSink struct {
+	sent []Summary
+	err  error
+}
+
+func (f *fakeSink) Send(ctx context.Context, s Summary) error {
+	f.sent = append(f.sent, s)
+	return f.err
+}
+
+func TestNotifierRoutesByTrigger(t *testing.T) {
+	always, onFailure := &fakeSink{err: errors.New("unreachable")}, &fakeSink{}
+	n := New()
+	n.Add("webhook", always, config.NotifyAlways)
+	n.Add("slack", onFailure, config.NotifyOnFailure)
+
+	err := n.Notify(context.Background(), Run{Org: "example-org", Result: &models.SyncResult{}})
+	if err == nil || !strings.Contains(err.Error(), "webhook: unreachable") {
+		t.Fatalf("expected the failing sink's error, got %v", err)
+	}
+	if len(always.sent) != 1 || len(onFailure.sent) != 0 {
+		t.Fatalf("expected only the always sink to be notified of a successful run, got %d and %d", len(always.sent), len(onFailure.sent))
+	}
+}
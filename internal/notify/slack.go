@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxListed caps each user list in a Slack message; the rest are counted.
+const maxListed = 10
+
+// Slack posts run summaries to a Slack incoming webhook.
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack creates a Slack incoming-webhook sink.
+func NewSlack(webhookURL string) *Slack {
+	return &Slack{url: webhookURL, client: httpClient}
+}
+
+type slackPayload struct {
+	Text   string       `json:"text"` // Notification fallback
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func mrkdwn(text string) slackText {
+	return slackText{Type: "mrkdwn", Text: text}
+}
+
+// Send posts s as a Block Kit message.
+func (sl *Slack) Send(ctx context.Context, s Summary) error {
+	return postJSON(ctx, sl.client, sl.url, slackMessage(s))
+}
+
+func slackMessage(s Summary) slackPayload {
+	title := slackEscape(s.Title())
+	blocks := []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + title + "*"}}}
+	if s.DryRun {
+		blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{mrkdwn("Dry run: nothing was changed in GitHub.")}})
+	}
+	blocks = append(blocks, slackBlock{Type: "section", Fields: []slackText{
+		mrkdwn(fmt.Sprintf("*Invited*\n%d", len(s.Invited))),
+		mrkdwn(fmt.Sprintf("*Removed*\n%d", len(s.Removed))),
+		mrkdwn(fmt.Sprintf("*Role changes*\n%d", len(s.RoleChanges))),
+		mrkdwn(fmt.Sprintf("*Failed*\n%d", len(s.Failures))),
+		mrkdwn(fmt.Sprintf("*Orphaned GitHub members*\n%d", len(s.Orphaned))),
+		mrkdwn(fmt.Sprintf("*Pending invitations*\n%d", s.Counts.PendingInvitations)),
+	}})
+
+	roles := make([]string, len(s.RoleChanges))
+	for i, c := range s.RoleChanges {
+		roles[i] = fmt.Sprintf("%s → %s", c.User, c.Role)
+	}
+	failures := make([]string, len(s.Failures))
+	for i, f := range s.Failures {
+		failures[i] = fmt.Sprintf("%s %s: %s", f.Action, f.User, f.Error)
+		if f.HTTPStatus != 0 {
+			failures[i] += fmt.Sprintf(" (HTTP %d)", f.HTTPStatus)
+		}
+	}
+	for _, list := range []struct {
+		heading string
+		items   []string
+	}{
+		{"Failures", failures},
+		{"Errors", s.Errors},
+		{"Invited", s.Invited},
+		{"Removed", s.Removed},
+		{"Role changes", roles},
+		{"Cancelled invitations", s.Cancelled},
+		{"Orphaned GitHub members", s.Orphaned},
+	} {
+		if len(list.items) > 0 {
+			blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: slackList(list.heading, list.items)}})
+		}
+	}
+
+	if s.RunID != "" {
+		ref := "Run " + s.RunID
+		if s.TraceID != "" {
+			ref += " · trace " + s.TraceID
+		}
+		blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{mrkdwn(ref)}})
+	}
+	return slackPayload{Text: title, Blocks: blocks}
+}
+
+// SendAlert posts a as a Block Kit message.
+func (sl *Slack) SendAlert(ctx context.Context, a Alert) error {
+	return postJSON(ctx, sl.client, sl.url, slackAlertMessage(a))
+}
+
+func slackAlertMessage(a Alert) slackPayload {
+	title := slackEscape(a.Title())
+	blocks := []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + title + "*"}}}
+	if a.Status == AlertResolved && a.Message != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: slackEscape(a.Message)}})
+	}
+	ref := "Firing since " + a.FiringSince.UTC().Format(time.RFC3339)
+	if a.RunID != "" {
+		ref += " · run " + a.RunID
+	}
+	if a.TraceID != "" {
+		ref += " · trace " + a.TraceID
+	}
+	blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{mrkdwn(ref)}})
+	return slackPayload{Text: title, Blocks: blocks}
+}
+
+// slackList renders up to maxListed items under heading.
+func slackList(heading string, items []string) string {
+	var b strings.Builder
+	b.WriteString("*" + heading + "*")
+	for i, item := range items {
+		if i == maxListed {
+			fmt.Fprintf(&b, "\n…and %d more", len(items)-maxListed)
+			break
+		}
+		b.WriteString("\n• " + slackEscape(item))
+	}
+	return b.String()
+}
+
+// slackEscape escapes the characters Slack treats as markup.
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
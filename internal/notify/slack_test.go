@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlackSend(t *testing.T) {
+	var got slackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	s := Summary{Org: "example-org", DryRun: true, Status: StatusSuccess, RunID: "run-1"}
+	for i := 0; i < 12; i++ {
+		s.Invited = append(s.Invited, fmt.Sprintf("user%d@example.com", i))
+	}
+	if err := NewSlack(server.URL).Send(context.Background(), s); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if !strings.Contains(got.Text, "[DRY RUN]") {
+		t.Fatalf("expected a dry-run label, got %q", got.Text)
+	}
+	var text strings.Builder
+	for _, block := range got.Blocks {
+		if block.Text != nil {
+			text.WriteString(block.Text.Text + "\n")
+		}
+		for _, e := range block.Elements {
+			text.WriteString(e.Text + "\n")
+		}
+	}
+	for _, want := range []string{"nothing was changed", "user9@example.com", "…and 2 more", "Run run-1"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected the message to contain %q, got:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "user10@example.com") {
+		t.Errorf("expected the invited list to be capped")
+	}
+}
+
+func TestSlackEscape(t *testing.T) {
+	if got := slackEscape("<a & b>"); got != "&lt;a &amp; b&gt;" {
+		t.Fatalf("unexpected escape: %q", got)
+	}
+}
+
+func TestSlackSendAlert(t *testing.T) {
+	var got slackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	a := Alert{Rule: "reconcile_errors", Org: "example-org", Status: AlertResolved, Message: "Reconciliation has no errors"}
+	if err := NewSlack(server.URL).SendAlert(context.Background(), a); err != nil {
+		t.Fatalf("SendAlert: %v", err)
+	}
+	if !strings.HasPrefix(got.Text, "✅ Resolved: reconcile_errors for example-org") {
+		t.Fatalf("expected a resolved title, got %q", got.Text)
+	}
+	if len(got.Blocks) != 3 || got.Blocks[1].Text == nil || got.Blocks[1].Text.Text != "Reconciliation has no errors" {
+		t.Fatalf("expected title, message and context blocks, got %+v", got.Blocks)
+	}
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+)
+
+// Webhook posts run summaries as JSON to a URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a JSON webhook sink.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: httpClient}
+}
+
+// webhookPayload is the JSON body: the Summary fields plus an event name and title.
+type webhookPayload struct {
+	Event string `json:"event"` // Always "sync.run"
+	Title string `json:"title"`
+	Summary
+}
+
+// Send posts s.
+func (w *Webhook) Send(ctx context.Context, s Summary) error {
+	return postJSON(ctx, w.client, w.url, webhookPayload{Event: "sync.run", Title: s.Title(), Summary: s})
+}
+
+// webhookAlertPayload is the JSON body of an alert: the Alert fields plus an event name and title.
+type webhookAlertPayload struct {
+	Event string `json:"event"` // Always "sync.alert"
+	Title string `json:"title"`
+	Alert
+}
+
+// SendAlert posts a.
+func (w *Webhook) SendAlert(ctx context.Context, a Alert) error {
+	return postJSON(ctx, w.client, w.url, webhookAlertPayload{Event: "sync.alert", Title: a.Title(), Alert: a})
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookSend(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected a JSON body, got %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	s := Summary{Org: "example-org", Status: StatusSuccess, Invited: []string{"new@example.com"}}
+	if err := NewWebhook(server.URL).Send(context.Background(), s); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got["event"] != "sync.run" || got["org"] != "example-org" || got["dry_run"] != false || got["status"] != "success" {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+	if invited, _ := got["invited"].([]any); len(invited) != 1 {
+		t.Fatalf("expected the invited list, got %v", got["invited"])
+	}
+}
+
+func TestWebhookSendRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL).Send(context.Background(), Summary{Org: "example-org"})
+	if err == nil || !strings.Contains(err.Error(), "status 404: no such hook") {
+		t.Fatalf("expected the rejection, got %v", err)
+	}
+}
+
+func TestWebhookSendAlert(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	a := Alert{Rule: "failed_actions", Org: "example-org", Status: AlertFiring, Message: "3 actions failed (threshold 0)", RunID: "run-1"}
+	if err := NewWebhook(server.URL).SendAlert(context.Background(), a); err != nil {
+		t.Fatalf("SendAlert: %v", err)
+	}
+	if got["event"] != "sync.alert" || got["rule"] != "failed_actions" || got["status"] != "firing" || got["run_id"] != "run-1" {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+	if title, _ := got["title"].(string); !strings.Contains(title, "3 actions failed") {
+		t.Fatalf("expected the message in the title, got %q", title)
+	}
+}
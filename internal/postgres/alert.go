@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// GetAlertState returns the org's alert state, or nil if none was saved.
+func (s *Store) GetAlertState(ctx context.Context, org string) (*models.AlertState, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT state FROM alert_states WHERE org = $1`, org).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting alert state: %w", err)
+	}
+	var state models.AlertState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding alert state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveAlertState replaces the org's alert state.
+func (s *Store) SaveAlertState(ctx context.Context, state models.AlertState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding alert state: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO alert_states (org, state, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (org) DO UPDATE SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at`,
+		state.Org, string(data), time.Now().UTC()); err != nil {
+		return fmt.Errorf("saving alert state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// AcquireLease takes the named lease with an upsert that only overwrites an
+// expired lease or one owner already holds.
+func (s *Store) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error) {
+	now := time.Now().UTC()
+	expires := now.Add(ttl)
+	result, err := s.db.ExecContext(ctx, `INSERT INTO run_leases (name, owner, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+		WHERE run_leases.expires_at < $4 OR run_leases.owner = EXCLUDED.owner`,
+		name, owner, expires, now)
+	if err != nil {
+		return nil, false, fmt.Errorf("acquiring lease: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, false, fmt.Errorf("acquiring lease: %w", err)
+	} else if n > 0 {
+		return &models.RunLease{Name: name, Owner: owner, ExpiresAt: expires}, true, nil
+	}
+
+	holder := models.RunLease{Name: name}
+	err = s.db.QueryRowContext(ctx, `SELECT owner, expires_at FROM run_leases WHERE name = $1`, name).Scan(&holder.Owner, &holder.ExpiresAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("getting lease: %w", err)
+	}
+	holder.ExpiresAt = holder.ExpiresAt.UTC()
+	return &holder, false, nil
+}
+
+// RenewLease extends the lease if owner still holds it.
+func (s *Store) RenewLease(ctx context.Context, name string, owner string, ttl time.Duration) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE run_leases SET expires_at = $1 WHERE name = $2 AND owner = $3`,
+		time.Now().UTC().Add(ttl), name, owner)
+	if err != nil {
+		return fmt.Errorf("renewing lease: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("renewing lease: %w", err)
+	} else if n == 0 {
+		return models.ErrLeaseLost
+	}
+	return nil
+}
+
+// ReleaseLease deletes the lease if owner still holds it.
+func (s *Store) ReleaseLease(ctx context.Context, name string, owner string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM run_leases WHERE name = $1 AND owner = $2`, name, owner); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+	return nil
+}
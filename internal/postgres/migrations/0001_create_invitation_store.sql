@@ -0,0 +1,28 @@
+-- Invitation mappings. sk keeps the INV#<id> / EXISTING#<login> convention
+-- shared with the DynamoDB store.
+CREATE TABLE invitation_mappings (
+    org          TEXT        NOT NULL,
+    sk           TEXT        NOT NULL,
+    email        TEXT        NOT NULL,
+    github_login TEXT,
+    status       TEXT        NOT NULL,
+    role         TEXT        NOT NULL,
+    invited_at   TIMESTAMPTZ NOT NULL,
+    resolved_at  TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ,
+    PRIMARY KEY (org, sk)
+);
+
+-- email-index access pattern: GetByEmail(email, org).
+CREATE INDEX invitation_mappings_email_idx ON invitation_mappings (email, org);
+
+-- status-index access pattern: GetPendingInvitations / GetAllResolvedMappings(org, status).
+CREATE INDEX invitation_mappings_status_idx ON invitation_mappings (org, status);
+
+CREATE TABLE audit_log_cursors (
+    org            TEXT        NOT NULL,
+    name           TEXT        NOT NULL,
+    last_timestamp BIGINT      NOT NULL,
+    last_run       TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (org, name)
+);
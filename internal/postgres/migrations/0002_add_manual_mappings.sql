@@ -0,0 +1,3 @@
+-- Mappings asserted by an operator with `mapping link` (sk MANUAL#<login>).
+-- They take precedence over mappings found by reconciliation.
+ALTER TABLE invitation_mappings ADD COLUMN manual BOOLEAN NOT NULL DEFAULT FALSE;
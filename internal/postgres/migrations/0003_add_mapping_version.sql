@@ -0,0 +1,2 @@
+-- Incremented by every conditional update, so writes based on a stale read are rejected.
+ALTER TABLE invitation_mappings ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
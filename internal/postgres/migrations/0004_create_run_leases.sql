@@ -0,0 +1,6 @@
+-- Distributed run lock: a lease is held by owner until it is released or expires_at passes.
+CREATE TABLE run_leases (
+    name       TEXT        PRIMARY KEY,
+    owner      TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,6 @@
+-- Alerting state per org: rule history and which rules are firing, as JSON.
+CREATE TABLE alert_states (
+    org        TEXT        PRIMARY KEY,
+    state      JSONB       NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,352 @@
+// Package postgres implements InvitationStore on PostgreSQL.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" driver
+)
+
+const mappingColumns = `org, sk, email, github_login, status, role, invited_at, resolved_at, expires_at, manual, version`
+
+// Store implements the InvitationStore interface using PostgreSQL.
+type Store struct {
+	db      *sql.DB
+	ttlDays int
+}
+
+// NewStore connects to Postgres, applies pending schema migrations and purges
+// mappings whose TTL has passed. cfg.DSN must already be resolved (see DSNSecret).
+func NewStore(ctx context.Context, cfg config.PostgresConfig) (*Store, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("postgres DSN is required")
+	}
+	db, err := sql.Open("pgx", cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres connection: %w", err)
+	}
+	db.SetMaxOpenConns(4)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM invitation_mappings WHERE expires_at < now()`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("purging expired mappings: %w", err)
+	}
+
+	ttlDays := cfg.TTLDays
+	if ttlDays <= 0 {
+		ttlDays = 90
+	}
+	return &Store{db: db, ttlDays: ttlDays}, nil
+}
+
+// Close closes the connection pool.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveInvitation stores a mapping, replacing any existing one with the same key.
+func (s *Store) SaveInvitation(ctx context.Context, mapping models.InvitationMapping) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO invitation_mappings (`+mappingColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (org, sk) DO UPDATE SET
+			email = EXCLUDED.email,
+			github_login = EXCLUDED.github_login,
+			status = EXCLUDED.status,
+			role = EXCLUDED.role,
+			invited_at = EXCLUDED.invited_at,
+			resolved_at = EXCLUDED.resolved_at,
+			expires_at = EXCLUDED.expires_at,
+			manual = EXCLUDED.manual,
+			version = EXCLUDED.version`,
+		orgFromPK(mapping.PK), mapping.SK, mapping.Email, mapping.GitHubLogin, string(mapping.Status), string(mapping.Role),
+		mapping.InvitedAt.UTC(), mapping.ResolvedAt, expiresAt(mapping.TTL), mapping.Manual, mapping.Version)
+	if err != nil {
+		return fmt.Errorf("saving invitation: %w", err)
+	}
+	return nil
+}
+
+// CreateMapping stores mapping only if no mapping with its key exists.
+func (s *Store) CreateMapping(ctx context.Context, mapping models.InvitationMapping) error {
+	org := orgFromPK(mapping.PK)
+	result, err := s.db.ExecContext(ctx, `INSERT INTO invitation_mappings (`+mappingColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (org, sk) DO NOTHING`,
+		org, mapping.SK, mapping.Email, mapping.GitHubLogin, string(mapping.Status), string(mapping.Role),
+		mapping.InvitedAt.UTC(), mapping.ResolvedAt, expiresAt(mapping.TTL), mapping.Manual, mapping.Version)
+	if err == nil {
+		var n int64
+		if n, err = result.RowsAffected(); err == nil && n == 0 {
+			current, getErr := scanMapping(s.db.QueryRowContext(ctx, `SELECT `+mappingColumns+` FROM invitation_mappings WHERE org = $1 AND sk = $2`,
+				org, mapping.SK))
+			if errors.Is(getErr, sql.ErrNoRows) {
+				current, getErr = nil, nil
+			}
+			if err = getErr; err == nil {
+				err = models.ExistingMapping(mapping, current)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("creating mapping: %w", err)
+	}
+	return nil
+}
+
+// GetInvitation retrieves an invitation by org and invitation ID.
+func (s *Store) GetInvitation(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+mappingColumns+` FROM invitation_mappings WHERE org = $1 AND sk = $2`,
+		org, invitationSK(invitationID))
+	mapping, err := scanMapping(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting invitation: %w", err)
+	}
+	return mapping, nil
+}
+
+// GetPendingInvitations returns all pending invitations for an org.
+func (s *Store) GetPendingInvitations(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+	mappings, err := s.queryMappings(ctx, `org = $1 AND status = $2`, org, string(models.InvitationPending))
+	if err != nil {
+		return nil, fmt.Errorf("querying pending invitations: %w", err)
+	}
+	return mappings, nil
+}
+
+// ResolveInvitation updates a pending invitation with the resolved GitHub username.
+func (s *Store) ResolveInvitation(ctx context.Context, org string, invitationID int64, githubLogin string) error {
+	sk := invitationSK(invitationID)
+	err := s.transition(ctx, org, sk, models.InvitationResolved, nil, func(tx *sql.Tx) error {
+		now := time.Now().UTC()
+		_, err := tx.ExecContext(ctx, `UPDATE invitation_mappings
+			SET github_login = $1, status = $2, resolved_at = $3, expires_at = $4, version = version + 1
+			WHERE org = $5 AND sk = $6`,
+			githubLogin, string(models.InvitationResolved), now, now.AddDate(0, 0, s.ttlDays),
+			org, sk)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("resolving invitation: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus changes the status of an invitation (failed, expired, cancelled, removed).
+func (s *Store) UpdateStatus(ctx context.Context, org string, invitationID int64, status models.InvitationStatus) error {
+	if err := s.setStatus(ctx, org, invitationSK(invitationID), status, nil); err != nil {
+		return fmt.Errorf("updating invitation status: %w", err)
+	}
+	return nil
+}
+
+// TransitionMapping moves a mapping of any kind to status, provided it still has
+// the status and version it was read with.
+func (s *Store) TransitionMapping(ctx context.Context, mapping models.InvitationMapping, status models.InvitationStatus) error {
+	if !mapping.Status.CanTransitionTo(status) {
+		return &models.TransitionError{SK: mapping.SK, From: mapping.Status, To: status, Err: models.ErrInvalidTransition}
+	}
+	if err := s.setStatus(ctx, orgFromPK(mapping.PK), mapping.SK, status, &mapping); err != nil {
+		return fmt.Errorf("updating mapping status: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) setStatus(ctx context.Context, org string, sk string, status models.InvitationStatus, expected *models.InvitationMapping) error {
+	return s.transition(ctx, org, sk, status, expected, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE invitation_mappings SET status = $1, version = version + 1 WHERE org = $2 AND sk = $3`,
+			string(status), org, sk)
+		return err
+	})
+}
+
+// transition runs update in a transaction holding a row lock on the mapping, so
+// concurrent runs cannot interleave changes. The update is rejected with a
+// *models.TransitionError if the mapping is missing, its status may not move to
+// status (empty for role updates), or it no longer matches expected.
+func (s *Store) transition(ctx context.Context, org string, sk string, status models.InvitationStatus, expected *models.InvitationMapping, update func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := scanMapping(tx.QueryRowContext(ctx, `SELECT `+mappingColumns+` FROM invitation_mappings WHERE org = $1 AND sk = $2 FOR UPDATE`,
+		org, sk))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.RejectedTransition(sk, nil, status)
+	}
+	if err != nil {
+		return err
+	}
+	if status != "" && !current.Status.CanTransitionTo(status) ||
+		expected != nil && (current.Status != expected.Status || current.Version != expected.Version) {
+		return models.RejectedTransition(sk, current, status)
+	}
+	if err := update(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateRole updates the role of an existing invitation mapping.
+func (s *Store) UpdateRole(ctx context.Context, org string, invitationID int64, role models.OrgRole) error {
+	sk := invitationSK(invitationID)
+	err := s.transition(ctx, org, sk, "", nil, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE invitation_mappings SET role = $1, version = version + 1 WHERE org = $2 AND sk = $3`,
+			string(role), org, sk)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("updating invitation role: %w", err)
+	}
+	return nil
+}
+
+// GetByEmail retrieves invitation mappings for a specific email.
+func (s *Store) GetByEmail(ctx context.Context, email string, org string) ([]models.InvitationMapping, error) {
+	mappings, err := s.queryMappings(ctx, `email = $1 AND org = $2`, email, org)
+	if err != nil {
+		return nil, fmt.Errorf("querying by email: %w", err)
+	}
+	return mappings, nil
+}
+
+// GetAuditLogCursor retrieves the last processed audit log cursor.
+func (s *Store) GetAuditLogCursor(ctx context.Context, org string) (*models.AuditLogCursor, error) {
+	cursor := models.AuditLogCursor{PK: "ORG#" + org, SK: "CURSOR#audit_log"}
+	err := s.db.QueryRowContext(ctx, `SELECT last_timestamp, last_run FROM audit_log_cursors WHERE org = $1 AND name = $2`,
+		org, "audit_log").Scan(&cursor.LastTimestamp, &cursor.LastRun)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting audit log cursor: %w", err)
+	}
+	cursor.LastRun = cursor.LastRun.UTC()
+	return &cursor, nil
+}
+
+// SaveAuditLogCursor stores the audit log cursor.
+func (s *Store) SaveAuditLogCursor(ctx context.Context, cursor models.AuditLogCursor) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO audit_log_cursors (org, name, last_timestamp, last_run)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (org, name) DO UPDATE SET last_timestamp = EXCLUDED.last_timestamp, last_run = EXCLUDED.last_run`,
+		orgFromPK(cursor.PK), strings.TrimPrefix(cursor.SK, "CURSOR#"), cursor.LastTimestamp, cursor.LastRun.UTC())
+	if err != nil {
+		return fmt.Errorf("saving cursor: %w", err)
+	}
+	return nil
+}
+
+// GetAllResolvedMappings returns all resolved email→username mappings for an org.
+func (s *Store) GetAllResolvedMappings(ctx context.Context, org string) (map[string]string, error) {
+	mappings, err := s.queryMappings(ctx, `org = $1 AND status = $2`, org, string(models.InvitationResolved))
+	if err != nil {
+		return nil, fmt.Errorf("querying resolved mappings: %w", err)
+	}
+	return models.ResolvedLogins(mappings), nil
+}
+
+// ListMappings returns every invitation mapping for an org, in any status.
+func (s *Store) ListMappings(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+	mappings, err := s.queryMappings(ctx, `org = $1`, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// DeleteMapping removes an invitation mapping by org and sort key.
+func (s *Store) DeleteMapping(ctx context.Context, org string, sk string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM invitation_mappings WHERE org = $1 AND sk = $2`, org, sk); err != nil {
+		return fmt.Errorf("deleting mapping: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) queryMappings(ctx context.Context, where string, args ...any) ([]models.InvitationMapping, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+mappingColumns+` FROM invitation_mappings WHERE `+where+` ORDER BY org, sk`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []models.InvitationMapping
+	for rows.Next() {
+		mapping, err := scanMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, *mapping)
+	}
+	return mappings, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanMapping reads a row into an InvitationMapping, rebuilding the DynamoDB-style
+// key attributes so callers see the same shape from every store.
+func scanMapping(row scanner) (*models.InvitationMapping, error) {
+	var m models.InvitationMapping
+	var org, status, role string
+	var githubLogin sql.NullString
+	var resolvedAt, expires sql.NullTime
+	if err := row.Scan(&org, &m.SK, &m.Email, &githubLogin, &status, &role, &m.InvitedAt, &resolvedAt, &expires, &m.Manual, &m.Version); err != nil {
+		return nil, err
+	}
+	m.PK = "ORG#" + org
+	m.Status = models.InvitationStatus(status)
+	m.Role = models.OrgRole(role)
+	m.InvitedAt = m.InvitedAt.UTC()
+	if githubLogin.Valid {
+		login := githubLogin.String
+		m.GitHubLogin = &login
+	}
+	if resolvedAt.Valid {
+		t := resolvedAt.Time.UTC()
+		m.ResolvedAt = &t
+	}
+	if expires.Valid {
+		m.TTL = expires.Time.Unix()
+	}
+	m.GSI1PK = "EMAIL#" + m.Email
+	m.GSI1SK = "ORG#" + org
+	m.GSI2PK = "ORG#" + org
+	m.GSI2SK = "STATUS#" + status
+	return &m, nil
+}
+
+func orgFromPK(pk string) string {
+	return strings.TrimPrefix(pk, "ORG#")
+}
+
+func invitationSK(invitationID int64) string {
+	return fmt.Sprintf("INV#%d", invitationID)
+}
+
+func expiresAt(ttl int64) *time.Time {
+	if ttl == 0 {
+		return nil
+	}
+	t := time.Unix(ttl, 0).UTC()
+	return &t
+}
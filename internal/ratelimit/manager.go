@@ -0,0 +1,314 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Resource names for API budgets.
+const (
+	ResourceCore     = "core"
+	ResourceSearch   = "search"
+	ResourceGraphQL  = "graphql"
+	ResourceAuditLog = "audit_log"
+	ResourceGoogle   = "google_directory"
+)
+
+// Priority classifies requests for budgeting.
+type Priority int
+
+const (
+	// PriorityNormal requests are always sent (paced if the budget is low).
+	PriorityNormal Priority = iota
+	// PriorityLow requests are deferred to a later run when the budget is low.
+	PriorityLow
+)
+
+// ErrDeferred is returned for low-priority requests skipped to preserve budget.
+var ErrDeferred = errors.New("request deferred: rate-limit budget low")
+
+type priorityKey struct{}
+
+// WithPriority marks requests made with ctx as having the given priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+type bucket struct {
+	limit     int
+	remaining int
+	used      int
+	reset     time.Time
+	known     bool
+	requests  int
+	deferred  int
+	waited    time.Duration
+}
+
+// Manager tracks rate-limit budgets from response headers and paces requests
+// before a limit is hit.
+type Manager struct {
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	calls      map[string]int // requests sent, by Endpoint
+	lowReserve float64        // fraction of the limit kept for normal-priority work
+	maxWait    time.Duration  // longest a single request is held back
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// NewManager creates a budget manager. lowReservePercent is the share of each
+// budget reserved for normal-priority requests; maxWait caps pacing delays.
+func NewManager(lowReservePercent int, maxWait time.Duration) *Manager {
+	return &Manager{
+		buckets:    make(map[string]*bucket),
+		calls:      make(map[string]int),
+		lowReserve: float64(lowReservePercent) / 100,
+		maxWait:    maxWait,
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (m *Manager) bucketLocked(resource string) *bucket {
+	b, ok := m.buckets[resource]
+	if !ok {
+		b = &bucket{}
+		m.buckets[resource] = b
+	}
+	return b
+}
+
+// Allow reports whether a request of the given priority may be sent now.
+// Low-priority requests are refused once the remaining budget drops into the reserve.
+func (m *Manager) Allow(resource string, p Priority) bool {
+	if p != PriorityLow {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := m.bucketLocked(resource)
+	if !b.known || b.limit == 0 || !m.now().Before(b.reset) {
+		return true
+	}
+	if float64(b.remaining) <= float64(b.limit)*m.lowReserve {
+		b.deferred++
+		return false
+	}
+	return true
+}
+
+// Wait paces a request against the resource budget. When the budget is
+// exhausted it waits for the reset; when it is low it spreads the remaining
+// requests over the time left in the window. Delays are capped at maxWait.
+func (m *Manager) Wait(ctx context.Context, resource string) error {
+	m.mu.Lock()
+	b := m.bucketLocked(resource)
+	var delay time.Duration
+	now := m.now()
+	if b.known && b.limit > 0 && now.Before(b.reset) {
+		untilReset := b.reset.Sub(now)
+		switch {
+		case b.remaining <= 0:
+			delay = untilReset
+		case float64(b.remaining) <= float64(b.limit)*0.1:
+			delay = untilReset / time.Duration(b.remaining+1)
+		}
+	}
+	if delay > m.maxWait {
+		if b.remaining <= 0 {
+			reset := b.reset
+			m.mu.Unlock()
+			return fmt.Errorf("%s rate limit exhausted until %s", resource, reset.Format(time.RFC3339))
+		}
+		delay = m.maxWait
+	}
+	if delay > 0 {
+		b.waited += delay
+	}
+	m.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	logrus.WithFields(logrus.Fields{
+		"resource": resource,
+		"delay":    delay.String(),
+	}).Debug("pacing request to preserve rate-limit budget")
+	return m.sleep(ctx, delay)
+}
+
+// Observe records a request and updates the budget from rate-limit headers.
+func (m *Manager) Observe(resource string, header http.Header) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r := header.Get("X-RateLimit-Resource"); r != "" {
+		resource = normalizeResource(r)
+	}
+	b := m.bucketLocked(resource)
+	b.requests++
+
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	b.limit = limit
+	b.remaining = remaining
+	b.known = true
+	if used, err := strconv.Atoi(header.Get("X-RateLimit-Used")); err == nil {
+		b.used = used
+	} else {
+		b.used = limit - remaining
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		b.reset = time.Unix(reset, 0)
+	}
+}
+
+// Usage returns a snapshot of budget usage per resource.
+func (m *Manager) Usage() map[string]models.RateLimitUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	usage := make(map[string]models.RateLimitUsage, len(m.buckets))
+	for name, b := range m.buckets {
+		if b.requests == 0 && b.deferred == 0 {
+			continue
+		}
+		usage[name] = models.RateLimitUsage{
+			Limit:     b.limit,
+			Remaining: b.remaining,
+			Used:      b.used,
+			Reset:     b.reset,
+			Requests:  b.requests,
+			Deferred:  b.deferred,
+			WaitedMs:  b.waited.Milliseconds(),
+		}
+	}
+	return usage
+}
+
+// APICalls returns the number of requests sent per endpoint (see Endpoint).
+func (m *Manager) APICalls() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	calls := make(map[string]int, len(m.calls))
+	for endpoint, n := range m.calls {
+		calls[endpoint] = n
+	}
+	return calls
+}
+
+func (m *Manager) countCall(endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[endpoint]++
+}
+
+// identifierAfter lists path segments followed by an identifier (org, group,
+// user, invitation ID), which Endpoint replaces with "*".
+var identifierAfter = map[string]bool{
+	"orgs":        true,
+	"groups":      true,
+	"users":       true,
+	"members":     true,
+	"memberships": true,
+	"invitations": true,
+}
+
+// Endpoint names the API endpoint of a request as its method and path with
+// identifiers replaced, e.g. "GET /orgs/*/members" or "DELETE /orgs/*/members/*".
+func Endpoint(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		if identifierAfter[segments[i-1]] && !identifierAfter[segments[i]] {
+			segments[i] = "*"
+		}
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
+
+// Classify maps a request to its rate-limit resource.
+func Classify(req *http.Request) string {
+	host := req.URL.Hostname()
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(host, "googleapis.com"):
+		return ResourceGoogle
+	case strings.HasPrefix(path, "/graphql"):
+		return ResourceGraphQL
+	case strings.HasPrefix(path, "/search/"):
+		return ResourceSearch
+	case strings.HasSuffix(path, "/audit-log"):
+		return ResourceAuditLog
+	default:
+		return ResourceCore
+	}
+}
+
+func normalizeResource(r string) string {
+	return strings.ReplaceAll(strings.ToLower(r), "-", "_")
+}
+
+// Transport returns an http.RoundTripper that paces requests and feeds
+// response headers into the manager. If base is nil, http.DefaultTransport is used.
+func (m *Manager) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{manager: m, base: base}
+}
+
+type transport struct {
+	manager *Manager
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	resource := Classify(req)
+	if !t.manager.Allow(resource, priorityFrom(ctx)) {
+		return nil, ErrDeferred
+	}
+	if err := t.manager.Wait(ctx, resource); err != nil {
+		return nil, err
+	}
+	t.manager.countCall(Endpoint(req))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.manager.Observe(resource, resp.Header)
+	return resp, nil
+}
@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func rateLimitHeader(resource string, limit, remaining int, reset time.Time) http.Header {
+	h := http.Header{}
+	h.Set("X-RateLimit-Resource", resource)
+	h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Used", strconv.Itoa(limit-remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return h
+}
+
+func newTestManager(now time.Time) (*Manager, *[]time.Duration) {
+	m := NewManager(20, time.Minute)
+	m.now = func() time.Time { return now }
+	var slept []time.Duration
+	m.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return m, &slept
+}
+
+func TestObserveRecordsUsage(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m, _ := newTestManager(now)
+
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 4990, now.Add(time.Hour)))
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 4989, now.Add(time.Hour)))
+	m.Observe(ResourceGoogle, http.Header{})
+
+	usage := m.Usage()
+	core := usage[ResourceCore]
+	if core.Requests != 2 || core.Remaining != 4989 || core.Limit != 5000 || core.Used != 11 {
+		t.Fatalf("unexpected core usage: %+v", core)
+	}
+	if !core.Reset.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected reset: %v", core.Reset)
+	}
+	if usage[ResourceGoogle].Requests != 1 {
+		t.Fatalf("expected google requests to be counted, got %+v", usage[ResourceGoogle])
+	}
+}
+
+func TestAllowDefersLowPriorityInReserve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m, _ := newTestManager(now)
+
+	if !m.Allow(ResourceCore, PriorityLow) {
+		t.Fatalf("expected low priority to be allowed with unknown budget")
+	}
+
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 900, now.Add(time.Hour)))
+	if m.Allow(ResourceCore, PriorityLow) {
+		t.Fatalf("expected low priority to be deferred inside the reserve")
+	}
+	if !m.Allow(ResourceCore, PriorityNormal) {
+		t.Fatalf("expected normal priority to be allowed")
+	}
+	if got := m.Usage()[ResourceCore].Deferred; got != 1 {
+		t.Fatalf("expected 1 deferred request, got %d", got)
+	}
+}
+
+func TestWaitPacesLowBudget(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m, slept := newTestManager(now)
+
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 4000, now.Add(time.Hour)))
+	if err := m.Wait(context.Background(), ResourceCore); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*slept) != 0 {
+		t.Fatalf("expected no pacing with a healthy budget, got %v", *slept)
+	}
+
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 99, now.Add(100*time.Second)))
+	if err := m.Wait(context.Background(), ResourceCore); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != time.Second {
+		t.Fatalf("expected a 1s pacing delay, got %v", *slept)
+	}
+}
+
+func TestWaitExhaustedBudget(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m, slept := newTestManager(now)
+
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 0, now.Add(30*time.Second)))
+	if err := m.Wait(context.Background(), ResourceCore); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 30*time.Second {
+		t.Fatalf("expected to wait for the reset, got %v", *slept)
+	}
+
+	m.Observe(ResourceCore, rateLimitHeader("core", 5000, 0, now.Add(time.Hour)))
+	if err := m.Wait(context.Background(), ResourceCore); err == nil {
+		t.Fatalf("expected error when the reset is beyond max wait")
+	}
+}
+
+func TestTransportDefersLowPriorityRequests(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-RateLimit-Resource", "core")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "600")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	}))
+	defer server.Close()
+
+	m := NewManager(20, time.Minute)
+	client := &http.Client{Transport: m.Transport(nil)}
+
+	resp, err := client.Get(server.URL + "/orgs/example/members")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	req, _ := http.NewRequestWithContext(WithPriority(context.Background(), PriorityLow), http.MethodGet, server.URL+"/users/user1", nil)
+	_, err = client.Do(req)
+	if !errors.Is(err, ErrDeferred) {
+		t.Fatalf("expected ErrDeferred, got %v", err)
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected 1 upstream request, got %d", requests)
+	}
+	usage := m.Usage()[ResourceCore]
+	if usage.Requests != 1 || usage.Deferred != 1 || usage.Remaining != 600 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if calls := m.APICalls(); len(calls) != 1 || calls["GET /orgs/*/members"] != 1 {
+		t.Fatalf("expected only the sent request to be counted, got %v", calls)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/orgs/example/members":           ResourceCore,
+		"https://api.github.com/graphql":                        ResourceGraphQL,
+		"https://api.github.com/search/users":                   ResourceSearch,
+		"https://api.github.com/orgs/example/audit-log":         ResourceAuditLog,
+		"https://admin.googleapis.com/admin/directory/v1/users": ResourceGoogle,
+	}
+	for url, want := range tests {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if got := Classify(req); got != want {
+			t.Errorf("Classify(%s) = %s, want %s", url, got, want)
+		}
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/orgs/example/members":                                  "GET /orgs/*/members",
+		"https://api.github.com/orgs/example/members/octocat":                          "GET /orgs/*/members/*",
+		"https://api.github.com/orgs/example/invitations/42":                           "GET /orgs/*/invitations/*",
+		"https://api.github.com/orgs/example/failed_invitations":                       "GET /orgs/*/failed_invitations",
+		"https://api.github.com/search/users":                                          "GET /search/users",
+		"https://api.github.com/graphql":                                               "GET /graphql",
+		"https://admin.googleapis.com/admin/directory/v1/groups/g@example.com/members": "GET /admin/directory/v1/groups/*/members",
+		"https://admin.googleapis.com/admin/directory/v1/users/a@example.com":          "GET /admin/directory/v1/users/*",
+	}
+	for url, want := range tests {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if got := Endpoint(req); got != want {
+			t.Errorf("Endpoint(%s) = %s, want %s", url, got, want)
+		}
+	}
+}
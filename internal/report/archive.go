@@ -0,0 +1,103 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+// Store keeps archived reports.
+type Store interface {
+	// Put writes body under key and returns its location, e.g. a path or s3:// URI.
+	Put(ctx context.Context, key string, contentType string, body []byte) (string, error)
+
+	// Prune deletes the reports of org written before cutoff and returns how many it deleted.
+	Prune(ctx context.Context, org string, cutoff time.Time) (int, error)
+}
+
+// reportStem matches the names Archive gives reports: a run ID, or the run's
+// start time followed by -failed when there is none.
+var reportStem = regexp.MustCompile(`^\d{8}T\d{6}Z-([0-9a-f]{8}|failed)$`)
+
+// isReportFile reports whether the base name of file is one Archive writes.
+func isReportFile(file string) bool {
+	ext := path.Ext(file)
+	for _, f := range formats {
+		if f.ext == ext {
+			return reportStem.MatchString(strings.TrimSuffix(path.Base(file), ext))
+		}
+	}
+	return false
+}
+
+// Archiver renders each run's report in the configured formats and writes it to a store.
+type Archiver struct {
+	store     Store
+	formats   []string
+	retention time.Duration // 0 keeps reports forever
+	now       func() time.Time
+}
+
+// NewArchiver creates an archiver writing formats to store and pruning reports
+// older than retentionDays (0 keeps them).
+func NewArchiver(store Store, formats []string, retentionDays int) *Archiver {
+	return &Archiver{
+		store:     store,
+		formats:   formats,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+		now:       time.Now,
+	}
+}
+
+// Archive writes the report of run and prunes expired reports. It returns the
+// location of each format written; formats that could not be written are
+// missing and their errors joined.
+func (a *Archiver) Archive(ctx context.Context, run Run) (map[string]string, error) {
+	now := a.now()
+	name := now.UTC().Format("20060102T150405Z") + "-failed"
+	if run.Result != nil && run.Result.RunID != "" {
+		name = run.Result.RunID
+	}
+
+	locations := map[string]string{}
+	var errs []error
+	for _, format := range a.formats {
+		body, err := Render(run, format, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f := formats[format]
+		location, err := a.store.Put(ctx, path.Join(run.Org, name+f.ext), f.contentType, body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("writing %s report: %w", format, err))
+			continue
+		}
+		locations[format] = location
+	}
+
+	if a.retention > 0 {
+		if _, err := a.store.Prune(ctx, run.Org, now.Add(-a.retention)); err != nil {
+			errs = append(errs, fmt.Errorf("pruning reports: %w", err))
+		}
+	}
+	return locations, errors.Join(errs...)
+}
+
+// NewStore creates the store of the configured backend.
+func NewStore(ctx context.Context, cfg config.ReportConfig) (Store, error) {
+	switch cfg.Backend {
+	case config.ReportBackendS3:
+		return NewS3Store(ctx, cfg.S3)
+	case config.ReportBackendDirectory:
+		return NewDirectoryStore(cfg.Directory)
+	default:
+		return nil, fmt.Errorf("unknown report backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+func TestArchiveToDirectory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDirectoryStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirectoryStore: %v", err)
+	}
+	archiver := NewArchiver(store, []string{config.ReportFormatHTML, config.ReportFormatMarkdown}, 30)
+
+	locations, err := archiver.Archive(context.Background(), sampleRun())
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	want := map[string]string{
+		config.ReportFormatHTML:     filepath.Join(dir, "example-org", "20240301T120000Z-abcd.html"),
+		config.ReportFormatMarkdown: filepath.Join(dir, "example-org", "20240301T120000Z-abcd.md"),
+	}
+	for format, file := range want {
+		if locations[format] != file {
+			t.Errorf("expected %s report at %s, got %q", format, file, locations[format])
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("expected %s to exist: %v", file, err)
+		}
+	}
+}
+
+func TestArchiveNamesFailedRuns(t *testing.T) {
+	store, _ := NewDirectoryStore(t.TempDir())
+	archiver := NewArchiver(store, []string{config.ReportFormatMarkdown}, 0)
+	archiver.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	locations, err := archiver.Archive(context.Background(), Run{Org: "example-org", Err: errors.New("boom")})
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if filepath.Base(locations[config.ReportFormatMarkdown]) != "20240301T120000Z-failed.md" {
+		t.Fatalf("expected a timestamped name, got %v", locations)
+	}
+}
+
+func TestDirectoryPrune(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewDirectoryStore(dir)
+	ctx := context.Background()
+	old, _ := store.Put(ctx, "example-org/20240301T120000Z-0a1b2c3d.html", "text/html", []byte("old"))
+	failed, _ := store.Put(ctx, "example-org/20240301T130000Z-failed.md", "text/markdown", []byte("failed"))
+	recent, _ := store.Put(ctx, "example-org/20240302T120000Z-0a1b2c3d.md", "text/markdown", []byte("recent"))
+	handWritten, _ := store.Put(ctx, "example-org/runbook.md", "text/markdown", []byte("keep"))
+	otherOrg, _ := store.Put(ctx, "other-org/20240301T120000Z-0a1b2c3d.html", "text/html", []byte("other"))
+	nested, _ := store.Put(ctx, "example-org/archive/20240301T120000Z-0a1b2c3d.html", "text/html", []byte("nested"))
+	other := filepath.Join(dir, "notes.txt")
+	_ = os.WriteFile(other, []byte("keep"), 0o644)
+	past := time.Now().Add(-48 * time.Hour)
+	for _, file := range []string{old, failed, handWritten, otherOrg, nested, other} {
+		_ = os.Chtimes(file, past, past)
+	}
+
+	deleted, err := store.Prune(ctx, "example-org", time.Now().Add(-24*time.Hour))
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 reports pruned, got %d (err %v)", deleted, err)
+	}
+	for file, exists := range map[string]bool{old: false, failed: false, recent: true, handWritten: true, otherOrg: true, nested: true, other: true} {
+		if _, err := os.Stat(file); (err == nil) != exists {
+			t.Errorf("expected %s to exist: %v", file, exists)
+		}
+	}
+
+	if deleted, err := store.Prune(ctx, "missing-org", time.Now()); err != nil || deleted != 0 {
+		t.Fatalf("expected nothing pruned for an org without reports, got %d (err %v)", deleted, err)
+	}
+}
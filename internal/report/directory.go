@@ -0,0 +1,67 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirectoryStore keeps reports as files under a directory, one subdirectory per org.
+type DirectoryStore struct {
+	dir string
+}
+
+// NewDirectoryStore creates a store writing under dir, creating it if needed.
+func NewDirectoryStore(dir string) (*DirectoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating report directory: %w", err)
+	}
+	return &DirectoryStore{dir: dir}, nil
+}
+
+// Put writes body to dir/key and returns the file path.
+func (s *DirectoryStore) Put(ctx context.Context, key string, contentType string, body []byte) (string, error) {
+	file := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("creating report directory: %w", err)
+	}
+	if err := os.WriteFile(file, body, 0o644); err != nil {
+		return "", fmt.Errorf("writing report: %w", err)
+	}
+	return file, nil
+}
+
+// Prune deletes the report files of org last modified before cutoff. Other files,
+// and files in other directories, are left alone.
+func (s *DirectoryStore) Prune(ctx context.Context, org string, cutoff time.Time) (int, error) {
+	orgDir := filepath.Join(s.dir, filepath.FromSlash(org))
+	entries, err := os.ReadDir(orgDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pruning report directory: %w", err)
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isReportFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return deleted, fmt.Errorf("pruning report directory: %w", err)
+		}
+		if info.ModTime().Before(cutoff) {
+			if err := os.Remove(filepath.Join(orgDir, entry.Name())); err != nil {
+				return deleted, fmt.Errorf("pruning report directory: %w", err)
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,190 @@
+// Package report renders a full report of each sync run to HTML and Markdown and
+// archives it to a directory or an S3 bucket.
+package report
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+//go:embed templates/*
+var templateFiles embed.FS
+
+var (
+	markdownTemplate = texttemplate.Must(texttemplate.New("report.md.tmpl").Funcs(texttemplate.FuncMap{"cell": markdownCell}).ParseFS(templateFiles, "templates/report.md.tmpl"))
+	htmlTemplate     = htmltemplate.Must(htmltemplate.New("report.html.tmpl").ParseFS(templateFiles, "templates/report.html.tmpl"))
+)
+
+// Run describes a finished sync run to report on.
+type Run struct {
+	Org    string
+	DryRun bool
+	Result *models.SyncResult // nil when the run failed before producing a result
+	Err    error
+}
+
+// view is what the templates render.
+type view struct {
+	Org       string
+	DryRun    bool
+	Failed    bool
+	RunID     string
+	Generated string
+	Error     string
+	Result    *models.SyncResult
+	Actions   []actionRow
+	Failures  []actionRow
+	Phases    []durationRow
+	APICalls  []countRow
+	Reconcile []countRow
+	Orphaned  []string
+	Errors    []string
+}
+
+type actionRow struct {
+	Type    models.ActionType
+	User    string
+	Role    string
+	Reason  string
+	Outcome string
+	Error   string
+}
+
+type durationRow struct {
+	Name string
+	Ms   int64
+}
+
+type countRow struct {
+	Name  string
+	Count int
+}
+
+// newView builds the report model of run, generated at now.
+func newView(run Run, now time.Time) view {
+	v := view{Org: run.Org, DryRun: run.DryRun, Generated: now.UTC().Format(time.RFC3339), Result: run.Result}
+	if run.Err != nil {
+		v.Failed = true
+		v.Error = run.Err.Error()
+	}
+	result := run.Result
+	if result == nil {
+		return v
+	}
+	v.RunID = result.RunID
+	v.Failed = v.Failed || !result.IsSuccess()
+	v.Orphaned = result.OrphanedGitHubUsers
+	v.Errors = result.Errors
+
+	for _, action := range result.Actions {
+		row := actionRow{Type: action.Type, User: action.Email, Reason: action.Reason, Outcome: outcome(action, run.DryRun)}
+		if action.Username != "" {
+			row.User = action.Username
+			if action.Email != "" && action.Email != action.Username {
+				row.User = fmt.Sprintf("%s (%s)", action.Username, action.Email)
+			}
+		}
+		if action.TargetRole != nil {
+			row.Role = string(*action.TargetRole)
+		}
+		if action.Error != nil {
+			row.Error = *action.Error
+			if action.HTTPStatus != 0 {
+				row.Error += fmt.Sprintf(" (HTTP %d)", action.HTTPStatus)
+			}
+			v.Failures = append(v.Failures, row)
+		}
+		v.Actions = append(v.Actions, row)
+	}
+
+	for _, phase := range []string{models.PhaseGoogleLoad, models.PhaseGitHubLoad, models.PhaseDiff, models.PhaseExecute, models.PhaseReconcile} {
+		if ms, ok := result.PhaseDurationsMs[phase]; ok {
+			v.Phases = append(v.Phases, durationRow{Name: phase, Ms: ms})
+		}
+	}
+	for endpoint, count := range result.APICalls {
+		v.APICalls = append(v.APICalls, countRow{Name: endpoint, Count: count})
+	}
+	sort.Slice(v.APICalls, func(i, j int) bool { return v.APICalls[i].Name < v.APICalls[j].Name })
+
+	if r := result.Reconciliation; r != nil {
+		v.Reconcile = []countRow{
+			{"New invitations saved", r.NewInvitationsSaved},
+			{"Resolved", r.Resolved},
+			{"Failed", r.Failed},
+			{"Expired", r.Expired},
+			{"Cancelled", r.Cancelled},
+			{"Members removed", r.MembersRemoved},
+			{"Roles updated", r.RolesUpdated},
+			{"Already in org resolved", r.AlreadyInOrgResolved},
+			{"Verified emails mapped", r.VerifiedEmailsMapped},
+			{"Transitions rejected", r.TransitionsRejected},
+		}
+	}
+	return v
+}
+
+// outcome describes what happened to a planned action.
+func outcome(action models.SyncAction, dryRun bool) string {
+	switch {
+	case action.Error != nil:
+		return "failed"
+	case action.Type == models.ActionSkip:
+		return "skipped"
+	case action.Executed:
+		return "done"
+	case dryRun:
+		return "dry run"
+	default:
+		return "not run"
+	}
+}
+
+// Render renders run in format, config.ReportFormatHTML or config.ReportFormatMarkdown.
+func Render(run Run, format string, now time.Time) ([]byte, error) {
+	v := newView(run, now)
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case config.ReportFormatHTML:
+		err = htmlTemplate.Execute(&buf, v)
+	case config.ReportFormatMarkdown:
+		err = markdownTemplate.Execute(&buf, v)
+	default:
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s report: %w", format, err)
+	}
+	if format == config.ReportFormatMarkdown {
+		// Optional sections leave runs of blank lines behind.
+		return blankLines.ReplaceAll(buf.Bytes(), []byte("\n\n")), nil
+	}
+	return buf.Bytes(), nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ").Replace(text)
+}
+
+// extension and content type per format.
+var formats = map[string]struct {
+	ext         string
+	contentType string
+}{
+	config.ReportFormatHTML:     {".html", "text/html; charset=utf-8"},
+	config.ReportFormatMarkdown: {".md", "text/markdown; charset=utf-8"},
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func sampleRun() Run {
+	admin := models.RoleOwner
+	failure := "422 Validation Failed"
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return Run{
+		Org: "example-org",
+		Result: &models.SyncResult{
+			RunID:     "20240301T120000Z-abcd",
+			StartTime: start,
+			Groups: []models.GroupInput{
+				{Email: "members@example.com", Role: models.RoleMember, Members: 12, Active: 11},
+				{Email: "owners@example.com", Role: models.RoleOwner, Members: 2, Active: 2},
+			},
+			Actions: []models.SyncAction{
+				{Type: models.ActionInvite, Email: "new@example.com", Reason: "in members group", Executed: true},
+				{Type: models.ActionUpdateRole, Email: "lead@example.com", Username: "lead", TargetRole: &admin, Reason: "in owners group | promoted", Error: &failure, HTTPStatus: 422},
+			},
+			Summary:             models.SyncSummary{ActionsPlanned: 2, ActionsExecuted: 1, ActionsFailed: 1, OrphanedGitHub: 1},
+			OrphanedGitHubUsers: []string{"ghost"},
+			Reconciliation:      &models.ReconcileResult{Resolved: 3, Errors: []string{"resolving invitation 7: throttled"}},
+			PhaseDurationsMs:    map[string]int64{models.PhaseDiff: 4, models.PhaseGoogleLoad: 120},
+			APICalls:            map[string]int{"GET /orgs/*/members": 2},
+		},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	out, err := Render(sampleRun(), config.ReportFormatMarkdown, time.Now())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	md := string(out)
+	for _, want := range []string{
+		"# GitHub sync report: example-org",
+		"| Status | ❌ failed |",
+		"| Google group members@example.com | member | 12 | 11 |",
+		"| invite | new@example.com |  | in members group | done |  |",
+		`| update_role | lead (lead@example.com) | admin | in owners group \| promoted | failed | 422 Validation Failed (HTTP 422) |`,
+		"| Resolved | 3 |",
+		"- resolving invitation 7: throttled",
+		"- ghost",
+		"| GET /orgs/*/members | 2 |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected %q in:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "\n\n\n") {
+		t.Errorf("expected no runs of blank lines")
+	}
+	if strings.Index(md, "| google_load |") > strings.Index(md, "| diff |") {
+		t.Errorf("expected phases in run order")
+	}
+}
+
+func TestRenderHTMLEscapes(t *testing.T) {
+	run := sampleRun()
+	run.DryRun = true
+	run.Result.OrphanedGitHubUsers = []string{"<script>alert(1)</script>"}
+	out, err := Render(run, config.ReportFormatHTML, time.Now())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	html := string(out)
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Fatalf("expected user values to be escaped")
+	}
+	if !strings.Contains(html, `<span class="dry-run">DRY RUN</span>`) || !strings.Contains(html, "<td>Google group members@example.com</td>") {
+		t.Fatalf("expected a dry-run label and the group inputs, got:\n%s", html)
+	}
+}
+
+func TestRenderFailedRunWithoutResult(t *testing.T) {
+	out, err := Render(Run{Org: "example-org", Err: errors.New("listing members: 401 Bad credentials")}, config.ReportFormatMarkdown, time.Now())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	md := string(out)
+	if !strings.Contains(md, "**Error:** listing members: 401 Bad credentials") || strings.Contains(md, "## Inputs") {
+		t.Fatalf("expected only the error, got:\n%s", md)
+	}
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
+)
+
+// maxDeletesPerRequest is the DeleteObjects limit on keys per call.
+const maxDeletesPerRequest = 1000
+
+// S3API defines the S3 client interface used for reports.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// S3Store keeps reports as objects under a bucket prefix, one key prefix per org.
+type S3Store struct {
+	client S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Store creates a store from the S3 report settings. With cfg.Endpoint set,
+// requests go to that local stand-in with static credentials and path-style URLs.
+func NewS3Store(ctx context.Context, cfg config.ReportS3Config) (*S3Store, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	if cfg.Endpoint != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider("local", "local", ""),
+		))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	tracing.InstrumentAWS(&awsCfg)
+
+	var clientOpts []func(*s3.Options)
+	if cfg.Endpoint != "" {
+		clientOpts = append(clientOpts, func(o *s3.Options) {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		})
+	}
+	return &S3Store{client: s3.NewFromConfig(awsCfg, clientOpts...), bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+// Put uploads body to prefix+key and returns its s3:// URI.
+func (s *S3Store) Put(ctx context.Context, key string, contentType string, body []byte) (string, error) {
+	objectKey := s.prefix + key
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(objectKey),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading report: %w", err)
+	}
+	return "s3://" + s.bucket + "/" + objectKey, nil
+}
+
+// Prune deletes the report objects of org, directly under prefix+org/, last
+// modified before cutoff. Other objects are left alone.
+func (s *S3Store) Prune(ctx context.Context, org string, cutoff time.Time) (int, error) {
+	orgPrefix := s.prefix
+	if org != "" {
+		orgPrefix += org + "/"
+	}
+	var expired []types.ObjectIdentifier
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(orgPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("listing reports: %w", err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			name, ok := strings.CutPrefix(key, orgPrefix)
+			if !ok || strings.Contains(name, "/") || !isReportFile(name) {
+				continue
+			}
+			if object.LastModified != nil && object.LastModified.Before(cutoff) {
+				expired = append(expired, types.ObjectIdentifier{Key: aws.String(key)})
+			}
+		}
+	}
+
+	deleted := 0
+	for start := 0; start < len(expired); start += maxDeletesPerRequest {
+		end := min(start+maxDeletesPerRequest, len(expired))
+		out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucket),
+			Delete: &types.Delete{Objects: expired[start:end], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("deleting reports: %w", err)
+		}
+		deleted += end - start - len(out.Errors)
+		if len(out.Errors) > 0 {
+			var failed []string
+			for _, e := range out.Errors {
+				failed = append(failed, aws.ToString(e.Key)+": "+aws.ToString(e.Message))
+			}
+			return deleted, fmt.Errorf("deleting reports: %s", strings.Join(failed, "; "))
+		}
+	}
+	return deleted, nil
+}
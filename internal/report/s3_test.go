@@ -0,0 +1,77 @@
+package report
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type mockS3 struct {
+	puts       map[string]string // key -> content type
+	objects    []types.Object
+	listPrefix string
+	deleted    []string
+}
+
+func (m *mockS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	_, _ = io.ReadAll(params.Body)
+	m.puts[aws.ToString(params.Key)] = aws.ToString(params.ContentType)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.listPrefix = aws.ToString(params.Prefix)
+	return &s3.ListObjectsV2Output{Contents: m.objects}, nil
+}
+
+func (m *mockS3) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	for _, object := range params.Delete.Objects {
+		m.deleted = append(m.deleted, aws.ToString(object.Key))
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func TestS3Put(t *testing.T) {
+	client := &mockS3{puts: map[string]string{}}
+	store := &S3Store{client: client, bucket: "sync-reports", prefix: "reports/"}
+
+	location, err := store.Put(context.Background(), "example-org/run-1.html", "text/html; charset=utf-8", []byte("<html></html>"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if location != "s3://sync-reports/reports/example-org/run-1.html" {
+		t.Fatalf("unexpected location %q", location)
+	}
+	if client.puts["reports/example-org/run-1.html"] != "text/html; charset=utf-8" {
+		t.Fatalf("expected the object with its content type, got %v", client.puts)
+	}
+}
+
+func TestS3Prune(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now()
+	client := &mockS3{objects: []types.Object{
+		{Key: aws.String("reports/example-org/20240301T120000Z-0a1b2c3d.html"), LastModified: &old},
+		{Key: aws.String("reports/example-org/20240301T130000Z-failed.md"), LastModified: &old},
+		{Key: aws.String("reports/example-org/20240302T120000Z-0a1b2c3d.md"), LastModified: &recent},
+		{Key: aws.String("reports/example-org/runbook.md"), LastModified: &old},
+		{Key: aws.String("reports/example-org/archive/20240301T120000Z-0a1b2c3d.html"), LastModified: &old},
+	}}
+	store := &S3Store{client: client, bucket: "sync-reports", prefix: "reports/"}
+
+	deleted, err := store.Prune(context.Background(), "example-org", time.Now().Add(-24*time.Hour))
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 reports pruned, got %d (err %v)", deleted, err)
+	}
+	if client.listPrefix != "reports/example-org/" {
+		t.Fatalf("expected the listing limited to the org's prefix, got %q", client.listPrefix)
+	}
+	if len(client.deleted) != 2 || client.deleted[0] != "reports/example-org/20240301T120000Z-0a1b2c3d.html" || client.deleted[1] != "reports/example-org/20240301T130000Z-failed.md" {
+		t.Fatalf("expected only the expired reports deleted, got %v", client.deleted)
+	}
+}
@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	stdsync "sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
+)
+
+// SecretsManagerAPI defines the Secrets Manager client interface used for secrets.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// SSMAPI defines the SSM client interface used for secrets.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// awsClients loads the AWS config once, on the first awssm or ssm reference, so
+// that resolvers without AWS references never need AWS credentials.
+type awsClients struct {
+	region string
+
+	once           stdsync.Once
+	err            error
+	secretsManager SecretsManagerAPI
+	ssm            SSMAPI
+}
+
+func newAWSClients(region string) *awsClients {
+	return &awsClients{region: region}
+}
+
+func (c *awsClients) load(ctx context.Context) error {
+	c.once.Do(func() {
+		var opts []func(*awsconfig.LoadOptions) error
+		if c.region != "" {
+			opts = append(opts, awsconfig.WithRegion(c.region))
+		}
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			c.err = fmt.Errorf("loading AWS config: %w", err)
+			return
+		}
+		tracing.InstrumentAWS(&awsCfg)
+		c.secretsManager = secretsmanager.NewFromConfig(awsCfg)
+		c.ssm = ssm.NewFromConfig(awsCfg)
+	})
+	return c.err
+}
+
+// SecretsManagerProvider reads AWS Secrets Manager secrets: awssm://name-or-arn.
+type SecretsManagerProvider struct {
+	clients *awsClients
+	client  SecretsManagerAPI
+}
+
+// NewSecretsManagerProvider creates a provider using client.
+func NewSecretsManagerProvider(client SecretsManagerAPI) *SecretsManagerProvider {
+	return &SecretsManagerProvider{client: client}
+}
+
+// Get returns the current string value of the secret name.
+func (p *SecretsManagerProvider) Get(ctx context.Context, name string) (string, error) {
+	client := p.client
+	if client == nil {
+		if err := p.clients.load(ctx); err != nil {
+			return "", err
+		}
+		client = p.clients.secretsManager
+	}
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", name)
+	}
+	return *out.SecretString, nil
+}
+
+// SSMProvider reads SSM Parameter Store parameters: ssm:///parameter/name.
+// SecureString parameters are decrypted.
+type SSMProvider struct {
+	clients *awsClients
+	client  SSMAPI
+}
+
+// NewSSMProvider creates a provider using client.
+func NewSSMProvider(client SSMAPI) *SSMProvider {
+	return &SSMProvider{client: client}
+}
+
+// Get returns the value of the parameter name.
+func (p *SSMProvider) Get(ctx context.Context, name string) (string, error) {
+	client := p.client
+	if client == nil {
+		if err := p.clients.load(ctx); err != nil {
+			return "", err
+		}
+		client = p.clients.ssm
+	}
+	out, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return "", err
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return "", fmt.Errorf("parameter %s has no value", name)
+	}
+	return *out.Parameter.Value, nil
+}
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type mockSecretsManager struct {
+	secretID string
+}
+
+func (m *mockSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	m.secretID = aws.ToString(params.SecretId)
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"token":"ghp_test"}`)}, nil
+}
+
+type mockSSM struct {
+	name       string
+	decryption bool
+}
+
+func (m *mockSSM) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	m.name, m.decryption = aws.ToString(params.Name), aws.ToBool(params.WithDecryption)
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Value: aws.String("ghp_test")}}, nil
+}
+
+func TestSecretsManagerProvider(t *testing.T) {
+	client := &mockSecretsManager{}
+	r := NewResolver(configWithoutCache())
+	r.Register("awssm", NewSecretsManagerProvider(client))
+
+	value, err := r.Resolve(context.Background(), "github-sync/app")
+	if err != nil || value != `{"token":"ghp_test"}` {
+		t.Fatalf("expected the whole secret, got %q, %v", value, err)
+	}
+	if client.secretID != "github-sync/app" {
+		t.Fatalf("expected a bare name to be read from Secrets Manager, got %q", client.secretID)
+	}
+	value, err = r.Resolve(context.Background(), "awssm://github-sync/app#token")
+	if err != nil || value != "ghp_test" {
+		t.Fatalf("expected the token field, got %q, %v", value, err)
+	}
+}
+
+func TestSSMProvider(t *testing.T) {
+	client := &mockSSM{}
+	r := NewResolver(configWithoutCache())
+	r.Register("ssm", NewSSMProvider(client))
+
+	value, err := r.Resolve(context.Background(), "ssm:///github-sync/token")
+	if err != nil || value != "ghp_test" {
+		t.Fatalf("expected the parameter value, got %q, %v", value, err)
+	}
+	if client.name != "/github-sync/token" || !client.decryption {
+		t.Fatalf("expected a decrypted read of /github-sync/token, got %q (decryption %v)", client.name, client.decryption)
+	}
+}
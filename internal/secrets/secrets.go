@@ -1,32 +1,189 @@
+// Package secrets resolves secret references: a bare AWS Secrets Manager name or
+// ARN, or a URI naming a provider (awssm://, ssm://, vault://, env:// or file://).
+// Resolved values are cached for a TTL so that warm Lambda invocations and
+// scheduled runs do not fetch them again.
 package secrets
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	stdsync "sync"
+	"time"
 
-	"github.com/aws/aws-secretsmanager-caching-go/v2/secretcache"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 )
 
-// Manager wraps the Secrets Manager cache client.
-type Manager struct {
-	cache *secretcache.Cache
+// Provider reads secrets from one backend.
+type Provider interface {
+	// Get returns the secret at path, the part of a reference after "scheme://".
+	Get(ctx context.Context, path string) (string, error)
 }
 
-// NewManager creates a new Secrets Manager cache.
-func NewManager() (*Manager, error) {
-	cache, err := secretcache.New()
+// Ref is a parsed secret reference.
+type Ref struct {
+	Scheme string
+	Path   string
+	Field  string // Field of a JSON secret to return; empty for the whole value
+}
+
+// String returns the reference without its field.
+func (r Ref) String() string {
+	return r.Scheme + "://" + r.Path
+}
+
+// ParseRef parses a secret reference. A reference without a scheme is a
+// Secrets Manager name or ARN, taken as is.
+func ParseRef(ref string) (Ref, error) {
+	scheme, rest, ok := strings.Cut(ref, "://")
+	if !ok {
+		if ref == "" {
+			return Ref{}, fmt.Errorf("secret reference is empty")
+		}
+		return Ref{Scheme: config.SecretSchemeAWSSM, Path: ref}, nil
+	}
+	r := Ref{Scheme: strings.ToLower(scheme), Path: rest}
+	if i := strings.LastIndex(rest, "#"); i >= 0 {
+		r.Path, r.Field = rest[:i], rest[i+1:]
+	}
+	if r.Path == "" {
+		return Ref{}, fmt.Errorf("secret reference %q has no path", ref)
+	}
+	return r, nil
+}
+
+// Resolver resolves secret references through its providers and caches the
+// values.
+type Resolver struct {
+	providers map[string]Provider
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu    stdsync.Mutex
+	cache map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// NewResolver creates a resolver with the awssm, ssm, vault, env and file
+// providers. AWS clients are created on first use.
+func NewResolver(cfg config.SecretsConfig) *Resolver {
+	r := &Resolver{
+		providers: map[string]Provider{},
+		ttl:       time.Duration(cfg.CacheTTLSeconds) * time.Second,
+		now:       time.Now,
+		cache:     map[string]cachedSecret{},
+	}
+	aws := newAWSClients(cfg.Region)
+	r.Register(config.SecretSchemeAWSSM, &SecretsManagerProvider{clients: aws})
+	r.Register(config.SecretSchemeSSM, &SSMProvider{clients: aws})
+	r.Register(config.SecretSchemeVault, NewVaultProvider(cfg.Vault))
+	r.Register(config.SecretSchemeEnv, EnvProvider{})
+	r.Register(config.SecretSchemeFile, FileProvider{})
+	return r
+}
+
+// Register sets the provider of scheme, replacing any previous one.
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// Resolve returns the value ref points to. Values are cached by reference,
+// without the field, so the fields of one secret share a fetch. Failures are
+// not cached.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	parsed, err := ParseRef(ref)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	provider, ok := r.providers[parsed.Scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown secret scheme %q", parsed.Scheme)
+	}
+
+	key := parsed.String()
+	value, ok := r.cached(key)
+	if !ok {
+		value, err = provider.Get(ctx, parsed.Path)
+		if err != nil {
+			return "", fmt.Errorf("reading secret %s: %w", key, err)
+		}
+		r.store(key, value)
 	}
-	return &Manager{cache: cache}, nil
+	if parsed.Field == "" {
+		return value, nil
+	}
+	return jsonField(value, parsed.Field, key)
+}
+
+// ResolveSecretValue resolves ref or, when ref is empty, reads filePath.
+func (r *Resolver) ResolveSecretValue(ctx context.Context, ref string, filePath string) (string, error) {
+	if ref != "" {
+		return r.Resolve(ctx, ref)
+	}
+	return LoadSecretFromFile(filePath)
+}
+
+func (r *Resolver) cached(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.cache[key]
+	if !ok || !r.now().Before(entry.expires) {
+		return "", false
+	}
+	return entry.value, true
+}
+
+func (r *Resolver) store(key string, value string) {
+	if r.ttl <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[key] = cachedSecret{value: value, expires: r.now().Add(r.ttl)}
+}
+
+// jsonField returns field of the JSON object value. String fields are returned
+// as is, other fields as JSON.
+func jsonField(value string, field string, key string) (string, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, so field %q cannot be selected", key, field)
+	}
+	raw, ok := object[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %q", key, field)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	return string(raw), nil
 }
 
-// GetSecretString retrieves a secret value from Secrets Manager.
-func (m *Manager) GetSecretString(secretName string) (string, error) {
-	if secretName == "" {
-		return "", fmt.Errorf("secret name is required")
+// EnvProvider reads secrets from environment variables: env://NAME.
+type EnvProvider struct{}
+
+// Get returns the value of the environment variable name.
+func (EnvProvider) Get(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
 	}
-	return m.cache.GetSecretString(secretName)
+	return value, nil
+}
+
+// FileProvider reads secrets from local files: file:///path or file://relative/path.
+type FileProvider struct{}
+
+// Get returns the content of the file at path.
+func (FileProvider) Get(ctx context.Context, path string) (string, error) {
+	return LoadSecretFromFile(path)
 }
 
 // LoadSecretFromFile reads a secret value from a local file.
@@ -40,15 +197,3 @@ func LoadSecretFromFile(path string) (string, error) {
 	}
 	return string(data), nil
 }
-
-// ResolveSecretValue loads a secret from Secrets Manager or falls back to local file.
-func ResolveSecretValue(secretName string, filePath string) (string, error) {
-	if secretName != "" {
-		manager, err := NewManager()
-		if err != nil {
-			return "", err
-		}
-		return manager.GetSecretString(secretName)
-	}
-	return LoadSecretFromFile(filePath)
-}
@@ -0,0 +1,152 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+func configWithoutCache() config.SecretsConfig {
+	return config.SecretsConfig{}
+}
+
+func TestParseRef(t *testing.T) {
+	cases := []struct {
+		ref     string
+		want    Ref
+		wantErr bool
+	}{
+		{ref: "github-sync/token", want: Ref{Scheme: "awssm", Path: "github-sync/token"}},
+		{ref: "arn:aws:secretsmanager:eu-west-1:111111111111:secret:token-AbCdEf", want: Ref{Scheme: "awssm", Path: "arn:aws:secretsmanager:eu-west-1:111111111111:secret:token-AbCdEf"}},
+		{ref: "awssm://github-sync/app#private_key", want: Ref{Scheme: "awssm", Path: "github-sync/app", Field: "private_key"}},
+		{ref: "ssm:///github-sync/token", want: Ref{Scheme: "ssm", Path: "/github-sync/token"}},
+		{ref: "VAULT://secret/github-sync#token", want: Ref{Scheme: "vault", Path: "secret/github-sync", Field: "token"}},
+		{ref: "env://GITHUB_TOKEN", want: Ref{Scheme: "env", Path: "GITHUB_TOKEN"}},
+		{ref: "file:///run/secrets/token", want: Ref{Scheme: "file", Path: "/run/secrets/token"}},
+		{ref: "", wantErr: true},
+		{ref: "env://", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseRef(tc.ref)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseRef(%q): expected an error", tc.ref)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("ParseRef(%q) = %+v, %v; want %+v", tc.ref, got, err, tc.want)
+		}
+	}
+}
+
+type countingProvider struct {
+	value string
+	err   error
+	calls int
+}
+
+func (p *countingProvider) Get(ctx context.Context, path string) (string, error) {
+	p.calls++
+	return p.value, p.err
+}
+
+func TestResolveCachesUntilTTL(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := NewResolver(config.SecretsConfig{CacheTTLSeconds: 300})
+	r.now = func() time.Time { return now }
+	provider := &countingProvider{value: `{"token":"ghp_test","app_id":42}`}
+	r.Register("test", provider)
+
+	for _, ref := range []string{"test://github#token", "test://github#token", "test://github#app_id"} {
+		if _, err := r.Resolve(context.Background(), ref); err != nil {
+			t.Fatalf("Resolve(%s): %v", ref, err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Fatalf("expected one fetch for the fields of one secret, got %d", provider.calls)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if _, err := r.Resolve(context.Background(), "test://github#token"); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if provider.calls != 2 {
+		t.Fatalf("expected the secret to be fetched again after the TTL, got %d fetches", provider.calls)
+	}
+}
+
+func TestResolveDoesNotCacheFailures(t *testing.T) {
+	r := NewResolver(config.SecretsConfig{CacheTTLSeconds: 300})
+	provider := &countingProvider{err: errors.New("throttled")}
+	r.Register("test", provider)
+
+	if _, err := r.Resolve(context.Background(), "test://github"); err == nil {
+		t.Fatalf("expected the provider error")
+	}
+	provider.value, provider.err = "ghp_test", nil
+	if value, err := r.Resolve(context.Background(), "test://github"); err != nil || value != "ghp_test" {
+		t.Fatalf("expected the retry to fetch the secret, got %q, %v", value, err)
+	}
+}
+
+func TestResolveWithoutCache(t *testing.T) {
+	r := NewResolver(configWithoutCache())
+	provider := &countingProvider{value: "ghp_test"}
+	r.Register("test", provider)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Resolve(context.Background(), "test://github"); err != nil {
+			t.Fatalf("Resolve: %v", err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Fatalf("expected a fetch per call with caching disabled, got %d", provider.calls)
+	}
+}
+
+func TestResolveFields(t *testing.T) {
+	r := NewResolver(configWithoutCache())
+	r.Register("test", &countingProvider{value: `{"token":"ghp_test","app":{"id":42}}`})
+
+	if value, _ := r.Resolve(context.Background(), "test://github#app"); value != `{"id":42}` {
+		t.Fatalf("expected a non-string field as JSON, got %q", value)
+	}
+	if _, err := r.Resolve(context.Background(), "test://github#missing"); err == nil {
+		t.Fatalf("expected an error for a missing field")
+	}
+
+	r.Register("plain", &countingProvider{value: "ghp_test"})
+	if _, err := r.Resolve(context.Background(), "plain://github#token"); err == nil {
+		t.Fatalf("expected an error selecting a field of a non-JSON secret")
+	}
+	if _, err := r.Resolve(context.Background(), "gcpsm://projects/p/secrets/token"); err == nil {
+		t.Fatalf("expected an error for an unknown scheme")
+	}
+}
+
+func TestLocalProviders(t *testing.T) {
+	t.Setenv("SYNC_TEST_TOKEN", "ghp_env")
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("ghp_file"), 0o600); err != nil {
+		t.Fatalf("writing token file: %v", err)
+	}
+	r := NewResolver(config.SecretsConfig{CacheTTLSeconds: 300})
+
+	if value, err := r.Resolve(context.Background(), "env://SYNC_TEST_TOKEN"); err != nil || value != "ghp_env" {
+		t.Fatalf("expected the environment variable, got %q, %v", value, err)
+	}
+	if _, err := r.Resolve(context.Background(), "env://SYNC_TEST_UNSET"); err == nil {
+		t.Fatalf("expected an error for an unset variable")
+	}
+	if value, err := r.Resolve(context.Background(), "file://"+path); err != nil || value != "ghp_file" {
+		t.Fatalf("expected the file content, got %q, %v", value, err)
+	}
+	if value, err := r.ResolveSecretValue(context.Background(), "", path); err != nil || value != "ghp_file" {
+		t.Fatalf("expected the file fallback, got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+// VaultProvider reads HashiCorp Vault KV v2 secrets: vault://mount/path. The
+// value is the secret's data as a JSON object; select a key with "#key".
+type VaultProvider struct {
+	cfg    config.VaultConfig
+	client *http.Client
+}
+
+// NewVaultProvider creates a provider for the Vault server of cfg.
+func NewVaultProvider(cfg config.VaultConfig) *VaultProvider {
+	return &VaultProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Get reads the latest version of the KV v2 secret at path, whose first segment
+// is the secrets engine mount.
+func (p *VaultProvider) Get(ctx context.Context, path string) (string, error) {
+	if p.cfg.Address == "" {
+		return "", fmt.Errorf("secrets.vault.address is not set")
+	}
+	mount, secretPath, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	if !ok || secretPath == "" {
+		return "", fmt.Errorf("vault path %q must be <mount>/<path>", path)
+	}
+	token, err := p.token()
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := strings.TrimSuffix(p.cfg.Address, "/") + "/v1/" + url.PathEscape(mount) + "/data/" + escapePath(secretPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.cfg.Namespace)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("reading vault secret: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("reading vault response: %w", err)
+	}
+
+	var payload struct {
+		Errors []string `json:"errors"`
+		Data   struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	if resp.StatusCode != http.StatusOK {
+		message := http.StatusText(resp.StatusCode)
+		if len(payload.Errors) > 0 {
+			message = strings.Join(payload.Errors, "; ")
+		}
+		return "", fmt.Errorf("vault returned %d: %s", resp.StatusCode, message)
+	}
+	if len(payload.Data.Data) == 0 || string(payload.Data.Data) == "null" {
+		return "", fmt.Errorf("vault secret has no data; is %s a KV v2 mount?", mount)
+	}
+	return string(payload.Data.Data), nil
+}
+
+// token returns the configured token, reading the token file if one is set so
+// that renewed tokens are picked up.
+func (p *VaultProvider) token() (string, error) {
+	if p.cfg.TokenFile != "" {
+		data, err := LoadSecretFromFile(p.cfg.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("reading vault token file: %w", err)
+		}
+		return strings.TrimSpace(data), nil
+	}
+	if p.cfg.Token == "" {
+		return "", fmt.Errorf("secrets.vault.token or secrets.vault.token_file is not set")
+	}
+	return p.cfg.Token, nil
+}
+
+// escapePath escapes each segment of a slash-separated path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
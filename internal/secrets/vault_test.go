@@ -0,0 +1,80 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "hvs.test" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.Header.Get("X-Vault-Namespace") != "platform" {
+			t.Errorf("expected the namespace header, got %q", r.Header.Get("X-Vault-Namespace"))
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/github-sync/prod":
+			_, _ = w.Write([]byte(`{"data":{"data":{"token":"ghp_test"},"metadata":{"version":3}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := newVaultServer(t)
+	r := NewResolver(config.SecretsConfig{Vault: config.VaultConfig{Address: server.URL + "/", Token: "hvs.test", Namespace: "platform"}})
+
+	value, err := r.Resolve(context.Background(), "vault://secret/github-sync/prod#token")
+	if err != nil || value != "ghp_test" {
+		t.Fatalf("expected the token field, got %q, %v", value, err)
+	}
+	value, err = r.Resolve(context.Background(), "vault://secret/github-sync/prod")
+	if err != nil || value != `{"token":"ghp_test"}` {
+		t.Fatalf("expected the secret data as JSON, got %q, %v", value, err)
+	}
+
+	_, err = r.Resolve(context.Background(), "vault://secret/github-sync/staging#token")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a not-found error, got %v", err)
+	}
+	_, err = r.Resolve(context.Background(), "vault://secret#token")
+	if err == nil {
+		t.Fatalf("expected an error for a path without a mount")
+	}
+}
+
+func TestVaultProviderTokenFile(t *testing.T) {
+	server := newVaultServer(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("hvs.expired\n"), 0o600); err != nil {
+		t.Fatalf("writing token file: %v", err)
+	}
+	provider := NewVaultProvider(config.VaultConfig{Address: server.URL, TokenFile: tokenFile, Namespace: "platform"})
+
+	_, err := provider.Get(context.Background(), "secret/github-sync/prod")
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected Vault's error message, got %v", err)
+	}
+	// Vault Agent renews the token in place.
+	if err := os.WriteFile(tokenFile, []byte("hvs.test\n"), 0o600); err != nil {
+		t.Fatalf("writing token file: %v", err)
+	}
+	if _, err := provider.Get(context.Background(), "secret/github-sync/prod"); err != nil {
+		t.Fatalf("expected the renewed token to be read, got %v", err)
+	}
+}
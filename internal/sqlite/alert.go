@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// GetAlertState returns the org's alert state, or nil if none was saved.
+func (s *Store) GetAlertState(ctx context.Context, org string) (*models.AlertState, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT state FROM alert_states WHERE org = ?`, org).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting alert state: %w", err)
+	}
+	var state models.AlertState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("decoding alert state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveAlertState replaces the org's alert state.
+func (s *Store) SaveAlertState(ctx context.Context, state models.AlertState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding alert state: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO alert_states (org, state, updated_at) VALUES (?, ?, ?)`,
+		state.Org, string(data), formatTime(s.now())); err != nil {
+		return fmt.Errorf("saving alert state: %w", err)
+	}
+	return nil
+}
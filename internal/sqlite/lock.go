@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// AcquireLease takes the named lease with an upsert that only overwrites an
+// expired lease or one owner already holds. expires_at is in Unix milliseconds.
+func (s *Store) AcquireLease(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error) {
+	now := s.now()
+	expires := now.Add(ttl).UnixMilli()
+	result, err := s.db.ExecContext(ctx, `INSERT INTO run_leases (name, owner, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE run_leases.expires_at < ? OR run_leases.owner = excluded.owner`,
+		name, owner, expires, now.UnixMilli())
+	if err != nil {
+		return nil, false, fmt.Errorf("acquiring lease: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, false, fmt.Errorf("acquiring lease: %w", err)
+	} else if n > 0 {
+		return &models.RunLease{Name: name, Owner: owner, ExpiresAt: time.UnixMilli(expires).UTC()}, true, nil
+	}
+
+	holder := models.RunLease{Name: name}
+	var holderExpires int64
+	err = s.db.QueryRowContext(ctx, `SELECT owner, expires_at FROM run_leases WHERE name = ?`, name).Scan(&holder.Owner, &holderExpires)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("getting lease: %w", err)
+	}
+	holder.ExpiresAt = time.UnixMilli(holderExpires).UTC()
+	return &holder, false, nil
+}
+
+// RenewLease extends the lease if owner still holds it.
+func (s *Store) RenewLease(ctx context.Context, name string, owner string, ttl time.Duration) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE run_leases SET expires_at = ? WHERE name = ? AND owner = ?`,
+		s.now().Add(ttl).UnixMilli(), name, owner)
+	if err != nil {
+		return fmt.Errorf("renewing lease: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("renewing lease: %w", err)
+	} else if n == 0 {
+		return models.ErrLeaseLost
+	}
+	return nil
+}
+
+// ReleaseLease deletes the lease if owner still holds it.
+func (s *Store) ReleaseLease(ctx context.Context, name string, owner string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM run_leases WHERE name = ? AND owner = ?`, name, owner); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+// Package sqlite implements InvitationStore on an embedded SQLite database,
+// for running reconciliation without AWS (CLI on a VM, CI).
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// schema mirrors the DynamoDB single-table layout: mappings keep their pk/sk and
+// GSI key attributes, and the two GSIs become indexes.
+const schema = `
+CREATE TABLE IF NOT EXISTS invitation_mappings (
+	pk           TEXT NOT NULL,
+	sk           TEXT NOT NULL,
+	email        TEXT NOT NULL,
+	github_login TEXT,
+	status       TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	invited_at   TEXT NOT NULL,
+	resolved_at  TEXT,
+	ttl          INTEGER NOT NULL DEFAULT 0,
+	gsi1pk       TEXT NOT NULL,
+	gsi1sk       TEXT NOT NULL,
+	gsi2pk       TEXT NOT NULL,
+	gsi2sk       TEXT NOT NULL,
+	PRIMARY KEY (pk, sk)
+);
+CREATE INDEX IF NOT EXISTS email_index ON invitation_mappings (gsi1pk, gsi1sk);
+CREATE INDEX IF NOT EXISTS status_index ON invitation_mappings (gsi2pk, gsi2sk);
+
+CREATE TABLE IF NOT EXISTS audit_log_cursors (
+	pk             TEXT NOT NULL,
+	sk             TEXT NOT NULL,
+	last_timestamp INTEGER NOT NULL,
+	last_run       TEXT NOT NULL,
+	PRIMARY KEY (pk, sk)
+);
+`
+
+// migrations upgrade databases created by older versions, in order.
+// PRAGMA user_version records how many have been applied.
+var migrations = []string{
+	`ALTER TABLE invitation_mappings ADD COLUMN manual INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE invitation_mappings ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS run_leases (
+		name       TEXT PRIMARY KEY,
+		owner      TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS alert_states (
+		org        TEXT PRIMARY KEY,
+		state      TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	)`,
+}
+
+const mappingColumns = `pk, sk, email, github_login, status, role, invited_at, resolved_at, ttl, manual, version, gsi1pk, gsi1sk, gsi2pk, gsi2sk`
+
+// Store implements the InvitationStore interface using SQLite.
+type Store struct {
+	db      *sql.DB
+	ttlDays int
+	now     func() time.Time
+}
+
+// NewStore opens (or creates) the SQLite database and applies the schema.
+// Mappings whose TTL has passed are purged on open, as DynamoDB TTL would.
+func NewStore(ctx context.Context, cfg config.SQLiteConfig) (*Store, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("sqlite path is required")
+	}
+	if dir := filepath.Dir(cfg.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("creating sqlite directory: %w", err)
+		}
+	}
+
+	dsn := "file:" + cfg.Path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+	// A single connection serializes writers and avoids SQLITE_BUSY between them.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying sqlite schema: %w", err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating sqlite schema: %w", err)
+	}
+
+	ttlDays := cfg.TTLDays
+	if ttlDays <= 0 {
+		ttlDays = 90
+	}
+	s := &Store{db: db, ttlDays: ttlDays, now: time.Now}
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM invitation_mappings WHERE ttl > 0 AND ttl < ?`, s.now().Unix()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("purging expired mappings: %w", err)
+	}
+	return s, nil
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveInvitation stores a mapping, replacing any existing one with the same key.
+func (s *Store) SaveInvitation(ctx context.Context, mapping models.InvitationMapping) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO invitation_mappings (`+mappingColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		mapping.PK, mapping.SK, mapping.Email, nullString(mapping.GitHubLogin), string(mapping.Status), string(mapping.Role),
+		formatTime(mapping.InvitedAt), nullTime(mapping.ResolvedAt), mapping.TTL, mapping.Manual, mapping.Version,
+		mapping.GSI1PK, mapping.GSI1SK, mapping.GSI2PK, mapping.GSI2SK)
+	if err != nil {
+		return fmt.Errorf("saving invitation: %w", err)
+	}
+	return nil
+}
+
+// CreateMapping stores mapping only if no mapping with its key exists.
+func (s *Store) CreateMapping(ctx context.Context, mapping models.InvitationMapping) error {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO invitation_mappings (`+mappingColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (pk, sk) DO NOTHING`,
+		mapping.PK, mapping.SK, mapping.Email, nullString(mapping.GitHubLogin), string(mapping.Status), string(mapping.Role),
+		formatTime(mapping.InvitedAt), nullTime(mapping.ResolvedAt), mapping.TTL, mapping.Manual, mapping.Version,
+		mapping.GSI1PK, mapping.GSI1SK, mapping.GSI2PK, mapping.GSI2SK)
+	if err == nil {
+		var n int64
+		if n, err = result.RowsAffected(); err == nil && n == 0 {
+			var current *models.InvitationMapping
+			if current, err = s.getMapping(ctx, mapping.PK, mapping.SK); err == nil {
+				err = models.ExistingMapping(mapping, current)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("creating mapping: %w", err)
+	}
+	return nil
+}
+
+// GetInvitation retrieves an invitation by org and invitation ID.
+func (s *Store) GetInvitation(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error) {
+	mapping, err := s.getMapping(ctx, "ORG#"+org, fmt.Sprintf("INV#%d", invitationID))
+	if err != nil {
+		return nil, fmt.Errorf("getting invitation: %w", err)
+	}
+	return mapping, nil
+}
+
+// getMapping reads one mapping by key, returning nil if it does not exist.
+func (s *Store) getMapping(ctx context.Context, pk string, sk string) (*models.InvitationMapping, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+mappingColumns+` FROM invitation_mappings WHERE pk = ? AND sk = ?`, pk, sk)
+	mapping, err := scanMapping(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return mapping, err
+}
+
+// GetPendingInvitations returns all pending invitations for an org using the status index.
+func (s *Store) GetPendingInvitations(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+	mappings, err := s.queryMappings(ctx, `gsi2pk = ? AND gsi2sk = ?`, "ORG#"+org, "STATUS#"+string(models.InvitationPending))
+	if err != nil {
+		return nil, fmt.Errorf("querying pending invitations: %w", err)
+	}
+	return mappings, nil
+}
+
+// ResolveInvitation updates a pending invitation with the resolved GitHub username.
+func (s *Store) ResolveInvitation(ctx context.Context, org string, invitationID int64, githubLogin string) error {
+	now := s.now().UTC()
+	ttl := now.AddDate(0, 0, s.ttlDays).Unix()
+	err := s.updateStatus(ctx, org, fmt.Sprintf("INV#%d", invitationID), models.InvitationResolved,
+		`, github_login = ?, resolved_at = ?, ttl = ?`, githubLogin, formatTime(now), ttl)
+	if err != nil {
+		return fmt.Errorf("resolving invitation: %w", err)
+	}
+	return nil
+}
+
+// UpdateStatus changes the status of an invitation (failed, expired, cancelled, removed).
+func (s *Store) UpdateStatus(ctx context.Context, org string, invitationID int64, status models.InvitationStatus) error {
+	if err := s.updateStatus(ctx, org, fmt.Sprintf("INV#%d", invitationID), status, ""); err != nil {
+		return fmt.Errorf("updating invitation status: %w", err)
+	}
+	return nil
+}
+
+// updateStatus moves mapping sk to status if its current status may reach status,
+// also applying the assignments in set with args.
+func (s *Store) updateStatus(ctx context.Context, org string, sk string, status models.InvitationStatus, set string, args ...any) error {
+	sources := models.TransitionSources(status)
+	if len(sources) == 0 {
+		return &models.TransitionError{SK: sk, To: status, Err: models.ErrInvalidTransition}
+	}
+	args = append([]any{string(status), "STATUS#" + string(status)}, args...)
+	args = append(args, "ORG#"+org, sk)
+	for _, from := range sources {
+		args = append(args, string(from))
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE invitation_mappings SET status = ?, gsi2sk = ?, version = version + 1`+set+`
+		WHERE pk = ? AND sk = ? AND status IN (?`+strings.Repeat(", ?", len(sources)-1)+`)`, args...)
+	if err != nil {
+		return err
+	}
+	return s.checkUpdated(ctx, result, "ORG#"+org, sk, status)
+}
+
+// TransitionMapping moves a mapping of any kind to status, provided it still has
+// the status and version it was read with.
+func (s *Store) TransitionMapping(ctx context.Context, mapping models.InvitationMapping, status models.InvitationStatus) error {
+	if !mapping.Status.CanTransitionTo(status) {
+		return &models.TransitionError{SK: mapping.SK, From: mapping.Status, To: status, Err: models.ErrInvalidTransition}
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE invitation_mappings SET status = ?, gsi2sk = ?, version = version + 1
+		WHERE pk = ? AND sk = ? AND status = ? AND version = ?`,
+		string(status), "STATUS#"+string(status), mapping.PK, mapping.SK, string(mapping.Status), mapping.Version)
+	if err == nil {
+		err = s.checkUpdated(ctx, result, mapping.PK, mapping.SK, status)
+	}
+	if err != nil {
+		return fmt.Errorf("updating mapping status: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole updates the role of an existing invitation mapping.
+func (s *Store) UpdateRole(ctx context.Context, org string, invitationID int64, role models.OrgRole) error {
+	pk, sk := "ORG#"+org, fmt.Sprintf("INV#%d", invitationID)
+	result, err := s.db.ExecContext(ctx, `UPDATE invitation_mappings SET role = ?, version = version + 1 WHERE pk = ? AND sk = ?`,
+		string(role), pk, sk)
+	if err == nil {
+		err = s.checkUpdated(ctx, result, pk, sk, "")
+	}
+	if err != nil {
+		return fmt.Errorf("updating invitation role: %w", err)
+	}
+	return nil
+}
+
+// checkUpdated returns a *models.TransitionError explaining why a conditional
+// update matched no row.
+func (s *Store) checkUpdated(ctx context.Context, result sql.Result, pk string, sk string, status models.InvitationStatus) error {
+	n, err := result.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+	current, err := s.getMapping(ctx, pk, sk)
+	if err != nil {
+		return fmt.Errorf("reading mapping after rejected update: %w", err)
+	}
+	return models.RejectedTransition(sk, current, status)
+}
+
+// GetByEmail retrieves invitation mappings for a specific email using the email index.
+func (s *Store) GetByEmail(ctx context.Context, email string, org string) ([]models.InvitationMapping, error) {
+	mappings, err := s.queryMappings(ctx, `gsi1pk = ? AND gsi1sk = ?`, "EMAIL#"+email, "ORG#"+org)
+	if err != nil {
+		return nil, fmt.Errorf("querying by email: %w", err)
+	}
+	return mappings, nil
+}
+
+// GetAuditLogCursor retrieves the last processed audit log cursor.
+func (s *Store) GetAuditLogCursor(ctx context.Context, org string) (*models.AuditLogCursor, error) {
+	var cursor models.AuditLogCursor
+	var lastRun string
+	err := s.db.QueryRowContext(ctx, `SELECT pk, sk, last_timestamp, last_run FROM audit_log_cursors WHERE pk = ? AND sk = ?`,
+		"ORG#"+org, "CURSOR#audit_log").Scan(&cursor.PK, &cursor.SK, &cursor.LastTimestamp, &lastRun)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting audit log cursor: %w", err)
+	}
+	if cursor.LastRun, err = parseTime(lastRun); err != nil {
+		return nil, fmt.Errorf("parsing audit log cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+// SaveAuditLogCursor stores the audit log cursor.
+func (s *Store) SaveAuditLogCursor(ctx context.Context, cursor models.AuditLogCursor) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO audit_log_cursors (pk, sk, last_timestamp, last_run) VALUES (?, ?, ?, ?)`,
+		cursor.PK, cursor.SK, cursor.LastTimestamp, formatTime(cursor.LastRun))
+	if err != nil {
+		return fmt.Errorf("saving cursor: %w", err)
+	}
+	return nil
+}
+
+// GetAllResolvedMappings returns all resolved email→username mappings for an org.
+func (s *Store) GetAllResolvedMappings(ctx context.Context, org string) (map[string]string, error) {
+	mappings, err := s.queryMappings(ctx, `gsi2pk = ? AND gsi2sk = ?`, "ORG#"+org, "STATUS#"+string(models.InvitationResolved))
+	if err != nil {
+		return nil, fmt.Errorf("querying resolved mappings: %w", err)
+	}
+	return models.ResolvedLogins(mappings), nil
+}
+
+// ListMappings returns every invitation mapping for an org, in any status.
+func (s *Store) ListMappings(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+	mappings, err := s.queryMappings(ctx, `pk = ?`, "ORG#"+org)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+// DeleteMapping removes an invitation mapping by org and sort key.
+func (s *Store) DeleteMapping(ctx context.Context, org string, sk string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM invitation_mappings WHERE pk = ? AND sk = ?`, "ORG#"+org, sk); err != nil {
+		return fmt.Errorf("deleting mapping: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) queryMappings(ctx context.Context, where string, args ...any) ([]models.InvitationMapping, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+mappingColumns+` FROM invitation_mappings WHERE `+where+` ORDER BY pk, sk`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []models.InvitationMapping
+	for rows.Next() {
+		mapping, err := scanMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, *mapping)
+	}
+	return mappings, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMapping(row scanner) (*models.InvitationMapping, error) {
+	var m models.InvitationMapping
+	var githubLogin, resolvedAt sql.NullString
+	var status, role, invitedAt string
+	if err := row.Scan(&m.PK, &m.SK, &m.Email, &githubLogin, &status, &role, &invitedAt, &resolvedAt, &m.TTL, &m.Manual, &m.Version,
+		&m.GSI1PK, &m.GSI1SK, &m.GSI2PK, &m.GSI2SK); err != nil {
+		return nil, err
+	}
+	m.Status = models.InvitationStatus(status)
+	m.Role = models.OrgRole(role)
+	if githubLogin.Valid {
+		login := githubLogin.String
+		m.GitHubLogin = &login
+	}
+	var err error
+	if m.InvitedAt, err = parseTime(invitedAt); err != nil {
+		return nil, fmt.Errorf("parsing invited_at: %w", err)
+	}
+	if resolvedAt.Valid {
+		t, err := parseTime(resolvedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing resolved_at: %w", err)
+		}
+		m.ResolvedAt = &t
+	}
+	return &m, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatTime(*t), Valid: true}
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/storetest"
+)
+
+func newTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+	store, err := NewStore(context.Background(), config.SQLiteConfig{Path: path, TTLDays: 90})
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStoreBehavior(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) interfaces.InvitationStore {
+		return newTestStore(t, filepath.Join(t.TempDir(), "sync.db"))
+	})
+}
+
+func TestStorePersistsAcrossOpens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "sync.db")
+	ctx := context.Background()
+
+	store := newTestStore(t, path)
+	if err := store.SaveInvitation(ctx, models.NewInvitationMapping("org", 1, "a@example.com", models.RoleMember, 90)); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+	store.Close()
+
+	reopened := newTestStore(t, path)
+	got, err := reopened.GetInvitation(ctx, "org", 1)
+	if err != nil || got == nil || got.Email != "a@example.com" {
+		t.Fatalf("expected mapping to persist, got %+v, %v", got, err)
+	}
+}
+
+func TestStorePurgesExpiredMappings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.db")
+	ctx := context.Background()
+
+	store := newTestStore(t, path)
+	expired := models.NewInvitationMapping("org", 1, "old@example.com", models.RoleMember, 90)
+	expired.TTL = time.Now().Add(-time.Hour).Unix()
+	_ = store.SaveInvitation(ctx, expired)
+	_ = store.SaveInvitation(ctx, models.NewInvitationMapping("org", 2, "new@example.com", models.RoleMember, 90))
+	store.Close()
+
+	reopened := newTestStore(t, path)
+	pending, err := reopened.GetPendingInvitations(ctx, "org")
+	if err != nil || len(pending) != 1 || pending[0].Email != "new@example.com" {
+		t.Fatalf("expected only the unexpired mapping, got %+v, %v", pending, err)
+	}
+}
+
+func TestStoreUpgradesOlderDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.db")
+	ctx := context.Background()
+
+	// A database created before the manual column existed.
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("creating old schema: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO invitation_mappings (pk, sk, email, status, role, invited_at, gsi1pk, gsi1sk, gsi2pk, gsi2sk)
+		VALUES ('ORG#org', 'INV#1', 'a@example.com', 'pending', 'member', '2025-01-01T00:00:00Z', 'EMAIL#a@example.com', 'ORG#org', 'ORG#org', 'STATUS#pending')`); err != nil {
+		t.Fatalf("inserting old row: %v", err)
+	}
+	db.Close()
+
+	store := newTestStore(t, path)
+	got, err := store.GetInvitation(ctx, "org", 1)
+	if err != nil || got == nil || got.Manual {
+		t.Fatalf("expected the old row to read back as automatic, got %+v, %v", got, err)
+	}
+	if err := store.SaveInvitation(ctx, models.NewManualMapping("org", "b@example.com", "b-login", models.RoleMember)); err != nil {
+		t.Fatalf("SaveInvitation after upgrade: %v", err)
+	}
+	store.Close()
+
+	// Reopening must not re-run applied migrations.
+	newTestStore(t, path)
+}
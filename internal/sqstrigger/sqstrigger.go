@@ -0,0 +1,93 @@
+// Package sqstrigger handles sync runs requested through SQS: each message names
+// a user or a Google group to sync, and messages whose run fails are reported as
+// batch item failures so that SQS retries only those.
+package sqstrigger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Message is the body of a sync request message. Exactly one of User and Group
+// is set.
+type Message struct {
+	User   string `json:"user,omitempty"`    // Google email or GitHub login
+	Group  string `json:"group,omitempty"`   // Google group whose members are synced
+	DryRun *bool  `json:"dry_run,omitempty"` // Overrides the configured dry-run setting
+}
+
+// IsEvent reports whether payload is an SQS batch event.
+func IsEvent(payload json.RawMessage) bool {
+	var probe struct {
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return false
+	}
+	return len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:sqs"
+}
+
+// ParseMessage decodes and checks a message body.
+func ParseMessage(body string) (Message, error) {
+	var msg Message
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&msg); err != nil {
+		return Message{}, fmt.Errorf("decoding message: %w", err)
+	}
+	msg.User = strings.TrimSpace(msg.User)
+	msg.Group = strings.TrimSpace(msg.Group)
+	if (msg.User == "") == (msg.Group == "") {
+		return Message{}, fmt.Errorf("message must name exactly one of user or group")
+	}
+	return msg, nil
+}
+
+// Apply sets the run settings of msg on cfg.
+func (m Message) Apply(cfg *config.Config) {
+	if m.DryRun != nil {
+		cfg.Sync.DryRun = *m.DryRun
+	}
+	cfg.Sync.OnlyUsers = nil
+	cfg.Sync.OnlyGroups = nil
+	if m.User != "" {
+		cfg.Sync.OnlyUsers = []string{m.User}
+	}
+	if m.Group != "" {
+		cfg.Sync.OnlyGroups = []string{m.Group}
+	}
+}
+
+// Process calls run for each message of event in order and returns the messages
+// that could not be decoded or whose run failed. Messages not started before
+// ctx ends are reported as failed too.
+func Process(ctx context.Context, event events.SQSEvent, run func(ctx context.Context, msg Message) error) events.SQSEventResponse {
+	var resp events.SQSEventResponse
+	for _, record := range event.Records {
+		fields := logrus.Fields{"message_id": record.MessageId, "receive_count": record.Attributes["ApproximateReceiveCount"]}
+		if err := ctx.Err(); err != nil {
+			logrus.WithContext(ctx).WithFields(fields).Warn("⚠ SQS sync request not started before the deadline — it will be retried")
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+			continue
+		}
+		msg, err := ParseMessage(record.Body)
+		if err == nil {
+			fields["user"], fields["group"] = msg.User, msg.Group
+			logrus.WithContext(ctx).WithFields(fields).Info("📨 Sync requested through SQS")
+			err = run(ctx, msg)
+		}
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).WithFields(fields).Warn("⚠ SQS sync request failed — it will be retried")
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: record.MessageId})
+		}
+	}
+	return resp
+}
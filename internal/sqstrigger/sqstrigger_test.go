@@ -0,0 +1,96 @@
+package sqstrigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+)
+
+func TestParseMessage(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		want    Message
+		wantErr bool
+	}{
+		{name: "user", body: `{"user":" jane@example.com "}`, want: Message{User: "jane@example.com"}},
+		{name: "group", body: `{"group":"new-hires@example.com"}`, want: Message{Group: "new-hires@example.com"}},
+		{name: "both", body: `{"user":"jane@example.com","group":"new-hires@example.com"}`, wantErr: true},
+		{name: "neither", body: `{"dry_run":true}`, wantErr: true},
+		{name: "unknown field", body: `{"email":"jane@example.com"}`, wantErr: true},
+		{name: "not json", body: `jane@example.com`, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseMessage(tc.body)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if err == nil && (got.User != tc.want.User || got.Group != tc.want.Group) {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	dryRun := false
+	cfg := &config.Config{Sync: config.SyncConfig{DryRun: true, OnlyUsers: []string{"stale@example.com"}}}
+	Message{Group: "new-hires@example.com", DryRun: &dryRun}.Apply(cfg)
+	if cfg.Sync.DryRun || cfg.Sync.OnlyUsers != nil || len(cfg.Sync.OnlyGroups) != 1 {
+		t.Fatalf("expected a real run limited to the group, got %+v", cfg.Sync)
+	}
+}
+
+func TestIsEvent(t *testing.T) {
+	sqsEvent, _ := json.Marshal(events.SQSEvent{Records: []events.SQSMessage{{MessageId: "1", EventSource: "aws:sqs"}}})
+	if !IsEvent(sqsEvent) {
+		t.Fatalf("expected an SQS event to be recognized")
+	}
+	for _, payload := range []string{`{"source":"aws.events","detail-type":"Scheduled Event"}`, `{"Records":[{"eventSource":"aws:sns"}]}`, `{"Records":[]}`} {
+		if IsEvent(json.RawMessage(payload)) {
+			t.Errorf("expected %s not to be an SQS event", payload)
+		}
+	}
+}
+
+func TestProcessReportsFailedMessages(t *testing.T) {
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "ok", Body: `{"user":"jane@example.com"}`},
+		{MessageId: "run-failed", Body: `{"group":"new-hires@example.com"}`},
+		{MessageId: "invalid", Body: `{}`},
+	}}
+	var ran []Message
+	resp := Process(context.Background(), event, func(ctx context.Context, msg Message) error {
+		ran = append(ran, msg)
+		if msg.Group != "" {
+			return errors.New("1 of 1 actions failed")
+		}
+		return nil
+	})
+	if len(ran) != 2 {
+		t.Fatalf("expected a run per valid message, got %+v", ran)
+	}
+	if len(resp.BatchItemFailures) != 2 || resp.BatchItemFailures[0].ItemIdentifier != "run-failed" || resp.BatchItemFailures[1].ItemIdentifier != "invalid" {
+		t.Fatalf("expected the failed and invalid messages, got %+v", resp.BatchItemFailures)
+	}
+}
+
+func TestProcessSkipsMessagesAfterDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	event := events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "first", Body: `{"user":"jane@example.com"}`},
+		{MessageId: "second", Body: `{"user":"john@example.com"}`},
+	}}
+	resp := Process(ctx, event, func(ctx context.Context, msg Message) error {
+		cancel()
+		return nil
+	})
+	if len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "second" {
+		t.Fatalf("expected the message not started to be retried, got %+v", resp.BatchItemFailures)
+	}
+}
@@ -0,0 +1,244 @@
+// Package storeio exports, imports and migrates invitation store data as JSON Lines.
+package storeio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// Record types in the export format.
+const (
+	RecordInvitation     = "invitation"
+	RecordAuditLogCursor = "audit_log_cursor"
+)
+
+// Record is one line of an export: either an invitation mapping or an audit log cursor.
+type Record struct {
+	Type       string                    `json:"type"`
+	Invitation *models.InvitationMapping `json:"invitation,omitempty"`
+	Cursor     *models.AuditLogCursor    `json:"cursor,omitempty"`
+}
+
+// Result summarizes an export, import or migration.
+type Result struct {
+	Invitations int  `json:"invitations"`
+	Cursors     int  `json:"cursors"`
+	Created     int  `json:"created"`
+	Updated     int  `json:"updated"`
+	Unchanged   int  `json:"unchanged"`
+	DryRun      bool `json:"dry_run"`
+}
+
+// Export writes every invitation mapping and the audit log cursor for org to w.
+func Export(ctx context.Context, store interfaces.InvitationStore, org string, w io.Writer) (Result, error) {
+	records, err := read(ctx, store, org)
+	if err != nil {
+		return Result{}, err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var result Result
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return result, fmt.Errorf("encoding %s record: %w", record.Type, err)
+		}
+		result.count(record)
+	}
+	if err := bw.Flush(); err != nil {
+		return result, fmt.Errorf("writing export: %w", err)
+	}
+	return result, nil
+}
+
+// Import reads an export from r and upserts its records into store. Every
+// record must belong to org. The whole input is validated before anything is
+// written; with dryRun nothing is written and the result reports what would change.
+func Import(ctx context.Context, store interfaces.InvitationStore, org string, r io.Reader, dryRun bool) (Result, error) {
+	records, err := decode(r)
+	if err != nil {
+		return Result{}, err
+	}
+	for i, record := range records {
+		if pk := record.pk(); pk != "ORG#"+org {
+			return Result{}, fmt.Errorf("record %d: belongs to %q, expected org %q", i+1, pk, org)
+		}
+	}
+	return apply(ctx, store, org, records, dryRun)
+}
+
+// Migrate copies every invitation mapping and the audit log cursor for org
+// from one store to another, upserting into the destination.
+func Migrate(ctx context.Context, from, to interfaces.InvitationStore, org string, dryRun bool) (Result, error) {
+	records, err := read(ctx, from, org)
+	if err != nil {
+		return Result{}, fmt.Errorf("reading source store: %w", err)
+	}
+	return apply(ctx, to, org, records, dryRun)
+}
+
+func read(ctx context.Context, store interfaces.InvitationStore, org string) ([]Record, error) {
+	mappings, err := store.ListMappings(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(mappings)+1)
+	for i := range mappings {
+		records = append(records, Record{Type: RecordInvitation, Invitation: &mappings[i]})
+	}
+	cursor, err := store.GetAuditLogCursor(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		records = append(records, Record{Type: RecordAuditLogCursor, Cursor: cursor})
+	}
+	return records, nil
+}
+
+func decode(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := record.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading import: %w", err)
+	}
+	return records, nil
+}
+
+// apply upserts records into store, classifying each against the current contents.
+func apply(ctx context.Context, store interfaces.InvitationStore, org string, records []Record, dryRun bool) (Result, error) {
+	mappings, err := store.ListMappings(ctx, org)
+	if err != nil {
+		return Result{}, fmt.Errorf("reading destination store: %w", err)
+	}
+	existing := make(map[string]models.InvitationMapping, len(mappings))
+	for _, m := range mappings {
+		existing[m.SK] = m
+	}
+	cursor, err := store.GetAuditLogCursor(ctx, org)
+	if err != nil {
+		return Result{}, fmt.Errorf("reading destination store: %w", err)
+	}
+
+	result := Result{DryRun: dryRun}
+	for _, record := range records {
+		result.count(record)
+		switch record.Type {
+		case RecordInvitation:
+			current, ok := existing[record.Invitation.SK]
+			switch {
+			case !ok:
+				result.Created++
+			case sameMapping(current, *record.Invitation):
+				result.Unchanged++
+				continue
+			default:
+				result.Updated++
+			}
+			if dryRun {
+				continue
+			}
+			if err := store.SaveInvitation(ctx, *record.Invitation); err != nil {
+				return result, fmt.Errorf("importing %s: %w", record.Invitation.SK, err)
+			}
+		case RecordAuditLogCursor:
+			switch {
+			case cursor == nil:
+				result.Created++
+			case cursor.LastTimestamp == record.Cursor.LastTimestamp:
+				result.Unchanged++
+				continue
+			default:
+				result.Updated++
+			}
+			if dryRun {
+				continue
+			}
+			if err := store.SaveAuditLogCursor(ctx, *record.Cursor); err != nil {
+				return result, fmt.Errorf("importing audit log cursor: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// sameMapping compares the stored fields of two mappings. Timestamps are compared
+// at millisecond precision because backends store them with different resolutions.
+func sameMapping(a, b models.InvitationMapping) bool {
+	return a.PK == b.PK && a.SK == b.SK && a.Email == b.Email &&
+		stringValue(a.GitHubLogin) == stringValue(b.GitHubLogin) &&
+		a.Status == b.Status && a.Role == b.Role &&
+		sameTime(a.InvitedAt, b.InvitedAt) && sameTimePtr(a.ResolvedAt, b.ResolvedAt) &&
+		a.TTL == b.TTL && a.Manual == b.Manual && a.Version == b.Version
+}
+
+func sameTime(a, b time.Time) bool {
+	return a.Truncate(time.Millisecond).Equal(b.Truncate(time.Millisecond))
+}
+
+func sameTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return sameTime(*a, *b)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (r Record) validate() error {
+	switch r.Type {
+	case RecordInvitation:
+		if r.Invitation == nil || r.Invitation.SK == "" {
+			return fmt.Errorf("invitation record without an invitation")
+		}
+	case RecordAuditLogCursor:
+		if r.Cursor == nil || r.Cursor.SK == "" {
+			return fmt.Errorf("audit_log_cursor record without a cursor")
+		}
+	default:
+		return fmt.Errorf("unknown record type %q", r.Type)
+	}
+	return nil
+}
+
+func (r Record) pk() string {
+	if r.Invitation != nil {
+		return r.Invitation.PK
+	}
+	return r.Cursor.PK
+}
+
+func (r *Result) count(record Record) {
+	if record.Type == RecordInvitation {
+		r.Invitations++
+	} else {
+		r.Cursors++
+	}
+}
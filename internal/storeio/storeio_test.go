@@ -0,0 +1,158 @@
+package storeio
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/sqlite"
+)
+
+func newStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	store, err := sqlite.NewStore(context.Background(), config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "sync.db"), TTLDays: 90})
+	if err != nil {
+		t.Fatalf("creating store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func seed(t *testing.T, store *sqlite.Store) {
+	t.Helper()
+	ctx := context.Background()
+	for i, email := range []string{"a@example.com", "b@example.com"} {
+		if err := store.SaveInvitation(ctx, models.NewInvitationMapping("org", int64(i+1), email, models.RoleMember, 90)); err != nil {
+			t.Fatalf("SaveInvitation: %v", err)
+		}
+	}
+	if err := store.ResolveInvitation(ctx, "org", 2, "b-login"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+	if err := store.SaveInvitation(ctx, models.NewInvitationMapping("other", 9, "x@example.com", models.RoleMember, 90)); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+	cursor := models.AuditLogCursor{PK: "ORG#org", SK: "CURSOR#audit_log", LastTimestamp: 42, LastRun: time.Now().UTC()}
+	if err := store.SaveAuditLogCursor(ctx, cursor); err != nil {
+		t.Fatalf("SaveAuditLogCursor: %v", err)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := newStore(t)
+	seed(t, source)
+
+	var buf bytes.Buffer
+	exported, err := Export(ctx, source, "org", &buf)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if exported.Invitations != 2 || exported.Cursors != 1 {
+		t.Fatalf("expected 2 invitations and 1 cursor, got %+v", exported)
+	}
+
+	dest := newStore(t)
+	dry, err := Import(ctx, dest, "org", bytes.NewReader(buf.Bytes()), true)
+	if err != nil {
+		t.Fatalf("Import dry run: %v", err)
+	}
+	if dry.Created != 3 || !dry.DryRun {
+		t.Fatalf("expected 3 records to create, got %+v", dry)
+	}
+	if mappings, _ := dest.ListMappings(ctx, "org"); len(mappings) != 0 {
+		t.Fatalf("dry run wrote %d mappings", len(mappings))
+	}
+
+	imported, err := Import(ctx, dest, "org", bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if imported.Created != 3 {
+		t.Fatalf("expected 3 created, got %+v", imported)
+	}
+	resolved, err := dest.GetAllResolvedMappings(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if resolved["b@example.com"] != "b-login" {
+		t.Fatalf("expected resolved mapping to survive import, got %v", resolved)
+	}
+	cursor, err := dest.GetAuditLogCursor(ctx, "org")
+	if err != nil || cursor == nil || cursor.LastTimestamp != 42 {
+		t.Fatalf("expected imported cursor, got %+v (err %v)", cursor, err)
+	}
+
+	again, err := Import(ctx, dest, "org", bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("re-Import: %v", err)
+	}
+	if again.Unchanged != 3 || again.Created != 0 || again.Updated != 0 {
+		t.Fatalf("expected re-import to be unchanged, got %+v", again)
+	}
+}
+
+func TestImportRejectsOtherOrg(t *testing.T) {
+	ctx := context.Background()
+	source := newStore(t)
+	seed(t, source)
+	var buf bytes.Buffer
+	if _, err := Export(ctx, source, "other", &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dest := newStore(t)
+	if _, err := Import(ctx, dest, "org", &buf, false); err == nil {
+		t.Fatal("expected an error importing another org's records")
+	}
+	if mappings, _ := dest.ListMappings(ctx, "other"); len(mappings) != 0 {
+		t.Fatalf("rejected import wrote %d mappings", len(mappings))
+	}
+}
+
+func TestImportRejectsMalformedInput(t *testing.T) {
+	dest := newStore(t)
+	for name, input := range map[string]string{
+		"invalid json": "{not json}\n",
+		"unknown type": `{"type":"something"}` + "\n",
+		"missing body": `{"type":"invitation"}` + "\n",
+	} {
+		if _, err := Import(context.Background(), dest, "org", strings.NewReader(input), false); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMigrateUpdatesChangedRecords(t *testing.T) {
+	ctx := context.Background()
+	source := newStore(t)
+	seed(t, source)
+	dest := newStore(t)
+
+	if _, err := Migrate(ctx, source, dest, "org", false); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if err := source.UpdateStatus(ctx, "org", 1, models.InvitationExpired); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	result, err := Migrate(ctx, source, dest, "org", false)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.Updated != 1 || result.Unchanged != 2 {
+		t.Fatalf("expected 1 updated and 2 unchanged, got %+v", result)
+	}
+	got, err := dest.GetInvitation(ctx, "org", 1)
+	if err != nil || got == nil || got.Status != models.InvitationExpired {
+		t.Fatalf("expected migrated status expired, got %+v (err %v)", got, err)
+	}
+	if mappings, _ := dest.ListMappings(ctx, "other"); len(mappings) != 0 {
+		t.Fatalf("migrate copied %d mappings from another org", len(mappings))
+	}
+}
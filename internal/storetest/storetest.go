@@ -0,0 +1,610 @@
+// Package storetest provides a behavior suite that every InvitationStore
+// implementation must pass.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// Run executes the InvitationStore behavior suite. newStore must return an
+// empty store; it is called once per subtest.
+func Run(t *testing.T, newStore func(t *testing.T) interfaces.InvitationStore) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store interfaces.InvitationStore)
+	}{
+		{"SaveAndGetInvitation", testSaveAndGetInvitation},
+		{"GetMissingInvitation", testGetMissingInvitation},
+		{"PendingInvitations", testPendingInvitations},
+		{"ResolveInvitation", testResolveInvitation},
+		{"UpdateStatus", testUpdateStatus},
+		{"UpdateRole", testUpdateRole},
+		{"GetByEmail", testGetByEmail},
+		{"ExistingMemberMapping", testExistingMemberMapping},
+		{"AuditLogCursor", testAuditLogCursor},
+		{"OrgIsolation", testOrgIsolation},
+		{"ListMappings", testListMappings},
+		{"DeleteMapping", testDeleteMapping},
+		{"ManualMappingPrecedence", testManualMappingPrecedence},
+		{"StatusTransitions", testStatusTransitions},
+		{"TransitionMapping", testTransitionMapping},
+		{"CreateMapping", testCreateMapping},
+		{"RunLock", testRunLock},
+		{"AlertState", testAlertState},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+func testSaveAndGetInvitation(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	mapping := models.NewInvitationMapping("org-a", 101, "alice@example.com", models.RoleMember, 90)
+	if err := store.SaveInvitation(ctx, mapping); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+
+	got, err := store.GetInvitation(ctx, "org-a", 101)
+	if err != nil {
+		t.Fatalf("GetInvitation: %v", err)
+	}
+	if got == nil {
+		t.Fatalf("expected invitation, got nil")
+	}
+	if got.PK != "ORG#org-a" || got.SK != "INV#101" || got.Email != "alice@example.com" {
+		t.Fatalf("unexpected keys: %+v", got)
+	}
+	if got.Status != models.InvitationPending || got.Role != models.RoleMember || got.GitHubLogin != nil {
+		t.Fatalf("unexpected state: %+v", got)
+	}
+	if got.GSI1PK != "EMAIL#alice@example.com" || got.GSI2SK != "STATUS#pending" {
+		t.Fatalf("unexpected index keys: %+v", got)
+	}
+	// Backends may store timestamps with microsecond precision.
+	if got.InvitedAt.Sub(mapping.InvitedAt).Abs() > time.Millisecond || got.TTL != mapping.TTL {
+		t.Fatalf("expected invited_at %v / ttl %d, got %v / %d", mapping.InvitedAt, mapping.TTL, got.InvitedAt, got.TTL)
+	}
+
+	// Saving again replaces the mapping.
+	mapping.Role = models.RoleOwner
+	if err := store.SaveInvitation(ctx, mapping); err != nil {
+		t.Fatalf("SaveInvitation (overwrite): %v", err)
+	}
+	got, _ = store.GetInvitation(ctx, "org-a", 101)
+	if got == nil || got.Role != models.RoleOwner {
+		t.Fatalf("expected overwritten role owner, got %+v", got)
+	}
+}
+
+func testGetMissingInvitation(t *testing.T, store interfaces.InvitationStore) {
+	got, err := store.GetInvitation(context.Background(), "org-a", 404)
+	if err != nil {
+		t.Fatalf("GetInvitation: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("expected nil for missing invitation, got %+v", got)
+	}
+}
+
+func testPendingInvitations(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 1, "one@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-a", 2, "two@example.com", models.RoleOwner, 90))
+	save(t, store, models.NewInvitationMapping("org-a", 3, "three@example.com", models.RoleMember, 90))
+	if err := store.UpdateStatus(ctx, "org-a", 3, models.InvitationExpired); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	pending, err := store.GetPendingInvitations(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("GetPendingInvitations: %v", err)
+	}
+	emails := map[string]bool{}
+	for _, m := range pending {
+		emails[m.Email] = true
+	}
+	if len(pending) != 2 || !emails["one@example.com"] || !emails["two@example.com"] {
+		t.Fatalf("expected two pending invitations, got %+v", pending)
+	}
+}
+
+func testResolveInvitation(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 7, "dev@example.com", models.RoleMember, 90))
+
+	before := time.Now().UTC().Add(-time.Second)
+	if err := store.ResolveInvitation(ctx, "org-a", 7, "dev-login"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+
+	got, err := store.GetInvitation(ctx, "org-a", 7)
+	if err != nil || got == nil {
+		t.Fatalf("GetInvitation: %v, %+v", err, got)
+	}
+	if got.Status != models.InvitationResolved || got.GSI2SK != "STATUS#resolved" {
+		t.Fatalf("expected resolved status, got %+v", got)
+	}
+	if got.GitHubLogin == nil || *got.GitHubLogin != "dev-login" {
+		t.Fatalf("expected github login dev-login, got %v", got.GitHubLogin)
+	}
+	if got.ResolvedAt == nil || got.ResolvedAt.Before(before) {
+		t.Fatalf("expected resolved_at to be set, got %v", got.ResolvedAt)
+	}
+
+	resolved, err := store.GetAllResolvedMappings(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if len(resolved) != 1 || resolved["dev@example.com"] != "dev-login" {
+		t.Fatalf("unexpected resolved mappings: %v", resolved)
+	}
+
+	pending, _ := store.GetPendingInvitations(ctx, "org-a")
+	if len(pending) != 0 {
+		t.Fatalf("expected no pending invitations after resolve, got %+v", pending)
+	}
+}
+
+func testUpdateStatus(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 8, "gone@example.com", models.RoleMember, 90))
+
+	if err := store.UpdateStatus(ctx, "org-a", 8, models.InvitationFailed); err != nil {
+		t.Fatalf("UpdateStatus(failed): %v", err)
+	}
+	got, _ := store.GetInvitation(ctx, "org-a", 8)
+	if got == nil || got.Status != models.InvitationFailed || got.GSI2SK != "STATUS#failed" || got.Version != 1 {
+		t.Fatalf("expected status failed at version 1, got %+v", got)
+	}
+
+	// Failed is final.
+	err := store.UpdateStatus(ctx, "org-a", 8, models.InvitationCancelled)
+	if !errors.Is(err, models.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	got, _ = store.GetInvitation(ctx, "org-a", 8)
+	if got == nil || got.Status != models.InvitationFailed || got.Version != 1 {
+		t.Fatalf("expected the rejected update to leave the mapping unchanged, got %+v", got)
+	}
+}
+
+func testUpdateRole(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 9, "lead@example.com", models.RoleMember, 90))
+	if err := store.UpdateRole(ctx, "org-a", 9, models.RoleOwner); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	got, _ := store.GetInvitation(ctx, "org-a", 9)
+	if got == nil || got.Role != models.RoleOwner || got.Status != models.InvitationPending {
+		t.Fatalf("expected owner role with unchanged status, got %+v", got)
+	}
+}
+
+func testGetByEmail(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 10, "multi@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-a", 11, "multi@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-b", 12, "multi@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-a", 13, "other@example.com", models.RoleMember, 90))
+
+	got, err := store.GetByEmail(ctx, "multi@example.com", "org-a")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 mappings for multi@example.com in org-a, got %+v", got)
+	}
+	for _, m := range got {
+		if m.Email != "multi@example.com" || m.PK != "ORG#org-a" {
+			t.Fatalf("unexpected mapping: %+v", m)
+		}
+	}
+
+	none, err := store.GetByEmail(ctx, "nobody@example.com", "org-a")
+	if err != nil || len(none) != 0 {
+		t.Fatalf("expected no mappings, got %+v, %v", none, err)
+	}
+}
+
+func testExistingMemberMapping(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	login := "existing-login"
+	now := time.Now().UTC().Truncate(time.Second)
+	mapping := models.InvitationMapping{
+		PK:          "ORG#org-a",
+		SK:          "EXISTING#" + login,
+		Email:       "existing@example.com",
+		GitHubLogin: &login,
+		Status:      models.InvitationResolved,
+		Role:        models.RoleMember,
+		InvitedAt:   now,
+		ResolvedAt:  &now,
+		TTL:         now.AddDate(0, 0, 90).Unix(),
+		GSI1PK:      "EMAIL#existing@example.com",
+		GSI1SK:      "ORG#org-a",
+		GSI2PK:      "ORG#org-a",
+		GSI2SK:      "STATUS#" + string(models.InvitationResolved),
+	}
+	save(t, store, mapping)
+
+	got, err := store.GetByEmail(ctx, "existing@example.com", "org-a")
+	if err != nil || len(got) != 1 || got[0].SK != "EXISTING#"+login {
+		t.Fatalf("expected EXISTING# mapping by email, got %+v, %v", got, err)
+	}
+	resolved, _ := store.GetAllResolvedMappings(ctx, "org-a")
+	if resolved["existing@example.com"] != login {
+		t.Fatalf("expected existing member in resolved mappings, got %v", resolved)
+	}
+}
+
+func testAuditLogCursor(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	got, err := store.GetAuditLogCursor(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("GetAuditLogCursor: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("expected no cursor, got %+v", got)
+	}
+
+	lastRun := time.Now().UTC().Truncate(time.Second)
+	for _, ts := range []int64{1700000000000, 1700000005000} {
+		if err := store.SaveAuditLogCursor(ctx, models.AuditLogCursor{
+			PK:            "ORG#org-a",
+			SK:            "CURSOR#audit_log",
+			LastTimestamp: ts,
+			LastRun:       lastRun,
+		}); err != nil {
+			t.Fatalf("SaveAuditLogCursor: %v", err)
+		}
+	}
+
+	got, err = store.GetAuditLogCursor(ctx, "org-a")
+	if err != nil || got == nil {
+		t.Fatalf("GetAuditLogCursor: %v, %+v", err, got)
+	}
+	if got.LastTimestamp != 1700000005000 || !got.LastRun.Equal(lastRun) {
+		t.Fatalf("unexpected cursor: %+v", got)
+	}
+
+	// Cursors are not invitations.
+	pending, _ := store.GetPendingInvitations(ctx, "org-a")
+	if len(pending) != 0 {
+		t.Fatalf("expected cursor not to appear as an invitation, got %+v", pending)
+	}
+}
+
+func testOrgIsolation(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 20, "a@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-b", 20, "b@example.com", models.RoleMember, 90))
+
+	got, _ := store.GetInvitation(ctx, "org-b", 20)
+	if got == nil || got.Email != "b@example.com" {
+		t.Fatalf("expected org-b invitation, got %+v", got)
+	}
+	pending, _ := store.GetPendingInvitations(ctx, "org-a")
+	if len(pending) != 1 || pending[0].Email != "a@example.com" {
+		t.Fatalf("expected only org-a pending invitation, got %+v", pending)
+	}
+}
+
+func testListMappings(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org-a", 30, "p@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-a", 31, "r@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org-b", 32, "other@example.com", models.RoleMember, 90))
+	if err := store.ResolveInvitation(ctx, "org-a", 31, "r-login"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+	if err := store.SaveAuditLogCursor(ctx, models.AuditLogCursor{PK: "ORG#org-a", SK: "CURSOR#audit_log", LastTimestamp: 1, LastRun: time.Now().UTC()}); err != nil {
+		t.Fatalf("SaveAuditLogCursor: %v", err)
+	}
+
+	mappings, err := store.ListMappings(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("ListMappings: %v", err)
+	}
+	sks := map[string]models.InvitationStatus{}
+	for _, m := range mappings {
+		sks[m.SK] = m.Status
+	}
+	if len(mappings) != 2 || sks["INV#30"] != models.InvitationPending || sks["INV#31"] != models.InvitationResolved {
+		t.Fatalf("expected both org-a mappings in any status and no cursor, got %+v", mappings)
+	}
+}
+
+func testDeleteMapping(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org", 40, "d@example.com", models.RoleMember, 90))
+	save(t, store, models.NewInvitationMapping("org", 41, "d@example.com", models.RoleMember, 90))
+
+	if err := store.DeleteMapping(ctx, "org", "INV#40"); err != nil {
+		t.Fatalf("DeleteMapping: %v", err)
+	}
+	if got, err := store.GetInvitation(ctx, "org", 40); err != nil || got != nil {
+		t.Fatalf("expected deleted mapping to be gone, got %+v (err %v)", got, err)
+	}
+	if got, err := store.GetByEmail(ctx, "d@example.com", "org"); err != nil || len(got) != 1 {
+		t.Fatalf("expected the other mapping to remain, got %+v (err %v)", got, err)
+	}
+	if err := store.DeleteMapping(ctx, "org", "INV#999"); err != nil {
+		t.Fatalf("DeleteMapping of a missing mapping should succeed: %v", err)
+	}
+}
+
+func testManualMappingPrecedence(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org", 50, "m@example.com", models.RoleMember, 90))
+	if err := store.ResolveInvitation(ctx, "org", 50, "auto-login"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+	save(t, store, models.NewManualMapping("org", "m@example.com", "manual-login", models.RoleMember))
+
+	resolved, err := store.GetAllResolvedMappings(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetAllResolvedMappings: %v", err)
+	}
+	if resolved["m@example.com"] != "manual-login" {
+		t.Fatalf("expected the manual mapping to win, got %q", resolved["m@example.com"])
+	}
+
+	mappings, err := store.GetByEmail(ctx, "m@example.com", "org")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	var manual *models.InvitationMapping
+	for i := range mappings {
+		if mappings[i].SK == "MANUAL#manual-login" {
+			manual = &mappings[i]
+		}
+	}
+	if manual == nil || !manual.Manual || manual.TTL != 0 || manual.Status != models.InvitationResolved {
+		t.Fatalf("expected a non-expiring manual resolved mapping, got %+v", mappings)
+	}
+}
+
+func testStatusTransitions(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewInvitationMapping("org", 60, "t@example.com", models.RoleMember, 90))
+	if err := store.ResolveInvitation(ctx, "org", 60, "t-login"); err != nil {
+		t.Fatalf("ResolveInvitation: %v", err)
+	}
+
+	// A slower run must not move the resolved mapping backwards.
+	for _, status := range []models.InvitationStatus{models.InvitationExpired, models.InvitationPending} {
+		err := store.UpdateStatus(ctx, "org", 60, status)
+		var transitionErr *models.TransitionError
+		if !errors.As(err, &transitionErr) || transitionErr.Err != models.ErrInvalidTransition {
+			t.Fatalf("UpdateStatus(%s): expected an invalid transition, got %v", status, err)
+		}
+	}
+	err := store.ResolveInvitation(ctx, "org", 60, "other-login")
+	if !errors.Is(err, models.ErrInvalidTransition) {
+		t.Fatalf("expected resolving twice to be rejected, got %v", err)
+	}
+	got, _ := store.GetInvitation(ctx, "org", 60)
+	if got == nil || got.Status != models.InvitationResolved || *got.GitHubLogin != "t-login" {
+		t.Fatalf("expected the mapping to stay resolved to t-login, got %+v", got)
+	}
+
+	if err := store.UpdateStatus(ctx, "org", 60, models.InvitationRemoved); err != nil {
+		t.Fatalf("UpdateStatus(removed): %v", err)
+	}
+
+	for name, err := range map[string]error{
+		"UpdateStatus":      store.UpdateStatus(ctx, "org", 404, models.InvitationCancelled),
+		"ResolveInvitation": store.ResolveInvitation(ctx, "org", 404, "login"),
+		"UpdateRole":        store.UpdateRole(ctx, "org", 404, models.RoleOwner),
+	} {
+		if !errors.Is(err, models.ErrMappingNotFound) {
+			t.Fatalf("%s on a missing mapping: expected ErrMappingNotFound, got %v", name, err)
+		}
+	}
+	if got, _ := store.GetInvitation(ctx, "org", 404); got != nil {
+		t.Fatalf("expected rejected updates not to create a mapping, got %+v", got)
+	}
+}
+
+func testTransitionMapping(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	save(t, store, models.NewManualMapping("org", "v@example.com", "v-login", models.RoleMember))
+	save(t, store, models.NewInvitationMapping("org", 70, "w@example.com", models.RoleMember, 90))
+
+	manual := mappingBySK(t, store, "org", "MANUAL#v-login")
+	if err := store.TransitionMapping(ctx, manual, models.InvitationRemoved); err != nil {
+		t.Fatalf("TransitionMapping: %v", err)
+	}
+	if got := mappingBySK(t, store, "org", "MANUAL#v-login"); got.Status != models.InvitationRemoved || got.Version != manual.Version+1 {
+		t.Fatalf("expected the manual mapping removed with its version incremented, got %+v", got)
+	}
+
+	// The mapping changed since it was read: the write is rejected.
+	stale, _ := store.GetInvitation(ctx, "org", 70)
+	if err := store.UpdateRole(ctx, "org", 70, models.RoleOwner); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	err := store.TransitionMapping(ctx, *stale, models.InvitationCancelled)
+	if !errors.Is(err, models.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if err := store.TransitionMapping(ctx, *stale, models.InvitationRemoved); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Fatalf("expected pending → removed to be rejected, got %v", err)
+	}
+	current, _ := store.GetInvitation(ctx, "org", 70)
+	if err := store.TransitionMapping(ctx, *current, models.InvitationCancelled); err != nil {
+		t.Fatalf("TransitionMapping with a fresh read: %v", err)
+	}
+}
+
+func testCreateMapping(t *testing.T, store interfaces.InvitationStore) {
+	ctx := context.Background()
+	login := "x-login"
+	now := time.Now().UTC().Truncate(time.Second)
+	mapping := models.InvitationMapping{
+		PK:          "ORG#org",
+		SK:          "EXISTING#" + login,
+		Email:       "x@example.com",
+		GitHubLogin: &login,
+		Status:      models.InvitationResolved,
+		Role:        models.RoleMember,
+		InvitedAt:   now,
+		ResolvedAt:  &now,
+		TTL:         now.AddDate(0, 0, 90).Unix(),
+		GSI1PK:      "EMAIL#x@example.com",
+		GSI1SK:      "ORG#org",
+		GSI2PK:      "ORG#org",
+		GSI2SK:      "STATUS#" + string(models.InvitationResolved),
+	}
+	if err := store.CreateMapping(ctx, mapping); err != nil {
+		t.Fatalf("CreateMapping: %v", err)
+	}
+	if err := store.TransitionMapping(ctx, mappingBySK(t, store, "org", mapping.SK), models.InvitationRemoved); err != nil {
+		t.Fatalf("TransitionMapping: %v", err)
+	}
+
+	// A second create neither fails silently nor brings the removed mapping back.
+	mapping.Role = models.RoleOwner
+	err := store.CreateMapping(ctx, mapping)
+	var transitionErr *models.TransitionError
+	if !errors.Is(err, models.ErrMappingExists) || !errors.As(err, &transitionErr) || transitionErr.From != models.InvitationRemoved {
+		t.Fatalf("expected ErrMappingExists from removed, got %v", err)
+	}
+	if got := mappingBySK(t, store, "org", mapping.SK); got.Status != models.InvitationRemoved || got.Role != models.RoleMember {
+		t.Fatalf("expected the removed mapping to be left unchanged, got %+v", got)
+	}
+}
+
+func mappingBySK(t *testing.T, store interfaces.InvitationStore, org string, sk string) models.InvitationMapping {
+	t.Helper()
+	mappings, err := store.ListMappings(context.Background(), org)
+	if err != nil {
+		t.Fatalf("ListMappings: %v", err)
+	}
+	for _, m := range mappings {
+		if m.SK == sk {
+			return m
+		}
+	}
+	t.Fatalf("mapping %s not found", sk)
+	return models.InvitationMapping{}
+}
+
+func save(t *testing.T, store interfaces.InvitationStore, mapping models.InvitationMapping) {
+	t.Helper()
+	if err := store.SaveInvitation(context.Background(), mapping); err != nil {
+		t.Fatalf("SaveInvitation: %v", err)
+	}
+}
+
+func testRunLock(t *testing.T, store interfaces.InvitationStore) {
+	lock, ok := store.(interfaces.RunLock)
+	if !ok {
+		t.Skip("store does not implement RunLock")
+	}
+	ctx := context.Background()
+	name := models.SyncLockName("org-a")
+
+	if _, ok, err := lock.AcquireLease(ctx, name, "owner-1", time.Minute); err != nil || !ok {
+		t.Fatalf("expected owner-1 to acquire the lease, got %v (err %v)", ok, err)
+	}
+	holder, ok, err := lock.AcquireLease(ctx, name, "owner-2", time.Minute)
+	if err != nil || ok {
+		t.Fatalf("expected owner-2 to be refused, got %v (err %v)", ok, err)
+	}
+	if holder.Owner != "owner-1" || !holder.ExpiresAt.After(time.Now()) {
+		t.Fatalf("expected the refusal to report owner-1's lease, got %+v", holder)
+	}
+	if _, ok, err := lock.AcquireLease(ctx, name, "owner-1", time.Minute); err != nil || !ok {
+		t.Fatalf("expected the holder to re-acquire its lease, got %v (err %v)", ok, err)
+	}
+	if _, ok, err := lock.AcquireLease(ctx, models.SyncLockName("org-b"), "owner-2", time.Minute); err != nil || !ok {
+		t.Fatalf("expected leases to be independent per name, got %v (err %v)", ok, err)
+	}
+
+	if err := lock.RenewLease(ctx, name, "owner-2", time.Minute); !errors.Is(err, models.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost renewing another owner's lease, got %v", err)
+	}
+	if err := lock.RenewLease(ctx, name, "owner-1", time.Minute); err != nil {
+		t.Fatalf("RenewLease: %v", err)
+	}
+
+	if err := lock.ReleaseLease(ctx, name, "owner-2"); err != nil {
+		t.Fatalf("ReleaseLease by non-holder: %v", err)
+	}
+	if _, ok, _ := lock.AcquireLease(ctx, name, "owner-2", time.Minute); ok {
+		t.Fatalf("expected a non-holder's release to leave the lease in place")
+	}
+	if err := lock.ReleaseLease(ctx, name, "owner-1"); err != nil {
+		t.Fatalf("ReleaseLease: %v", err)
+	}
+	if _, ok, err := lock.AcquireLease(ctx, name, "owner-2", time.Minute); err != nil || !ok {
+		t.Fatalf("expected the released lease to be free, got %v (err %v)", ok, err)
+	}
+
+	// An expired lease is taken over, and its former holder can no longer renew it.
+	expiring := models.SyncLockName("org-c")
+	if _, ok, err := lock.AcquireLease(ctx, expiring, "owner-1", -time.Second); err != nil || !ok {
+		t.Fatalf("AcquireLease: %v (err %v)", ok, err)
+	}
+	if _, ok, err := lock.AcquireLease(ctx, expiring, "owner-2", time.Minute); err != nil || !ok {
+		t.Fatalf("expected an expired lease to be taken over, got %v (err %v)", ok, err)
+	}
+	if err := lock.RenewLease(ctx, expiring, "owner-1", time.Minute); !errors.Is(err, models.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost after takeover, got %v", err)
+	}
+}
+
+func testAlertState(t *testing.T, store interfaces.InvitationStore) {
+	alerts, ok := store.(interfaces.AlertStateStore)
+	if !ok {
+		t.Skip("store does not implement AlertStateStore")
+	}
+	ctx := context.Background()
+	got, err := alerts.GetAlertState(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("GetAlertState: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("expected no alert state, got %+v", got)
+	}
+
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	state := models.AlertState{
+		Org:              "org-a",
+		FirstRunAt:       since,
+		OrphanCount:      4,
+		OrphanCountKnown: true,
+		OrphanGrowthRuns: 2,
+		Rules:            map[string]models.AlertRuleState{"failed_actions": {FiringSince: since, NotifiedAt: since}},
+		UpdatedAt:        since,
+	}
+	if err := alerts.SaveAlertState(ctx, state); err != nil {
+		t.Fatalf("SaveAlertState: %v", err)
+	}
+	state.Rules = nil
+	state.LastSuccessAt = since.Add(time.Hour)
+	if err := alerts.SaveAlertState(ctx, state); err != nil {
+		t.Fatalf("SaveAlertState (replace): %v", err)
+	}
+
+	got, err = alerts.GetAlertState(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("GetAlertState: %v", err)
+	}
+	if got == nil || got.OrphanCount != 4 || got.OrphanGrowthRuns != 2 || len(got.Rules) != 0 || !got.LastSuccessAt.Equal(state.LastSuccessAt) {
+		t.Fatalf("expected the replaced state, got %+v", got)
+	}
+	if other, err := alerts.GetAlertState(ctx, "org-b"); err != nil || other != nil {
+		t.Fatalf("expected alert state to be per org, got %+v (err %v)", other, err)
+	}
+}
@@ -2,16 +2,24 @@ package sync
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 	ghclient "github.com/daniloc96/google-workspace-github-sync/internal/github"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// ExecuteActions executes sync actions unless dry-run is enabled.
-func ExecuteActions(ctx context.Context, client interfaces.GitHubClient, org string, actions []models.SyncAction, dryRun bool) ([]models.SyncAction, error) {
+// ExecuteActions executes sync actions unless dry-run is enabled. It starts no
+// further action once a stop is requested or ctx is cancelled. If done is
+// not nil, it is called with each attempted action as soon as it completes, so
+// callers can record it before the next action starts.
+func ExecuteActions(ctx context.Context, client interfaces.GitHubClient, org string, actions []models.SyncAction, dryRun bool, done func(action models.SyncAction)) ([]models.SyncAction, error) {
 	for i := range actions {
 		action := &actions[i]
 		if dryRun {
@@ -19,133 +27,182 @@ func ExecuteActions(ctx context.Context, client interfaces.GitHubClient, org str
 			continue
 		}
 
-		switch action.Type {
-		case models.ActionInvite:
-			if action.TargetRole == nil {
-				errMsg := "target role is required"
-				action.Error = &errMsg
-				continue
-			}
-			invResult, err := client.CreateInvitation(ctx, org, action.Email, *action.TargetRole)
-			if err != nil {
-				logrus.WithError(err).WithFields(logrus.Fields{
-					"email":       action.Email,
-					"target_role": *action.TargetRole,
-					"is_already":  ghclient.IsAlreadyMemberError(err),
-				}).Info("invite failed — checking error type")
-				if ghclient.IsAlreadyMemberError(err) {
-					action.AlreadyInOrg = true
-					// User is already in the org — try to update their role if we have a target role.
-					if action.TargetRole != nil {
-						logrus.WithFields(logrus.Fields{
-							"email":       action.Email,
-							"target_role": *action.TargetRole,
-						}).Info("🔍 searching GitHub username by email for role update")
-						username, searchErr := client.SearchUserByEmail(ctx, action.Email)
-						if searchErr != nil {
-							logrus.WithError(searchErr).WithField("email", action.Email).Warn("failed to search user by email for role update")
-						}
-						logrus.WithFields(logrus.Fields{
-							"email":    action.Email,
-							"username": username,
-						}).Info("🔍 search result")
-						if username != "" {
-							roleErr := client.UpdateMemberRole(ctx, org, username, *action.TargetRole)
-							if roleErr != nil {
-								logrus.WithError(roleErr).WithFields(logrus.Fields{
-									"email":    action.Email,
-									"username": username,
-									"role":     *action.TargetRole,
-								}).Warn("failed to update role for already-in-org user")
-								errMsg := roleErr.Error()
-								action.Error = &errMsg
-								continue
-							}
-							// Successfully upgraded invite → role update.
-							logrus.WithFields(logrus.Fields{
+		if action.Type == models.ActionSkip {
+			continue
+		}
+		if StopRequested(ctx) {
+			logrus.WithContext(ctx).WithField("remaining", len(actions)-i).Warn("🛑 Stop requested — not starting the remaining actions")
+			break
+		}
+		if cause := context.Cause(ctx); cause != nil {
+			logrus.WithContext(ctx).WithError(cause).WithField("remaining", len(actions)-i).Warn("🛑 Run cancelled — not starting the remaining actions")
+			break
+		}
+
+		actionCtx, span := tracing.Start(ctx, "sync.action."+string(action.Type),
+			attribute.String("sync.action.email", action.Email),
+		)
+		executeAction(actionCtx, client, org, action)
+		endActionSpan(span, action)
+		if done != nil {
+			done(*action)
+		}
+	}
+
+	return actions, nil
+}
+
+// executeAction applies one action, recording its outcome on the action.
+func executeAction(ctx context.Context, client interfaces.GitHubClient, org string, action *models.SyncAction) {
+	switch action.Type {
+	case models.ActionInvite:
+		if action.TargetRole == nil {
+			errMsg := "target role is required"
+			action.Error = &errMsg
+			return
+		}
+		invResult, err := client.CreateInvitation(ctx, org, action.Email, *action.TargetRole)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"email":       action.Email,
+				"target_role": *action.TargetRole,
+				"is_already":  ghclient.IsAlreadyMemberError(err),
+			}).Info("invite failed — checking error type")
+			if ghclient.IsAlreadyMemberError(err) {
+				action.AlreadyInOrg = true
+				// User is already in the org — try to update their role if we have a target role.
+				if action.TargetRole != nil {
+					logrus.WithContext(ctx).WithFields(logrus.Fields{
+						"email":       action.Email,
+						"target_role": *action.TargetRole,
+					}).Info("🔍 searching GitHub username by email for role update")
+					username, searchErr := client.SearchUserByEmail(ctx, action.Email)
+					if searchErr != nil {
+						logrus.WithContext(ctx).WithError(searchErr).WithField("email", action.Email).Warn("failed to search user by email for role update")
+					}
+					logrus.WithContext(ctx).WithFields(logrus.Fields{
+						"email":    action.Email,
+						"username": username,
+					}).Info("🔍 search result")
+					if username != "" {
+						roleErr := client.UpdateMemberRole(ctx, org, username, *action.TargetRole)
+						if roleErr != nil {
+							logrus.WithContext(ctx).WithError(roleErr).WithFields(logrus.Fields{
 								"email":    action.Email,
 								"username": username,
 								"role":     *action.TargetRole,
-							}).Info("🔄 invite upgraded to role update (user already in org)")
-							action.Type = models.ActionUpdateRole
-							action.Executed = true
-							action.AlreadyInOrg = true
-							action.Username = username
-							action.GoogleEmail = action.Email
-							action.Reason = "invite upgraded: user already in org, role updated"
-							t := time.Now()
-							action.Timestamp = &t
-							continue
+							}).Warn("failed to update role for already-in-org user")
+							errMsg := roleErr.Error()
+							action.Error = &errMsg
+							action.HTTPStatus = ghclient.StatusCode(roleErr)
+							return
 						}
+						// Successfully upgraded invite → role update.
+						logrus.WithContext(ctx).WithFields(logrus.Fields{
+							"email":    action.Email,
+							"username": username,
+							"role":     *action.TargetRole,
+						}).Info("🔄 invite upgraded to role update (user already in org)")
+						action.Type = models.ActionUpdateRole
+						action.Executed = true
+						action.AlreadyInOrg = true
+						action.Username = username
+						action.GoogleEmail = action.Email
+						action.Reason = "invite upgraded: user already in org, role updated"
+						action.HTTPStatus = http.StatusOK
+						t := time.Now()
+						action.Timestamp = &t
+						return
 					}
-					// Could not find username — fall through to mark as already-in-org without role update.
-					errMsg := err.Error()
-					action.Error = &errMsg
-					action.Executed = false
-					continue
 				}
+				// Could not find username — fall through to mark as already-in-org without role update.
 				errMsg := err.Error()
 				action.Error = &errMsg
-				continue
-			}
-			action.Executed = true
-			if invResult != nil {
-				action.InvitationID = invResult.InvitationID
+				action.HTTPStatus = ghclient.StatusCode(err)
+				action.Executed = false
+				return
 			}
-			t := time.Now()
-			action.Timestamp = &t
-		case models.ActionRemove:
-			err := client.RemoveMember(ctx, org, action.Email)
-			if err != nil {
-				errMsg := err.Error()
-				action.Error = &errMsg
-				continue
-			}
-			action.Executed = true
-			t := time.Now()
-			action.Timestamp = &t
-		case models.ActionUpdateRole:
-			if action.TargetRole == nil {
-				errMsg := "target role is required"
-				action.Error = &errMsg
-				continue
-			}
-			logrus.WithFields(logrus.Fields{
+			errMsg := err.Error()
+			action.Error = &errMsg
+			action.HTTPStatus = ghclient.StatusCode(err)
+			return
+		}
+		action.Executed = true
+		action.HTTPStatus = http.StatusCreated
+		if invResult != nil {
+			action.InvitationID = invResult.InvitationID
+		}
+		t := time.Now()
+		action.Timestamp = &t
+	case models.ActionRemove:
+		err := client.RemoveMember(ctx, org, action.Email)
+		if err != nil {
+			errMsg := err.Error()
+			action.Error = &errMsg
+			action.HTTPStatus = ghclient.StatusCode(err)
+			return
+		}
+		action.Executed = true
+		action.HTTPStatus = http.StatusNoContent
+		t := time.Now()
+		action.Timestamp = &t
+	case models.ActionUpdateRole:
+		if action.TargetRole == nil {
+			errMsg := "target role is required"
+			action.Error = &errMsg
+			return
+		}
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"email":       action.Email,
+			"target_role": *action.TargetRole,
+		}).Info("executing role update")
+		err := client.UpdateMemberRole(ctx, org, action.Email, *action.TargetRole)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 				"email":       action.Email,
 				"target_role": *action.TargetRole,
-			}).Info("executing role update")
-			err := client.UpdateMemberRole(ctx, org, action.Email, *action.TargetRole)
-			if err != nil {
-				logrus.WithError(err).WithFields(logrus.Fields{
-					"email":       action.Email,
-					"target_role": *action.TargetRole,
-				}).Warn("role update failed")
-				errMsg := err.Error()
-				action.Error = &errMsg
-				continue
-			}
-			action.Executed = true
-			t := time.Now()
-			action.Timestamp = &t
-		case models.ActionCancelInvite:
-			if action.InvitationID == nil {
-				errMsg := "invitation ID is required for cancel"
-				action.Error = &errMsg
-				continue
-			}
-			err := client.CancelInvitation(ctx, org, *action.InvitationID)
-			if err != nil {
-				errMsg := err.Error()
-				action.Error = &errMsg
-				continue
-			}
-			action.Executed = true
-			t := time.Now()
-			action.Timestamp = &t
-		default:
-			continue
+			}).Warn("role update failed")
+			errMsg := err.Error()
+			action.Error = &errMsg
+			action.HTTPStatus = ghclient.StatusCode(err)
+			return
+		}
+		action.Executed = true
+		action.HTTPStatus = http.StatusOK
+		t := time.Now()
+		action.Timestamp = &t
+	case models.ActionCancelInvite:
+		if action.InvitationID == nil {
+			errMsg := "invitation ID is required for cancel"
+			action.Error = &errMsg
+			return
 		}
+		err := client.CancelInvitation(ctx, org, *action.InvitationID)
+		if err != nil {
+			errMsg := err.Error()
+			action.Error = &errMsg
+			action.HTTPStatus = ghclient.StatusCode(err)
+			return
+		}
+		action.Executed = true
+		action.HTTPStatus = http.StatusNoContent
+		t := time.Now()
+		action.Timestamp = &t
 	}
+}
 
-	return actions, nil
+// endActionSpan records the outcome of action on its span and ends it.
+func endActionSpan(span trace.Span, action *models.SyncAction) {
+	span.SetAttributes(
+		attribute.String("sync.action.final_type", string(action.Type)),
+		attribute.Bool("sync.action.executed", action.Executed),
+		attribute.Bool("sync.action.already_in_org", action.AlreadyInOrg),
+	)
+	if action.HTTPStatus != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", action.HTTPStatus))
+	}
+	if action.Error != nil {
+		span.SetStatus(codes.Error, *action.Error)
+	}
+	span.End()
 }
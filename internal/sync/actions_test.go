@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/daniloc96/google-workspace-github-sync/internal/github"
@@ -26,7 +27,7 @@ func TestExecuteInviteAction(t *testing.T) {
 		},
 	}
 
-	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false)
+	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false, nil)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -36,6 +37,9 @@ func TestExecuteInviteAction(t *testing.T) {
 	if !updated[0].Executed {
 		t.Fatalf("expected action to be marked executed")
 	}
+	if updated[0].HTTPStatus != http.StatusCreated {
+		t.Fatalf("expected the invitation's 201 status, got %d", updated[0].HTTPStatus)
+	}
 }
 
 func TestExecuteRemoveAction(t *testing.T) {
@@ -55,15 +59,15 @@ func TestExecuteRemoveAction(t *testing.T) {
 		},
 	}
 
-	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false)
+	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false, nil)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 	if !called {
 		t.Fatalf("expected RemoveMember to be called")
 	}
-	if !updated[0].Executed {
-		t.Fatalf("expected action to be marked executed")
+	if !updated[0].Executed || updated[0].HTTPStatus != http.StatusNoContent {
+		t.Fatalf("expected action to be marked executed with a 204 status, got %+v", updated[0])
 	}
 }
 
@@ -86,15 +90,42 @@ func TestExecuteUpdateRoleAction(t *testing.T) {
 		},
 	}
 
-	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false)
+	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false, nil)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 	if !called {
 		t.Fatalf("expected UpdateMemberRole to be called")
 	}
-	if !updated[0].Executed {
-		t.Fatalf("expected action to be marked executed")
+	if !updated[0].Executed || updated[0].HTTPStatus != http.StatusOK {
+		t.Fatalf("expected action to be marked executed with a 200 status, got %+v", updated[0])
+	}
+}
+
+func TestExecuteActionsReportsEachCompletedAction(t *testing.T) {
+	var completed []string
+	mock := &github.MockClient{
+		RemoveMemberFunc: func(ctx context.Context, org string, username string) error {
+			if len(completed) != 1 && username == "user2" {
+				t.Errorf("expected user1 to be reported before user2 is removed, got %v", completed)
+			}
+			return nil
+		},
+	}
+	actions := []models.SyncAction{
+		{Type: models.ActionRemove, Email: "user1"},
+		{Type: models.ActionSkip, Email: "user3"},
+		{Type: models.ActionRemove, Email: "user2"},
+	}
+
+	_, err := ExecuteActions(context.Background(), mock, "example-org", actions, false, func(action models.SyncAction) {
+		completed = append(completed, action.Email)
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(completed) != 2 || completed[0] != "user1" || completed[1] != "user2" {
+		t.Fatalf("expected the two removals to be reported in order, got %v", completed)
 	}
 }
 
@@ -133,7 +164,7 @@ func TestExecuteInviteAlreadyInOrgUpgradesToRoleUpdate(t *testing.T) {
 		},
 	}
 
-	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false)
+	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false, nil)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -185,7 +216,7 @@ func TestExecuteInviteAlreadyInOrgNoSearchResult(t *testing.T) {
 		},
 	}
 
-	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false)
+	updated, err := ExecuteActions(context.Background(), mock, "example-org", actions, false, nil)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
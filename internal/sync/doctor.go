@@ -0,0 +1,238 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Doctor cross-checks invitation mappings against live GitHub members and
+// invitations, and repairs the inconsistencies it finds.
+type Doctor struct {
+	store        interfaces.InvitationStore
+	githubClient interfaces.GitHubClient
+	cfg          *config.Config
+	now          func() time.Time
+}
+
+// NewDoctor creates a new Doctor.
+func NewDoctor(store interfaces.InvitationStore, githubClient interfaces.GitHubClient, cfg *config.Config) *Doctor {
+	return &Doctor{
+		store:        store,
+		githubClient: githubClient,
+		cfg:          cfg,
+		now:          time.Now,
+	}
+}
+
+// Check reports every inconsistent mapping for the configured org. It only reads.
+func (d *Doctor) Check(ctx context.Context) (*models.DoctorReport, error) {
+	org := d.cfg.GitHub.Organization
+
+	mappings, err := d.store.ListMappings(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	members, err := d.githubClient.ListMembers(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing members: %w", err)
+	}
+	pending, err := d.githubClient.ListPendingInvitations(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing pending invitations: %w", err)
+	}
+	failed, err := d.githubClient.ListFailedInvitations(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing failed invitations: %w", err)
+	}
+
+	memberLogins := make(map[string]struct{}, len(members))
+	for _, m := range members {
+		if m.Username != nil {
+			memberLogins[strings.ToLower(*m.Username)] = struct{}{}
+		}
+	}
+	pendingIDs := invitationIDs(pending)
+	failedIDs := invitationIDs(failed)
+
+	report := &models.DoctorReport{Org: org, Mappings: len(mappings)}
+	var active []models.InvitationMapping
+	now := d.now().UTC()
+	for _, m := range mappings {
+		switch m.Status {
+		case models.InvitationResolved:
+			if m.GitHubLogin == nil {
+				continue
+			}
+			if _, ok := memberLogins[strings.ToLower(*m.GitHubLogin)]; !ok {
+				report.Issues = append(report.Issues, newDoctorIssue(models.DoctorResolvedNotMember, m,
+					"GitHub login is not an org member", models.InvitationRemoved))
+				continue
+			}
+		case models.InvitationPending:
+			invID, ok := parseInvitationSK(m.SK)
+			if !ok {
+				continue
+			}
+			if _, ok := pendingIDs[invID]; !ok {
+				report.Issues = append(report.Issues, stalePendingIssue(m, invID, failedIDs, now))
+				continue
+			}
+		default:
+			continue
+		}
+		active = append(active, m)
+	}
+	report.Issues = append(report.Issues, duplicateIssues(active)...)
+
+	return report, nil
+}
+
+// Repair applies the proposed repair of every repairable issue in the report.
+// Status changes use TransitionMapping, so EXISTING# records can be repaired too
+// and a mapping changed since Check is left alone and reported as an error.
+func (d *Doctor) Repair(ctx context.Context, report *models.DoctorReport) {
+	org := d.cfg.GitHub.Organization
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		var err error
+		switch {
+		case issue.Delete:
+			err = d.store.DeleteMapping(ctx, org, issue.SK)
+		case issue.NewStatus != "":
+			err = d.store.TransitionMapping(ctx, issue.Mapping, issue.NewStatus)
+		default:
+			continue
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("repairing %s (%s): %v", issue.SK, issue.Email, err))
+			continue
+		}
+		issue.Repaired = true
+		report.Repaired++
+		logrus.WithFields(logrus.Fields{
+			"category": issue.Category,
+			"email":    issue.Email,
+			"sk":       issue.SK,
+		}).Info("🩺 Repaired invitation mapping")
+	}
+}
+
+// stalePendingIssue proposes a final status for a pending mapping whose invitation
+// is gone: failed if GitHub reports it failed, expired once past the invitation
+// lifetime, otherwise cancelled (withdrawn outside the sync).
+func stalePendingIssue(m models.InvitationMapping, invID int64, failedIDs map[int64]struct{}, now time.Time) models.DoctorIssue {
+	if _, ok := failedIDs[invID]; ok {
+		return newDoctorIssue(models.DoctorStalePending, m, "invitation failed on GitHub", models.InvitationFailed)
+	}
+	if now.Sub(m.InvitedAt) > invitationExpiryDays*24*time.Hour {
+		return newDoctorIssue(models.DoctorStalePending, m,
+			fmt.Sprintf("invitation not pending on GitHub and older than %d days", invitationExpiryDays), models.InvitationExpired)
+	}
+	return newDoctorIssue(models.DoctorStalePending, m, "invitation no longer pending on GitHub", models.InvitationCancelled)
+}
+
+// duplicateIssues finds emails with several active mappings of the same status.
+// A manual mapping, else the most recent one, is kept and the others are merged
+// into it; resolved duplicates pointing at different logins with no manual
+// mapping among them need manual attention.
+func duplicateIssues(active []models.InvitationMapping) []models.DoctorIssue {
+	groups := make(map[string][]models.InvitationMapping)
+	var keys []string
+	for _, m := range active {
+		key := strings.ToLower(m.Email) + "|" + string(m.Status)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], m)
+	}
+	sort.Strings(keys)
+
+	var issues []models.DoctorIssue
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(a, b int) bool {
+			if group[a].Manual != group[b].Manual {
+				return group[a].Manual
+			}
+			return mappingTime(group[a]).After(mappingTime(group[b]))
+		})
+
+		logins := make(map[string]struct{})
+		for _, m := range group {
+			if m.GitHubLogin != nil {
+				logins[strings.ToLower(*m.GitHubLogin)] = struct{}{}
+			}
+		}
+		if len(logins) > 1 && !group[0].Manual {
+			for _, m := range group {
+				issues = append(issues, newDoctorIssue(models.DoctorDuplicate, m,
+					fmt.Sprintf("%d resolved mappings with different GitHub logins", len(group)), ""))
+			}
+			continue
+		}
+
+		kept := group[0]
+		for _, m := range group[1:] {
+			issue := newDoctorIssue(models.DoctorDuplicate, m, fmt.Sprintf("duplicate of %s", kept.SK), "")
+			issue.Delete = true
+			issue.KeptSK = kept.SK
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func newDoctorIssue(category models.DoctorCategory, m models.InvitationMapping, detail string, newStatus models.InvitationStatus) models.DoctorIssue {
+	issue := models.DoctorIssue{
+		Category:  category,
+		Email:     m.Email,
+		SK:        m.SK,
+		Status:    m.Status,
+		Detail:    detail,
+		NewStatus: newStatus,
+		Mapping:   m,
+	}
+	if m.GitHubLogin != nil {
+		issue.GitHubLogin = *m.GitHubLogin
+	}
+	return issue
+}
+
+func mappingTime(m models.InvitationMapping) time.Time {
+	if m.ResolvedAt != nil {
+		return *m.ResolvedAt
+	}
+	return m.InvitedAt
+}
+
+func invitationIDs(invites []models.GitHubOrgMember) map[int64]struct{} {
+	ids := make(map[int64]struct{}, len(invites))
+	for _, inv := range invites {
+		if inv.InvitationID != nil {
+			ids[*inv.InvitationID] = struct{}{}
+		}
+	}
+	return ids
+}
+
+func parseInvitationSK(sk string) (int64, bool) {
+	if !strings.HasPrefix(sk, "INV#") {
+		return 0, false
+	}
+	var invID int64
+	if _, err := fmt.Sscanf(strings.TrimPrefix(sk, "INV#"), "%d", &invID); err != nil {
+		return 0, false
+	}
+	return invID, true
+}
@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ddb "github.com/daniloc96/google-workspace-github-sync/internal/dynamodb"
+	"github.com/daniloc96/google-workspace-github-sync/internal/github"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func resolvedMapping(sk, email, login string, resolvedAt time.Time) models.InvitationMapping {
+	m := models.NewInvitationMapping("test-org", 0, email, models.RoleMember, 90)
+	m.SK = sk
+	m.GitHubLogin = &login
+	m = withStatus(m, models.InvitationResolved)
+	m.ResolvedAt = &resolvedAt
+	return m
+}
+
+func withStatus(m models.InvitationMapping, status models.InvitationStatus) models.InvitationMapping {
+	m.Status = status
+	m.GSI2SK = "STATUS#" + string(status)
+	return m
+}
+
+func pendingMapping(invID int64, email string, invitedAt time.Time) models.InvitationMapping {
+	m := models.NewInvitationMapping("test-org", invID, email, models.RoleMember, 90)
+	m.InvitedAt = invitedAt
+	return m
+}
+
+func doctorFixture(mappings []models.InvitationMapping) (*Doctor, *ddb.MockStore) {
+	store := &ddb.MockStore{
+		ListMappingsFunc: func(ctx context.Context, org string) ([]models.InvitationMapping, error) {
+			return mappings, nil
+		},
+	}
+	ghClient := &github.MockClient{
+		ListMembersFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return []models.GitHubOrgMember{{Username: ptrString("Alice")}, {Username: ptrString("dave")}}, nil
+		},
+		ListPendingInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return []models.GitHubOrgMember{{InvitationID: ptrInt64(10)}, {InvitationID: ptrInt64(11)}}, nil
+		},
+		ListFailedInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return []models.GitHubOrgMember{{InvitationID: ptrInt64(20)}}, nil
+		},
+	}
+	return NewDoctor(store, ghClient, reconcilerCfg()), store
+}
+
+func TestDoctorCheckCategorizesIssues(t *testing.T) {
+	now := time.Now().UTC()
+	d, store := doctorFixture([]models.InvitationMapping{
+		resolvedMapping("INV#1", "alice@example.com", "alice", now),               // member (case-insensitive)
+		resolvedMapping("EXISTING#bob", "bob@example.com", "bob", now),            // left the org
+		pendingMapping(10, "carol@example.com", now),                              // still pending
+		pendingMapping(20, "failed@example.com", now),                             // failed on GitHub
+		pendingMapping(21, "old@example.com", now.AddDate(0, 0, -30)),             // gone and past expiry
+		pendingMapping(22, "gone@example.com", now),                               // gone, recent
+		resolvedMapping("INV#2", "dave@example.com", "dave", now.Add(-time.Hour)), // older duplicate
+		resolvedMapping("EXISTING#dave", "Dave@example.com", "dave", now),         // kept duplicate
+	})
+
+	report, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if report.Mappings != 8 {
+		t.Fatalf("expected 8 mappings, got %d", report.Mappings)
+	}
+
+	want := map[string]struct {
+		category  models.DoctorCategory
+		newStatus models.InvitationStatus
+		delete    bool
+	}{
+		"EXISTING#bob": {models.DoctorResolvedNotMember, models.InvitationRemoved, false},
+		"INV#20":       {models.DoctorStalePending, models.InvitationFailed, false},
+		"INV#21":       {models.DoctorStalePending, models.InvitationExpired, false},
+		"INV#22":       {models.DoctorStalePending, models.InvitationCancelled, false},
+		"INV#2":        {models.DoctorDuplicate, "", true},
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("expected %d issues, got %+v", len(want), report.Issues)
+	}
+	for _, issue := range report.Issues {
+		w, ok := want[issue.SK]
+		if !ok {
+			t.Fatalf("unexpected issue for %s: %+v", issue.SK, issue)
+		}
+		if issue.Category != w.category || issue.NewStatus != w.newStatus || issue.Delete != w.delete {
+			t.Errorf("%s: got category=%s new_status=%q delete=%v", issue.SK, issue.Category, issue.NewStatus, issue.Delete)
+		}
+	}
+	if report.Count(models.DoctorStalePending) != 3 {
+		t.Fatalf("expected 3 stale pending issues, got %d", report.Count(models.DoctorStalePending))
+	}
+	if len(store.SavedInvitations) != 0 || len(store.Transitions) != 0 || len(store.DeletedSKs) != 0 {
+		t.Fatal("Check must not write to the store")
+	}
+}
+
+func TestDoctorDuplicateWithConflictingLoginsNeedsManualRepair(t *testing.T) {
+	now := time.Now().UTC()
+	d, _ := doctorFixture([]models.InvitationMapping{
+		resolvedMapping("EXISTING#alice", "shared@example.com", "alice", now),
+		resolvedMapping("EXISTING#dave", "shared@example.com", "dave", now),
+	})
+
+	report, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Issues) != 2 {
+		t.Fatalf("expected both mappings reported, got %+v", report.Issues)
+	}
+	for _, issue := range report.Issues {
+		if issue.Repairable() {
+			t.Fatalf("conflicting duplicates must not be auto-repaired: %+v", issue)
+		}
+	}
+}
+
+func TestDoctorDuplicateKeepsManualMapping(t *testing.T) {
+	now := time.Now().UTC()
+	manual := models.NewManualMapping("test-org", "shared@example.com", "alice", models.RoleMember)
+	manual.ResolvedAt = ptrTime(now.Add(-time.Hour))
+	d, _ := doctorFixture([]models.InvitationMapping{
+		manual,
+		resolvedMapping("EXISTING#dave", "shared@example.com", "dave", now),
+	})
+
+	report, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].SK != "EXISTING#dave" || !report.Issues[0].Delete || report.Issues[0].KeptSK != "MANUAL#alice" {
+		t.Fatalf("expected the automatic mapping merged into the manual one, got %+v", report.Issues)
+	}
+}
+
+func TestDoctorRepair(t *testing.T) {
+	now := time.Now().UTC()
+	d, store := doctorFixture([]models.InvitationMapping{
+		resolvedMapping("EXISTING#bob", "bob@example.com", "bob", now),
+		pendingMapping(22, "gone@example.com", now),
+		resolvedMapping("INV#2", "dave@example.com", "dave", now.Add(-time.Hour)),
+		resolvedMapping("EXISTING#dave", "dave@example.com", "dave", now),
+	})
+
+	report, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	d.Repair(context.Background(), report)
+
+	if report.Repaired != 3 || len(report.Errors) != 0 {
+		t.Fatalf("expected 3 repairs and no errors, got %d (%v)", report.Repaired, report.Errors)
+	}
+	if len(store.Transitions) != 2 || len(store.SavedInvitations) != 0 {
+		t.Fatalf("expected 2 status repairs, got %+v", store.Transitions)
+	}
+	for _, call := range store.Transitions {
+		switch call.Mapping.SK {
+		case "EXISTING#bob":
+			if call.Status != models.InvitationRemoved || call.Mapping.Status != models.InvitationResolved {
+				t.Errorf("expected bob moved from resolved to removed, got %+v", call)
+			}
+		case "INV#22":
+			if call.Status != models.InvitationCancelled || call.Mapping.Status != models.InvitationPending {
+				t.Errorf("expected invitation 22 moved from pending to cancelled, got %+v", call)
+			}
+		default:
+			t.Errorf("unexpected transition of %s", call.Mapping.SK)
+		}
+	}
+	if len(store.DeletedSKs) != 1 || store.DeletedSKs[0] != "INV#2" {
+		t.Fatalf("expected the older duplicate deleted, got %v", store.DeletedSKs)
+	}
+}
+
+func ptrInt64(value int64) *int64 {
+	return &value
+}
+
+func ptrTime(value time.Time) *time.Time {
+	return &value
+}
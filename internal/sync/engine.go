@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -10,7 +11,10 @@ import (
 	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Engine orchestrates a sync run.
@@ -18,6 +22,11 @@ type Engine struct {
 	googleClient interfaces.GoogleClient
 	githubClient interfaces.GitHubClient
 	reconciler   *Reconciler
+	journal      interfaces.ActionJournal
+	actor        string
+	lock         interfaces.RunLock
+	lockOwner    string
+	lockTTL      time.Duration
 	cfg          *config.Config
 	mu           sync.Mutex
 	running      bool
@@ -33,8 +42,25 @@ func (e *Engine) SetReconciler(r *Reconciler) {
 	e.reconciler = r
 }
 
-// Sync performs a synchronization run.
-func (e *Engine) Sync(ctx context.Context) (*models.SyncResult, error) {
+// SetJournal sets the action journal and the identity recorded as the actor.
+// If nil, executed actions are not journaled.
+func (e *Engine) SetJournal(j interfaces.ActionJournal, actor string) {
+	e.journal = j
+	e.actor = actor
+}
+
+// SetRunLock sets the distributed lock taken for the org at the start of every
+// run that applies changes, held as owner and renewed until the run ends.
+// If nil, only runs within this process are serialized.
+func (e *Engine) SetRunLock(lock interfaces.RunLock, owner string, ttl time.Duration) {
+	e.lock = lock
+	e.lockOwner = owner
+	e.lockTTL = ttl
+}
+
+// Sync performs a synchronization run. When another process holds the run lock
+// it returns a *models.LockedError without doing anything.
+func (e *Engine) Sync(ctx context.Context) (result *models.SyncResult, err error) {
 	e.mu.Lock()
 	if e.running {
 		e.mu.Unlock()
@@ -48,63 +74,124 @@ func (e *Engine) Sync(ctx context.Context) (*models.SyncResult, error) {
 		e.mu.Unlock()
 	}()
 
+	ctx, span := tracing.Start(ctx, "sync.run",
+		attribute.String("sync.org", e.cfg.GitHub.Organization),
+		attribute.Bool("sync.dry_run", e.cfg.Sync.DryRun),
+	)
+	defer func() { endRunSpan(span, err) }()
+
+	// Dry runs change nothing, so they neither need the lock nor block a real run.
+	if e.lock == nil || e.cfg.Sync.DryRun {
+		return e.run(ctx)
+	}
+	runCtx, release, err := e.acquireRunLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err = e.run(runCtx)
+	if cause := context.Cause(runCtx); errors.Is(cause, models.ErrLeaseLost) {
+		if err != nil {
+			return result, fmt.Errorf("%w: %w", cause, err)
+		}
+		return result, cause
+	}
+	return result, err
+}
+
+// endRunSpan ends the span of a run. A run skipped for the run lock is not a failure.
+func endRunSpan(span trace.Span, err error) {
+	if errors.Is(err, models.ErrRunLocked) {
+		span.SetAttributes(attribute.Bool("sync.skipped", true))
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+func (e *Engine) run(ctx context.Context) (_ *models.SyncResult, err error) {
 	start := time.Now()
+	runID := newRunID(start)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("sync.run_id", runID))
+	phases := newPhaseTimer(start)
+	defer func() { phases.abort(err) }()
 
-	membersGroup, err := e.googleClient.GetGroupMembers(ctx, e.cfg.Google.MembersGroup)
+	phaseCtx := phases.begin(ctx, models.PhaseGoogleLoad)
+	membersGroup, err := e.googleClient.GetGroupMembers(phaseCtx, e.cfg.Google.MembersGroup)
 	if err != nil {
 		return nil, err
 	}
-	ownersGroup, err := e.googleClient.GetGroupMembers(ctx, e.cfg.Google.OwnersGroup)
+	ownersGroup, err := e.googleClient.GetGroupMembers(phaseCtx, e.cfg.Google.OwnersGroup)
 	if err != nil {
 		return nil, err
 	}
 
+	var runErrors, externalMembers []string
 	if e.cfg.Sync.IgnoreSuspended {
-		if err := applySuspensionStatus(ctx, e.googleClient, membersGroup, ownersGroup); err != nil {
+		externalMembers, err = applyUserStatus(phaseCtx, e.googleClient, membersGroup, ownersGroup)
+		if err != nil {
 			return nil, err
 		}
+		for _, email := range externalMembers {
+			logrus.WithContext(ctx).WithField("email", email).Warn("⚠ Google user status unknown: not in the Workspace customer's domains (non-fatal)")
+		}
 	}
 
-	githubMembers, err := e.githubClient.ListMembers(ctx, e.cfg.GitHub.Organization)
+	var scope []string
+	if e.cfg.Sync.IsScoped() {
+		scope, err = e.resolveScope(phaseCtx, membersGroup, ownersGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+	phases.end()
+
+	phaseCtx = phases.begin(ctx, models.PhaseGitHubLoad)
+	githubMembers, err := e.githubClient.ListMembers(phaseCtx, e.cfg.GitHub.Organization)
 	if err != nil {
 		return nil, err
 	}
 
-	pendingInvites, err := e.githubClient.ListPendingInvitations(ctx, e.cfg.GitHub.Organization)
+	pendingInvites, err := e.githubClient.ListPendingInvitations(phaseCtx, e.cfg.GitHub.Organization)
 	if err != nil {
 		return nil, err
 	}
 
 	// Phase 1: Google groups loaded.
-	logrus.WithFields(logrus.Fields{
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"members_group": len(membersGroup),
 		"owners_group":  len(ownersGroup),
 	}).Info("📋 [1/5] Google groups loaded")
 	for _, m := range membersGroup {
-		logrus.WithFields(logrus.Fields{"email": m.Email, "group": "members", "active": m.IsActive()}).Debug("  Google group member")
+		logrus.WithContext(ctx).WithFields(logrus.Fields{"email": m.Email, "group": "members", "active": m.IsActive()}).Debug("  Google group member")
 	}
 	for _, m := range ownersGroup {
-		logrus.WithFields(logrus.Fields{"email": m.Email, "group": "owners", "active": m.IsActive()}).Debug("  Google group member")
+		logrus.WithContext(ctx).WithFields(logrus.Fields{"email": m.Email, "group": "owners", "active": m.IsActive()}).Debug("  Google group member")
 	}
 
 	// Build email mappings from DynamoDB (if reconciler is available).
 	var emailMappings *EmailMappings
 	if e.reconciler != nil {
-		emailMappings = e.buildEmailMappings(ctx)
+		emailMappings = e.buildEmailMappings(phaseCtx)
 	}
 
 	// Fetch verified domain emails via GraphQL (Enterprise Cloud feature).
 	// This maps verified-domain emails → GitHub usernames for all org members,
 	// even when their email is private. Non-fatal: diff works without it.
-	var verifiedEmails map[string]string
-	verifiedEmails, err = e.githubClient.ListMembersWithVerifiedEmails(ctx, e.cfg.GitHub.Organization)
-	if err != nil {
-		logrus.WithError(err).Warn("⚠ Could not fetch verified domain emails via GraphQL (sync will continue without them)")
-		verifiedEmails = nil
+	// ListMembers usually returns them already; only query separately when it didn't
+	// and the client has not already learned that the org does not expose them.
+	verifiedEmails := verifiedEmailsFromMembers(githubMembers)
+	if len(verifiedEmails) == 0 && !verifiedEmailsUnavailable(e.githubClient) {
+		verifiedEmails, err = e.githubClient.ListMembersWithVerifiedEmails(phaseCtx, e.cfg.GitHub.Organization)
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("⚠ Could not fetch verified domain emails via GraphQL (sync will continue without them)")
+			verifiedEmails = nil
+		}
 	}
+	phases.end()
 
 	// Phase 2: GitHub org loaded.
-	logrus.WithFields(logrus.Fields{
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"org":     e.cfg.GitHub.Organization,
 		"members": len(githubMembers),
 		"pending": len(pendingInvites),
@@ -114,7 +201,7 @@ func (e *Engine) Sync(ctx context.Context) (*models.SyncResult, error) {
 		if m.Email != nil {
 			fields["email"] = *m.Email
 		}
-		logrus.WithFields(fields).Debug("  GitHub org member")
+		logrus.WithContext(ctx).WithFields(fields).Debug("  GitHub org member")
 	}
 	for _, inv := range pendingInvites {
 		fields := logrus.Fields{"invitation_id": ptrInt64Val(inv.InvitationID), "role": inv.Role}
@@ -124,41 +211,92 @@ func (e *Engine) Sync(ctx context.Context) (*models.SyncResult, error) {
 		if inv.Username != nil {
 			fields["username"] = *inv.Username
 		}
-		logrus.WithFields(fields).Debug("  GitHub pending invitation")
+		logrus.WithContext(ctx).WithFields(fields).Debug("  GitHub pending invitation")
 	}
 
+	phases.begin(ctx, models.PhaseDiff)
 	actions := CalculateDiff(membersGroup, ownersGroup, githubMembers, pendingInvites, e.cfg.Sync.RemoveExtraMembers, emailMappings, verifiedEmails)
-	logrus.WithField("actions", len(actions)).Info("🔍 [3/5] Diff calculated")
+	if e.cfg.Sync.IsScoped() {
+		actions = scopeActions(actions, scope)
+	}
+	phases.end()
+	logrus.WithContext(ctx).WithField("actions", len(actions)).Info("🔍 [3/5] Diff calculated")
+	if e.cfg.Sync.IsScoped() {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"users":  e.cfg.Sync.OnlyUsers,
+			"groups": e.cfg.Sync.OnlyGroups,
+			"scope":  len(scope),
+		}).Info("🎯 Run limited to the requested users")
+	}
 	if e.cfg.Sync.DryRun {
 		for _, action := range actions {
-			logrus.WithFields(action.LogFields()).Info("  [DRY RUN] would execute")
+			logrus.WithContext(ctx).WithFields(action.LogFields()).Info("  [DRY RUN] would execute")
 		}
 	}
 	if len(actions) > 0 {
-		logrus.WithField("dry_run", e.cfg.Sync.DryRun).Info("⚡ [4/5] Executing actions")
+		logrus.WithContext(ctx).WithField("dry_run", e.cfg.Sync.DryRun).Info("⚡ [4/5] Executing actions")
 	} else {
-		logrus.Info("⚡ [4/5] No actions to execute")
+		logrus.WithContext(ctx).Info("⚡ [4/5] No actions to execute")
+	}
+	phaseCtx = phases.begin(ctx, models.PhaseExecute)
+	// Journal each attempted change as it completes (opt-in, non-fatal), so a run
+	// cut short still records the changes it made.
+	var journalAction func(action models.SyncAction)
+	entries, written := 0, 0
+	if e.journal != nil && !e.cfg.Sync.DryRun {
+		journalAction = func(action models.SyncAction) {
+			entry, ok := journalEntry(runID, e.cfg.GitHub.Organization, e.actor, entries, action)
+			if !ok {
+				return
+			}
+			entries++
+			// A run cancelled mid-action still records what it did.
+			if err := e.journal.Append(context.WithoutCancel(phaseCtx), []models.JournalEntry{entry}); err != nil {
+				logrus.WithContext(ctx).WithError(err).WithFields(action.LogFields()).Warn("⚠ Could not write action journal (non-fatal)")
+				return
+			}
+			written++
+		}
 	}
-	updatedActions, err := ExecuteActions(ctx, e.githubClient, e.cfg.GitHub.Organization, actions, e.cfg.Sync.DryRun)
+	updatedActions, err := ExecuteActions(phaseCtx, e.githubClient, e.cfg.GitHub.Organization, actions, e.cfg.Sync.DryRun, journalAction)
 	if err != nil {
 		return nil, err
 	}
+	if written > 0 {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{"run_id": runID, "entries": written}).Info("📓 Actions journaled")
+	}
+	phases.end()
 
-	// Invitation reconciliation (opt-in, non-fatal).
+	if StopRequested(ctx) {
+		if n := notStarted(updatedActions); n > 0 {
+			runErrors = append(runErrors, fmt.Sprintf("run stopped before %d of %d actions", n, len(updatedActions)))
+		}
+	}
+	cancelled := context.Cause(ctx)
+	if cancelled != nil {
+		runErrors = append(runErrors, fmt.Sprintf("run cancelled (%v) before %d of %d actions", cancelled, notStarted(updatedActions), len(updatedActions)))
+	}
+
+	// Invitation reconciliation (opt-in, non-fatal). A cancelled run skips it:
+	// every call would fail on the cancelled context.
 	var reconcileResult *models.ReconcileResult
-	if e.reconciler != nil && !e.cfg.Sync.DryRun {
-		logrus.Info("🔄 [5/5] Running invitation reconciliation")
-		reconcileResult, err = e.reconciler.Reconcile(ctx, updatedActions)
+	if e.reconciler != nil && !e.cfg.Sync.DryRun && cancelled == nil {
+		logrus.WithContext(ctx).Info("🔄 [5/5] Running invitation reconciliation")
+		phaseCtx = phases.begin(ctx, models.PhaseReconcile)
+		reconcileResult, err = e.reconciler.Reconcile(phaseCtx, updatedActions)
 		if err != nil {
-			logrus.WithError(err).Warn("⚠ Reconciliation failed (non-fatal, sync results are still valid)")
+			logrus.WithContext(ctx).WithError(err).Warn("⚠ Reconciliation failed (non-fatal, sync results are still valid)")
 		}
 
 		// Ensure DynamoDB mappings exist for Google members matched via verified domain emails.
 		// This handles users already in the org who are recognized by CalculateDiff (no invite
 		// generated) but don't yet have a DynamoDB record for tracking.
-		if reconcileResult != nil && verifiedEmails != nil {
-			e.reconciler.EnsureVerifiedEmailMappings(ctx, verifiedEmails, membersGroup, ownersGroup, reconcileResult)
+		if reconcileResult != nil && verifiedEmails != nil && !StopRequested(ctx) {
+			stepCtx, span := tracing.Start(phaseCtx, "reconcile.ensure_verified_email_mappings")
+			e.reconciler.EnsureVerifiedEmailMappings(stepCtx, verifiedEmails, membersGroup, ownersGroup, reconcileResult)
+			span.End()
 		}
+		phases.end()
 	}
 
 	end := time.Now()
@@ -172,19 +310,123 @@ func (e *Engine) Sync(ctx context.Context) (*models.SyncResult, error) {
 	summary.OrphanedGitHub = len(orphanedUsers)
 
 	return &models.SyncResult{
+		RunID:               runID,
+		TraceID:             tracing.TraceID(ctx),
 		DryRun:              e.cfg.Sync.DryRun,
 		StartTime:           start,
 		EndTime:             end,
 		DurationMs:          end.Sub(start).Milliseconds(),
 		Actions:             updatedActions,
 		Summary:             summary,
+		Errors:              runErrors,
 		InvitedUsers:        invitedUsers,
 		AlreadyInOrgUsers:   alreadyInOrgUsers,
 		OrphanedGitHubUsers: orphanedUsers,
+		ExternalMembers:     externalMembers,
 		Reconciliation:      reconcileResult,
+		PhaseDurationsMs:    phases.durations,
+		Scope:               e.cfg.Sync.OnlyUsers,
+		ScopeGroups:         e.cfg.Sync.OnlyGroups,
+		Groups: []models.GroupInput{
+			groupInput(e.cfg.Google.MembersGroup, models.RoleMember, membersGroup),
+			groupInput(e.cfg.Google.OwnersGroup, models.RoleOwner, ownersGroup),
+		},
 	}, nil
 }
 
+// resolveScope returns the users a scoped run is limited to: Sync.OnlyUsers and
+// the members of Sync.OnlyGroups. The configured groups are not read again.
+func (e *Engine) resolveScope(ctx context.Context, membersGroup, ownersGroup []models.GoogleGroupMember) ([]string, error) {
+	scope := append([]string(nil), e.cfg.Sync.OnlyUsers...)
+	for _, group := range e.cfg.Sync.OnlyGroups {
+		var members []models.GoogleGroupMember
+		switch {
+		case strings.EqualFold(group, e.cfg.Google.MembersGroup):
+			members = membersGroup
+		case strings.EqualFold(group, e.cfg.Google.OwnersGroup):
+			members = ownersGroup
+		default:
+			var err error
+			members, err = e.googleClient.GetGroupMembers(ctx, group)
+			if err != nil {
+				return nil, fmt.Errorf("listing members of %s: %w", group, err)
+			}
+		}
+		for _, m := range members {
+			if m.Email != "" {
+				scope = append(scope, m.Email)
+			}
+		}
+	}
+	return scope, nil
+}
+
+// scopeActions keeps the actions about one of users, matched case-insensitively
+// against each action's email and GitHub login.
+func scopeActions(actions []models.SyncAction, users []string) []models.SyncAction {
+	wanted := make(map[string]struct{}, len(users))
+	for _, user := range users {
+		wanted[strings.ToLower(user)] = struct{}{}
+	}
+	var scoped []models.SyncAction
+	for _, action := range actions {
+		_, byEmail := wanted[strings.ToLower(action.Email)]
+		_, byLogin := wanted[strings.ToLower(action.Username)]
+		if (byEmail && action.Email != "") || (byLogin && action.Username != "") {
+			scoped = append(scoped, action)
+		}
+	}
+	return scoped
+}
+
+// groupInput summarizes a Google group read by the run.
+func groupInput(email string, role models.OrgRole, members []models.GoogleGroupMember) models.GroupInput {
+	input := models.GroupInput{Email: email, Role: role, Members: len(members)}
+	for i := range members {
+		if members[i].IsActive() {
+			input.Active++
+		}
+	}
+	return input
+}
+
+// phaseTimer measures consecutive sync phases and traces each in its own span.
+type phaseTimer struct {
+	start     time.Time
+	durations map[string]int64
+	phase     string
+	span      trace.Span
+}
+
+func newPhaseTimer(start time.Time) *phaseTimer {
+	return &phaseTimer{start: start, durations: make(map[string]int64)}
+}
+
+// begin starts phase's span, returning a context for the phase's calls.
+func (p *phaseTimer) begin(ctx context.Context, phase string) context.Context {
+	p.phase = phase
+	ctx, p.span = tracing.Start(ctx, "sync."+phase)
+	return ctx
+}
+
+// end records the time since the previous phase ended as the current phase's
+// duration and ends its span.
+func (p *phaseTimer) end() {
+	now := time.Now()
+	p.durations[p.phase] = now.Sub(p.start).Milliseconds()
+	p.start = now
+	p.span.End()
+	p.span = nil
+}
+
+// abort ends the span of a phase that did not finish, marking it failed with err.
+func (p *phaseTimer) abort(err error) {
+	if p.span != nil {
+		tracing.End(p.span, err)
+		p.span = nil
+	}
+}
+
 // buildEmailMappings fetches resolved and pending mappings from DynamoDB.
 // Returns nil if fetching fails (non-fatal — diff will work without enrichment).
 func (e *Engine) buildEmailMappings(ctx context.Context) *EmailMappings {
@@ -192,13 +434,13 @@ func (e *Engine) buildEmailMappings(ctx context.Context) *EmailMappings {
 
 	resolved, err := e.reconciler.store.GetAllResolvedMappings(ctx, org)
 	if err != nil {
-		logrus.WithError(err).Warn("⚠ Could not fetch resolved mappings from DynamoDB (sync will continue without enrichment)")
+		logrus.WithContext(ctx).WithError(err).Warn("⚠ Could not fetch resolved mappings from DynamoDB (sync will continue without enrichment)")
 		return nil
 	}
 
 	pendingMappings, err := e.reconciler.store.GetPendingInvitations(ctx, org)
 	if err != nil {
-		logrus.WithError(err).Warn("⚠ Could not fetch pending mappings from DynamoDB (sync will continue without enrichment)")
+		logrus.WithContext(ctx).WithError(err).Warn("⚠ Could not fetch pending mappings from DynamoDB (sync will continue without enrichment)")
 		return nil
 	}
 
@@ -211,7 +453,7 @@ func (e *Engine) buildEmailMappings(ctx context.Context) *EmailMappings {
 		}
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"resolved_mappings": len(resolved),
 		"pending_mappings":  len(pending),
 	}).Debug("loaded email mappings from DynamoDB")
@@ -222,7 +464,34 @@ func (e *Engine) buildEmailMappings(ctx context.Context) *EmailMappings {
 	}
 }
 
-func applySuspensionStatus(ctx context.Context, client interfaces.GoogleClient, membersGroup []models.GoogleGroupMember, ownersGroup []models.GoogleGroupMember) error {
+// verifiedEmailsUnavailable reports whether client knows the org's verified-domain
+// emails cannot be read.
+func verifiedEmailsUnavailable(client interfaces.GitHubClient) bool {
+	reporter, ok := client.(interfaces.VerifiedEmailsReporter)
+	return ok && reporter.VerifiedEmailsUnavailable()
+}
+
+// verifiedEmailsFromMembers builds a lowercase verified-domain email → username map
+// from members listed with their verified emails. Returns nil if none carry any.
+func verifiedEmailsFromMembers(members []models.GitHubOrgMember) map[string]string {
+	var result map[string]string
+	for _, m := range members {
+		if m.Username == nil {
+			continue
+		}
+		for _, email := range m.VerifiedEmails {
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[strings.ToLower(email)] = *m.Username
+		}
+	}
+	return result
+}
+
+// applyUserStatus marks group members that are suspended, archived, or deleted in Google Workspace.
+// It returns the emails whose status is unknown because they are outside the customer's domains.
+func applyUserStatus(ctx context.Context, client interfaces.GoogleClient, membersGroup []models.GoogleGroupMember, ownersGroup []models.GoogleGroupMember) ([]string, error) {
 	emails := make([]string, 0, len(membersGroup)+len(ownersGroup))
 	seen := map[string]struct{}{}
 	for _, member := range append(membersGroup, ownersGroup...) {
@@ -235,17 +504,27 @@ func applySuspensionStatus(ctx context.Context, client interfaces.GoogleClient,
 		seen[member.Email] = struct{}{}
 		emails = append(emails, member.Email)
 	}
-	statuses, err := client.GetUsersSuspendedStatus(ctx, emails)
+	statuses, err := client.GetUsersStatus(ctx, emails)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for i := range membersGroup {
-		membersGroup[i].IsSuspended = statuses[membersGroup[i].Email]
+	var unknown []string
+	for _, email := range emails {
+		if statuses[email].External {
+			unknown = append(unknown, email)
+		}
 	}
-	for i := range ownersGroup {
-		ownersGroup[i].IsSuspended = statuses[ownersGroup[i].Email]
+	apply := func(members []models.GoogleGroupMember) {
+		for i := range members {
+			status := statuses[members[i].Email]
+			members[i].IsSuspended = status.Suspended
+			members[i].IsArchived = status.Archived
+			members[i].IsDeleted = status.Deleted
+		}
 	}
-	return nil
+	apply(membersGroup)
+	apply(ownersGroup)
+	return unknown, nil
 }
 
 func buildSummary(googleMembers []models.GoogleGroupMember, githubMembers []models.GitHubOrgMember, pendingInvites []models.GitHubOrgMember, actions []models.SyncAction) models.SyncSummary {
@@ -2,13 +2,20 @@ package sync
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"sync"
 	"testing"
 
 	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	"github.com/daniloc96/google-workspace-github-sync/internal/github"
 	"github.com/daniloc96/google-workspace-github-sync/internal/google"
+	"github.com/daniloc96/google-workspace-github-sync/internal/journal"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestDryRunDoesNotExecuteActions(t *testing.T) {
@@ -165,3 +172,402 @@ func TestSyncVerifiedEmailsPreventsInvite(t *testing.T) {
 		}
 	}
 }
+
+func TestSyncSkipsVerifiedEmailsQueryWhenUnavailable(t *testing.T) {
+	githubClient := &github.MockClient{
+		ListMembersWithVerifiedEmailsFunc: func(ctx context.Context, org string) (map[string]string, error) {
+			t.Fatalf("expected no verified emails query once the client knows they are unavailable")
+			return nil, nil
+		},
+		VerifiedEmailsUnavailableFunc: func() bool { return true },
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: true},
+	}
+
+	if _, err := NewEngine(&google.MockClient{}, githubClient, cfg).Sync(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestSyncReportsExternalGroupMembers(t *testing.T) {
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{{Email: "contractor@partner.example", Type: "USER", Status: "ACTIVE"}}, nil
+		},
+		GetUsersStatusFunc: func(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error) {
+			return map[string]models.GoogleUserStatus{"contractor@partner.example": {External: true}}, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: true, IgnoreSuspended: true},
+	}
+
+	result, err := NewEngine(googleClient, &github.MockClient{}, cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Errors) != 0 || !result.IsSuccess() {
+		t.Fatalf("expected the external member not to fail the run, got %v", result.Errors)
+	}
+	if len(result.ExternalMembers) != 1 || result.ExternalMembers[0] != "contractor@partner.example" {
+		t.Fatalf("expected the external member to be reported, got %v", result.ExternalMembers)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].Type != models.ActionInvite {
+		t.Fatalf("expected the external member not to be treated as deleted, got %#v", result.Actions)
+	}
+}
+
+func TestSyncSkipsArchivedAndDeletedUsers(t *testing.T) {
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{
+				{Email: "active@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "archived@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "deleted@example.com", Type: "USER", Status: "ACTIVE"},
+			}, nil
+		},
+		GetUsersStatusFunc: func(ctx context.Context, emails []string) (map[string]models.GoogleUserStatus, error) {
+			return map[string]models.GoogleUserStatus{
+				"archived@example.com": {Archived: true},
+				"deleted@example.com":  {Deleted: true},
+			}, nil
+		},
+	}
+	githubClient := &github.MockClient{}
+
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: true, IgnoreSuspended: true},
+	}
+
+	result, err := NewEngine(googleClient, githubClient, cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].Email != "active@example.com" {
+		t.Fatalf("expected only active@example.com to be invited, got %#v", result.Actions)
+	}
+}
+
+func TestSyncJournalsExecutedActions(t *testing.T) {
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{
+				{Email: "new@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "broken@example.com", Type: "USER", Status: "ACTIVE"},
+			}, nil
+		},
+	}
+	invitationID := int64(42)
+	mockJournal := &journal.MockJournal{}
+	var journaledBefore []int
+	githubClient := &github.MockClient{
+		CreateInvitationFunc: func(ctx context.Context, org string, email string, role models.OrgRole) (*models.GitHubOrgMember, error) {
+			journaledBefore = append(journaledBefore, len(mockJournal.Appended))
+			if email == "broken@example.com" {
+				return nil, fmt.Errorf("boom")
+			}
+			return &models.GitHubOrgMember{InvitationID: &invitationID}, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: false},
+	}
+
+	engine := NewEngine(googleClient, githubClient, cfg)
+	engine.SetJournal(mockJournal, "sync-bot")
+	result, err := engine.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(journaledBefore) != 2 || journaledBefore[0] != 0 || journaledBefore[1] != 1 {
+		t.Fatalf("expected each action to be journaled before the next one starts, got %v", journaledBefore)
+	}
+	if len(mockJournal.Appended) != 2 {
+		t.Fatalf("expected 2 journal entries, got %#v", mockJournal.Appended)
+	}
+	for _, entry := range mockJournal.Appended {
+		if entry.RunID != result.RunID || entry.Actor != "sync-bot" || entry.Org != "example-org" {
+			t.Fatalf("unexpected entry metadata: %+v", entry)
+		}
+		switch entry.Email {
+		case "new@example.com":
+			if entry.Outcome != models.JournalSucceeded || entry.After.Status != models.MembershipInvited || entry.InvitationID == nil || entry.HTTPStatus != http.StatusCreated {
+				t.Fatalf("unexpected entry for successful invite: %+v", entry)
+			}
+		case "broken@example.com":
+			if entry.Outcome != models.JournalFailed || entry.After.Status != models.MembershipAbsent || entry.Error == "" {
+				t.Fatalf("unexpected entry for failed invite: %+v", entry)
+			}
+		default:
+			t.Fatalf("unexpected entry: %+v", entry)
+		}
+	}
+}
+
+func TestDryRunDoesNotJournal(t *testing.T) {
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			return []models.GoogleGroupMember{{Email: "user@example.com", Type: "USER", Status: "ACTIVE"}}, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: true},
+	}
+
+	mockJournal := &journal.MockJournal{}
+	engine := NewEngine(googleClient, &github.MockClient{}, cfg)
+	engine.SetJournal(mockJournal, "sync-bot")
+	if _, err := engine.Sync(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mockJournal.Appended) != 0 {
+		t.Fatalf("expected no journal entries in dry-run, got %#v", mockJournal.Appended)
+	}
+}
+
+func TestSyncReportsPhaseDurations(t *testing.T) {
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: true},
+	}
+	engine := NewEngine(&google.MockClient{}, &github.MockClient{}, cfg)
+	result, err := engine.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, phase := range []string{models.PhaseGoogleLoad, models.PhaseGitHubLoad, models.PhaseDiff, models.PhaseExecute} {
+		if _, ok := result.PhaseDurationsMs[phase]; !ok {
+			t.Errorf("expected a duration for phase %s, got %v", phase, result.PhaseDurationsMs)
+		}
+	}
+	if _, ok := result.PhaseDurationsMs[models.PhaseReconcile]; ok {
+		t.Errorf("expected no reconcile phase without a reconciler")
+	}
+}
+
+func TestSyncLimitedToUsers(t *testing.T) {
+	var invited []string
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{
+				{Email: "jane@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "john@example.com", Type: "USER", Status: "ACTIVE"},
+			}, nil
+		},
+	}
+	stale := "stale-user"
+	githubClient := &github.MockClient{
+		CreateInvitationFunc: func(ctx context.Context, org string, email string, role models.OrgRole) (*models.GitHubOrgMember, error) {
+			invited = append(invited, email)
+			return &models.GitHubOrgMember{}, nil
+		},
+		ListMembersFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return []models.GitHubOrgMember{{Username: &stale, Role: models.RoleMember}}, nil
+		},
+		ListPendingInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return nil, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{RemoveExtraMembers: true, OnlyUsers: []string{"John@Example.com"}},
+	}
+
+	result, err := NewEngine(googleClient, githubClient, cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].Email != "john@example.com" {
+		t.Fatalf("expected only john's invite, got %#v", result.Actions)
+	}
+	if len(invited) != 1 || invited[0] != "john@example.com" {
+		t.Fatalf("expected only john to be invited, got %v", invited)
+	}
+	if len(result.Scope) != 1 || result.Summary.ActionsPlanned != 1 {
+		t.Fatalf("expected the scope and scoped summary on the result, got %v, %+v", result.Scope, result.Summary)
+	}
+}
+
+func TestSyncLimitedToGroup(t *testing.T) {
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			switch groupEmail {
+			case "members@example.com":
+				return []models.GoogleGroupMember{
+					{Email: "jane@example.com", Type: "USER", Status: "ACTIVE"},
+					{Email: "john@example.com", Type: "USER", Status: "ACTIVE"},
+				}, nil
+			case "new-hires@example.com":
+				return []models.GoogleGroupMember{{Email: "jane@example.com", Type: "USER", Status: "ACTIVE"}}, nil
+			}
+			return nil, nil
+		},
+	}
+	githubClient := &github.MockClient{
+		ListMembersFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return nil, nil
+		},
+		ListPendingInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return nil, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: true, OnlyGroups: []string{"new-hires@example.com"}},
+	}
+
+	result, err := NewEngine(googleClient, githubClient, cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].Email != "jane@example.com" {
+		t.Fatalf("expected only the new hire's invite, got %#v", result.Actions)
+	}
+	if len(result.ScopeGroups) != 1 {
+		t.Fatalf("expected the scope groups on the result, got %v", result.ScopeGroups)
+	}
+}
+
+func TestSyncStopsBetweenActions(t *testing.T) {
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{
+				{Email: "jane@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "john@example.com", Type: "USER", Status: "ACTIVE"},
+			}, nil
+		},
+	}
+	stop := make(chan struct{})
+	githubClient := &github.MockClient{
+		CreateInvitationFunc: func(ctx context.Context, org string, email string, role models.OrgRole) (*models.GitHubOrgMember, error) {
+			close(stop) // Shutdown arrives while the first invite is in flight.
+			if ctx.Err() != nil {
+				t.Errorf("expected the action in flight not to be cancelled")
+			}
+			return &models.GitHubOrgMember{}, nil
+		},
+		ListMembersFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return nil, nil
+		},
+		ListPendingInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return nil, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+	}
+
+	result, err := NewEngine(googleClient, githubClient, cfg).Sync(WithStop(context.Background(), stop))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Summary.ActionsExecuted != 1 || len(result.Actions) != 2 {
+		t.Fatalf("expected 1 of 2 actions executed, got %+v", result.Summary)
+	}
+	if len(result.Errors) != 1 || result.IsSuccess() {
+		t.Fatalf("expected the stop to be reported, got %v", result.Errors)
+	}
+}
+
+func TestSyncTracesPhasesAndActions(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{
+				{Email: "new@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "broken@example.com", Type: "USER", Status: "ACTIVE"},
+			}, nil
+		},
+	}
+	githubClient := &github.MockClient{
+		CreateInvitationFunc: func(ctx context.Context, org string, email string, role models.OrgRole) (*models.GitHubOrgMember, error) {
+			if email == "broken@example.com" {
+				return nil, fmt.Errorf("boom")
+			}
+			return &models.GitHubOrgMember{}, nil
+		},
+	}
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+	}
+
+	result, err := NewEngine(googleClient, githubClient, cfg).Sync(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	if len(spans["sync.run"]) != 1 {
+		t.Fatalf("expected one run span, got %v", spans)
+	}
+	run := spans["sync.run"][0]
+	if result.TraceID == "" || result.TraceID != run.SpanContext().TraceID().String() {
+		t.Fatalf("expected the result to carry the run's trace ID, got %q", result.TraceID)
+	}
+	for _, phase := range []string{models.PhaseGoogleLoad, models.PhaseGitHubLoad, models.PhaseDiff, models.PhaseExecute} {
+		got := spans["sync."+phase]
+		if len(got) != 1 || got[0].Parent().SpanID() != run.SpanContext().SpanID() {
+			t.Errorf("expected one %s span under the run span, got %d", phase, len(got))
+		}
+	}
+	invites := spans["sync.action.invite"]
+	if len(invites) != 2 {
+		t.Fatalf("expected a span per invite, got %d", len(invites))
+	}
+	execute := spans["sync."+models.PhaseExecute][0]
+	failed := 0
+	for _, span := range invites {
+		if span.Parent().SpanID() != execute.SpanContext().SpanID() {
+			t.Errorf("expected action spans under the execute phase")
+		}
+		if span.Status().Code == codes.Error {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("expected the failed invite's span to be marked failed, got %d", failed)
+	}
+}
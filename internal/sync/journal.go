@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+// newRunID returns a sortable, unique identifier for a sync run.
+func newRunID(start time.Time) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return start.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// journalEntry builds the journal entry of an attempted action. Skipped and
+// unattempted actions (e.g. dry-run) are not journaled.
+func journalEntry(runID, org, actor string, sequence int, action models.SyncAction) (models.JournalEntry, bool) {
+	if action.Type == models.ActionSkip || (!action.Executed && action.Error == nil) {
+		return models.JournalEntry{}, false
+	}
+	before, after := membershipStates(action)
+	entry := models.JournalEntry{
+		RunID:        runID,
+		Sequence:     sequence,
+		Org:          org,
+		Actor:        actor,
+		Action:       action.Type,
+		Email:        action.Email,
+		Username:     action.Username,
+		InvitationID: action.InvitationID,
+		Before:       before,
+		After:        after,
+		Outcome:      models.JournalSucceeded,
+		HTTPStatus:   action.HTTPStatus,
+		Reason:       action.Reason,
+		Timestamp:    time.Now().UTC(),
+	}
+	if action.Timestamp != nil {
+		entry.Timestamp = action.Timestamp.UTC()
+	}
+	if !action.Executed {
+		entry.Outcome = models.JournalFailed
+		entry.After = before
+	}
+	if action.Error != nil {
+		entry.Error = *action.Error
+	}
+	return entry, true
+}
+
+// membershipStates returns the membership before and after a successful action.
+func membershipStates(action models.SyncAction) (before, after models.MembershipState) {
+	switch action.Type {
+	case models.ActionInvite:
+		return models.MembershipState{Status: models.MembershipAbsent},
+			models.MembershipState{Status: models.MembershipInvited, Role: action.TargetRole}
+	case models.ActionRemove:
+		return models.MembershipState{Status: models.MembershipMember, Role: action.CurrentRole},
+			models.MembershipState{Status: models.MembershipAbsent}
+	case models.ActionUpdateRole:
+		return models.MembershipState{Status: models.MembershipMember, Role: action.CurrentRole},
+			models.MembershipState{Status: models.MembershipMember, Role: action.TargetRole}
+	case models.ActionCancelInvite:
+		return models.MembershipState{Status: models.MembershipInvited, Role: action.CurrentRole},
+			models.MembershipState{Status: models.MembershipAbsent}
+	}
+	return models.MembershipState{}, models.MembershipState{}
+}
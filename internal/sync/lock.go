@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// releaseTimeout bounds releasing the lease after the run, whose context may be cancelled.
+const releaseTimeout = 10 * time.Second
+
+// acquireRunLock takes the org's run lock and renews it every third of its TTL.
+// The returned context is cancelled with models.ErrLeaseLost if another owner
+// takes the lease over; release stops renewal and frees the lease.
+func (e *Engine) acquireRunLock(ctx context.Context) (context.Context, func(), error) {
+	name := models.SyncLockName(e.cfg.GitHub.Organization)
+	holder, ok, err := e.lock.AcquireLease(ctx, name, e.lockOwner, e.lockTTL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("acquiring run lock: %w", err)
+	}
+	if !ok {
+		return nil, nil, &models.LockedError{Holder: *holder}
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{"lock": name, "owner": e.lockOwner, "expires_at": holder.ExpiresAt}).Debug("run lock acquired")
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.renewRunLock(runCtx, name, cancel)
+	}()
+
+	release := func() {
+		cancel(nil)
+		<-done
+		releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		defer cancelRelease()
+		if err := e.lock.ReleaseLease(releaseCtx, name, e.lockOwner); err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("⚠ Could not release run lock (it expires after its TTL)")
+		}
+	}
+	return runCtx, release, nil
+}
+
+// renewRunLock is the lease heartbeat. Renewal errors other than a lost lease are
+// retried on the next tick: the lease stays ours until it expires.
+func (e *Engine) renewRunLock(ctx context.Context, name string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(e.lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := e.lock.RenewLease(ctx, name, e.lockOwner, e.lockTTL)
+		switch {
+		case err == nil:
+		case errors.Is(err, models.ErrLeaseLost):
+			logrus.WithContext(ctx).WithField("lock", name).Error("❌ Run lock lost to another invocation; stopping sync")
+			cancel(err)
+			return
+		case ctx.Err() == nil:
+			logrus.WithContext(ctx).WithError(err).Warn("⚠ Could not renew run lock (will retry)")
+		}
+	}
+}
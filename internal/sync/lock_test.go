@@ -0,0 +1,148 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	ddb "github.com/daniloc96/google-workspace-github-sync/internal/dynamodb"
+	"github.com/daniloc96/google-workspace-github-sync/internal/github"
+	"github.com/daniloc96/google-workspace-github-sync/internal/google"
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+func lockTestEngine(dryRun bool, googleClient *google.MockClient) *Engine {
+	cfg := &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+		Sync:   config.SyncConfig{DryRun: dryRun},
+	}
+	return NewEngine(googleClient, &github.MockClient{}, cfg)
+}
+
+func TestSyncHoldsRunLock(t *testing.T) {
+	store := &ddb.MockStore{}
+	engine := lockTestEngine(false, &google.MockClient{})
+	engine.SetRunLock(store, "invocation-1", time.Minute)
+
+	if _, err := engine.Sync(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(store.AcquiredLeases) != 1 || store.AcquiredLeases[0] != "sync#example-org" {
+		t.Fatalf("expected the org's lease to be acquired, got %v", store.AcquiredLeases)
+	}
+	if len(store.ReleasedLeases) != 1 || store.ReleasedLeases[0] != "sync#example-org" {
+		t.Fatalf("expected the lease to be released, got %v", store.ReleasedLeases)
+	}
+}
+
+func TestSyncSkipsWhenRunLocked(t *testing.T) {
+	holder := models.RunLease{Name: "sync#example-org", Owner: "invocation-1", ExpiresAt: time.Now().Add(time.Minute)}
+	store := &ddb.MockStore{
+		AcquireLeaseFunc: func(ctx context.Context, name string, owner string, ttl time.Duration) (*models.RunLease, bool, error) {
+			return &holder, false, nil
+		},
+	}
+	googleCalled := false
+	engine := lockTestEngine(false, &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			googleCalled = true
+			return nil, nil
+		},
+	})
+	engine.SetRunLock(store, "invocation-2", time.Minute)
+
+	_, err := engine.Sync(context.Background())
+	var locked *models.LockedError
+	if !errors.As(err, &locked) || !errors.Is(err, models.ErrRunLocked) || locked.Holder.Owner != "invocation-1" {
+		t.Fatalf("expected a LockedError naming the holder, got %v", err)
+	}
+	if googleCalled {
+		t.Fatalf("expected a locked run to do nothing")
+	}
+	if len(store.ReleasedLeases) != 0 {
+		t.Fatalf("expected another owner's lease not to be released, got %v", store.ReleasedLeases)
+	}
+}
+
+func TestDryRunDoesNotTakeRunLock(t *testing.T) {
+	store := &ddb.MockStore{}
+	engine := lockTestEngine(true, &google.MockClient{})
+	engine.SetRunLock(store, "invocation-1", time.Minute)
+
+	if _, err := engine.Sync(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(store.AcquiredLeases) != 0 {
+		t.Fatalf("expected dry run not to take the lock, got %v", store.AcquiredLeases)
+	}
+}
+
+func TestSyncStopsWhenLeaseLost(t *testing.T) {
+	store := &ddb.MockStore{
+		RenewLeaseFunc: func(ctx context.Context, name string, owner string, ttl time.Duration) error {
+			return models.ErrLeaseLost
+		},
+	}
+	engine := lockTestEngine(false, &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	engine.SetRunLock(store, "invocation-1", 30*time.Millisecond)
+
+	_, err := engine.Sync(context.Background())
+	if !errors.Is(err, models.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if len(store.RenewedLeases) == 0 {
+		t.Fatalf("expected the lease to be renewed while the run was in progress")
+	}
+}
+
+func TestSyncStopsActionsWhenLeaseLostDuringExecution(t *testing.T) {
+	store := &ddb.MockStore{
+		RenewLeaseFunc: func(ctx context.Context, name string, owner string, ttl time.Duration) error {
+			return models.ErrLeaseLost
+		},
+	}
+	googleClient := &google.MockClient{
+		GetGroupMembersFunc: func(ctx context.Context, groupEmail string) ([]models.GoogleGroupMember, error) {
+			if groupEmail != "members@example.com" {
+				return nil, nil
+			}
+			return []models.GoogleGroupMember{
+				{Email: "first@example.com", Type: "USER", Status: "ACTIVE"},
+				{Email: "second@example.com", Type: "USER", Status: "ACTIVE"},
+			}, nil
+		},
+	}
+	var invited []string
+	githubClient := &github.MockClient{
+		CreateInvitationFunc: func(ctx context.Context, org string, email string, role models.OrgRole) (*models.GitHubOrgMember, error) {
+			invited = append(invited, email)
+			// The lease is taken over while the first invitation is in flight.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	engine := NewEngine(googleClient, githubClient, &config.Config{
+		Google: config.GoogleConfig{MembersGroup: "members@example.com", OwnersGroup: "owners@example.com"},
+		GitHub: config.GitHubConfig{Organization: "example-org"},
+	})
+	engine.SetRunLock(store, "invocation-1", 30*time.Millisecond)
+
+	result, err := engine.Sync(context.Background())
+	if !errors.Is(err, models.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if len(invited) != 1 {
+		t.Fatalf("expected no action to start after the lease was lost, got %v", invited)
+	}
+	if result == nil || len(result.Errors) != 1 {
+		t.Fatalf("expected the result to report the actions not started, got %+v", result)
+	}
+}
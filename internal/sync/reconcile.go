@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,7 +10,11 @@ import (
 	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const invitationExpiryDays = 7
@@ -20,6 +25,7 @@ type Reconciler struct {
 	store        interfaces.InvitationStore
 	githubClient interfaces.GitHubClient
 	cfg          *config.Config
+	rejected     int // Store writes rejected during the current Reconcile
 }
 
 // NewReconciler creates a new Reconciler.
@@ -36,49 +42,74 @@ func NewReconciler(store interfaces.InvitationStore, githubClient interfaces.Git
 func (r *Reconciler) Reconcile(ctx context.Context, executedActions []models.SyncAction) (*models.ReconcileResult, error) {
 	org := r.cfg.GitHub.Organization
 	result := &models.ReconcileResult{}
+	r.rejected = 0
 
 	// Step 1: Save newly executed invitations to DynamoDB.
-	saved, errs := r.saveNewInvitations(ctx, org, executedActions)
+	stepCtx, span := startStep(ctx, "save_new_invitations")
+	saved, errs := r.saveNewInvitations(stepCtx, org, executedActions)
+	endStep(span, errs)
 	result.NewInvitationsSaved = saved
 	result.Errors = append(result.Errors, errs...)
 
 	// Step 1b: Mark cancelled invitations in DynamoDB.
-	cancelled, cancelErrs := r.markCancelledInvitations(ctx, org, executedActions)
+	stepCtx, span = startStep(ctx, "mark_cancelled_invitations")
+	cancelled, cancelErrs := r.markCancelledInvitations(stepCtx, org, executedActions)
+	endStep(span, cancelErrs)
 	result.Cancelled = cancelled
 	result.Errors = append(result.Errors, cancelErrs...)
 
 	// Step 1c: Mark removed members in DynamoDB.
-	removed, removeErrs := r.handleRemovedMembers(ctx, org, executedActions)
+	stepCtx, span = startStep(ctx, "handle_removed_members")
+	removed, removeErrs := r.handleRemovedMembers(stepCtx, org, executedActions)
+	endStep(span, removeErrs)
 	result.MembersRemoved = removed
 	result.Errors = append(result.Errors, removeErrs...)
 
 	// Step 1d: Update roles in DynamoDB.
-	roleUpdated, roleErrs := r.handleRoleUpdates(ctx, org, executedActions)
+	stepCtx, span = startStep(ctx, "handle_role_updates")
+	roleUpdated, roleErrs := r.handleRoleUpdates(stepCtx, org, executedActions)
+	endStep(span, roleErrs)
 	result.RolesUpdated = roleUpdated
 	result.Errors = append(result.Errors, roleErrs...)
 
 	// Step 1e: Create DynamoDB records for already-in-org members resolved via search/verified emails.
-	alreadyResolved, alreadyErrs := r.handleAlreadyInOrgMembers(ctx, org, executedActions)
+	stepCtx, span = startStep(ctx, "handle_already_in_org_members")
+	alreadyResolved, alreadyErrs := r.handleAlreadyInOrgMembers(stepCtx, org, executedActions)
+	endStep(span, alreadyErrs)
 	result.AlreadyInOrgResolved = alreadyResolved
 	result.Errors = append(result.Errors, alreadyErrs...)
 
+	// A run stopping for shutdown only records what it did; the next run picks up
+	// the steps that query GitHub.
+	if StopRequested(ctx) {
+		logrus.WithContext(ctx).WithField("new_saved", result.NewInvitationsSaved).Info("🛑 Stop requested — executed actions recorded, remaining reconciliation skipped")
+		return result, nil
+	}
+
 	// Step 2: Check pending invitations that now have login resolved via GitHub API.
-	resolved, errs := r.resolvePendingWithLogin(ctx, org)
+	stepCtx, span = startStep(ctx, "resolve_pending_with_login")
+	resolved, errs := r.resolvePendingWithLogin(stepCtx, org)
+	endStep(span, errs)
 	result.Resolved += resolved
 	result.Errors = append(result.Errors, errs...)
 
 	// Step 3: Consult audit log for org.add_member events.
-	resolvedAudit, errs := r.resolveFromAuditLog(ctx, org)
+	stepCtx, span = startStep(ctx, "resolve_from_audit_log")
+	resolvedAudit, errs := r.resolveFromAuditLog(stepCtx, org)
+	endStep(span, errs)
 	result.Resolved += resolvedAudit
 	result.Errors = append(result.Errors, errs...)
 
 	// Step 4: Handle failed and expired invitations.
-	failed, expired, errs := r.handleFailedAndExpired(ctx, org)
+	stepCtx, span = startStep(ctx, "handle_failed_and_expired")
+	failed, expired, errs := r.handleFailedAndExpired(stepCtx, org)
+	endStep(span, errs)
 	result.Failed = failed
 	result.Expired = expired
 	result.Errors = append(result.Errors, errs...)
+	result.TransitionsRejected = r.rejected
 
-	logrus.WithFields(logrus.Fields{
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
 		"new_saved":                result.NewInvitationsSaved,
 		"resolved":                 result.Resolved,
 		"failed":                   result.Failed,
@@ -87,6 +118,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, executedActions []models.Syn
 		"members_removed":          result.MembersRemoved,
 		"roles_updated":            result.RolesUpdated,
 		"already_in_org_resolved":  result.AlreadyInOrgResolved,
+		"transitions_rejected":     result.TransitionsRejected,
 		"errors":                   len(result.Errors),
 	}).Info("🔄 Invitation reconciliation completed")
 
@@ -107,12 +139,12 @@ func (r *Reconciler) saveNewInvitations(ctx context.Context, org string, actions
 
 		if err := r.store.SaveInvitation(ctx, mapping); err != nil {
 			errMsg := fmt.Sprintf("saving invitation for %s: %v", action.Email, err)
-			logrus.WithError(err).WithField("email", action.Email).Warn("failed to save invitation mapping")
+			logrus.WithContext(ctx).WithError(err).WithField("email", action.Email).Warn("failed to save invitation mapping")
 			errs = append(errs, errMsg)
 			continue
 		}
 
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"email":         action.Email,
 			"invitation_id": *action.InvitationID,
 		}).Info("📋 Saved new invitation mapping")
@@ -149,11 +181,14 @@ func (r *Reconciler) resolvePendingWithLogin(ctx context.Context, org string) (i
 		}
 
 		if err := r.store.ResolveInvitation(ctx, org, *invite.InvitationID, *invite.Username); err != nil {
+			if r.skipRejected(ctx, err, mapping.Email) {
+				continue
+			}
 			errs = append(errs, fmt.Sprintf("resolving invitation %d: %v", *invite.InvitationID, err))
 			continue
 		}
 
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"email":         mapping.Email,
 			"github_login":  *invite.Username,
 			"invitation_id": *invite.InvitationID,
@@ -208,11 +243,14 @@ func (r *Reconciler) resolveFromAuditLog(ctx context.Context, org string) (int,
 		}
 
 		if err := r.store.ResolveInvitation(ctx, org, entry.InvitationID, entry.User); err != nil {
+			if r.skipRejected(ctx, err, mapping.Email) {
+				continue
+			}
 			errs = append(errs, fmt.Sprintf("resolving invitation %d from audit log: %v", entry.InvitationID, err))
 			continue
 		}
 
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"email":         mapping.Email,
 			"github_login":  entry.User,
 			"invitation_id": entry.InvitationID,
@@ -263,11 +301,14 @@ func (r *Reconciler) handleFailedAndExpired(ctx context.Context, org string) (in
 			}
 
 			if err := r.store.UpdateStatus(ctx, org, *invite.InvitationID, models.InvitationFailed); err != nil {
+				if r.skipRejected(ctx, err, mapping.Email) {
+					continue
+				}
 				errs = append(errs, fmt.Sprintf("marking invitation %d as failed: %v", *invite.InvitationID, err))
 				continue
 			}
 
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"email":         mapping.Email,
 				"invitation_id": *invite.InvitationID,
 			}).Warn("❌ Invitation failed")
@@ -324,11 +365,14 @@ func (r *Reconciler) handleFailedAndExpired(ctx context.Context, org string) (in
 		// If not in current pending AND not in failed → likely expired.
 		if _, isPending := pendingIDs[invID]; !isPending {
 			if err := r.store.UpdateStatus(ctx, org, invID, models.InvitationExpired); err != nil {
+				if r.skipRejected(ctx, err, mapping.Email) {
+					continue
+				}
 				errs = append(errs, fmt.Sprintf("marking invitation %d as expired: %v", invID, err))
 				continue
 			}
 
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"email":         mapping.Email,
 				"invitation_id": invID,
 				"invited_at":    mapping.InvitedAt,
@@ -340,6 +384,39 @@ func (r *Reconciler) handleFailedAndExpired(ctx context.Context, org string) (in
 	return failed, expired, errs
 }
 
+// skipRejected reports whether err is a write the store rejected because the
+// mapping no longer allows it, typically because an overlapping run or event
+// already moved it on. Such writes are counted and logged rather than reported as errors.
+func (r *Reconciler) skipRejected(ctx context.Context, err error, email string) bool {
+	var transitionErr *models.TransitionError
+	if !errors.As(err, &transitionErr) {
+		return false
+	}
+	r.rejected++
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"email":  email,
+		"sk":     transitionErr.SK,
+		"from":   transitionErr.From,
+		"to":     transitionErr.To,
+		"reason": transitionErr.Err,
+	}).Info("⏭️ Mapping update skipped: the stored mapping no longer allows it")
+	return true
+}
+
+// startStep starts the span of a reconciliation step.
+func startStep(ctx context.Context, step string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "reconcile."+step)
+}
+
+// endStep ends a step's span, marking it failed if the step reported errors.
+func endStep(span trace.Span, errs []string) {
+	if len(errs) > 0 {
+		span.SetAttributes(attribute.Int("reconcile.errors", len(errs)))
+		span.SetStatus(codes.Error, errs[0])
+	}
+	span.End()
+}
+
 // resolveRole extracts the target role from a SyncAction.
 func (r *Reconciler) resolveRole(action models.SyncAction) models.OrgRole {
 	if action.TargetRole != nil {
@@ -359,13 +436,16 @@ func (r *Reconciler) markCancelledInvitations(ctx context.Context, org string, a
 		}
 
 		if err := r.store.UpdateStatus(ctx, org, *action.InvitationID, models.InvitationCancelled); err != nil {
+			if r.skipRejected(ctx, err, action.Email) {
+				continue
+			}
 			errMsg := fmt.Sprintf("marking invitation %d as cancelled: %v", *action.InvitationID, err)
-			logrus.WithError(err).WithField("invitation_id", *action.InvitationID).Warn("failed to mark invitation as cancelled in DynamoDB")
+			logrus.WithContext(ctx).WithError(err).WithField("invitation_id", *action.InvitationID).Warn("failed to mark invitation as cancelled in DynamoDB")
 			errs = append(errs, errMsg)
 			continue
 		}
 
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"email":         action.Email,
 			"invitation_id": *action.InvitationID,
 		}).Info("🚫 Invitation cancelled and marked in DynamoDB")
@@ -390,7 +470,7 @@ func (r *Reconciler) handleRemovedMembers(ctx context.Context, org string, actio
 		mappings, err := r.store.GetByEmail(ctx, action.GoogleEmail, org)
 		if err != nil {
 			errMsg := fmt.Sprintf("looking up DynamoDB record for removed member %s (email: %s): %v", action.Email, action.GoogleEmail, err)
-			logrus.WithError(err).WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 				"username": action.Email,
 				"email":    action.GoogleEmail,
 			}).Warn("failed to look up DynamoDB record for removed member")
@@ -411,8 +491,11 @@ func (r *Reconciler) handleRemovedMembers(ctx context.Context, org string, actio
 			}
 
 			if err := r.store.UpdateStatus(ctx, org, invID, models.InvitationRemoved); err != nil {
+				if r.skipRejected(ctx, err, action.GoogleEmail) {
+					continue
+				}
 				errMsg := fmt.Sprintf("marking invitation %d as removed for %s: %v", invID, action.Email, err)
-				logrus.WithError(err).WithFields(logrus.Fields{
+				logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 					"username":      action.Email,
 					"invitation_id": invID,
 				}).Warn("failed to mark DynamoDB record as removed")
@@ -420,7 +503,7 @@ func (r *Reconciler) handleRemovedMembers(ctx context.Context, org string, actio
 				continue
 			}
 
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"username":      action.Email,
 				"email":         action.GoogleEmail,
 				"invitation_id": invID,
@@ -446,7 +529,7 @@ func (r *Reconciler) handleRoleUpdates(ctx context.Context, org string, actions
 		mappings, err := r.store.GetByEmail(ctx, action.GoogleEmail, org)
 		if err != nil {
 			errMsg := fmt.Sprintf("looking up DynamoDB record for role update %s (email: %s): %v", action.Email, action.GoogleEmail, err)
-			logrus.WithError(err).WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 				"username": action.Email,
 				"email":    action.GoogleEmail,
 			}).Warn("failed to look up DynamoDB record for role update")
@@ -467,8 +550,11 @@ func (r *Reconciler) handleRoleUpdates(ctx context.Context, org string, actions
 			}
 
 			if err := r.store.UpdateRole(ctx, org, invID, *action.TargetRole); err != nil {
+				if r.skipRejected(ctx, err, action.GoogleEmail) {
+					continue
+				}
 				errMsg := fmt.Sprintf("updating role for invitation %d to %s: %v", invID, *action.TargetRole, err)
-				logrus.WithError(err).WithFields(logrus.Fields{
+				logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 					"username":      action.Email,
 					"invitation_id": invID,
 				}).Warn("failed to update role in DynamoDB")
@@ -476,7 +562,7 @@ func (r *Reconciler) handleRoleUpdates(ctx context.Context, org string, actions
 				continue
 			}
 
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"username":      action.Email,
 				"email":         action.GoogleEmail,
 				"invitation_id": invID,
@@ -528,7 +614,7 @@ func (r *Reconciler) EnsureVerifiedEmailMappings(ctx context.Context, verifiedEm
 		existing, err := r.store.GetByEmail(ctx, email, org)
 		if err != nil {
 			errMsg := fmt.Sprintf("checking existing mapping for verified email %s: %v", email, err)
-			logrus.WithError(err).WithField("email", email).Warn("failed to check DynamoDB for verified email mapping")
+			logrus.WithContext(ctx).WithError(err).WithField("email", email).Warn("failed to check DynamoDB for verified email mapping")
 			result.Errors = append(result.Errors, errMsg)
 			continue
 		}
@@ -565,9 +651,18 @@ func (r *Reconciler) EnsureVerifiedEmailMappings(ctx context.Context, verifiedEm
 			GSI2SK:      "STATUS#" + string(models.InvitationResolved),
 		}
 
-		if err := r.store.SaveInvitation(ctx, mapping); err != nil {
+		// A removed or otherwise non-resolved EXISTING# record for this login is left as it is.
+		err = r.store.CreateMapping(ctx, mapping)
+		if errors.Is(err, models.ErrMappingExists) {
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"email":    email,
+				"username": username,
+			}).Info("⏭️ Verified email login already has a DynamoDB mapping, leaving it unchanged")
+			continue
+		}
+		if err != nil {
 			errMsg := fmt.Sprintf("saving verified email mapping for %s (%s): %v", email, username, err)
-			logrus.WithError(err).WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 				"email":    email,
 				"username": username,
 			}).Warn("failed to save verified email DynamoDB mapping")
@@ -575,7 +670,7 @@ func (r *Reconciler) EnsureVerifiedEmailMappings(ctx context.Context, verifiedEm
 			continue
 		}
 
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"email":    email,
 			"username": username,
 			"role":     desiredRole,
@@ -607,7 +702,7 @@ func (r *Reconciler) handleAlreadyInOrgMembers(ctx context.Context, org string,
 		existing, err := r.store.GetByEmail(ctx, email, org)
 		if err != nil {
 			errMsg := fmt.Sprintf("checking existing mapping for %s: %v", email, err)
-			logrus.WithError(err).WithField("email", email).Warn("failed to check existing DynamoDB mapping for already-in-org member")
+			logrus.WithContext(ctx).WithError(err).WithField("email", email).Warn("failed to check existing DynamoDB mapping for already-in-org member")
 			errs = append(errs, errMsg)
 			continue
 		}
@@ -620,7 +715,7 @@ func (r *Reconciler) handleAlreadyInOrgMembers(ctx context.Context, org string,
 			}
 		}
 		if alreadyMapped {
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
 				"email":    email,
 				"username": action.Username,
 			}).Debug("already-in-org member already has a resolved DynamoDB mapping, skipping")
@@ -653,9 +748,18 @@ func (r *Reconciler) handleAlreadyInOrgMembers(ctx context.Context, org string,
 			GSI2SK:      "STATUS#" + string(models.InvitationResolved),
 		}
 
-		if err := r.store.SaveInvitation(ctx, mapping); err != nil {
+		// A removed or otherwise non-resolved EXISTING# record for this login is left as it is.
+		err = r.store.CreateMapping(ctx, mapping)
+		if errors.Is(err, models.ErrMappingExists) {
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"email":    email,
+				"username": action.Username,
+			}).Info("⏭️ Already-in-org member already has a DynamoDB mapping, leaving it unchanged")
+			continue
+		}
+		if err != nil {
 			errMsg := fmt.Sprintf("saving already-in-org mapping for %s (%s): %v", email, action.Username, err)
-			logrus.WithError(err).WithFields(logrus.Fields{
+			logrus.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
 				"email":    email,
 				"username": action.Username,
 			}).Warn("failed to save already-in-org DynamoDB mapping")
@@ -663,7 +767,7 @@ func (r *Reconciler) handleAlreadyInOrgMembers(ctx context.Context, org string,
 			continue
 		}
 
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"email":    email,
 			"username": action.Username,
 			"role":     role,
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -234,6 +235,41 @@ func TestReconcileMarksFailedInvitations(t *testing.T) {
 	}
 }
 
+func TestReconcileSkipsRejectedTransitions(t *testing.T) {
+	failedInvID := int64(301)
+	store := &ddb.MockStore{
+		GetInvitationFunc: func(ctx context.Context, org string, invitationID int64) (*models.InvitationMapping, error) {
+			return &models.InvitationMapping{SK: "INV#301", Email: "raced@example.com", Status: models.InvitationPending}, nil
+		},
+		// An overlapping run resolved the invitation after it was read.
+		UpdateStatusFunc: func(ctx context.Context, org string, invitationID int64, status models.InvitationStatus) error {
+			return fmt.Errorf("updating invitation status: %w", &models.TransitionError{
+				SK: "INV#301", From: models.InvitationResolved, To: status, Err: models.ErrInvalidTransition,
+			})
+		},
+	}
+	ghClient := &github.MockClient{
+		ListPendingInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return nil, nil
+		},
+		GetAuditLogAddMemberEventsFunc: func(ctx context.Context, org string, afterTimestamp int64) ([]models.AuditLogEntry, error) {
+			return nil, nil
+		},
+		ListFailedInvitationsFunc: func(ctx context.Context, org string) ([]models.GitHubOrgMember, error) {
+			return []models.GitHubOrgMember{{InvitationID: &failedInvID}}, nil
+		},
+	}
+
+	r := NewReconciler(store, ghClient, reconcilerCfg())
+	result, err := r.Reconcile(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.Failed != 0 || result.TransitionsRejected != 1 || len(result.Errors) != 0 {
+		t.Fatalf("expected the rejected write to be skipped without an error, got %+v", result)
+	}
+}
+
 func TestReconcileMarksExpiredInvitations(t *testing.T) {
 	store := &ddb.MockStore{
 		GetPendingInvitationsFunc: func(ctx context.Context, org string) ([]models.InvitationMapping, error) {
@@ -847,6 +883,28 @@ func TestEnsureVerifiedEmailMappings_SkipsAlreadyMapped(t *testing.T) {
 	}
 }
 
+func TestEnsureVerifiedEmailMappings_KeepsRemovedExistingRecord(t *testing.T) {
+	store := &ddb.MockStore{
+		CreateMappingFunc: func(ctx context.Context, mapping models.InvitationMapping) error {
+			return &models.TransitionError{SK: mapping.SK, From: models.InvitationRemoved, To: mapping.Status, Err: models.ErrMappingExists}
+		},
+	}
+	r := NewReconciler(store, &github.MockClient{}, reconcilerCfg())
+
+	membersGroup := []models.GoogleGroupMember{
+		{Email: "user@company.com", Type: "USER", Status: "ACTIVE"},
+	}
+	result := &models.ReconcileResult{}
+	r.EnsureVerifiedEmailMappings(context.Background(), map[string]string{"user@company.com": "ghuser"}, membersGroup, nil, result)
+
+	if result.VerifiedEmailsMapped != 0 || len(result.Errors) != 0 {
+		t.Fatalf("expected the removed record to be skipped without an error, got %d mapped, errors %v", result.VerifiedEmailsMapped, result.Errors)
+	}
+	if len(store.SavedInvitations) != 0 {
+		t.Fatalf("expected no mapping to be written, got %+v", store.SavedInvitations)
+	}
+}
+
 func TestEnsureVerifiedEmailMappings_SkipsNonGoogleMember(t *testing.T) {
 	store := &ddb.MockStore{}
 	ghClient := &github.MockClient{}
@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+)
+
+type stopKey struct{}
+
+// WithStop returns a copy of ctx through which a run can be asked to stop: once
+// stop is closed, the run finishes the action in progress, records the actions it
+// executed and returns without starting another. Unlike cancelling ctx, stopping
+// never interrupts a call in flight.
+func WithStop(ctx context.Context, stop <-chan struct{}) context.Context {
+	return context.WithValue(ctx, stopKey{}, stop)
+}
+
+// StopRequested reports whether the run using ctx has been asked to stop.
+func StopRequested(ctx context.Context) bool {
+	stop, _ := ctx.Value(stopKey{}).(<-chan struct{})
+	if stop == nil {
+		return false
+	}
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// notStarted counts the actions a stopped run did not get to.
+func notStarted(actions []models.SyncAction) int {
+	n := 0
+	for _, action := range actions {
+		if !action.Executed && action.Error == nil && action.Type != models.ActionSkip && !action.AlreadyInOrg {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestTraceID(t *testing.T) {
+	if id := TraceID(context.Background()); id != "" {
+		t.Fatalf("expected no trace ID outside a span, got %q", id)
+	}
+
+	useRecorder(t)
+	ctx, span := Start(context.Background(), "test")
+	defer span.End()
+	if id := TraceID(ctx); id != span.SpanContext().TraceID().String() {
+		t.Fatalf("expected the span's trace ID, got %q", id)
+	}
+}
+
+func TestEndRecordsError(t *testing.T) {
+	recorder := useRecorder(t)
+	_, span := Start(context.Background(), "test")
+	End(span, errors.New("boom"))
+
+	ended := recorder.Ended()
+	if len(ended) != 1 || ended[0].Status().Code != codes.Error || ended[0].Status().Description != "boom" {
+		t.Fatalf("expected a failed span, got %+v", ended)
+	}
+}
+
+func TestLogHookAddsTraceFields(t *testing.T) {
+	useRecorder(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.AddHook(LogHook{})
+	var fired *logrus.Entry
+	logger.AddHook(captureHook{entry: &fired})
+
+	ctx, span := Start(context.Background(), "test")
+	defer span.End()
+	logger.WithContext(ctx).Info("traced")
+	if fired.Data["trace_id"] != span.SpanContext().TraceID().String() || fired.Data["span_id"] != span.SpanContext().SpanID().String() {
+		t.Fatalf("expected trace fields, got %v", fired.Data)
+	}
+
+	logger.Info("untraced")
+	if _, ok := fired.Data["trace_id"]; ok {
+		t.Fatalf("expected no trace fields without a context, got %v", fired.Data)
+	}
+}
+
+type captureHook struct{ entry **logrus.Entry }
+
+func (h captureHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h captureHook) Fire(entry *logrus.Entry) error {
+	*h.entry = entry
+	return nil
+}
+
+func TestTransportNamesSpansByEndpoint(t *testing.T) {
+	var traceparent string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer api.Close()
+
+	recorder := useRecorder(t)
+	ctx, parent := Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, api.URL+"/orgs/example-org/members", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	var found bool
+	for _, span := range recorder.Ended() {
+		if span.Name() == "GET /orgs/*/members" {
+			found = span.Parent().SpanID() == parent.SpanContext().SpanID()
+		}
+	}
+	if !found {
+		t.Fatalf("expected a request span under the parent, got %d spans", len(recorder.Ended()))
+	}
+	if traceparent != "" {
+		t.Fatalf("expected trace context not to be sent to the API, got %q", traceparent)
+	}
+}
@@ -2,26 +2,81 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	stdsync "sync"
+	"time"
 
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/daniloc96/google-workspace-github-sync/cmd"
+	"github.com/daniloc96/google-workspace-github-sync/internal/alert"
 	"github.com/daniloc96/google-workspace-github-sync/internal/config"
 	store "github.com/daniloc96/google-workspace-github-sync/internal/dynamodb"
 	"github.com/daniloc96/google-workspace-github-sync/internal/github"
 	"github.com/daniloc96/google-workspace-github-sync/internal/google"
+	"github.com/daniloc96/google-workspace-github-sync/internal/httpcache"
+	"github.com/daniloc96/google-workspace-github-sync/internal/httptrigger"
+	"github.com/daniloc96/google-workspace-github-sync/internal/interfaces"
+	"github.com/daniloc96/google-workspace-github-sync/internal/journal"
+	"github.com/daniloc96/google-workspace-github-sync/internal/metrics"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
+	"github.com/daniloc96/google-workspace-github-sync/internal/notify"
+	"github.com/daniloc96/google-workspace-github-sync/internal/postgres"
+	"github.com/daniloc96/google-workspace-github-sync/internal/ratelimit"
+	"github.com/daniloc96/google-workspace-github-sync/internal/report"
 	"github.com/daniloc96/google-workspace-github-sync/internal/secrets"
+	"github.com/daniloc96/google-workspace-github-sync/internal/sqlite"
+	"github.com/daniloc96/google-workspace-github-sync/internal/sqstrigger"
 	"github.com/daniloc96/google-workspace-github-sync/internal/sync"
+	"github.com/daniloc96/google-workspace-github-sync/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
-	cmd.SetLambdaHandler(HandleRequest)
+	cmd.SetLambdaHandler(Handle)
 	cmd.SetRunSync(runSync)
+	cmd.SetJournalOpener(openJournal)
+	cmd.SetStoreOpener(openInvitationStore)
+	cmd.SetStoreDoctor(runStoreDoctor)
+	cmd.SetMetricsHandler(prometheusHandler)
 	cmd.Execute()
 }
 
-// HandleRequest is the AWS Lambda handler.
+// Handle is the AWS Lambda entry point. HTTP requests from a function URL or an
+// API Gateway HTTP API go to HandleHTTPRequest, SQS batches to HandleSQSEvent and
+// other events to HandleRequest.
+func Handle(ctx context.Context, payload json.RawMessage) (any, error) {
+	if sqstrigger.IsEvent(payload) {
+		var event events.SQSEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decoding SQS event: %w", err)
+		}
+		return HandleSQSEvent(ctx, event)
+	}
+	if httptrigger.IsRequest(payload) {
+		var req events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("decoding HTTP request: %w", err)
+		}
+		return HandleHTTPRequest(ctx, req)
+	}
+	var event models.LambdaEvent
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decoding event: %w", err)
+		}
+	}
+	return HandleRequest(ctx, event)
+}
+
+// HandleRequest is the AWS Lambda handler for scheduled and direct invocations.
 func HandleRequest(ctx context.Context, event models.LambdaEvent) (*models.LambdaResponse, error) {
 	if event.Source != "" || event.DetailType != "" {
 		if !isScheduledEvent(event) {
@@ -39,6 +94,10 @@ func HandleRequest(ctx context.Context, event models.LambdaEvent) (*models.Lambd
 	}
 
 	result, err := runSync(ctx, cfg)
+	if errors.Is(err, models.ErrRunLocked) {
+		logrus.WithError(err).Info("⏭️ Sync skipped: another invocation is running")
+		return models.NewSkippedResponse("locked"), nil
+	}
 	if err != nil {
 		return models.NewErrorResponse(err), nil
 	}
@@ -50,47 +109,687 @@ func isScheduledEvent(event models.LambdaEvent) bool {
 	return event.Source == "aws.events" && event.DetailType == "Scheduled Event"
 }
 
-var runSync = func(ctx context.Context, cfg *config.Config) (*models.SyncResult, error) {
-	googleCreds, err := secrets.ResolveSecretValue(cfg.Google.CredentialsSecret, cfg.Google.CredentialsFile)
+// HandleHTTPRequest serves a run requested over HTTP. Failures are returned as
+// HTTP error responses rather than invocation errors.
+func HandleHTTPRequest(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	cfg, err := config.Load("")
 	if err != nil {
-		return nil, fmt.Errorf("google credentials: %w", err)
+		return httptrigger.ErrorResponse(err), nil
+	}
+	if !cfg.HTTP.Enabled {
+		return httptrigger.ErrorResponse(&httptrigger.Error{Status: http.StatusForbidden, Message: "HTTP trigger is disabled"}), nil
+	}
+
+	hmacKey, err := resolveHMACKey(ctx, cfg)
+	if err != nil {
+		return httptrigger.ErrorResponse(err), nil
+	}
+	caller, err := httptrigger.NewAuthenticator(cfg.HTTP, hmacKey).Authenticate(req)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"caller":    caller,
+			"source_ip": req.RequestContext.HTTP.SourceIP,
+			"path":      req.RawPath,
+		}).Warn("⛔ HTTP sync request rejected")
+		return httptrigger.ErrorResponse(err), nil
+	}
+
+	request, err := httptrigger.Parse(req)
+	if err != nil {
+		return httptrigger.ErrorResponse(err), nil
+	}
+	request.Apply(cfg)
+	if err := config.Validate(cfg); err != nil {
+		return httptrigger.ErrorResponse(err), nil
+	}
+	logrus.WithFields(logrus.Fields{
+		"mode":    request.Mode,
+		"user":    request.User,
+		"dry_run": cfg.Sync.DryRun,
+		"caller":  caller,
+	}).Info("🌐 Sync requested over HTTP")
+
+	result, err := runSync(ctx, cfg)
+	if errors.Is(err, models.ErrRunLocked) {
+		logrus.WithError(err).Info("⏭️ Sync skipped: another invocation is running")
+		skipped := models.NewSkippedResponse("locked")
+		skipped.StatusCode = http.StatusConflict
+		return httptrigger.JSON(http.StatusConflict, skipped), nil
+	}
+	if err != nil {
+		return httptrigger.ErrorResponse(err), nil
+	}
+	if request.Mode == httptrigger.ModePlan {
+		return httptrigger.JSON(http.StatusOK, httptrigger.NewPlan(result)), nil
+	}
+	return httptrigger.JSON(http.StatusOK, models.NewSuccessResponse(result)), nil
+}
+
+// HandleSQSEvent runs a sync limited to the user or group named by each message
+// of an SQS batch. Messages whose run fails, including runs with failed actions
+// or skipped for the run lock, are returned as batch item failures for SQS to
+// retry; a configuration error fails the whole batch.
+func HandleSQSEvent(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	cfg, err := config.Load("")
+	if err != nil {
+		return events.SQSEventResponse{}, err
+	}
+	if err := config.Validate(cfg); err != nil {
+		return events.SQSEventResponse{}, err
+	}
+
+	return sqstrigger.Process(ctx, event, func(ctx context.Context, msg sqstrigger.Message) error {
+		runCfg := *cfg
+		msg.Apply(&runCfg)
+		result, err := runSync(ctx, &runCfg)
+		if err != nil {
+			return err
+		}
+		if failed := result.Summary.ActionsFailed; failed > 0 {
+			return fmt.Errorf("%d of %d actions failed", failed, result.Summary.ActionsPlanned)
+		}
+		return nil
+	}), nil
+}
+
+// resolveHMACKey returns the HTTP trigger's HMAC key, resolving its secret
+// reference if needed. It is empty unless requests are HMAC-signed.
+func resolveHMACKey(ctx context.Context, cfg *config.Config) (string, error) {
+	if cfg.HTTP.Auth != config.HTTPAuthHMAC || cfg.HTTP.HMACKey != "" {
+		return cfg.HTTP.HMACKey, nil
+	}
+	key, err := sharedSecrets(cfg.Secrets).Resolve(ctx, cfg.HTTP.HMACKeySecret)
+	if err != nil {
+		return "", fmt.Errorf("resolving HTTP HMAC key: %w", err)
+	}
+	return strings.TrimSpace(key), nil
+}
+
+var runSync = func(ctx context.Context, cfg *config.Config) (result *models.SyncResult, err error) {
+	if cfg.Tracing.Enabled {
+		if provider := sharedTracerProvider(ctx, cfg.Tracing); provider != nil {
+			defer flushTraces(ctx, provider)
+		}
+	}
+
+	recorder := newMetricsRecorder(ctx, cfg)
+	if len(recorder) > 0 {
+		defer func() { recordRunMetrics(ctx, recorder, cfg, result, err) }()
+	}
+	if notifier := newNotifier(ctx, cfg); notifier.Len() > 0 {
+		defer func() { notifyRun(ctx, notifier, cfg, result, err) }()
+	}
+
+	var dynamoStore *store.Store
+	if cfg.DynamoDB.Enabled {
+		var storeErr error
+		dynamoStore, storeErr = store.NewStore(ctx, cfg.DynamoDB)
+		if storeErr != nil {
+			logrus.WithError(storeErr).Warn("⚠ DynamoDB store init failed — DynamoDB-backed features disabled")
+			dynamoStore = nil
+		}
 	}
 
-	githubToken := cfg.GitHub.Token
-	if githubToken == "" {
-		token, tokenErr := secrets.ResolveSecretValue(cfg.GitHub.TokenSecret, "")
-		if tokenErr != nil {
-			return nil, fmt.Errorf("github token: %w", tokenErr)
+	var invitationStore interfaces.InvitationStore
+	if cfg.StoreEnabled() {
+		var closeStore func()
+		var storeErr error
+		invitationStore, closeStore, storeErr = newInvitationStore(ctx, cfg, dynamoStore)
+		if storeErr != nil {
+			logrus.WithError(storeErr).Warn("⚠ Invitation store init failed — invitation reconciliation disabled")
+		} else {
+			defer closeStore()
 		}
-		githubToken = token
+	}
+	// Registered after closeStore so that alerts are evaluated while the store is open.
+	if evaluator := newAlertEvaluator(ctx, cfg, invitationStore); evaluator != nil {
+		defer func() { evaluateAlerts(ctx, evaluator, cfg, result, err) }()
+	}
+	// Registered last so that the report is archived, and its location set on the
+	// result, before the run is reported anywhere else.
+	if archiver := newReportArchiver(ctx, cfg); archiver != nil {
+		defer func() { archiveReport(ctx, archiver, cfg, result, err) }()
 	}
 
-	googleClient, err := google.NewClient(ctx, []byte(googleCreds), cfg.Google.AdminEmail)
+	githubToken, err := resolveGitHubToken(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
-	githubClient, err := github.NewClient(githubToken)
+
+	// Transport chain (outermost first): auth → tracing → rate-limit budget → API
+	// latency metrics → HTTP cache → network.
+	var base http.RoundTripper
+	if cacheTransport := newCacheTransport(cfg, dynamoStore); cacheTransport != nil {
+		base = cacheTransport
+		defer func() {
+			logrus.WithFields(logrus.Fields{
+				"backend": cfg.Cache.Backend,
+				"stats":   cacheTransport.Stats(),
+			}).Info("🗄️ HTTP cache usage")
+		}()
+	}
+	if cfg.Metrics.HasBackend(config.MetricsBackendPrometheus) {
+		base = metrics.Transport(base, recorder)
+	}
+	budget := ratelimit.NewManager(cfg.RateLimit.LowPriorityReserve, time.Duration(cfg.RateLimit.MaxWaitSeconds)*time.Second)
+	transport := budget.Transport(base)
+	if cfg.Tracing.Enabled {
+		transport = tracing.Transport(transport)
+	}
+	googleOpts := []google.ClientOption{google.WithTransport(transport)}
+	githubOpts := []github.ClientOption{github.WithTransport(transport)}
+
+	googleClient, err := newGoogleClient(ctx, cfg, googleOpts...)
+	if err != nil {
+		return nil, err
+	}
+	githubClient, err := github.NewClient(githubToken, githubOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	engine := sync.NewEngine(googleClient, githubClient, cfg)
 
-	// Initialize invitation reconciliation if DynamoDB is enabled.
-	if cfg.DynamoDB.Enabled {
-		dynamoStore, storeErr := store.NewStore(ctx, cfg.DynamoDB)
-		if storeErr != nil {
-			logrus.WithError(storeErr).Warn("⚠ DynamoDB store init failed — invitation reconciliation disabled")
-		} else {
-			reconciler := sync.NewReconciler(dynamoStore, githubClient, cfg)
-			engine.SetReconciler(reconciler)
+	// Initialize invitation reconciliation if an invitation store is configured.
+	if invitationStore != nil {
+		engine.SetReconciler(sync.NewReconciler(invitationStore, githubClient, cfg))
+		logInvitationStore(cfg)
+		if lock, ok := invitationStore.(interfaces.RunLock); ok && cfg.Lock.Enabled {
+			engine.SetRunLock(lock, runLockOwner(ctx), time.Duration(cfg.Lock.TTLSeconds)*time.Second)
+		}
+	}
+
+	if cfg.Journal.Enabled {
+		actionJournal, journalErr := newJournal(cfg, dynamoStore)
+		if journalErr != nil {
+			return nil, fmt.Errorf("action journal: %w", journalErr)
+		}
+		engine.SetJournal(actionJournal, journalActor(ctx, githubClient))
+		logrus.WithField("backend", cfg.Journal.Backend).Info("✅ Action journal enabled")
+	}
+
+	result, err = engine.Sync(ctx)
+	if result != nil {
+		result.APICalls = budget.APICalls()
+		result.RateLimits = budget.Usage()
+		for resource, usage := range result.RateLimits {
 			logrus.WithFields(logrus.Fields{
-				"table":    cfg.DynamoDB.TableName,
-				"region":   cfg.DynamoDB.Region,
-				"ttl_days": cfg.DynamoDB.TTLDays,
-			}).Info("✅ Invitation reconciliation enabled (DynamoDB)")
+				"resource":  resource,
+				"requests":  usage.Requests,
+				"remaining": usage.Remaining,
+				"limit":     usage.Limit,
+				"deferred":  usage.Deferred,
+				"waited_ms": usage.WaitedMs,
+			}).Info("📊 API rate-limit usage")
+		}
+		if dynamoStore != nil {
+			result.StoreQueries = dynamoStore.QueryStats()
+			for op, stats := range result.StoreQueries {
+				logrus.WithFields(logrus.Fields{
+					"operation":  op,
+					"queries":    stats.Queries,
+					"pages":      stats.Pages,
+					"max_pages":  stats.MaxPages,
+					"items":      stats.Items,
+					"read_units": stats.ReadUnits,
+				}).Info("📊 DynamoDB query usage")
+			}
 		}
 	}
+	return result, err
+}
 
-	return engine.Sync(ctx)
+// newMetricsRecorder returns a recorder for every configured metrics backend.
+// Backend setup failures are non-fatal: the sync runs without those metrics.
+func newMetricsRecorder(ctx context.Context, cfg *config.Config) metrics.Multi {
+	if !cfg.Metrics.Enabled {
+		return nil
+	}
+	var recorder metrics.Multi
+	for _, backend := range cfg.Metrics.Backends {
+		switch backend {
+		case config.MetricsBackendCloudWatch:
+			emitter, err := metrics.New(ctx, cfg.Metrics)
+			if err != nil {
+				logrus.WithError(err).Warn("⚠ CloudWatch metrics init failed — CloudWatch metrics disabled")
+				continue
+			}
+			recorder = append(recorder, emitter)
+		case config.MetricsBackendPrometheus:
+			recorder = append(recorder, sharedPrometheus(cfg.Metrics.Prometheus))
+		}
+	}
+	return recorder
+}
+
+var (
+	prometheusOnce     stdsync.Once
+	prometheusRecorder *metrics.Prometheus
+)
+
+// sharedPrometheus returns the process's Prometheus backend. It is created, and
+// /metrics served, on first use so that every run in a long-running process adds
+// to the same counters. When prometheusHandler created it first, the serve
+// command already serves /metrics.
+func sharedPrometheus(cfg config.PrometheusConfig) *metrics.Prometheus {
+	prometheusOnce.Do(func() {
+		prometheusRecorder = metrics.NewPrometheus()
+		if err := prometheusRecorder.Serve(cfg.ListenAddress); err != nil {
+			logrus.WithError(err).Warn("⚠ Prometheus metrics endpoint unavailable")
+			return
+		}
+		logrus.WithField("address", cfg.ListenAddress).Info("📈 Serving Prometheus metrics at /metrics")
+	})
+	return prometheusRecorder
+}
+
+// prometheusHandler returns the /metrics handler of the process's Prometheus
+// backend when it shares its listen address with the serve command, which then
+// mounts it next to its health endpoints. It returns nil otherwise.
+func prometheusHandler(cfg *config.Config) http.Handler {
+	if !cfg.Metrics.HasBackend(config.MetricsBackendPrometheus) || cfg.Metrics.Prometheus.ListenAddress != cfg.Serve.ListenAddress {
+		return nil
+	}
+	prometheusOnce.Do(func() {
+		prometheusRecorder = metrics.NewPrometheus()
+	})
+	return prometheusRecorder.Handler()
+}
+
+var (
+	secretsOnce    stdsync.Once
+	secretResolver *secrets.Resolver
+)
+
+// sharedSecrets returns the process's secret resolver, so that resolved secrets
+// are reused across warm Lambda invocations and scheduled runs until their TTL.
+func sharedSecrets(cfg config.SecretsConfig) *secrets.Resolver {
+	secretsOnce.Do(func() {
+		secretResolver = secrets.NewResolver(cfg)
+	})
+	return secretResolver
+}
+
+var (
+	tracingOnce    stdsync.Once
+	tracerProvider *sdktrace.TracerProvider
+)
+
+// sharedTracerProvider installs the process's OpenTelemetry tracer provider on
+// first use and adds trace IDs to log entries that carry a traced context.
+// It returns nil if the exporter cannot be set up; the sync then runs untraced.
+func sharedTracerProvider(ctx context.Context, cfg config.TracingConfig) *sdktrace.TracerProvider {
+	tracingOnce.Do(func() {
+		provider, err := tracing.NewProvider(ctx, cfg)
+		if err != nil {
+			logrus.WithError(err).Warn("⚠ Tracing init failed — tracing disabled")
+			return
+		}
+		otel.SetTracerProvider(provider)
+		logrus.AddHook(tracing.LogHook{})
+		tracerProvider = provider
+		logrus.WithFields(logrus.Fields{
+			"service":      cfg.ServiceName,
+			"sample_ratio": cfg.SampleRatio,
+		}).Info("✅ OpenTelemetry tracing enabled")
+	})
+	return tracerProvider
+}
+
+// flushTraces exports the run's spans before returning, as a Lambda environment
+// may be frozen as soon as the invocation ends.
+func flushTraces(ctx context.Context, provider *sdktrace.TracerProvider) {
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := provider.ForceFlush(flushCtx); err != nil {
+		logrus.WithError(err).Warn("⚠ Could not export trace spans (non-fatal)")
+	}
+}
+
+// recordRunMetrics records a run with every metrics backend. Runs skipped because
+// another invocation holds the run lock are not reported; that invocation reports
+// its own.
+func recordRunMetrics(ctx context.Context, recorder metrics.Recorder, cfg *config.Config, result *models.SyncResult, runErr error) {
+	if errors.Is(runErr, models.ErrRunLocked) {
+		return
+	}
+	run := metrics.Run{Org: cfg.GitHub.Organization, DryRun: cfg.Sync.DryRun, Result: result, Err: runErr}
+	if err := recorder.RecordRun(ctx, run); err != nil {
+		logrus.WithError(err).Warn("⚠ Could not record run metrics (non-fatal)")
+		return
+	}
+	logrus.WithField("backends", cfg.Metrics.Backends).Debug("Run metrics recorded")
+}
+
+// newNotifier returns a notifier with every enabled sink. A sink whose URL cannot
+// be resolved is left out; the sync runs without it.
+func newNotifier(ctx context.Context, cfg *config.Config) *notify.Notifier {
+	notifier := notify.New()
+	for _, sink := range []struct {
+		name string
+		cfg  config.NotifySinkConfig
+		new  func(url string) notify.Sink
+	}{
+		{"slack", cfg.Notify.Slack, func(url string) notify.Sink { return notify.NewSlack(url) }},
+		{"webhook", cfg.Notify.Webhook, func(url string) notify.Sink { return notify.NewWebhook(url) }},
+	} {
+		if !sink.cfg.Enabled {
+			continue
+		}
+		if url, ok := notifySinkURL(ctx, cfg, sink.name, sink.cfg); ok {
+			notifier.Add(sink.name, sink.new(url), sink.cfg.Trigger)
+		}
+	}
+	return notifier
+}
+
+// notifySinkURL returns the sink's URL, resolving its secret reference if needed.
+func notifySinkURL(ctx context.Context, cfg *config.Config, name string, sink config.NotifySinkConfig) (string, bool) {
+	if sink.URL != "" {
+		return sink.URL, true
+	}
+	url, err := sharedSecrets(cfg.Secrets).Resolve(ctx, sink.URLSecret)
+	if err != nil {
+		logrus.WithError(err).WithField("sink", name).Warn("⚠ Notification URL unavailable — sink disabled")
+		return "", false
+	}
+	return strings.TrimSpace(url), true
+}
+
+// newAlertEvaluator returns the alert evaluator, or nil if alerting is disabled or
+// the invitation store, which keeps the alert state, is unavailable.
+func newAlertEvaluator(ctx context.Context, cfg *config.Config, invitationStore interfaces.InvitationStore) *alert.Evaluator {
+	if !cfg.Alerts.Enabled {
+		return nil
+	}
+	alertStore, ok := invitationStore.(interfaces.AlertStateStore)
+	if !ok {
+		logrus.Warn("⚠ Alerting needs the invitation store — alerting disabled")
+		return nil
+	}
+	evaluator := alert.New(cfg.Alerts, alertStore)
+	for _, sink := range []struct {
+		name string
+		cfg  config.NotifySinkConfig
+		new  func(url string) notify.AlertSink
+	}{
+		{config.AlertSinkSlack, cfg.Notify.Slack, func(url string) notify.AlertSink { return notify.NewSlack(url) }},
+		{config.AlertSinkWebhook, cfg.Notify.Webhook, func(url string) notify.AlertSink { return notify.NewWebhook(url) }},
+	} {
+		if !cfg.Alerts.HasSink(sink.name) {
+			continue
+		}
+		if url, ok := notifySinkURL(ctx, cfg, sink.name, sink.cfg); ok {
+			evaluator.AddSink(sink.name, sink.new(url))
+		}
+	}
+	return evaluator
+}
+
+// evaluateAlerts evaluates the alert rules against the run. Runs skipped because
+// another invocation holds the run lock are not evaluated.
+func evaluateAlerts(ctx context.Context, evaluator *alert.Evaluator, cfg *config.Config, result *models.SyncResult, runErr error) {
+	if errors.Is(runErr, models.ErrRunLocked) {
+		return
+	}
+	run := alert.Run{Org: cfg.GitHub.Organization, Result: result, Err: runErr}
+	if err := evaluator.Evaluate(ctx, run); err != nil {
+		logrus.WithError(err).Warn("⚠ Could not evaluate or send alerts (non-fatal)")
+	}
+}
+
+// newReportArchiver returns the run report archiver, or nil if reports are
+// disabled or the report store cannot be created.
+func newReportArchiver(ctx context.Context, cfg *config.Config) *report.Archiver {
+	if !cfg.Report.Enabled {
+		return nil
+	}
+	reportStore, err := report.NewStore(ctx, cfg.Report)
+	if err != nil {
+		logrus.WithError(err).Warn("⚠ Report store init failed — run reports disabled")
+		return nil
+	}
+	return report.NewArchiver(reportStore, cfg.Report.Formats, cfg.Report.RetentionDays)
+}
+
+// archiveReport renders and archives the run report and records where it went on
+// the result. Runs skipped because another invocation holds the run lock are not
+// reported.
+func archiveReport(ctx context.Context, archiver *report.Archiver, cfg *config.Config, result *models.SyncResult, runErr error) {
+	if errors.Is(runErr, models.ErrRunLocked) {
+		return
+	}
+	run := report.Run{Org: cfg.GitHub.Organization, DryRun: cfg.Sync.DryRun, Result: result, Err: runErr}
+	locations, err := archiver.Archive(ctx, run)
+	if len(locations) > 0 {
+		if result != nil {
+			result.Report = locations
+		}
+		logrus.WithField("locations", locations).Info("📄 Run report archived")
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("⚠ Could not archive run report (non-fatal)")
+	}
+}
+
+// notifyRun sends the run summary to the notification sinks. Runs skipped because
+// another invocation holds the run lock are not reported.
+func notifyRun(ctx context.Context, notifier *notify.Notifier, cfg *config.Config, result *models.SyncResult, runErr error) {
+	if errors.Is(runErr, models.ErrRunLocked) {
+		return
+	}
+	run := notify.Run{Org: cfg.GitHub.Organization, DryRun: cfg.Sync.DryRun, Result: result, Err: runErr}
+	if err := notifier.Notify(ctx, run); err != nil {
+		logrus.WithError(err).Warn("⚠ Could not send run notification (non-fatal)")
+	}
+}
+
+// resolveGitHubToken returns the configured GitHub token, resolving its secret reference if needed.
+func resolveGitHubToken(ctx context.Context, cfg *config.Config) (string, error) {
+	if cfg.GitHub.Token != "" {
+		return cfg.GitHub.Token, nil
+	}
+	token, err := sharedSecrets(cfg.Secrets).Resolve(ctx, cfg.GitHub.TokenSecret)
+	if err != nil {
+		return "", fmt.Errorf("github token: %w", err)
+	}
+	return token, nil
+}
+
+// runStoreDoctor cross-checks the configured invitation store against GitHub
+// and, if repair is set, fixes what it can.
+func runStoreDoctor(ctx context.Context, cfg *config.Config, repair bool) (*models.DoctorReport, error) {
+	githubToken, err := resolveGitHubToken(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	budget := ratelimit.NewManager(cfg.RateLimit.LowPriorityReserve, time.Duration(cfg.RateLimit.MaxWaitSeconds)*time.Second)
+	githubClient, err := github.NewClient(githubToken, github.WithTransport(budget.Transport(nil)))
+	if err != nil {
+		return nil, err
+	}
+	invitationStore, closeStore, err := openInvitationStore(ctx, cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore()
+
+	doctor := sync.NewDoctor(invitationStore, githubClient, cfg)
+	report, err := doctor.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if repair {
+		doctor.Repair(ctx, report)
+	}
+	return report, nil
+}
+
+// newInvitationStore returns the configured invitation store and a function that releases it.
+func newInvitationStore(ctx context.Context, cfg *config.Config, dynamoStore *store.Store) (interfaces.InvitationStore, func(), error) {
+	switch cfg.Store.Backend {
+	case config.StoreBackendSQLite:
+		sqliteStore, err := sqlite.NewStore(ctx, cfg.Store.SQLite)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sqliteStore, func() { _ = sqliteStore.Close() }, nil
+	case config.StoreBackendPostgres:
+		pgCfg := cfg.Store.Postgres
+		if pgCfg.DSN == "" {
+			dsn, err := sharedSecrets(cfg.Secrets).Resolve(ctx, pgCfg.DSNSecret)
+			if err != nil {
+				return nil, nil, fmt.Errorf("postgres dsn: %w", err)
+			}
+			pgCfg.DSN = dsn
+		}
+		pgStore, err := postgres.NewStore(ctx, pgCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pgStore, func() { _ = pgStore.Close() }, nil
+	default:
+		if dynamoStore == nil {
+			return nil, nil, fmt.Errorf("DynamoDB store is not available")
+		}
+		return dynamoStore, func() {}, nil
+	}
+}
+
+// openInvitationStore opens an invitation store for the store CLI commands.
+// An empty backend selects the configured one.
+func openInvitationStore(ctx context.Context, cfg *config.Config, backend string) (interfaces.InvitationStore, func(), error) {
+	storeCfg := *cfg
+	if backend != "" {
+		storeCfg.Store.Backend = backend
+	}
+	switch storeCfg.Store.Backend {
+	case "", config.StoreBackendDynamoDB:
+		dynamoStore, err := store.NewStore(ctx, storeCfg.DynamoDB)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newInvitationStore(ctx, &storeCfg, dynamoStore)
+	case config.StoreBackendSQLite, config.StoreBackendPostgres:
+		return newInvitationStore(ctx, &storeCfg, nil)
+	default:
+		return nil, nil, fmt.Errorf("unknown store backend %q", backend)
+	}
+}
+
+func logInvitationStore(cfg *config.Config) {
+	switch cfg.Store.Backend {
+	case config.StoreBackendSQLite:
+		logrus.WithFields(logrus.Fields{
+			"path":     cfg.Store.SQLite.Path,
+			"ttl_days": cfg.Store.SQLite.TTLDays,
+		}).Info("✅ Invitation reconciliation enabled (SQLite)")
+	case config.StoreBackendPostgres:
+		logrus.WithField("ttl_days", cfg.Store.Postgres.TTLDays).Info("✅ Invitation reconciliation enabled (Postgres)")
+	default:
+		logrus.WithFields(logrus.Fields{
+			"table":    cfg.DynamoDB.TableName,
+			"region":   cfg.DynamoDB.Region,
+			"ttl_days": cfg.DynamoDB.TTLDays,
+		}).Info("✅ Invitation reconciliation enabled (DynamoDB)")
+	}
+}
+
+// newCacheTransport returns the HTTP conditional-request cache transport, or nil if disabled.
+// Cache setup failures are non-fatal: the sync runs uncached.
+func newCacheTransport(cfg *config.Config, dynamoStore *store.Store) *httpcache.Transport {
+	if !cfg.Cache.Enabled {
+		return nil
+	}
+	switch cfg.Cache.Backend {
+	case config.CacheBackendDynamoDB:
+		if dynamoStore == nil {
+			logrus.Warn("⚠ HTTP cache needs the DynamoDB store — caching disabled")
+			return nil
+		}
+		return httpcache.NewTransport(dynamoStore.HTTPCache(), nil)
+	default:
+		fileStore, err := httpcache.NewFileStore(cfg.Cache.Directory)
+		if err != nil {
+			logrus.WithError(err).Warn("⚠ HTTP cache init failed — caching disabled")
+			return nil
+		}
+		return httpcache.NewTransport(fileStore, nil)
+	}
+}
+
+// newJournal returns the configured action journal.
+func newJournal(cfg *config.Config, dynamoStore *store.Store) (interfaces.ActionJournal, error) {
+	switch cfg.Journal.Backend {
+	case config.JournalBackendDynamoDB:
+		if dynamoStore == nil {
+			return nil, fmt.Errorf("journal backend dynamodb needs the DynamoDB store")
+		}
+		return dynamoStore.Journal(), nil
+	default:
+		return journal.NewFileJournal(cfg.Journal.Path)
+	}
+}
+
+// openJournal opens the configured action journal for the journal CLI command.
+func openJournal(ctx context.Context, cfg *config.Config) (interfaces.ActionJournal, error) {
+	var dynamoStore *store.Store
+	if cfg.Journal.Backend == config.JournalBackendDynamoDB {
+		var err error
+		dynamoStore, err = store.NewStore(ctx, cfg.DynamoDB)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newJournal(cfg, dynamoStore)
+}
+
+// journalActor identifies the GitHub token owner for journal entries.
+// App installation tokens have no user, so they are recorded as "github-app".
+func journalActor(ctx context.Context, githubClient *github.Client) string {
+	login, err := githubClient.AuthenticatedLogin(ctx)
+	if err != nil {
+		logrus.WithError(err).Debug("could not resolve token owner for the action journal")
+		if github.StatusCode(err) == http.StatusForbidden {
+			return "github-app"
+		}
+		return "unknown"
+	}
+	return login
+}
+
+// runLockOwner identifies this process as a run lock holder: the Lambda request
+// ID when invoked by Lambda, otherwise the host and process ID.
+func runLockOwner(ctx context.Context) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		return "lambda:" + lc.AwsRequestID
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+// newGoogleClient builds the Google client for the configured auth mode.
+func newGoogleClient(ctx context.Context, cfg *config.Config, opts ...google.ClientOption) (*google.Client, error) {
+	if cfg.Google.IsKeyless() {
+		// An optional external account configuration (workload identity federation);
+		// without one, Application Default Credentials are used.
+		var externalAccount []byte
+		if cfg.Google.CredentialsSecret != "" || cfg.Google.CredentialsFile != "" {
+			value, err := sharedSecrets(cfg.Secrets).ResolveSecretValue(ctx, cfg.Google.CredentialsSecret, cfg.Google.CredentialsFile)
+			if err != nil {
+				return nil, fmt.Errorf("google external account config: %w", err)
+			}
+			externalAccount = []byte(value)
+		}
+		return google.NewKeylessClient(ctx, cfg.Google.ServiceAccount, cfg.Google.AdminEmail, externalAccount, opts...)
+	}
+
+	googleCreds, err := sharedSecrets(cfg.Secrets).ResolveSecretValue(ctx, cfg.Google.CredentialsSecret, cfg.Google.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("google credentials: %w", err)
+	}
+	return google.NewClient(ctx, []byte(googleCreds), cfg.Google.AdminEmail, opts...)
 }
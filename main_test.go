@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/daniloc96/google-workspace-github-sync/internal/config"
+	"github.com/daniloc96/google-workspace-github-sync/internal/httptrigger"
 	"github.com/daniloc96/google-workspace-github-sync/internal/models"
 )
 
@@ -111,3 +116,135 @@ func TestHandleRequestScheduledEvent(t *testing.T) {
 		t.Fatalf("expected status 200, got %d (%s)", resp.StatusCode, resp.Message)
 	}
 }
+
+func setTriggerTestEnv(t *testing.T) {
+	t.Setenv("GOOGLE_ADMIN_EMAIL", "admin@example.com")
+	t.Setenv("GOOGLE_MEMBERS_GROUP", "members@example.com")
+	t.Setenv("GOOGLE_OWNERS_GROUP", "owners@example.com")
+	t.Setenv("GOOGLE_CREDENTIALS_FILE", "/tmp/creds.json")
+	t.Setenv("GITHUB_ORG", "example-org")
+	t.Setenv("GITHUB_TOKEN", "ghp_test")
+	t.Setenv("HTTP_ENABLED", "true")
+	t.Setenv("HTTP_AUTH", "hmac")
+	t.Setenv("HTTP_HMAC_KEY", "s3cret")
+	os.Unsetenv("AWS_LAMBDA_FUNCTION_NAME")
+}
+
+func signedHTTPRequest(key, method, path, query string) events.APIGatewayV2HTTPRequest {
+	req := events.APIGatewayV2HTTPRequest{Version: "2.0", RawPath: path, RawQueryString: query}
+	req.RequestContext.Stage = "$default"
+	req.RequestContext.HTTP.Method = method
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Headers = map[string]string{
+		"x-sync-timestamp": timestamp,
+		"x-sync-signature": httptrigger.Sign(key, timestamp, method, path, query, nil),
+	}
+	return req
+}
+
+func TestHandleHTTPRequestSingleUser(t *testing.T) {
+	originalRunSync := runSync
+	defer func() { runSync = originalRunSync }()
+	setTriggerTestEnv(t)
+
+	var got *config.Config
+	runSync = func(ctx context.Context, cfg *config.Config) (*models.SyncResult, error) {
+		got = cfg
+		return &models.SyncResult{DryRun: cfg.Sync.DryRun, Scope: cfg.Sync.OnlyUsers, Summary: models.SyncSummary{ActionsPlanned: 1}}, nil
+	}
+
+	resp, err := HandleHTTPRequest(context.Background(), signedHTTPRequest("s3cret", "POST", "/sync/users/jane%40example.com", "dry_run=false"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d (%s)", resp.StatusCode, resp.Body)
+	}
+	if got == nil || got.Sync.DryRun || len(got.Sync.OnlyUsers) != 1 || got.Sync.OnlyUsers[0] != "jane@example.com" {
+		t.Fatalf("expected a real run limited to jane, got %+v", got)
+	}
+	var body models.LambdaResponse
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil || body.Result == nil || len(body.Result.Scope) != 1 {
+		t.Fatalf("expected the run result in the body, got %s", resp.Body)
+	}
+}
+
+func TestHandleHTTPRequestRejectsBadSignature(t *testing.T) {
+	originalRunSync := runSync
+	defer func() { runSync = originalRunSync }()
+	setTriggerTestEnv(t)
+
+	runSync = func(ctx context.Context, cfg *config.Config) (*models.SyncResult, error) {
+		t.Fatalf("expected no run for an unauthenticated request")
+		return nil, nil
+	}
+
+	resp, _ := HandleHTTPRequest(context.Background(), signedHTTPRequest("wrong-key", "POST", "/sync", ""))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d (%s)", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestHandleDispatchesHTTPRequests(t *testing.T) {
+	originalRunSync := runSync
+	defer func() { runSync = originalRunSync }()
+	setTriggerTestEnv(t)
+
+	runSync = func(ctx context.Context, cfg *config.Config) (*models.SyncResult, error) {
+		if !cfg.Sync.DryRun {
+			t.Fatalf("expected plan requests to be dry runs")
+		}
+		return &models.SyncResult{DryRun: true, Actions: []models.SyncAction{{Type: models.ActionInvite, Email: "jane@example.com"}}}, nil
+	}
+
+	payload, _ := json.Marshal(signedHTTPRequest("s3cret", "GET", "/plan", ""))
+	out, err := Handle(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp, ok := out.(events.APIGatewayV2HTTPResponse)
+	if !ok || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected an HTTP response, got %#v", out)
+	}
+	var plan httptrigger.Plan
+	if err := json.Unmarshal([]byte(resp.Body), &plan); err != nil || len(plan.Actions) != 1 {
+		t.Fatalf("expected the plan in the body, got %s", resp.Body)
+	}
+
+	out, err = Handle(context.Background(), json.RawMessage(`{"source":"aws.events","detail-type":"Scheduled Event"}`))
+	if _, ok := out.(*models.LambdaResponse); err != nil || !ok {
+		t.Fatalf("expected scheduled events to reach HandleRequest, got %#v, %v", out, err)
+	}
+}
+
+func TestHandleSQSEvent(t *testing.T) {
+	originalRunSync := runSync
+	defer func() { runSync = originalRunSync }()
+	setTriggerTestEnv(t)
+
+	var scopes [][]string
+	runSync = func(ctx context.Context, cfg *config.Config) (*models.SyncResult, error) {
+		scopes = append(scopes, append(cfg.Sync.OnlyUsers, cfg.Sync.OnlyGroups...))
+		result := &models.SyncResult{Summary: models.SyncSummary{ActionsPlanned: 1}}
+		if len(cfg.Sync.OnlyGroups) > 0 {
+			result.Summary.ActionsFailed = 1
+		}
+		return result, nil
+	}
+
+	payload := `{"Records":[
+		{"messageId":"jane","eventSource":"aws:sqs","body":"{\"user\":\"jane@example.com\",\"dry_run\":false}"},
+		{"messageId":"new-hires","eventSource":"aws:sqs","body":"{\"group\":\"new-hires@example.com\"}"}
+	]}`
+	out, err := Handle(context.Background(), json.RawMessage(payload))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	resp, ok := out.(events.SQSEventResponse)
+	if !ok || len(resp.BatchItemFailures) != 1 || resp.BatchItemFailures[0].ItemIdentifier != "new-hires" {
+		t.Fatalf("expected the message with failed actions to be retried, got %#v", out)
+	}
+	if len(scopes) != 2 || scopes[0][0] != "jane@example.com" || scopes[1][0] != "new-hires@example.com" {
+		t.Fatalf("expected a run per message limited to it, got %v", scopes)
+	}
+}